@@ -0,0 +1,325 @@
+// tracectl - 저장된 trace를 SQL 없이 조회하는 운영용 CLI
+//
+//	tracectl tail [-path-prefix /api] [-min-status 500]
+//	tracectl get <trace-id>
+//	tracectl top --since 1h
+//	tracectl export -format parquet -o steps.parquet --since 24h
+//	tracectl export -format xlsx -o steps.xlsx --since 24h
+//	tracectl import -format combined /var/log/nginx/access.log
+//
+// 저장소 연결은 서버와 같은 TRACE_ 환경 변수(TRACE_DSN, TRACE_TABLE_NAME 등) 또는 -config YAML 파일을 사용한다
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"trace/internal/trace"
+	_ "trace/internal/trace/xlsx"
+)
+
+const usage = `Usage: tracectl [-config file] [-dsn dsn] <command> [flags]
+
+Commands:
+  tail               새로 저장되는 Step을 계속 출력
+  get <trace-id>     Trace ID의 모든 Step을 시간순으로 출력
+  top                최근 요청 수 기준 상위 엔드포인트 출력
+  export             조건에 맞는 Step을 CSV, NDJSON, Parquet, xlsx로 내보내기
+  import [file...]   기존 접근 로그(combined, JSON lines)를 과거 Step으로 저장
+
+각 명령의 플래그는 "tracectl <command> -h"로 확인하세요.
+`
+
+func main() {
+	flags := flag.NewFlagSet("tracectl", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), usage) }
+	configPath := flags.String("config", "", "YAML 설정 파일 (없으면 TRACE_ 환경 변수)")
+	dsn := flags.String("dsn", "", "저장소 DSN (설정의 DSN보다 우선, 기본 sqlite://trace.db)")
+	_ = flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store, cfg, err := connect(*configPath, *dsn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tracectl:", err)
+		os.Exit(1)
+	}
+
+	cmd, args := flags.Arg(0), flags.Args()[1:]
+	switch cmd {
+	case "tail":
+		err = runTail(ctx, store, args)
+	case "get":
+		err = runGet(ctx, store, args)
+	case "top":
+		err = runTop(ctx, store, args)
+	case "export":
+		err = runExport(ctx, store, args)
+	case "import":
+		err = runImport(ctx, store, cfg, args)
+	default:
+		fmt.Fprintf(os.Stderr, "tracectl: unknown command %q\n\n", cmd)
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "tracectl:", err)
+		os.Exit(1)
+	}
+}
+
+// connect - 서버와 같은 설정(테이블 이름, 암호화 키)으로 저장소에 연결
+func connect(configPath, dsn string) (*trace.Store, trace.Config, error) {
+	cfg, err := trace.ConfigFromEnv()
+	if configPath != "" {
+		cfg, err = trace.ConfigFromFile(configPath)
+	}
+	if err != nil {
+		return nil, cfg, err
+	}
+
+	trace.RegisterDialector("sqlite", func(dsn string) gorm.Dialector {
+		return sqlite.Open(strings.TrimPrefix(dsn, "sqlite://"))
+	})
+	db, err := trace.OpenDSN(cmp.Or(dsn, cfg.DSN, "sqlite://trace.db"))
+	if err != nil {
+		return nil, cfg, err
+	}
+	store, err := trace.NewStore(db, cfg)
+	return store, cfg, err
+}
+
+// runTail - 저장소를 주기적으로 조회해 새로 저장된 Step 출력
+// Step은 flush 주기만큼 늦게 저장되므로 최근 lag 구간을 다시 조회하고 이미 출력한 Step은 건너뛴다
+func runTail(ctx context.Context, store *trace.Store, args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ExitOnError)
+	var filter trace.QueryFilter
+	flags.StringVar(&filter.PathPrefix, "path-prefix", "", "경로 접두사")
+	flags.StringVar(&filter.UserID, "user-id", "", "사용자 ID")
+	flags.StringVar(&filter.ServiceName, "service", "", "서비스 이름")
+	flags.IntVar(&filter.MinStatus, "min-status", 0, "최소 상태 코드 (예: 500)")
+	flags.BoolVar(&filter.RequestsOnly, "requests-only", false, "하위 span 제외")
+	interval := flags.Duration("interval", 2*time.Second, "조회 주기")
+	lag := flags.Duration("lag", 30*time.Second, "다시 조회할 최근 구간 (flush 주기보다 길게)")
+	asJSON := flags.Bool("json", false, "한 줄에 Step 하나씩 JSON으로 출력")
+	_ = flags.Parse(args)
+
+	type key struct {
+		traceID, spanID string
+		seq, createdAt  int64
+	}
+	seen := make(map[key]bool)
+	started := time.Now()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		filter.From = now.Add(-*lag)
+		filter.Limit = 1000
+		result, err := store.Query(ctx, filter)
+		if err != nil {
+			return err
+		}
+		// 최신순으로 조회되므로 오래된 것부터 출력
+		slices.Reverse(result.Steps)
+		for _, step := range result.Steps {
+			k := key{step.TraceID, step.SpanID, step.Seq, step.CreatedAt}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			// 시작 전에 저장된 Step은 출력하지 않음
+			if step.CreatedAt < started.Unix() {
+				continue
+			}
+			if err := printStep(os.Stdout, step, *asJSON); err != nil {
+				return err
+			}
+		}
+		for k := range seen {
+			if k.createdAt < filter.From.Unix() {
+				delete(seen, k)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// runGet - Trace ID의 모든 Step을 저장 순서대로 출력 (하위 span은 들여쓰기)
+func runGet(ctx context.Context, store *trace.Store, args []string) error {
+	flags := flag.NewFlagSet("get", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "한 줄에 Step 하나씩 JSON으로 출력")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: tracectl get [-json] <trace-id>")
+	}
+
+	result, err := store.Query(ctx, trace.QueryFilter{TraceID: flags.Arg(0), Limit: 1000})
+	if err != nil {
+		return err
+	}
+	if len(result.Steps) == 0 {
+		return fmt.Errorf("trace %q not found", flags.Arg(0))
+	}
+	slices.SortFunc(result.Steps, func(a, b trace.Step) int {
+		return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), cmp.Compare(a.Seq, b.Seq))
+	})
+	for _, step := range result.Steps {
+		if err := printStep(os.Stdout, step, *asJSON); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runTop - 최근 구간의 요청 수 기준 상위 엔드포인트를 표로 출력
+func runTop(ctx context.Context, store *trace.Store, args []string) error {
+	flags := flag.NewFlagSet("top", flag.ExitOnError)
+	since := flags.Duration("since", time.Hour, "집계할 최근 구간")
+	n := flags.Int("n", 20, "출력할 엔드포인트 수")
+	asJSON := flags.Bool("json", false, "JSON으로 출력")
+	_ = flags.Parse(args)
+
+	summaries, err := store.TopEndpoints(ctx, *since, *n)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaries)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tCOUNT\tP50\tP95\tP99\tERRORS")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%dms\t%dms\t%dms\t%.1f%%\n", s.Method, s.Path, s.Count, s.P50Ms, s.P95Ms, s.P99Ms, s.ErrorRate*100)
+	}
+	return w.Flush()
+}
+
+// runExport - 조건에 맞는 Step을 파일 또는 표준 출력으로 내보내기
+func runExport(ctx context.Context, store *trace.Store, args []string) (err error) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	var filter trace.QueryFilter
+	flags.StringVar(&filter.PathPrefix, "path-prefix", "", "경로 접두사")
+	flags.StringVar(&filter.UserID, "user-id", "", "사용자 ID")
+	flags.StringVar(&filter.ServiceName, "service", "", "서비스 이름")
+	flags.IntVar(&filter.MinStatus, "min-status", 0, "최소 상태 코드 (예: 500)")
+	flags.BoolVar(&filter.RequestsOnly, "requests-only", false, "하위 span 제외")
+	flags.IntVar(&filter.Limit, "limit", 0, "최대 Step 수 (0이면 전체)")
+	since := flags.Duration("since", 0, "최근 구간만 내보내기 (0이면 전체 기간)")
+	format := flags.String("format", "csv", "출력 형식 (csv, ndjson, parquet, xlsx)")
+	output := flags.String("o", "", "출력 파일 (없으면 표준 출력)")
+	excelSafe := flags.Bool("excel-safe", true, "CSV에서 =, +, -, @ 등으로 시작하는 값 앞에 ' 추가")
+	maxCell := flags.Int("max-cell", 0, "셀 최대 바이트 수 (0이면 Excel 제한 32767)")
+	bom := flags.Bool("bom", false, "CSV 앞에 UTF-8 BOM 추가 (Excel에서 한글이 깨지지 않음)")
+	_ = flags.Parse(args)
+	if *since > 0 {
+		filter.From = time.Now().Add(-*since)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = f
+	}
+	opts := []trace.ExportOption{trace.WithExcelSafe(*excelSafe), trace.WithMaxCellBytes(*maxCell)}
+	if *bom {
+		opts = append(opts, trace.WithBOM())
+	}
+	return store.Export(ctx, filter, trace.ExportFormat(*format), w, opts...)
+}
+
+// runImport - 접근 로그 파일(없으면 표준 입력)을 읽어 과거 Step으로 저장
+func runImport(ctx context.Context, store *trace.Store, cfg trace.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "combined", "로그 형식 (combined, json)")
+	until := flags.String("until", "", "이 시각(RFC3339) 이후 로그는 건너뜀 (미들웨어 도입 시각)")
+	opts := trace.ImportOptions{}
+	flags.StringVar(&opts.ServiceName, "service", cfg.ServiceName, "서비스 이름")
+	flags.StringVar(&opts.Environment, "env", cfg.Environment, "배포 환경")
+	_ = flags.Parse(args)
+	opts.Format = trace.ImportFormat(*format)
+	if *until != "" {
+		t, err := time.Parse(time.RFC3339, *until)
+		if err != nil {
+			return fmt.Errorf("invalid -until: %w", err)
+		}
+		opts.Until = t
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, name := range files {
+		r := io.Reader(os.Stdin)
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		result, err := store.Import(ctx, r, opts)
+		if result.Lines == 0 && err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d lines, %d imported, %d skipped\n", name, result.Lines, result.Imported, result.Skipped)
+		for _, msg := range result.Errors {
+			fmt.Fprintf(os.Stderr, "  line %s\n", msg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printStep - Step 한 줄 출력 (시각, 상태, 지연 시간, 메서드와 경로 또는 span 이름, Trace ID)
+func printStep(w io.Writer, step trace.Step, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(step)
+	}
+	at := time.Unix(step.CreatedAt, 0).Format(time.DateTime)
+	if step.SpanName != "" {
+		_, err := fmt.Fprintf(w, "%s      %6dms    └ %s  %s\n", at, step.LatencyMs, step.SpanName, step.TraceID)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s  %3d %6dms  %s %s  %s\n", at, step.StatusCode, step.LatencyMs, step.Method, step.Path, step.TraceID)
+	return err
+}
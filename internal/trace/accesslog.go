@@ -0,0 +1,125 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLogFormat 접근 로그 형식
+type AccessLogFormat int
+
+const (
+	// AccessLogNone 접근 로그 미출력 (기본값)
+	AccessLogNone AccessLogFormat = iota
+	// AccessLogText gin.Logger()와 호환되는 텍스트 형식
+	AccessLogText
+	// AccessLogJSON 한 줄 JSON 형식
+	AccessLogJSON
+)
+
+// WithAccessLog 접근 로그 출력 설정 (gin.Logger() 대체용)
+// 필터를 통과한 모든 요청에 대해 핸들러 실행 후 gin.DefaultWriter로 출력한다
+func WithAccessLog(format AccessLogFormat) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.AccessLog = format
+	}
+}
+
+// accessLogEntry 접근 로그 한 줄에 필요한 값
+type accessLogEntry struct {
+	Time       time.Time
+	StatusCode int
+	Latency    time.Duration
+	ClientIP   string
+	Method     string
+	Path       string
+	TraceID    string
+	Errors     string
+}
+
+// writeAccessLog - 형식에 맞춰 접근 로그 출력
+func writeAccessLog(w io.Writer, format AccessLogFormat, entry accessLogEntry) {
+	var line string
+	switch format {
+	case AccessLogText:
+		line = formatAccessLogText(entry, isTerminal(w))
+	case AccessLogJSON:
+		line = formatAccessLogJSON(entry)
+	default:
+		return
+	}
+	fmt.Fprint(w, line)
+}
+
+// formatAccessLogText - gin의 기본 LogFormatter와 같은 형식
+// 쿼리 스트링에는 토큰이 포함될 수 있으므로 경로만 기록한다
+func formatAccessLogText(entry accessLogEntry, color bool) string {
+	var statusColor, methodColor, resetColor string
+	if color {
+		params := gin.LogFormatterParams{StatusCode: entry.StatusCode, Method: entry.Method}
+		statusColor = params.StatusCodeColor()
+		methodColor = params.MethodColor()
+		resetColor = params.ResetColor()
+	}
+
+	latency := entry.Latency
+	if latency > time.Minute {
+		latency = latency.Truncate(time.Second)
+	}
+
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		entry.Time.Format("2006/01/02 - 15:04:05"),
+		statusColor, entry.StatusCode, resetColor,
+		latency,
+		entry.ClientIP,
+		methodColor, entry.Method, resetColor,
+		entry.Path,
+		entry.Errors,
+	)
+}
+
+func formatAccessLogJSON(entry accessLogEntry) string {
+	b, err := json.Marshal(struct {
+		Time      string `json:"time"`
+		Status    int    `json:"status"`
+		LatencyMs int64  `json:"latency_ms"`
+		Latency   string `json:"latency"`
+		ClientIP  string `json:"client_ip"`
+		Method    string `json:"method"`
+		Path      string `json:"path"`
+		TraceID   string `json:"trace_id,omitempty"`
+		Error     string `json:"error,omitempty"`
+	}{
+		Time:      entry.Time.Format(time.RFC3339Nano),
+		Status:    entry.StatusCode,
+		LatencyMs: entry.Latency.Milliseconds(),
+		Latency:   entry.Latency.String(),
+		ClientIP:  entry.ClientIP,
+		Method:    entry.Method,
+		Path:      entry.Path,
+		TraceID:   entry.TraceID,
+		Error:     entry.Errors,
+	})
+	if err != nil {
+		return ""
+	}
+	return string(b) + "\n"
+}
+
+// isTerminal - gin과 마찬가지로 터미널 출력일 때만 색상 사용
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
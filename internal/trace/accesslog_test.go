@@ -0,0 +1,192 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ginLogLine - gin 기본 LogFormatter 출력에서 시각과 응답 시간을 읽음
+var ginLogLine = regexp.MustCompile(`^\[GIN\] (\d{4}/\d{2}/\d{2} - \d{2}:\d{2}:\d{2}) \|[^|]*\| +(\S+) \|`)
+
+func parseGinLogLine(t *testing.T, line string) (time.Time, time.Duration) {
+	t.Helper()
+	m := ginLogLine.FindStringSubmatch(line)
+	if m == nil {
+		t.Fatalf("unexpected gin log line %q", line)
+	}
+	ts, err := time.ParseInLocation("2006/01/02 - 15:04:05", m[1], time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latency, err := time.ParseDuration(m[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts, latency
+}
+
+// TestAccessLogTextMatchesGinLogger - 같은 요청을 gin.LoggerWithConfig(기본 LogFormatter)와 WithAccessLog로 기록해 비교
+func TestAccessLogTextMatchesGinLogger(t *testing.T) {
+	tracer, err := New(Config{Sink: nopSink{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracer.Stop(context.Background())
+
+	var ginOut, traceOut bytes.Buffer
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = &traceOut
+	defer func() { gin.DefaultWriter = defaultWriter }()
+
+	r := gin.New()
+	r.Use(gin.LoggerWithConfig(gin.LoggerConfig{Output: &ginOut}))
+	r.Use(tracer.Middleware(WithAccessLog(AccessLogText)))
+	r.GET("/orders/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/orders", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	r.OPTIONS("/orders", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/fail", func(c *gin.Context) {
+		_ = c.Error(errors.New("db timeout"))
+		_ = c.Error(errors.New("retry failed"))
+		c.Status(http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		name, method, target string
+		// gin 출력에서 빼야 하는 쿼리 스트링 (접근 로그는 토큰이 포함될 수 있는 쿼리를 기록하지 않음)
+		query string
+	}{
+		{"ok", "GET", "/orders/1", ""},
+		{"created", "POST", "/orders", ""},
+		{"longest method", "OPTIONS", "/orders", ""},
+		{"not found", "DELETE", "/missing", ""},
+		{"private errors", "GET", "/fail", ""},
+		{"query stripped", "GET", "/orders/2?user_id=u&access_token=secret", "?user_id=u&access_token=secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ginOut.Reset()
+			traceOut.Reset()
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.RemoteAddr = "192.0.2.10:54321"
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			want := strings.Replace(ginOut.String(), tt.query, "", 1)
+			got := traceOut.String()
+
+			// gin이 기록한 시각과 응답 시간으로 다시 포맷하면 gin 출력과 같음
+			ts, latency := parseGinLogLine(t, want)
+			formatted := formatAccessLogText(accessLogEntry{
+				Time:       ts,
+				StatusCode: traceStatus(t, got),
+				Latency:    latency,
+				ClientIP:   "192.0.2.10",
+				Method:     tt.method,
+				Path:       req.URL.Path,
+				Errors:     privateErrors(tt.name),
+			}, false)
+			if formatted != want {
+				t.Errorf("formatted line\n%q\nwant gin's\n%q", formatted, want)
+			}
+
+			// 미들웨어 출력은 시각과 응답 시간을 제외하고 gin 출력과 같음
+			gotTime, _ := parseGinLogLine(t, got)
+			if d := gotTime.Sub(ts); d < -time.Second || d > time.Second {
+				t.Errorf("access log time %v, gin logged %v", gotTime, ts)
+			}
+			if mask(got) != mask(want) {
+				t.Errorf("access log line\n%q\nwant gin's\n%q", got, want)
+			}
+		})
+	}
+}
+
+// traceStatus - 접근 로그 줄의 상태 코드
+func traceStatus(t *testing.T, line string) int {
+	t.Helper()
+	fields := strings.Split(line, "|")
+	if len(fields) < 2 {
+		t.Fatalf("unexpected access log line %q", line)
+	}
+	status, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+// privateErrors - /fail 요청에서 c.Error로 남긴 에러의 gin 표기
+func privateErrors(name string) string {
+	if name != "private errors" {
+		return ""
+	}
+	return "Error #01: db timeout\nError #02: retry failed\n"
+}
+
+// mask - 요청마다 달라지는 시각과 응답 시간을 지운 줄
+func mask(line string) string {
+	return ginLogLine.ReplaceAllString(line, "[GIN] - | latency |")
+}
+
+func TestAccessLogGolden(t *testing.T) {
+	at := time.Date(2024, 3, 9, 14, 5, 7, 123456789, time.UTC)
+	tests := []struct {
+		name   string
+		format func(accessLogEntry) string
+		entry  accessLogEntry
+		want   string
+	}{
+		{
+			name:   "text",
+			format: func(e accessLogEntry) string { return formatAccessLogText(e, false) },
+			entry:  accessLogEntry{Time: at, StatusCode: 200, Latency: 1234567 * time.Nanosecond, ClientIP: "192.0.2.10", Method: "GET", Path: "/orders/1", TraceID: "t-1"},
+			want:   "[GIN] 2024/03/09 - 14:05:07 | 200 |    1.234567ms |      192.0.2.10 | GET      \"/orders/1\"\n",
+		},
+		{
+			name:   "text over a minute is truncated to seconds",
+			format: func(e accessLogEntry) string { return formatAccessLogText(e, false) },
+			entry:  accessLogEntry{Time: at, StatusCode: 504, Latency: 61*time.Second + 250*time.Millisecond, ClientIP: "2001:db8::1", Method: "POST", Path: "/reports"},
+			want:   "[GIN] 2024/03/09 - 14:05:07 | 504 |          1m1s |     2001:db8::1 | POST     \"/reports\"\n",
+		},
+		{
+			name:   "text with errors",
+			format: func(e accessLogEntry) string { return formatAccessLogText(e, false) },
+			entry:  accessLogEntry{Time: at, StatusCode: 500, Latency: 15 * time.Microsecond, ClientIP: "192.0.2.10", Method: "DELETE", Path: "/orders/1", Errors: "Error #01: boom\n"},
+			want:   "[GIN] 2024/03/09 - 14:05:07 | 500 |          15µs |      192.0.2.10 | DELETE   \"/orders/1\"\nError #01: boom\n",
+		},
+		{
+			name:   "text with color",
+			format: func(e accessLogEntry) string { return formatAccessLogText(e, true) },
+			entry:  accessLogEntry{Time: at, StatusCode: 404, Latency: 2 * time.Millisecond, ClientIP: "192.0.2.10", Method: "GET", Path: "/missing"},
+			want:   "[GIN] 2024/03/09 - 14:05:07 |\033[90;43m 404 \033[0m|           2ms |      192.0.2.10 |\033[97;44m GET     \033[0m \"/missing\"\n",
+		},
+		{
+			name:   "json",
+			format: formatAccessLogJSON,
+			entry:  accessLogEntry{Time: at, StatusCode: 200, Latency: 1234567 * time.Nanosecond, ClientIP: "192.0.2.10", Method: "GET", Path: "/orders/1", TraceID: "t-1"},
+			want:   `{"time":"2024-03-09T14:05:07.123456789Z","status":200,"latency_ms":1,"latency":"1.234567ms","client_ip":"192.0.2.10","method":"GET","path":"/orders/1","trace_id":"t-1"}` + "\n",
+		},
+		{
+			name:   "json with errors",
+			format: formatAccessLogJSON,
+			entry:  accessLogEntry{Time: at, StatusCode: 500, Latency: 61 * time.Second, ClientIP: "192.0.2.10", Method: "POST", Path: "/fail", Errors: "Error #01: boom\n"},
+			want:   `{"time":"2024-03-09T14:05:07.123456789Z","status":500,"latency_ms":61000,"latency":"1m1s","client_ip":"192.0.2.10","method":"POST","path":"/fail","error":"Error #01: boom\n"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.format(tt.entry); got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
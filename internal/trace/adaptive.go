@@ -0,0 +1,77 @@
+package trace
+
+import (
+	"sync"
+	"time"
+)
+
+// 적응형 샘플링이 따로 관리하는 최대 라우트 수 (넘는 라우트는 하나의 버킷을 공유)
+const maxAdaptiveRoutes = 1024
+
+// 라우트 수 제한을 넘은 라우트가 공유하는 버킷 이름
+const adaptiveOverflowRoute = "\x00other"
+
+// WithAdaptiveSampling 라우트별 초당 저장 Step 수가 maxPerSecond를 넘지 않도록 비율을 자동 조정
+// 직전 1초 동안의 요청 수로 다음 1초의 비율을 정하므로 트래픽이 급증해도 저장량이 일정하게 유지된다
+// WithSampleRate/WithRouteSampling 비율에 곱해지며, WithTailSampling으로 항상 저장하는 요청은 제한하지 않는다
+func WithAdaptiveSampling(maxPerSecond float64) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.AdaptiveMaxPerSecond = max(maxPerSecond, 0)
+		config.adaptive = newAdaptiveSampler(config.AdaptiveMaxPerSecond)
+	}
+}
+
+// adaptiveSampler - 라우트별 처리량으로 저장 확률 계산
+type adaptiveSampler struct {
+	target float64
+
+	mu     sync.Mutex
+	routes map[string]*adaptiveWindow
+}
+
+// adaptiveWindow - 라우트 하나의 1초 단위 요청 수
+type adaptiveWindow struct {
+	start       time.Time
+	seen        int     // 현재 구간에 들어온 (저장 대상) 요청 수
+	probability float64 // 현재 구간에 적용하는 저장 확률
+}
+
+func newAdaptiveSampler(target float64) *adaptiveSampler {
+	return &adaptiveSampler{target: target, routes: make(map[string]*adaptiveWindow)}
+}
+
+// probability - 요청을 기록하고 이 라우트에 적용할 저장 확률 반환
+// rate는 적응형 조정 전의 비율이며, 목표는 rate를 적용한 뒤의 저장 수 기준이다
+func (s *adaptiveSampler) probability(route string, rate float64, now time.Time) float64 {
+	if s == nil || s.target <= 0 || rate <= 0 {
+		return 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.routes[route]
+	if !ok {
+		if len(s.routes) >= maxAdaptiveRoutes {
+			route = adaptiveOverflowRoute
+			w = s.routes[route]
+		}
+		if w == nil {
+			w = &adaptiveWindow{start: now, probability: 1}
+			s.routes[route] = w
+		}
+	}
+
+	if elapsed := now.Sub(w.start); elapsed >= time.Second {
+		// 직전 구간의 예상 저장 수가 목표를 넘으면 비율을 낮춤 (한 구간 이상 비었으면 초기화)
+		expected := float64(w.seen) * rate
+		if elapsed >= 2*time.Second || expected <= s.target {
+			w.probability = 1
+		} else {
+			w.probability = s.target / expected
+		}
+		w.start, w.seen = now, 0
+	}
+	w.seen++
+	return w.probability
+}
@@ -0,0 +1,168 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Aggregate 기본값과 제한
+const (
+	defaultAggregateWindow = time.Hour
+	maxAggregateBuckets    = 1000
+)
+
+// errInvalidAggQuery 잘못된 집계 조건 (조회 API는 400으로 응답)
+var errInvalidAggQuery = errors.New("trace: invalid aggregate query")
+
+// AggQuery 지연 시간 집계 조건 (빈 값인 조건은 적용하지 않음)
+type AggQuery struct {
+	From time.Time `json:"from,omitempty"` // 이상 (기본 To - 1시간)
+	To   time.Time `json:"to,omitempty"`   // 미만 (기본 현재 시각)
+	// 시간 구간 크기 (From 기준으로 나눔, 0이면 전체 기간을 구간 하나로 집계, 최소 1초)
+	Bucket time.Duration `json:"bucket,omitempty"`
+
+	PathPrefix  string `json:"path_prefix,omitempty"`
+	Method      string `json:"method,omitempty"`
+	ServiceName string `json:"service_name,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Team        string `json:"team,omitempty"` // 담당 팀 라벨 (Labeled, WithStaticLabels의 team)
+	// true면 경로에 더해 담당 팀별로 나누어 집계 (AggRow.Team)
+	GroupByTeam bool `json:"group_by_team,omitempty"`
+}
+
+// AggRow 경로와 시간 구간별 집계 결과 (요청 Step만 집계, 하위 span 제외)
+type AggRow struct {
+	Path        string    `json:"path"`
+	Method      string    `json:"method"`
+	Team        string    `json:"team,omitempty"` // AggQuery.GroupByTeam일 때만 설정
+	BucketStart time.Time `json:"bucket_start"`
+	Count       int64     `json:"count"`
+	P50Ms       int64     `json:"p50_ms"`
+	P90Ms       int64     `json:"p90_ms"`
+	P95Ms       int64     `json:"p95_ms"`
+	P99Ms       int64     `json:"p99_ms"`
+	ErrorRate   float64   `json:"error_rate"` // 5xx 비율 (0~1)
+}
+
+// Aggregate - 경로(path, method)와 시간 구간별 요청 수, 에러율, p50/p90/p95/p99 지연 시간 집계
+// 구간마다 지연 시간별 요청 수를 쿼리 한 번으로 가져와 백분위를 계산하므로 DB 종류와 관계없이 정확한 값을 반환한다
+// 결과는 경로, 메서드, (GroupByTeam이면) 팀, 구간 시작 시각 순으로 정렬되며 요청이 없는 구간은 포함하지 않는다
+func Aggregate(ctx context.Context, db *gorm.DB, q AggQuery) ([]AggRow, error) {
+	return storeFor(db).Aggregate(ctx, q)
+}
+
+// Aggregate - 경로와 시간 구간별 요청 수, 에러율, 지연 시간 백분위 집계
+func (s *Store) Aggregate(ctx context.Context, q AggQuery) ([]AggRow, error) {
+	if q.To.IsZero() {
+		q.To = time.Now()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-defaultAggregateWindow)
+	}
+	if !q.From.Before(q.To) {
+		return nil, fmt.Errorf("%w: From must be before To", errInvalidAggQuery)
+	}
+	bucket := q.Bucket
+	if bucket <= 0 {
+		bucket = q.To.Sub(q.From)
+	}
+	bucketSec := max(int64(bucket/time.Second), 1)
+	if n := (q.To.Unix() - q.From.Unix()) / bucketSec; n > maxAggregateBuckets {
+		return nil, fmt.Errorf("%w: %d buckets per route (max %d); use a larger Bucket", errInvalidAggQuery, n, maxAggregateBuckets)
+	}
+
+	steps, err := s.stepsFrom(ctx, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	tx := steps().
+		Where(requestStepsOnly).
+		Where("created_at >= ? AND created_at < ?", q.From.Unix(), q.To.Unix())
+	if q.PathPrefix != "" {
+		tx = tx.Where("path LIKE ? ESCAPE '!'", escapeLike(q.PathPrefix)+"%")
+	}
+	if q.Method != "" {
+		tx = tx.Where("method = ?", q.Method)
+	}
+	if q.ServiceName != "" {
+		tx = tx.Where("service_name = ?", q.ServiceName)
+	}
+	if q.Environment != "" {
+		tx = tx.Where("environment = ?", q.Environment)
+	}
+	if q.Version != "" {
+		tx = tx.Where("version = ?", q.Version)
+	}
+	if q.Team != "" {
+		tx = tx.Where("team = ?", q.Team)
+	}
+	// 팀별로 나누지 않으면 빈 값으로 묶음
+	team, group := "'' AS team", "path, method, bucket, latency_ms"
+	if q.GroupByTeam {
+		team, group = "team", "path, method, team, bucket, latency_ms"
+	}
+
+	// 구간 시작 = created_at - (created_at - From) % 구간 크기 (From 이후 행만 조회하므로 나머지는 0 이상)
+	var rows []struct {
+		Path      string
+		Method    string
+		Team      string
+		Bucket    int64
+		LatencyMs int64
+		Count     int64
+		Errors    int64
+	}
+	err = tx.
+		Select("path, method, "+team+", created_at - (created_at - ?) % ? AS bucket, latency_ms, COUNT(*) AS count, "+
+			"SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) AS errors", q.From.Unix(), bucketSec).
+		Group(group).
+		Order(group).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 같은 그룹의 행은 지연 시간 오름차순으로 이어져 있다
+	result := make([]AggRow, 0)
+	for start := 0; start < len(rows); {
+		end := start
+		var count, errs int64
+		for end < len(rows) && rows[end].Path == rows[start].Path && rows[end].Method == rows[start].Method &&
+			rows[end].Team == rows[start].Team && rows[end].Bucket == rows[start].Bucket {
+			count += rows[end].Count
+			errs += rows[end].Errors
+			end++
+		}
+
+		agg := AggRow{
+			Path:        rows[start].Path,
+			Method:      rows[start].Method,
+			Team:        rows[start].Team,
+			BucketStart: time.Unix(rows[start].Bucket, 0),
+			Count:       count,
+			ErrorRate:   float64(errs) / float64(count),
+		}
+		for _, pct := range []struct {
+			p   float64
+			dst *int64
+		}{{0.50, &agg.P50Ms}, {0.90, &agg.P90Ms}, {0.95, &agg.P95Ms}, {0.99, &agg.P99Ms}} {
+			// latencyPercentile과 같은 위치 (정렬된 값의 (count-1)*p번째)
+			rank := int64(float64(count-1) * pct.p)
+			var seen int64
+			for _, r := range rows[start:end] {
+				if seen += r.Count; seen > rank {
+					*pct.dst = r.LatencyMs
+					break
+				}
+			}
+		}
+		result = append(result, agg)
+		start = end
+	}
+	return result, nil
+}
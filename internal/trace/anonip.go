@@ -0,0 +1,96 @@
+package trace
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// IP 해시 salt 기본 교체 주기
+const defaultIPHashRotation = 24 * time.Hour
+
+// IPAnonymization 저장할 클라이언트 IP 처리 방식 (Config.IPAnonymization)
+type IPAnonymization int
+
+const (
+	// IPKeep IP를 그대로 저장 (기본값)
+	IPKeep IPAnonymization = iota
+	// IPTruncate IPv4는 마지막 옥텟, IPv6는 마지막 80비트를 0으로 바꾸어 저장 (대략적인 지역 정보 유지)
+	IPTruncate
+	// IPHash IPHashRotation마다 바뀌는 랜덤 salt의 HMAC 해시로 저장 (같은 주기 안에서만 같은 IP끼리 묶을 수 있음)
+	IPHash
+)
+
+func (a IPAnonymization) String() string {
+	switch a {
+	case IPKeep:
+		return "keep"
+	case IPTruncate:
+		return "truncate"
+	case IPHash:
+		return "hash"
+	default:
+		return fmt.Sprintf("IPAnonymization(%d)", int(a))
+	}
+}
+
+// truncateIP - IPv4는 /24, IPv6는 /48로 자른 주소 (IP가 아니면 빈 값)
+func truncateIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.Addr().String()
+}
+
+// ipHasher - 주기마다 salt를 새로 만들어 IP를 해시 (이전 주기의 salt는 보관하지 않으므로 되돌릴 수 없음)
+type ipHasher struct {
+	rotation time.Duration
+
+	mu     sync.Mutex
+	period int64
+	salt   []byte
+}
+
+func (h *ipHasher) hash(ip string, now time.Time) string {
+	h.mu.Lock()
+	if period := now.UnixNano() / int64(h.rotation); period != h.period || h.salt == nil {
+		h.period = period
+		h.salt = make([]byte, 32)
+		rand.Read(h.salt)
+	}
+	mac := hmac.New(sha256.New, h.salt)
+	h.mu.Unlock()
+
+	mac.Write([]byte(ip))
+	return "iphash:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// anonymizeIP - Config.IPAnonymization에 따라 저장할 IP 반환
+func (t *Tracer) anonymizeIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	switch t.cfg.IPAnonymization {
+	case IPTruncate:
+		return truncateIP(ip)
+	case IPHash:
+		return t.ipHasher.hash(ip, time.Now())
+	default:
+		return ip
+	}
+}
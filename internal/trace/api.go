@@ -0,0 +1,346 @@
+package trace
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIConfig 조회 API 설정
+type APIConfig struct {
+	// 모든 조회 API 앞에 실행할 인증/인가 미들웨어
+	Auth []gin.HandlerFunc
+	// 조회할 Store (없으면 전달한 db를 기본 Tracer의 테이블 구성과 암호화 키로 조회)
+	Store *Store
+	// 실시간 tail과 디버그 캡처에 사용할 Tracer (없으면 기본 Tracer)
+	Tracer *Tracer
+	// 의존 관계 그래프(GET /dependencies)의 호스트 이름 변환 함수 (WithHostResolver)
+	HostResolver func(host string) string
+}
+
+// APIOption 함수형 옵션 타입
+type APIOption func(*APIConfig)
+
+// WithAPIAuth 조회 API 인증 미들웨어 설정
+func WithAPIAuth(handlers ...gin.HandlerFunc) APIOption {
+	return func(config *APIConfig) {
+		config.Auth = append(config.Auth, handlers...)
+	}
+}
+
+// WithAPIStore 조회할 Store 설정 (New로 만든 Tracer의 테이블을 조회할 때 tracer.Store() 전달)
+func WithAPIStore(store *Store) APIOption {
+	return func(config *APIConfig) {
+		config.Store = store
+	}
+}
+
+// WithAPITracer 실시간 tail과 디버그 캡처에 사용할 Tracer 설정 (New로 만든 Tracer를 사용할 때)
+func WithAPITracer(t *Tracer) APIOption {
+	return func(config *APIConfig) {
+		config.Tracer = t
+	}
+}
+
+// WithAPIHostResolver 의존 관계 그래프(GET /dependencies)에서 호출 대상 호스트를 노드 이름으로 바꾸는 함수 설정
+func WithAPIHostResolver(resolve func(host string) string) APIOption {
+	return func(config *APIConfig) {
+		config.HostResolver = resolve
+	}
+}
+
+// tracer - 요청마다 사용할 Tracer (기본 Tracer는 API 등록 뒤에 시작될 수 있으므로 요청 시점에 결정)
+func (config *APIConfig) tracer() func() *Tracer {
+	if config.Tracer != nil {
+		return func() *Tracer { return config.Tracer }
+	}
+	return defaultTracer.Load
+}
+
+// store - 요청마다 사용할 조회 핸들 (기본 Tracer는 API 등록 뒤에 시작될 수 있으므로 요청 시점에 결정)
+func (config *APIConfig) store(db *gorm.DB) func() *Store {
+	if config.Store != nil {
+		return func() *Store { return config.Store }
+	}
+	return func() *Store { return storeFor(db) }
+}
+
+// APIRoutes - Query 기반 JSON 조회 API 등록
+//
+//	GET /traces             조건 조회 (QueryFilter와 같은 이름의 쿼리 파라미터)
+//	GET /traces/:id         Trace ID의 모든 Step (하위 span 포함)
+//	GET /users/:id/traces   사용자의 Step
+//	GET /tenants/:id/traces 테넌트의 Step
+//	GET /sessions/:id/traces 세션의 Step
+//	GET /aggregate          경로와 시간 구간별 지연 시간 백분위, 요청 수, 에러율 (Aggregate)
+//	GET /top                요청 수 기준 상위 엔드포인트 (TopEndpoints, window=1h, n=20, /endpoints도 같은 응답)
+//	GET /tail               기본 Tracer(WithAPITracer)에 새로 수집되는 Step (Server-Sent Events, TailHandler)
+//	GET /captures           활성 디버그 캡처 (POST로 시작, DELETE /captures/:id로 종료)
+//	GET /dependencies       외부 호출로 만든 서비스 의존 관계 그래프 (DependencyMap, from, to 기본 최근 1시간)
+//
+// 사용자 ID와 요청 경로가 노출되므로 WithAdminAuth(토큰, HMAC 서명, IP 허용 목록)나 WithAPIAuth로 인증을 설정해야 한다
+func APIRoutes(r *gin.RouterGroup, db *gorm.DB, opts ...APIOption) {
+	config := &APIConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if len(config.Auth) == 0 {
+		log.Printf("trace API mounted at %s without authentication", r.BasePath())
+	}
+	mountAPI(r.Group("", config.Auth...), config.store(db), config.tracer(), config.HostResolver)
+}
+
+// mountAPI - 조회 API 핸들러 등록 (인증은 호출자가 그룹에 설정)
+func mountAPI(g *gin.RouterGroup, store func() *Store, tracer func() *Tracer, resolveHost func(string) string) {
+	g.GET("/traces", func(c *gin.Context) {
+		filter, err := parseQueryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respondQuery(c, store(), filter, false)
+	})
+	g.GET("/traces/:id", func(c *gin.Context) {
+		filter, err := parseQueryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.TraceID = c.Param("id")
+		respondQuery(c, store(), filter, true)
+	})
+	g.GET("/users/:id/traces", func(c *gin.Context) {
+		filter, err := parseQueryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.UserID = c.Param("id")
+		respondQuery(c, store(), filter, false)
+	})
+	g.GET("/tenants/:id/traces", func(c *gin.Context) {
+		filter, err := parseQueryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.TenantID = c.Param("id")
+		respondQuery(c, store(), filter, false)
+	})
+	g.GET("/sessions/:id/traces", func(c *gin.Context) {
+		filter, err := parseQueryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.SessionID = c.Param("id")
+		respondQuery(c, store(), filter, false)
+	})
+	g.GET("/tail", tailHandler(tracer))
+	mountDebugCaptures(g, tracer)
+	top := func(c *gin.Context) {
+		window, n, ok := dashboardWindow(c)
+		if !ok {
+			return
+		}
+		summaries, err := store().TopEndpoints(c.Request.Context(), window, n)
+		if err != nil {
+			log.Printf("trace top endpoints query failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query endpoints"})
+			return
+		}
+		c.JSON(http.StatusOK, summaries)
+	}
+	g.GET("/top", top)
+	g.GET("/endpoints", top)
+	g.GET("/aggregate", func(c *gin.Context) {
+		q, err := parseAggQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		rows, err := store().Aggregate(c.Request.Context(), q)
+		if errors.Is(err, errInvalidAggQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("trace aggregate failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to aggregate steps"})
+			return
+		}
+		c.JSON(http.StatusOK, rows)
+	})
+	g.GET("/teams", func(c *gin.Context) {
+		window, _, ok := dashboardWindow(c)
+		if !ok {
+			return
+		}
+		summaries, err := store().TeamSummaries(c.Request.Context(), window)
+		if err != nil {
+			log.Printf("trace team summaries failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to summarize teams"})
+			return
+		}
+		c.JSON(http.StatusOK, summaries)
+	})
+	g.GET("/teams/error-budget", func(c *gin.Context) {
+		window, _, ok := dashboardWindow(c)
+		if !ok {
+			return
+		}
+		slo, err := strconv.ParseFloat(c.Query("slo"), 64)
+		if err != nil || slo <= 0 || slo >= 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid slo: " + strconv.Quote(c.Query("slo")) + " (between 0 and 1, e.g. 0.999)"})
+			return
+		}
+		budgets, err := store().TeamErrorBudgets(c.Request.Context(), window, slo)
+		if err != nil {
+			log.Printf("trace team error budgets failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to summarize teams"})
+			return
+		}
+		c.JSON(http.StatusOK, budgets)
+	})
+	g.GET("/dependencies", func(c *gin.Context) {
+		filter, err := parseQueryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// 기본 구간은 방금 저장된 호출(같은 초)까지 포함
+		now := time.Now()
+		to := cmp.Or(filter.To, now.Add(time.Second))
+		from := cmp.Or(filter.From, now.Add(-time.Hour))
+		graph, err := store().DependencyMap(c.Request.Context(), from, to, WithHostResolver(resolveHost))
+		if err != nil {
+			log.Printf("trace dependency map failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build dependency map"})
+			return
+		}
+		c.JSON(http.StatusOK, graph)
+	})
+}
+
+// respondQuery - 조회 결과 응답 (notFound면 첫 페이지가 비었을 때 404)
+func respondQuery(c *gin.Context, store *Store, filter QueryFilter, notFound bool) {
+	result, err := store.Query(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("trace API query failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query traces"})
+		return
+	}
+	if notFound && len(result.Steps) == 0 && filter.Offset == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "trace not found"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// parseQueryFilter - 쿼리 파라미터를 QueryFilter로 변환
+// 시각은 RFC3339 또는 Unix 초, 최소 응답 시간은 밀리초(min_latency_ms)로 받는다
+func parseQueryFilter(c *gin.Context) (QueryFilter, error) {
+	filter := QueryFilter{
+		UserID:      c.Query("user_id"),
+		TenantID:    c.Query("tenant_id"),
+		SessionID:   c.Query("session_id"),
+		Country:     c.Query("country"),
+		ServiceName: c.Query("service_name"),
+		Environment: c.Query("environment"),
+		Version:     c.Query("version"),
+		TraceID:     c.Query("trace_id"),
+		CaptureID:   c.Query("capture_id"),
+		Provenance:  c.Query("provenance"),
+		PathPrefix:  c.Query("path_prefix"),
+	}
+
+	var errs []error
+	intParam := func(name string) int {
+		v := c.Query(name)
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %q", name, v))
+		}
+		return n
+	}
+	timeParam := func(name string) time.Time {
+		v := c.Query(name)
+		if v == "" {
+			return time.Time{}
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %q (RFC3339 or unix seconds)", name, v))
+			return time.Time{}
+		}
+		return time.Unix(sec, 0)
+	}
+
+	filter.MinStatus = intParam("min_status")
+	filter.MaxStatus = intParam("max_status")
+	filter.MinLatency = time.Duration(intParam("min_latency_ms")) * time.Millisecond
+	filter.From = timeParam("from")
+	filter.To = timeParam("to")
+	filter.Limit = intParam("limit")
+	filter.Offset = intParam("offset")
+	for _, v := range c.QueryArray("param") {
+		name, value, ok := strings.Cut(v, ":")
+		if !ok || name == "" {
+			errs = append(errs, fmt.Errorf("invalid param: %q (name:value)", v))
+			continue
+		}
+		if filter.Params == nil {
+			filter.Params = make(map[string]string)
+		}
+		filter.Params[name] = value
+	}
+	filter.RequestsOnly = c.Query("requests_only") == "true"
+	return filter, errors.Join(errs...)
+}
+
+// parseAggQuery - 쿼리 파라미터를 AggQuery로 변환 (bucket은 Go duration 형식, 예: 5m)
+func parseAggQuery(c *gin.Context) (AggQuery, error) {
+	filter, err := parseQueryFilter(c)
+	if err != nil {
+		return AggQuery{}, err
+	}
+	q := AggQuery{
+		From:        filter.From,
+		To:          filter.To,
+		PathPrefix:  filter.PathPrefix,
+		Method:      c.Query("method"),
+		ServiceName: filter.ServiceName,
+		Environment: filter.Environment,
+		Version:     filter.Version,
+		Team:        c.Query("team"),
+	}
+	switch v := c.Query("group_by"); v {
+	case "":
+	case "team":
+		q.GroupByTeam = true
+	default:
+		return q, fmt.Errorf("invalid group_by: %q (team)", v)
+	}
+	if v := c.Query("bucket"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return q, fmt.Errorf("invalid bucket: %q", v)
+		}
+		q.Bucket = d
+	}
+	return q, nil
+}
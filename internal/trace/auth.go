@@ -0,0 +1,228 @@
+package trace
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HMAC 서명 요청 헤더와 허용하는 시각 차이
+const (
+	AdminTimestampHeader = "X-Trace-Timestamp" // 서명 시각 (Unix 초)
+	AdminSignatureHeader = "X-Trace-Signature" // hex(HMAC-SHA256(secret, 시각 + "\n" + 메서드 + "\n" + 요청 URI + "\n" + hex(SHA-256(본문))))
+	adminSignatureSkew   = 5 * time.Minute
+	// 서명을 검사할 최대 요청 본문 크기 (넘으면 401)
+	maxSignedBodyBytes = 1 << 20
+)
+
+// AdminAuth 조회 API, 대시보드, 실시간 tail 내장 인증 설정
+// 설정한 방식을 모두 통과해야 허용하며, 아무것도 설정하지 않으면 모든 요청을 허용한다
+type AdminAuth struct {
+	// Authorization: Bearer 토큰 (상수 시간 비교)
+	BearerToken string
+	// HMAC 서명 비밀 키 (AdminTimestampHeader, AdminSignatureHeader로 서명한 요청만 허용, 본문도 서명에 포함)
+	HMACSecret []byte
+	// Basic 인증 사용자 이름과 비밀번호 (브라우저로 여는 대시보드용, 상수 시간 비교, 실패하면 WWW-Authenticate 헤더로 로그인 창 표시)
+	BasicAccounts map[string]string
+	// 허용할 클라이언트 IP 또는 CIDR (IPv4, IPv6, 파싱할 수 없는 값은 로그를 남기고 무시)
+	AllowCIDRs []string
+	// AllowCIDRs 판단 시 X-Forwarded-For를 읽을 신뢰할 프록시 (없으면 연결 주소만 사용)
+	TrustedProxies []string
+	// 추가 검사 (에러를 반환하면 401)
+	Check func(c *gin.Context) error
+	// 인증 실패를 집계할 Tracer (없으면 기본 Tracer)
+	Tracer *Tracer
+}
+
+// configured - 인증 방식을 하나 이상 설정했는지 확인
+func (a AdminAuth) configured() bool {
+	return a.BearerToken != "" || len(a.HMACSecret) > 0 || len(a.BasicAccounts) > 0 || len(a.AllowCIDRs) > 0 || a.Check != nil
+}
+
+// WithAdminAuth 조회 API, 대시보드 내장 인증 설정 (WithAPIAuth의 미들웨어보다 먼저 실행)
+// 인증 방식을 하나도 설정하지 않으면 무시하므로 인증 없이 등록했다는 경고 로그가 남는다
+func WithAdminAuth(auth AdminAuth) APIOption {
+	guard := AdminGuard(auth)
+	return func(config *APIConfig) {
+		if !auth.configured() {
+			return
+		}
+		config.Auth = append([]gin.HandlerFunc{guard}, config.Auth...)
+	}
+}
+
+// AdminGuard - AdminAuth로 요청을 검사하는 미들웨어
+// APIRoutes, DashboardRoutes 밖에서 직접 등록하는 관리용 핸들러(HealthHandler, MetricsHandler, TailHandler 등)에 같은 인증을 적용할 때 사용한다
+// 실패하면 어떤 trace를 요청했는지와 관계없이 같은 응답(허용하지 않는 IP는 403, 나머지는 401)을 반환한다
+func AdminGuard(auth AdminAuth) gin.HandlerFunc {
+	allow := parsePrefixes("admin allowlist", auth.AllowCIDRs)
+	trusted := parsePrefixes("admin trusted proxy", auth.TrustedProxies)
+	ipConfig := &MiddlewareConfig{TrustedProxies: trusted}
+	token := []byte(auth.BearerToken)
+
+	return func(c *gin.Context) {
+		status := http.StatusOK
+		switch {
+		case len(auth.AllowCIDRs) > 0 && !allowedAdminIP(c, ipConfig, allow):
+			status = http.StatusForbidden
+		case len(token) > 0 && !validBearer(c, token):
+			status = http.StatusUnauthorized
+		case len(auth.HMACSecret) > 0 && !validSignature(c, auth.HMACSecret, time.Now()):
+			status = http.StatusUnauthorized
+		case len(auth.BasicAccounts) > 0 && !validBasic(c, auth.BasicAccounts):
+			c.Header("WWW-Authenticate", `Basic realm="trace"`)
+			status = http.StatusUnauthorized
+		case auth.Check != nil && auth.Check(c) != nil:
+			status = http.StatusUnauthorized
+		}
+		if status == http.StatusOK {
+			c.Next()
+			return
+		}
+
+		tracer := auth.Tracer
+		if tracer == nil {
+			tracer = defaultTracer.Load()
+		}
+		tracer.countAuthFailure()
+		c.AbortWithStatusJSON(status, gin.H{"error": http.StatusText(status)})
+	}
+}
+
+// AuthFailures - 조회 API, 대시보드에서 인증에 실패한 요청 수
+func (t *Tracer) AuthFailures() uint64 {
+	if t == nil {
+		return 0
+	}
+	return uint64(t.authFailures.Load())
+}
+
+// AuthFailures - 기본 Tracer에 집계된 인증 실패 요청 수
+func AuthFailures() uint64 {
+	return defaultTracer.Load().AuthFailures()
+}
+
+func (t *Tracer) countAuthFailure() {
+	if t != nil {
+		t.authFailures.Add(1)
+	}
+}
+
+// allowedAdminIP - 클라이언트 IP가 허용 목록에 있는지 확인
+// 신뢰할 프록시가 없으면 gin의 ClientIP 대신 연결 주소만 사용한다 (gin 기본 설정은 모든 프록시 헤더를 신뢰)
+func allowedAdminIP(c *gin.Context, config *MiddlewareConfig, allow []netip.Prefix) bool {
+	var ip string
+	if len(config.TrustedProxies) > 0 {
+		ip = clientIP(c, config, nil)
+	} else {
+		host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+		if err != nil {
+			host = c.Request.RemoteAddr
+		}
+		ip = host
+	}
+	addr, ok := normalizeIP(ip)
+	return ok && isTrusted(addr, allow)
+}
+
+func validBearer(c *gin.Context, token []byte) bool {
+	got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), token) == 1
+}
+
+// validBasic - Basic 인증 사용자의 비밀번호가 일치하는지 확인
+func validBasic(c *gin.Context, accounts map[string]string) bool {
+	user, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return false
+	}
+	want, exists := accounts[user]
+	// 없는 사용자도 같은 비교를 거쳐 응답 시간으로 사용자 이름이 드러나지 않도록 함
+	match := subtle.ConstantTimeCompare([]byte(password), []byte(want)) == 1
+	return exists && match
+}
+
+// validSignature - 서명 시각이 허용 범위 안이고 서명이 일치하는지 확인 (재전송 공격 범위를 시각 차이로 제한)
+// 본문 해시를 서명에 포함하므로 가로챈 서명으로 다른 본문을 보낼 수 없으며, 읽은 본문은 핸들러가 다시 읽을 수 있도록 되돌린다
+func validSignature(c *gin.Context, secret []byte, now time.Time) bool {
+	timestamp := c.GetHeader(AdminTimestampHeader)
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > adminSignatureSkew || d < -adminSignatureSkew {
+		return false
+	}
+	signature, err := hex.DecodeString(c.GetHeader(AdminSignatureHeader))
+	if err != nil {
+		return false
+	}
+	var body []byte
+	if c.Request.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(c.Request.Body, maxSignedBodyBytes+1))
+		c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil || len(body) > maxSignedBodyBytes {
+			return false
+		}
+	}
+	return hmac.Equal(signature, adminSignature(secret, timestamp, c.Request.Method, c.Request.URL.RequestURI(), body))
+}
+
+// SignAdminRequest - 요청에 HMAC 서명 헤더 설정 (AdminAuth.HMACSecret으로 보호한 API를 호출하는 클라이언트용)
+func SignAdminRequest(req *http.Request, secret []byte) error {
+	if len(secret) == 0 {
+		return errors.New("trace: empty admin secret")
+	}
+	body, err := requestBody(req)
+	if err != nil {
+		return fmt.Errorf("trace: read request body to sign: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(AdminTimestampHeader, timestamp)
+	req.Header.Set(AdminSignatureHeader, hex.EncodeToString(adminSignature(secret, timestamp, req.Method, req.URL.RequestURI(), body)))
+	return nil
+}
+
+// requestBody - 요청 본문을 읽고, 요청을 그대로 보낼 수 있도록 본문을 되돌림 (GetBody가 있으면 원본을 읽지 않음)
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	return body, nil
+}
+
+func adminSignature(secret []byte, timestamp, method, uri string, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "\n" + method + "\n" + uri + "\n" + hex.EncodeToString(sum[:])))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,245 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// adminServer - auth로 보호한 조회 API (user-1의 trace 하나를 저장해 둔 상태)
+func adminServer(t *testing.T, auth trace.AdminAuth) (*gin.Engine, *trace.Tracer, string) {
+	t.Helper()
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, tracer, "/orders?user_id=user-1&access_token=token")
+	stopTracer(t, tracer)
+	result, err := tracer.Store().Query(context.Background(), trace.QueryFilter{UserID: "user-1"})
+	if err != nil || len(result.Steps) != 1 {
+		t.Fatalf("seed: %v %+v", err, result)
+	}
+
+	auth.Tracer = tracer
+	r := gin.New()
+	trace.APIRoutes(r.Group("/debug"), nil, trace.WithAPIStore(tracer.Store()), trace.WithAdminAuth(auth))
+	return r, tracer, result.Steps[0].TraceID
+}
+
+func adminRequest(r *gin.Engine, remote, target string, edit func(*http.Request)) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.RemoteAddr = remote
+	if edit != nil {
+		edit(req)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminAuthBearer(t *testing.T) {
+	r, tracer, traceID := adminServer(t, trace.AdminAuth{BearerToken: "s3cret"})
+	bearer := func(token string) func(*http.Request) {
+		return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	if w := adminRequest(r, "10.0.0.1:1234", "/debug/traces/"+traceID, bearer("s3cret")); w.Code != http.StatusOK {
+		t.Fatalf("valid token: status %d", w.Code)
+	}
+	// 존재하는 trace와 없는 trace의 실패 응답이 같아야 한다
+	existing := adminRequest(r, "10.0.0.1:1234", "/debug/traces/"+traceID, bearer("wrong"))
+	missing := adminRequest(r, "10.0.0.1:1234", "/debug/traces/ffffffffffffffffffffffffffffffff", bearer("wrong"))
+	if existing.Code != http.StatusUnauthorized || existing.Code != missing.Code || existing.Body.String() != missing.Body.String() {
+		t.Fatalf("failure responses differ: %d %q / %d %q", existing.Code, existing.Body, missing.Code, missing.Body)
+	}
+	if w := adminRequest(r, "10.0.0.1:1234", "/debug/traces/"+traceID, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("missing token: status %d", w.Code)
+	}
+	if got := tracer.Stats().AuthFailures; got != 3 {
+		t.Fatalf("AuthFailures = %d, want 3", got)
+	}
+}
+
+func TestAdminAuthHMAC(t *testing.T) {
+	secret := []byte("hmac-key")
+	r, _, traceID := adminServer(t, trace.AdminAuth{HMACSecret: secret})
+	target := "/debug/traces/" + traceID + "?limit=10"
+
+	signed := func(req *http.Request) {
+		if err := trace.SignAdminRequest(req, secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w := adminRequest(r, "10.0.0.1:1234", target, signed); w.Code != http.StatusOK {
+		t.Fatalf("signed request: status %d", w.Code)
+	}
+
+	tests := map[string]func(*http.Request){
+		"unsigned":  nil,
+		"wrong key": func(req *http.Request) { _ = trace.SignAdminRequest(req, []byte("other")) },
+		"tampered uri": func(req *http.Request) {
+			signed(req)
+			req.URL.RawQuery = "limit=1000"
+		},
+		"expired": func(req *http.Request) {
+			signed(req)
+			old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+			req.Header.Set(trace.AdminTimestampHeader, old)
+		},
+	}
+	for name, edit := range tests {
+		if w := adminRequest(r, "10.0.0.1:1234", target, edit); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d, want 401", name, w.Code)
+		}
+	}
+}
+
+func TestAdminAuthHMACSignsBody(t *testing.T) {
+	secret := []byte("hmac-key")
+	r := gin.New()
+	r.POST("/captures", trace.AdminGuard(trace.AdminAuth{HMACSecret: secret}), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusCreated, string(body))
+	})
+	post := func(body string, edit func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/captures", strings.NewReader(body))
+		edit(req)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	sign := func(req *http.Request) {
+		if err := trace.SignAdminRequest(req, secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 서명 검사 후에도 핸들러가 본문을 그대로 읽음
+	body := `{"user_id":"user-1","duration":"10m"}`
+	if w := post(body, sign); w.Code != http.StatusCreated || w.Body.String() != body {
+		t.Fatalf("signed request: status %d body %q", w.Code, w.Body)
+	}
+	// 가로챈 서명 헤더로 다른 본문을 보내면 거부
+	replayed := post(`{"user_id":"victim","duration":"24h"}`, func(req *http.Request) {
+		original := httptest.NewRequest("POST", "/captures", strings.NewReader(body))
+		sign(original)
+		req.Header.Set(trace.AdminTimestampHeader, original.Header.Get(trace.AdminTimestampHeader))
+		req.Header.Set(trace.AdminSignatureHeader, original.Header.Get(trace.AdminSignatureHeader))
+	})
+	if replayed.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed signature with another body: status %d, want 401", replayed.Code)
+	}
+}
+
+func TestAdminAuthBasic(t *testing.T) {
+	r := gin.New()
+	r.GET("/ui", trace.AdminGuard(trace.AdminAuth{BasicAccounts: map[string]string{"admin": "s3cret"}}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	tests := []struct {
+		name           string
+		user, password string
+		want           int
+	}{
+		{"valid", "admin", "s3cret", http.StatusOK},
+		{"wrong password", "admin", "guess", http.StatusUnauthorized},
+		{"unknown user", "root", "s3cret", http.StatusUnauthorized},
+		{"no credentials", "", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := adminRequest(r, "10.0.0.1:1234", "/ui", func(req *http.Request) {
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+		})
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+		}
+		// 브라우저가 로그인 창을 띄우도록 실패 응답에 WWW-Authenticate 헤더
+		if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: missing WWW-Authenticate header", tt.name)
+		}
+	}
+}
+
+func TestAdminAuthAllowlist(t *testing.T) {
+	r, _, _ := adminServer(t, trace.AdminAuth{
+		AllowCIDRs:     []string{"10.1.0.0/16", "2001:db8::/32", "192.0.2.7", "not-a-cidr"},
+		TrustedProxies: []string{"172.16.0.0/12"},
+	})
+	xff := func(value string) func(*http.Request) {
+		return func(req *http.Request) { req.Header.Set("X-Forwarded-For", value) }
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		edit   func(*http.Request)
+		want   int
+	}{
+		{"ipv4 in range", "10.1.255.255:1234", nil, http.StatusOK},
+		{"ipv4 outside range", "10.2.0.1:1234", nil, http.StatusForbidden},
+		{"single ip", "192.0.2.7:1234", nil, http.StatusOK},
+		{"ipv6 in range", "[2001:db8::1]:1234", nil, http.StatusOK},
+		{"ipv6 with zone", "[2001:db8::1%eth0]:1234", nil, http.StatusOK},
+		{"ipv6 outside range", "[2001:db9::1]:1234", nil, http.StatusForbidden},
+		{"ipv4-mapped ipv6", "[::ffff:10.1.0.1]:1234", nil, http.StatusOK},
+		{"header from untrusted remote", "10.2.0.1:1234", xff("10.1.0.1"), http.StatusForbidden},
+		{"header via trusted proxy", "172.16.0.1:1234", xff("10.1.0.1"), http.StatusOK},
+		{"spoofed left-most value", "172.16.0.1:1234", xff("10.1.0.1, 203.0.113.9"), http.StatusForbidden},
+		{"proxy chain", "172.16.0.1:1234", xff("2001:db8::5, 172.16.0.2"), http.StatusOK},
+		{"trusted proxy without header", "172.16.0.1:1234", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := adminRequest(r, tt.remote, "/debug/traces?limit=1", tt.edit); w.Code != tt.want {
+				t.Fatalf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminAuthCheckGuardsDashboardAndTail(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+
+	auth := trace.AdminAuth{
+		Tracer: tracer,
+		Check: func(c *gin.Context) error {
+			if c.GetHeader("X-Admin") != "yes" {
+				return errors.New("not an admin")
+			}
+			return nil
+		},
+	}
+	r := gin.New()
+	trace.DashboardRoutes(r.Group("/ui"), nil, trace.WithAPIStore(tracer.Store()), trace.WithAdminAuth(auth))
+	r.GET("/healthz", trace.AdminGuard(auth), tracer.HealthHandler())
+
+	for _, path := range []string{"/ui/", "/ui/api/slowest", "/ui/api/tail", "/healthz"} {
+		if w := adminRequest(r, "10.0.0.1:1234", path, nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d, want 401", path, w.Code)
+		}
+	}
+	admin := func(req *http.Request) { req.Header.Set("X-Admin", "yes") }
+	if w := adminRequest(r, "10.0.0.1:1234", "/ui/", admin); w.Code != http.StatusOK {
+		t.Errorf("dashboard with check passed: status %d", w.Code)
+	}
+	if got := tracer.AuthFailures(); got != 4 {
+		t.Errorf("AuthFailures = %d, want 4", got)
+	}
+}
@@ -0,0 +1,241 @@
+package trace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// B3Format B3 헤더 주입 형식
+type B3Format int
+
+const (
+	// B3Multi X-B3-TraceId, X-B3-SpanId 등 여러 헤더 (기본값)
+	B3Multi B3Format = iota
+	// B3Single "b3: {TraceId}-{SpanId}-{Sampled}-{ParentSpanId}" 단일 헤더
+	B3Single
+)
+
+// B3 헤더 이름
+const (
+	b3SingleHeader       = "b3"
+	b3TraceIDHeader      = "X-B3-TraceId"
+	b3SpanIDHeader       = "X-B3-SpanId"
+	b3ParentSpanIDHeader = "X-B3-ParentSpanId"
+	b3SampledHeader      = "X-B3-Sampled"
+	b3FlagsHeader        = "X-B3-Flags"
+)
+
+// gin.Context에 저장되는 키
+const (
+	SpanIDKey = "span_id"
+
+	b3StateKey = "trace_b3"
+)
+
+// WithB3Propagation Zipkin B3 헤더 연동 설정
+// 요청에 B3 헤더(단일/다중 모두 허용)가 있으면 그 trace ID를 Step.TraceID로, span ID를 Step.ParentSpanID로 사용한다
+// format은 InjectB3로 외부 호출에 헤더를 넣을 때의 형식이다
+// WithTraceContextPropagation과 함께 쓰면 요청의 올바른 traceparent 헤더를 B3보다 우선한다
+func WithB3Propagation(format B3Format) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.B3Propagation = true
+		config.B3Format = format
+	}
+}
+
+// b3State 요청 하나의 trace 전파 정보 (B3, W3C traceparent)
+type b3State struct {
+	b3          bool // WithB3Propagation
+	traceparent bool // WithTraceContextPropagation
+	format      B3Format
+	sampled     string // 상위 서비스의 샘플링 결정 ("1", "0", "d" 또는 빈 값)
+	tracestate  string // 상위 서비스의 W3C tracestate (그대로 전달)
+}
+
+// b3Context 요청 헤더에서 읽은 B3 값
+type b3Context struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+	sampled      string
+}
+
+// parseB3 - 요청 헤더에서 B3 값 읽기 (단일 헤더 우선, trace ID가 없거나 형식이 틀리면 false)
+func parseB3(header http.Header) (b3Context, bool) {
+	if single := header.Get(b3SingleHeader); single != "" {
+		return parseB3Single(single)
+	}
+
+	b3 := b3Context{
+		traceID:      strings.ToLower(header.Get(b3TraceIDHeader)),
+		spanID:       strings.ToLower(header.Get(b3SpanIDHeader)),
+		parentSpanID: strings.ToLower(header.Get(b3ParentSpanIDHeader)),
+		sampled:      header.Get(b3SampledHeader),
+	}
+	if header.Get(b3FlagsHeader) == "1" {
+		b3.sampled = "d"
+	}
+	switch b3.sampled {
+	case "true":
+		b3.sampled = "1"
+	case "false":
+		b3.sampled = "0"
+	}
+	return b3, validB3(b3)
+}
+
+func parseB3Single(value string) (b3Context, bool) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(value)), "-")
+	// 샘플링 결정만 있는 형식("0", "1", "d")은 합류할 trace가 없음
+	if len(parts) < 2 || len(parts) > 4 {
+		return b3Context{}, false
+	}
+
+	b3 := b3Context{traceID: parts[0], spanID: parts[1]}
+	if len(parts) > 2 {
+		b3.sampled = parts[2]
+	}
+	if len(parts) > 3 {
+		b3.parentSpanID = parts[3]
+	}
+	return b3, validB3(b3)
+}
+
+func validB3(b3 b3Context) bool {
+	if len(b3.traceID) != 16 && len(b3.traceID) != 32 || !isHex(b3.traceID) {
+		return false
+	}
+	if len(b3.spanID) != 16 || !isHex(b3.spanID) {
+		return false
+	}
+	if b3.parentSpanID != "" && (len(b3.parentSpanID) != 16 || !isHex(b3.parentSpanID)) {
+		return false
+	}
+	switch b3.sampled {
+	case "", "0", "1", "d":
+		return true
+	}
+	return false
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveTraceID - 요청의 Trace ID와 상위 span ID 결정
+// 켜진 연동 중 요청에 올바른 헤더가 있으면 상위 trace에 합류하고(traceparent, B3 순), 아니면 생성 함수를 사용한다
+func resolveTraceID(c *gin.Context, config *MiddlewareConfig, tracer *Tracer, userID, token string) (string, string) {
+	if config.B3Propagation || config.TraceContextPropagation {
+		state := &b3State{b3: config.B3Propagation, traceparent: config.TraceContextPropagation, format: config.B3Format}
+		c.Set(b3StateKey, state)
+		if config.TraceContextPropagation {
+			if tp, ok := parseTraceparent(c.Request.Header.Get(traceparentHeader)); ok {
+				state.sampled, state.tracestate = tp.sampled, c.Request.Header.Get(tracestateHeader)
+				return tp.traceID, tp.spanID
+			}
+		}
+		if config.B3Propagation {
+			if b3, ok := parseB3(c.Request.Header); ok {
+				state.sampled = b3.sampled
+				return b3.traceID, b3.spanID
+			}
+		}
+	}
+	if config.TraceIDFromHeader != "" {
+		if id := c.GetHeader(config.TraceIDFromHeader); validRequestID(id) {
+			return id, ""
+		}
+	}
+	if config.TraceIDGenerator != nil {
+		return config.TraceIDGenerator(userID, token), ""
+	}
+	return tracer.generateTraceID(userID, token), ""
+}
+
+// upstreamSampled - 상위 서비스가 이 trace를 샘플링에서 제외하지 않았는지 (B3 Sampled가 "0"이거나 traceparent의 sampled 플래그가 꺼져 있으면 false)
+func upstreamSampled(c *gin.Context) bool {
+	state, _ := c.Get(b3StateKey)
+	b3, _ := state.(*b3State)
+	return b3 == nil || b3.sampled != "0"
+}
+
+// newSpanID - 요청마다 새 8바이트 span ID
+func newSpanID() string {
+	return fmt.Sprintf("%016x", rand.Uint64())
+}
+
+// HexTraceID - Trace ID를 B3/OpenTelemetry에서 쓰는 16바이트 hex 형식으로 변환
+// 16~32자리 hex는 그대로(16자리는 앞을 0으로 채움), 더 긴 hex(기본 생성기)는 앞 32자리를 사용하고,
+// UUID와 ULID는 같은 128비트 값을, 그 외 형식(레거시 ID 등)은 해시하여 사용한다
+func HexTraceID(traceID string) string {
+	lower := strings.ToLower(traceID)
+	if len(lower) == 36 && strings.Count(lower, "-") == 4 && lower[8] == '-' && lower[13] == '-' && lower[18] == '-' && lower[23] == '-' {
+		lower = strings.ReplaceAll(lower, "-", "")
+	}
+	switch {
+	case len(lower) == 16 && isHex(lower):
+		return strings.Repeat("0", 16) + lower
+	case len(lower) >= 32 && isHex(lower[:32]):
+		return lower[:32]
+	}
+	if b, ok := decodeULID(strings.ToUpper(traceID)); ok {
+		return hex.EncodeToString(b[:])
+	}
+	sum := sha256.Sum256([]byte(traceID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// InjectB3 - 외부 서비스 호출 헤더에 현재 요청의 B3 값 기록
+// 호출마다 새 span ID를 만들고 현재 요청의 span을 부모로 기록하므로 다음 서비스는 같은 trace에 합류한다
+// trace 미들웨어가 Step을 수집하지 않은 요청이면 아무것도 하지 않는다
+func InjectB3(c *gin.Context, header http.Header) {
+	state, _ := c.Get(b3StateKey)
+	b3, _ := state.(*b3State)
+	injectB3(header, b3, c.GetString(TraceIDKey), c.GetString(SpanIDKey), newSpanID())
+}
+
+// injectB3 - 부모 span(parentSpanID) 아래의 새 span(spanID)으로 B3 헤더 기록
+func injectB3(header http.Header, b3 *b3State, traceID, parentSpanID, spanID string) {
+	if traceID == "" || parentSpanID == "" {
+		return
+	}
+	if b3 == nil {
+		b3 = &b3State{}
+	}
+
+	// 상위 서비스에서 받은 64비트 trace ID는 그대로 전달
+	if len(traceID) != 16 || !isHex(traceID) {
+		traceID = HexTraceID(traceID)
+	}
+	if b3.format == B3Single {
+		sampled := b3.sampled
+		if sampled == "" {
+			// 부모 span ID를 넣으려면 샘플링 자리를 비울 수 없으므로 저장 대상임을 뜻하는 1을 사용
+			sampled = "1"
+		}
+		header.Set(b3SingleHeader, traceID+"-"+spanID+"-"+sampled+"-"+parentSpanID)
+		return
+	}
+
+	header.Set(b3TraceIDHeader, traceID)
+	header.Set(b3SpanIDHeader, spanID)
+	header.Set(b3ParentSpanIDHeader, parentSpanID)
+	switch b3.sampled {
+	case "d":
+		header.Set(b3FlagsHeader, "1")
+	case "0", "1":
+		header.Set(b3SampledHeader, b3.sampled)
+	}
+}
@@ -0,0 +1,149 @@
+package trace
+
+import (
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 잘린 본문 끝에 붙는 표시
+const truncatedBodySuffix = "...[truncated]"
+
+// WithBodyCapture 요청/응답 본문 저장 설정 (디버깅용, 기본 비활성화)
+// 각 본문은 최대 크기까지만 저장되며 텍스트가 아닌 Content-Type(이미지, 바이너리 등)은 저장하지 않는다
+// 0 이하의 크기는 해당 본문을 저장하지 않음을 뜻한다
+func WithBodyCapture(maxRequestBytes, maxResponseBytes int) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.MaxRequestBodyBytes = max(maxRequestBytes, 0)
+		config.MaxResponseBodyBytes = max(maxResponseBytes, 0)
+	}
+}
+
+// limitedBuffer - 최대 크기까지만 보관하는 버퍼
+type limitedBuffer struct {
+	data      []byte
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - len(b.data)
+	if len(p) > room {
+		p = p[:max(room, 0)]
+		b.truncated = true
+	}
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+// text - 저장할 문자열, 올바른 UTF-8 텍스트가 아니면 false
+// 잘린 경우 끝에서 잘린 문자를 제거하고 표시를 붙인다
+func (b *limitedBuffer) text() (string, bool) {
+	data := b.data
+	if b.truncated {
+		for i := 1; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	if !utf8.Valid(data) {
+		return "", false
+	}
+	if b.truncated {
+		return string(data) + truncatedBodySuffix, true
+	}
+	return string(data), true
+}
+
+// captureReader - 핸들러가 읽는 요청 본문을 그대로 전달하면서 앞부분을 복사
+// 본문을 미리 읽지 않으므로 스트리밍 요청도 영향을 받지 않는다
+type captureReader struct {
+	io.ReadCloser
+	buf *limitedBuffer
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.buf.Write(p[:n])
+	return n, err
+}
+
+// captureWriter - 응답을 그대로 쓰면서 앞부분을 복사
+type captureWriter struct {
+	gin.ResponseWriter
+	buf *limitedBuffer
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.buf.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// bodyCapture - 요청 하나의 본문 저장 상태
+type bodyCapture struct {
+	request  *limitedBuffer
+	response *limitedBuffer
+	writer   gin.ResponseWriter // 복원할 원래 writer
+}
+
+// startBodyCapture - 설정에 따라 요청 본문과 응답 writer를 감싼다 (비활성화면 nil)
+func startBodyCapture(c *gin.Context, config *MiddlewareConfig) *bodyCapture {
+	if config.MaxRequestBodyBytes == 0 && config.MaxResponseBodyBytes == 0 {
+		return nil
+	}
+
+	bc := &bodyCapture{writer: c.Writer}
+	if config.MaxRequestBodyBytes > 0 && c.Request.Body != nil && isTextContentType(c.Request.Header.Get("Content-Type")) {
+		bc.request = &limitedBuffer{limit: config.MaxRequestBodyBytes}
+		c.Request.Body = &captureReader{ReadCloser: c.Request.Body, buf: bc.request}
+	}
+	if config.MaxResponseBodyBytes > 0 {
+		bc.response = &limitedBuffer{limit: config.MaxResponseBodyBytes}
+		c.Writer = &captureWriter{ResponseWriter: c.Writer, buf: bc.response}
+	}
+	return bc
+}
+
+// finish - 원래 writer를 복원하고 저장할 요청/응답 본문 반환
+func (bc *bodyCapture) finish(c *gin.Context) (string, string) {
+	if bc == nil {
+		return "", ""
+	}
+	c.Writer = bc.writer
+
+	var requestBody, responseBody string
+	if bc.request != nil {
+		requestBody, _ = bc.request.text()
+	}
+	if bc.response != nil && isTextContentType(c.Writer.Header().Get("Content-Type")) {
+		responseBody, _ = bc.response.text()
+	}
+	return requestBody, responseBody
+}
+
+// isTextContentType - 저장할 수 있는 텍스트 형식인지 확인 (Content-Type이 없으면 내용으로 판단)
+func isTextContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/x-www-form-urlencoded",
+		"application/javascript", "application/graphql", "application/x-ndjson":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
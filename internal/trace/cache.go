@@ -0,0 +1,72 @@
+package trace
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const cacheHitKey = "trace_cache_hit"
+
+type cacheMarkerKey struct{}
+
+// cacheMarker - 요청 컨텍스트를 통해 공유되는 캐시 적중 표시
+// c.Copy()로 복사된 컨텍스트에서 호출해도 원래 요청에 반영된다
+type cacheMarker struct {
+	name atomic.Pointer[string]
+}
+
+// MarkCacheHit - 응답이 애플리케이션 캐시에서 제공되었음을 표시
+// 여러 번 호출하면 마지막 호출의 cacheName이 기록된다 (last-write-wins, trace 미들웨어 앞과 뒤, c.Copy()로 복사한 컨텍스트의 호출 모두 포함)
+// trace 미들웨어보다 앞이나 뒤 어느 위치의 미들웨어에서 호출해도 되지만, trace 미들웨어가 반환한 뒤의 호출(앞 미들웨어의 c.Next() 이후 등)은 기록되지 않는다
+func MarkCacheHit(c *gin.Context, cacheName string) {
+	c.Set(cacheHitKey, cacheName)
+	if marker, ok := c.Request.Context().Value(cacheMarkerKey{}).(*cacheMarker); ok {
+		marker.name.Store(&cacheName)
+	}
+}
+
+// installCacheMarker - 요청 컨텍스트에 캐시 표시 저장소 등록
+func installCacheMarker(c *gin.Context) *cacheMarker {
+	marker := &cacheMarker{}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), cacheMarkerKey{}, marker))
+	return marker
+}
+
+// cacheHit - 핸들러 실행 후 캐시 적중 여부와 캐시 이름 반환
+func cacheHit(c *gin.Context, marker *cacheMarker) (bool, string) {
+	if marker != nil {
+		if name := marker.name.Load(); name != nil {
+			return true, *name
+		}
+	}
+	if name, ok := c.Get(cacheHitKey); ok {
+		s, _ := name.(string)
+		return true, s
+	}
+	return false, ""
+}
+
+// CacheHitRatio - 기간 내 경로의 캐시 적중 비율 (0~1) 반환, 요청이 없으면 0
+func CacheHitRatio(ctx context.Context, db *gorm.DB, path string, from, to time.Time) (float64, error) {
+	var result struct {
+		Total int64
+		Hits  int64
+	}
+	steps, err := storeFor(db).stepsFrom(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	err = steps().
+		Where(requestStepsOnly).
+		Select("COUNT(*) AS total, SUM(CASE WHEN cache_hit THEN 1 ELSE 0 END) AS hits").
+		Where("path = ? AND created_at >= ? AND created_at < ?", path, from.Unix(), to.Unix()).
+		Scan(&result).Error
+	if err != nil || result.Total == 0 {
+		return 0, err
+	}
+	return float64(result.Hits) / float64(result.Total), nil
+}
@@ -0,0 +1,153 @@
+package trace_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// fakeCache - 경로가 cached에 있으면 캐시 적중으로 표시하는 캐시 미들웨어
+// abort가 true면 캐시된 응답을 보내고 요청을 중단하며, false면 적중만 표시하고 다음 핸들러로 넘긴다
+func fakeCache(name string, cached map[string]bool, abort bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cached[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+		trace.MarkCacheHit(c, name)
+		if abort {
+			c.String(http.StatusOK, "cached")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// cacheHits - 경로별 요청 Step
+func cacheHits(t *testing.T, tracer *trace.Tracer) map[string]trace.Step {
+	t.Helper()
+	steps := map[string]trace.Step{}
+	for _, step := range storedSteps(t, tracer, trace.QueryFilter{RequestsOnly: true}) {
+		steps[step.Path] = step
+	}
+	return steps
+}
+
+func TestMarkCacheHitBeforeAndAfterTracer(t *testing.T) {
+	cached := map[string]bool{"/items/hit": true}
+	tests := []struct {
+		name  string
+		chain func(tracer *trace.Tracer) []gin.HandlerFunc
+	}{
+		// 캐시 미들웨어가 먼저 실행되어 표시할 때는 요청 컨텍스트의 저장소가 아직 없으므로 gin.Context 값으로 전달
+		{"cache before tracer", func(tracer *trace.Tracer) []gin.HandlerFunc {
+			return []gin.HandlerFunc{fakeCache("edge", cached, false), tracer.Middleware()}
+		}},
+		{"cache after tracer", func(tracer *trace.Tracer) []gin.HandlerFunc {
+			return []gin.HandlerFunc{tracer.Middleware(), fakeCache("edge", cached, true)}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := gin.New()
+			r.Use(tt.chain(tracer)...)
+			handler := func(c *gin.Context) { c.String(http.StatusOK, "fresh") }
+			r.GET("/items/hit", handler)
+			r.GET("/items/miss", handler)
+			for _, path := range []string{"/items/hit", "/items/miss"} {
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path+"?user_id=u&access_token=a", nil))
+			}
+			stopTracer(t, tracer)
+
+			steps := cacheHits(t, tracer)
+			if hit := steps["/items/hit"]; !hit.CacheHit || hit.CacheName != "edge" {
+				t.Errorf("cached request recorded CacheHit=%v CacheName=%q, want true \"edge\"", hit.CacheHit, hit.CacheName)
+			}
+			if miss, ok := steps["/items/miss"]; !ok || miss.CacheHit || miss.CacheName != "" {
+				t.Errorf("uncached request recorded %+v, want no cache hit", miss)
+			}
+		})
+	}
+}
+
+func TestMarkCacheHitLastWriteWins(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(
+		fakeCache("edge", map[string]bool{"/before-and-after": true, "/copy": true}, false),
+		tracer.Middleware(),
+		fakeCache("local", map[string]bool{"/before-and-after": true}, false),
+	)
+	r.GET("/before-and-after", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/copy", func(c *gin.Context) {
+		// 핸들러가 반환하기 전에 c.Copy()로 복사한 컨텍스트에서 호출해도 원래 요청에 기록
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func(c *gin.Context) {
+			defer wg.Done()
+			trace.MarkCacheHit(c, "async")
+		}(c.Copy())
+		wg.Wait()
+		c.Status(http.StatusOK)
+	})
+	for _, path := range []string{"/before-and-after", "/copy"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path+"?user_id=u&access_token=a", nil))
+	}
+	stopTracer(t, tracer)
+
+	steps := cacheHits(t, tracer)
+	if got := steps["/before-and-after"].CacheName; got != "local" {
+		t.Errorf("marked before and after the tracer: CacheName = %q, want the last call %q", got, "local")
+	}
+	if got := steps["/copy"].CacheName; got != "async" {
+		t.Errorf("marked on a copied context: CacheName = %q, want the last call %q", got, "async")
+	}
+}
+
+func TestCacheHitRatio(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	tracer, err := trace.New(trace.Config{DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(tracer.Middleware(), func(c *gin.Context) {
+		if c.Query("cached") == "1" {
+			trace.MarkCacheHit(c, "response-cache")
+		}
+	})
+	r.GET("/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/other", func(c *gin.Context) { c.Status(http.StatusOK) })
+	for _, target := range []string{"/items?cached=1&", "/items?cached=1&", "/items?cached=1&", "/items?", "/other?"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target+"user_id=u&access_token=a", nil))
+	}
+	stopTracer(t, tracer)
+
+	now := time.Now()
+	ratio, err := trace.CacheHitRatio(context.Background(), db, "/items", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil || ratio != 0.75 {
+		t.Fatalf("CacheHitRatio(/items) = %v, %v, want 0.75", ratio, err)
+	}
+	if ratio, err := trace.CacheHitRatio(context.Background(), db, "/other", now.Add(-time.Hour), now.Add(time.Hour)); err != nil || ratio != 0 {
+		t.Fatalf("CacheHitRatio(/other) = %v, %v, want 0", ratio, err)
+	}
+	if ratio, err := trace.CacheHitRatio(context.Background(), db, "/none", now.Add(-time.Hour), now.Add(time.Hour)); err != nil || ratio != 0 {
+		t.Fatalf("CacheHitRatio without requests = %v, %v, want 0", ratio, err)
+	}
+}
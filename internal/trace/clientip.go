@@ -0,0 +1,175 @@
+package trace
+
+import (
+	"log"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvalidClientIPs - IP로 파싱되지 않아 빈 값으로 저장된 요청 수
+func (t *Tracer) InvalidClientIPs() uint64 {
+	if t == nil {
+		return 0
+	}
+	return uint64(t.invalidClientIPs.Load())
+}
+
+// InvalidClientIPs - 기본 Tracer에서 IP로 파싱되지 않아 빈 값으로 저장된 요청 수
+func InvalidClientIPs() uint64 {
+	return defaultTracer.Load().InvalidClientIPs()
+}
+
+func (t *Tracer) countInvalidClientIP() {
+	if t != nil {
+		t.invalidClientIPs.Add(1)
+	}
+}
+
+// WithTrustedProxies 신뢰할 프록시 CIDR 설정 (gin 엔진 설정과 무관하게 미들웨어에서 직접 판단)
+// 단일 IP도 허용하며, 파싱할 수 없는 값은 로그를 남기고 무시한다
+func WithTrustedProxies(cidrs ...string) MiddlewareOption {
+	prefixes := parsePrefixes("trusted proxy", cidrs)
+	return func(config *MiddlewareConfig) {
+		config.TrustedProxies = prefixes
+	}
+}
+
+// WithClientIPHeader 클라이언트 IP를 읽을 헤더와 위치 설정
+// position은 오른쪽부터 1로 시작 (예: X-Forwarded-For의 오른쪽에서 두 번째 값이면 2)
+// 헤더는 WithTrustedProxies에 속한 출처의 요청에서만 읽으므로 함께 설정해야 한다 (없으면 연결 주소 사용)
+func WithClientIPHeader(name string, position int) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.ClientIPHeader = name
+		config.ClientIPHeaderPosition = max(position, 1)
+	}
+}
+
+// parsePrefixes - IP 또는 CIDR 목록 파싱 (파싱할 수 없는 값은 로그를 남기고 무시)
+func parsePrefixes(what string, cidrs []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := parsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			log.Printf("invalid %s %q: %v", what, cidr, err)
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// normalizeIP - IP 문자열을 표준 형식으로 변환 (zone 제거, IPv4-mapped IPv6는 IPv4로)
+func normalizeIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.WithZone("").Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// warnUntrustedClientIPHeader - 신뢰할 프록시 없이 설정한 ClientIPHeader는 읽지 않음을 알림
+func warnUntrustedClientIPHeader(config *MiddlewareConfig) {
+	if config.ClientIPHeader != "" && len(config.TrustedProxies) == 0 {
+		log.Printf("trace: client IP header %q is ignored because no trusted proxies are configured (WithTrustedProxies)", config.ClientIPHeader)
+	}
+}
+
+// clientIP - 설정에 따라 클라이언트 IP 추출
+// 옵션이 없으면 gin의 ClientIP를 같은 방식으로 정규화하여 사용하고, 파싱할 수 없는 IP는 tracer에 집계한다
+func clientIP(c *gin.Context, config *MiddlewareConfig, tracer *Tracer) string {
+	if len(config.TrustedProxies) == 0 && config.ClientIPHeader == "" {
+		addr, ok := normalizeIP(c.ClientIP())
+		if !ok {
+			tracer.countInvalidClientIP()
+			return ""
+		}
+		return addr.String()
+	}
+
+	host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		host = c.Request.RemoteAddr
+	}
+	remote, ok := normalizeIP(host)
+	if !ok {
+		tracer.countInvalidClientIP()
+		return ""
+	}
+
+	// 신뢰하지 않는 출처의 요청은 프록시 헤더를 무시 (헤더 위조 방지, 신뢰할 프록시가 없으면 모든 출처)
+	if !isTrusted(remote, config.TrustedProxies) {
+		return remote.String()
+	}
+
+	if config.ClientIPHeader != "" {
+		values := headerValues(c, config.ClientIPHeader)
+		idx := len(values) - config.ClientIPHeaderPosition
+		if idx < 0 {
+			return remote.String()
+		}
+		addr, ok := normalizeIP(values[idx])
+		if !ok {
+			tracer.countInvalidClientIP()
+			return ""
+		}
+		return addr.String()
+	}
+
+	// X-Forwarded-For를 오른쪽부터 확인하여 신뢰하지 않는 첫 번째 IP를 클라이언트로 판단
+	values := headerValues(c, "X-Forwarded-For")
+	for i := len(values) - 1; i >= 0; i-- {
+		addr, ok := normalizeIP(values[i])
+		if !ok {
+			tracer.countInvalidClientIP()
+			return ""
+		}
+		if !isTrusted(addr, config.TrustedProxies) {
+			return addr.String()
+		}
+	}
+	return remote.String()
+}
+
+// headerValues - 여러 줄로 나뉜 헤더까지 포함하여 쉼표로 구분된 값 목록 반환
+func headerValues(c *gin.Context, name string) []string {
+	var values []string
+	for _, line := range c.Request.Header.Values(name) {
+		for _, v := range strings.Split(line, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,128 @@
+package trace_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// serveFrom - 미들웨어로 X-Forwarded-For가 있는 요청을 한 번 처리 (httptest의 연결 주소는 192.0.2.1)
+func serveFrom(t *testing.T, middleware gin.HandlerFunc, forwardedFor string) {
+	t.Helper()
+	r := gin.New()
+	r.Use(middleware)
+	r.GET("/ip", func(c *gin.Context) { c.Status(200) })
+	req := httptest.NewRequest("GET", "/ip?user_id=user-1&access_token=token", nil)
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func storedIPs(t *testing.T, tracer *trace.Tracer) []string {
+	t.Helper()
+	stopTracer(t, tracer)
+	result, err := tracer.Store().Query(context.Background(), trace.QueryFilter{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ips []string
+	for _, step := range result.Steps {
+		ips = append(ips, step.IP)
+	}
+	return ips
+}
+
+func TestClientIPHeaderIsReadOnlyFromTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []trace.MiddlewareOption
+		want    string
+	}{
+		{"no trusted proxies", []trace.MiddlewareOption{trace.WithClientIPHeader("X-Forwarded-For", 1)}, "192.0.2.1"},
+		{"untrusted peer", []trace.MiddlewareOption{trace.WithTrustedProxies("10.0.0.0/8"), trace.WithClientIPHeader("X-Forwarded-For", 1)}, "192.0.2.1"},
+		{"trusted peer", []trace.MiddlewareOption{trace.WithTrustedProxies("192.0.2.0/24"), trace.WithClientIPHeader("X-Forwarded-For", 1)}, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			serveFrom(t, tracer.Middleware(tt.options...), "203.0.113.7")
+			if ips := storedIPs(t, tracer); len(ips) != 1 || ips[0] != tt.want {
+				t.Fatalf("IPs = %v, want [%s]", ips, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidClientIPsAreCountedPerTracer(t *testing.T) {
+	newTracer := func() *trace.Tracer {
+		tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tracer
+	}
+	bad, good := newTracer(), newTracer()
+	options := []trace.MiddlewareOption{trace.WithTrustedProxies("192.0.2.0/24"), trace.WithClientIPHeader("X-Forwarded-For", 1)}
+
+	serveFrom(t, bad.Middleware(options...), "not-an-ip")
+	serveFrom(t, good.Middleware(options...), "203.0.113.7")
+
+	if ips := storedIPs(t, bad); len(ips) != 1 || ips[0] != "" {
+		t.Fatalf("IPs = %v, want one empty IP", ips)
+	}
+	storedIPs(t, good)
+	if got := bad.InvalidClientIPs(); got != 1 || bad.Stats().InvalidClientIPs != 1 {
+		t.Fatalf("bad tracer InvalidClientIPs = %d (stats %d), want 1", got, bad.Stats().InvalidClientIPs)
+	}
+	if got := good.InvalidClientIPs(); got != 0 {
+		t.Fatalf("good tracer InvalidClientIPs = %d, want 0", got)
+	}
+}
+
+func TestDefaultClientIPIsNormalized(t *testing.T) {
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		want         string
+		invalid      uint64
+	}{
+		{"remote address", "192.0.2.1:1234", "", "192.0.2.1", 0},
+		{"IPv4-mapped remote address", "[::ffff:192.0.2.9]:1234", "", "192.0.2.9", 0},
+		{"IPv4-mapped forwarded address", "192.0.2.1:1234", "::ffff:203.0.113.7", "203.0.113.7", 0},
+		{"IPv6 forwarded address", "192.0.2.1:1234", "2001:DB8::0001", "2001:db8::1", 0},
+		{"unparsable remote address", "not-an-ip", "", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 옵션 없이 gin의 ClientIP를 사용하는 경로
+			r := gin.New()
+			r.Use(tracer.Middleware())
+			r.GET("/ip", func(c *gin.Context) { c.Status(200) })
+			req := httptest.NewRequest("GET", "/ip?user_id=user-1&access_token=token", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if ips := storedIPs(t, tracer); len(ips) != 1 || ips[0] != tt.want {
+				t.Fatalf("IPs = %q, want [%q]", ips, tt.want)
+			}
+			if got := tracer.InvalidClientIPs(); got != tt.invalid {
+				t.Fatalf("InvalidClientIPs = %d, want %d", got, tt.invalid)
+			}
+		})
+	}
+}
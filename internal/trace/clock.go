@@ -0,0 +1,42 @@
+package trace
+
+import "time"
+
+// clock - 워커와 주기 작업이 사용하는 시계 (테스트에서 wakeup 횟수를 세기 위해 교체)
+type clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) clockTimer
+}
+
+// clockTimer - time.Timer와 같은 동작의 타이머
+type clockTimer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+// systemClock - 실제 시계
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) clockTimer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct{ t *time.Timer }
+
+// signal - 대기 중인 쪽을 깨우는 신호 (이미 신호가 있으면 버림)
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+
+// Reset - Go 1.23부터 Reset은 이전 만료 값을 채널에 남기지 않는다
+func (t systemTimer) Reset(d time.Duration) { t.t.Reset(d) }
+
+func (t systemTimer) Stop() { t.t.Stop() }
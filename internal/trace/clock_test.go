@@ -0,0 +1,78 @@
+package trace
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock - Advance로만 시간이 흐르는 시계
+// 타이머가 만료될 때마다 fired를 늘리고, 타이머를 만들거나 Reset할 때마다 resets로 대기 시간을 알린다
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	fired  int
+	resets chan time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1_700_000_000, 0), resets: make(chan time.Duration, 100)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) NewTimer(d time.Duration) clockTimer {
+	f.mu.Lock()
+	t := &fakeTimer{clk: f, c: make(chan time.Time, 1), when: f.now.Add(d), active: true}
+	f.timers = append(f.timers, t)
+	f.mu.Unlock()
+	f.resets <- d
+	return t
+}
+
+// Advance - 시간을 d만큼 보내고 만료된 타이머 발동
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for _, t := range f.timers {
+		if t.active && !t.when.After(f.now) {
+			t.active = false
+			f.fired++
+			t.c <- f.now
+		}
+	}
+}
+
+// Fired - 지금까지 발동한 타이머 수 (워커 wakeup 수)
+func (f *fakeClock) Fired() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fired
+}
+
+type fakeTimer struct {
+	clk    *fakeClock
+	c      chan time.Time
+	when   time.Time
+	active bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Reset(d time.Duration) {
+	t.clk.mu.Lock()
+	t.when, t.active = t.clk.now.Add(d), true
+	t.clk.mu.Unlock()
+	t.clk.resets <- d
+}
+
+func (t *fakeTimer) Stop() {
+	t.clk.mu.Lock()
+	defer t.clk.mu.Unlock()
+	t.active = false
+}
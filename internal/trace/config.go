@@ -0,0 +1,249 @@
+package trace
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// EffectiveSettings Start에 적용된 실제 설정 (디버깅 및 /stats 노출용)
+type EffectiveSettings struct {
+	Name                 string   `json:"name,omitempty"`
+	FlushInterval        string   `json:"flush_interval"`
+	FlushJitter          string   `json:"flush_jitter"`
+	AlignFlushTo         string   `json:"align_flush_to"`
+	BatchSize            int      `json:"batch_size"`
+	BufferSize           int      `json:"buffer_size"`
+	ExpectedRPS          int      `json:"expected_rps"`
+	MaxOpenConn          int      `json:"max_open_conn"`
+	MaxIdleConn          int      `json:"max_idle_conn"`
+	ConnMaxLifetime      string   `json:"conn_max_lifetime"`
+	PoolMode             string   `json:"pool_mode"`
+	ServiceName          string   `json:"service_name,omitempty"`
+	Environment          string   `json:"environment,omitempty"`
+	Version              string   `json:"version"`
+	TopEndpointsCacheTTL string   `json:"top_endpoints_cache_ttl"`
+	Overflow             string   `json:"overflow"`
+	PerUserOrdering      bool     `json:"per_user_ordering"`
+	ShardDepths          []int    `json:"shard_depths,omitempty"`
+	TraceIDSecretSet     bool     `json:"trace_id_secret_set"`
+	LegacyTraceIDs       bool     `json:"legacy_trace_ids"`
+	Retention            string   `json:"retention,omitempty"`
+	PartitionByDay       bool     `json:"partition_by_day"`
+	TableName            string   `json:"table_name"`
+	SpillPath            string   `json:"spill_path,omitempty"`
+	ShutdownSnapshotPath string   `json:"shutdown_snapshot_path,omitempty"`
+	IPAnonymization      string   `json:"ip_anonymization"`
+	EncryptionEnabled    bool     `json:"encryption_enabled"`
+	GeoEnrichment        bool     `json:"geo_enrichment"`
+	Warnings             []string `json:"warnings,omitempty"`
+
+	// KubernetesMetadata가 true일 때 Step에 기록하는 Pod 정보
+	Kubernetes *KubernetesMetadata `json:"kubernetes,omitempty"`
+}
+
+// newEffectiveSettings - Tracer에 적용된 설정 요약
+func newEffectiveSettings(cfg Config, version string, warnings []string) EffectiveSettings {
+	settings := EffectiveSettings{
+		Name:                 cfg.Name,
+		FlushInterval:        cfg.FlushInterval.String(),
+		FlushJitter:          cfg.FlushJitter.String(),
+		AlignFlushTo:         cfg.AlignFlushTo.String(),
+		BatchSize:            cfg.BatchSize,
+		BufferSize:           cfg.BufferSize,
+		ExpectedRPS:          cfg.ExpectedRPS,
+		MaxOpenConn:          cfg.MaxOpenConn,
+		MaxIdleConn:          cfg.MaxIdleConn,
+		ConnMaxLifetime:      cfg.ConnMaxLifetime.String(),
+		PoolMode:             poolMode(cfg),
+		ServiceName:          cfg.ServiceName,
+		Environment:          cfg.Environment,
+		Version:              version,
+		TopEndpointsCacheTTL: topEndpointsCacheTTL(cfg).String(),
+		Overflow:             cfg.Overflow.String(),
+		PerUserOrdering:      cfg.PerUserOrdering,
+		TraceIDSecretSet:     len(cfg.TraceIDSecret) > 0,
+		LegacyTraceIDs:       cfg.LegacyTraceIDs,
+		PartitionByDay:       cfg.PartitionByDay,
+		TableName:            qualifiedTableName(cfg),
+		SpillPath:            cfg.SpillPath,
+		ShutdownSnapshotPath: cfg.ShutdownSnapshotPath,
+		IPAnonymization:      cfg.IPAnonymization.String(),
+		EncryptionEnabled:    len(cfg.EncryptionKey) > 0 || cfg.EncryptionKeyFunc != nil,
+		GeoEnrichment:        cfg.GeoResolver != nil,
+		Warnings:             warnings,
+	}
+	if cfg.Retention > 0 {
+		settings.Retention = cfg.Retention.String()
+	}
+	return settings
+}
+
+// applyDefaults - 생략한(0인) 값을 DefaultConfig의 값으로 채움 (음수는 validateConfig에서 에러)
+// BatchSize와 BufferSize 중 하나만 지정하면 다른 하나를 그에 맞춰 BatchSize가 BufferSize를 넘지 않게 한다
+// SeparateConnection의 MaxOpenConn은 전용 풀 기본값(2)을 사용하도록 그대로 둔다
+func applyDefaults(cfg Config) Config {
+	defaults := DefaultConfig()
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = defaults.FlushInterval
+	}
+	switch {
+	case cfg.BatchSize == 0 && cfg.BufferSize == 0:
+		cfg.BatchSize, cfg.BufferSize = defaults.BatchSize, defaults.BufferSize
+	case cfg.BatchSize == 0:
+		cfg.BatchSize = min(defaults.BatchSize, max(cfg.BufferSize, 1))
+	case cfg.BufferSize == 0:
+		cfg.BufferSize = max(defaults.BufferSize, cfg.BatchSize)
+	}
+	if cfg.MaxOpenConn == 0 && !cfg.SeparateConnection {
+		cfg.MaxOpenConn = defaults.MaxOpenConn
+	}
+	if cfg.MaxIdleConn == 0 {
+		cfg.MaxIdleConn = defaults.MaxIdleConn
+	}
+	if cfg.ConnMaxLifetime == 0 {
+		cfg.ConnMaxLifetime = defaults.ConnMaxLifetime
+	}
+	if cfg.IPHashRotation == 0 {
+		cfg.IPHashRotation = defaultIPHashRotation
+	}
+	if cfg.ErrorRateWindow == 0 {
+		cfg.ErrorRateWindow = defaultErrorRateWindow
+	}
+	if cfg.ErrorRateMinRequests == 0 {
+		cfg.ErrorRateMinRequests = defaultErrorRateMinRequests
+	}
+	if cfg.EnrichConcurrency == 0 {
+		cfg.EnrichConcurrency = defaultEnrichConcurrency
+	}
+	if cfg.EnrichTimeout == 0 {
+		cfg.EnrichTimeout = defaultEnrichTimeout
+	}
+	if cfg.OperationDeadline == 0 {
+		cfg.OperationDeadline = defaultOperationDeadline
+	}
+	if cfg.ExitFlushTimeout == 0 {
+		cfg.ExitFlushTimeout = defaultExitFlushTimeout
+	}
+	return cfg
+}
+
+// validateConfig - 설정 값과 값 사이의 관계를 검사
+// 잘못된 설정은 error로, 동작은 하지만 의도와 다를 가능성이 큰 설정은 경고로 반환한다
+func validateConfig(cfg Config) (warnings []string, err error) {
+	var errs []error
+
+	if cfg.DB == nil && cfg.Sink == nil && cfg.DSN == "" {
+		errs = append(errs, errors.New("no sink configured: set DB, DSN or Sink"))
+	}
+	if cfg.DSN != "" && cfg.SeparateConnection {
+		errs = append(errs, errors.New("SeparateConnection is not needed with DSN: a DSN connection is always dedicated to tracing"))
+	}
+	if cfg.FlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("FlushInterval must be positive (got %s); synchronous per-step flushing is not supported, use a small interval such as 100ms instead", cfg.FlushInterval))
+	}
+	if cfg.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("BatchSize must be positive (got %d); leave it 0 to use the default of %d", cfg.BatchSize, DefaultConfig().BatchSize))
+	}
+	if cfg.BufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("BufferSize must be positive (got %d); leave it 0 to use the default of %d", cfg.BufferSize, DefaultConfig().BufferSize))
+	}
+	if cfg.MaxOpenConn < 0 || cfg.MaxIdleConn < 0 || cfg.ConnMaxLifetime < 0 {
+		errs = append(errs, fmt.Errorf("MaxOpenConn (%d), MaxIdleConn (%d) and ConnMaxLifetime (%s) must not be negative", cfg.MaxOpenConn, cfg.MaxIdleConn, cfg.ConnMaxLifetime))
+	}
+	if cfg.BatchSize > 0 && cfg.BufferSize > 0 && cfg.BatchSize > cfg.BufferSize {
+		errs = append(errs, fmt.Errorf("BatchSize (%d) must not exceed BufferSize (%d): the buffer can never hold a full batch, so size-triggered flushes never fire and every step waits for FlushInterval", cfg.BatchSize, cfg.BufferSize))
+	}
+	if cfg.FlushJitter < 0 {
+		errs = append(errs, fmt.Errorf("FlushJitter must not be negative (got %s)", cfg.FlushJitter))
+	}
+	if cfg.AlignFlushTo < 0 {
+		errs = append(errs, fmt.Errorf("AlignFlushTo must not be negative (got %s)", cfg.AlignFlushTo))
+	}
+	if cfg.OrderingShards < 0 || cfg.ShardBufferSize < 0 {
+		errs = append(errs, fmt.Errorf("OrderingShards (%d) and ShardBufferSize (%d) must not be negative", cfg.OrderingShards, cfg.ShardBufferSize))
+	}
+	if cfg.Overflow.mode == overflowBlockTimeout && cfg.Overflow.timeout <= 0 {
+		errs = append(errs, fmt.Errorf("BlockWithTimeout requires a positive timeout (got %s); use DropNewest to never wait", cfg.Overflow.timeout))
+	}
+	if cfg.SpillMaxBytes < 0 || cfg.SpillReplayInterval < 0 {
+		errs = append(errs, fmt.Errorf("SpillMaxBytes (%d) and SpillReplayInterval (%s) must not be negative", cfg.SpillMaxBytes, cfg.SpillReplayInterval))
+	}
+	if cfg.HealthMaxBufferUtilization < 0 || cfg.HealthMaxBufferUtilization > 1 {
+		errs = append(errs, fmt.Errorf("HealthMaxBufferUtilization must be between 0 and 1 (got %g)", cfg.HealthMaxBufferUtilization))
+	}
+	if cfg.IPAnonymization < IPKeep || cfg.IPAnonymization > IPHash {
+		errs = append(errs, fmt.Errorf("IPAnonymization %s is not supported; use IPKeep, IPTruncate or IPHash", cfg.IPAnonymization))
+	}
+	if len(cfg.EncryptionKey) > 0 && cfg.EncryptionKeyFunc != nil {
+		errs = append(errs, errors.New("set either EncryptionKey or EncryptionKeyFunc, not both"))
+	}
+	if cfg.IPHashRotation < 0 {
+		errs = append(errs, fmt.Errorf("IPHashRotation must not be negative (got %s)", cfg.IPHashRotation))
+	}
+	if cfg.ErrorRateWindow < 0 || cfg.ErrorRateMinRequests < 0 {
+		errs = append(errs, fmt.Errorf("ErrorRateWindow (%s) and ErrorRateMinRequests (%d) must not be negative", cfg.ErrorRateWindow, cfg.ErrorRateMinRequests))
+	}
+	if cfg.ErrorRateThreshold < 0 || cfg.ErrorRateThreshold > 1 {
+		errs = append(errs, fmt.Errorf("ErrorRateThreshold must be between 0 and 1 (got %g)", cfg.ErrorRateThreshold))
+	}
+	if cfg.Rollups && cfg.DB == nil && cfg.DSN == "" {
+		errs = append(errs, errors.New("Rollups requires DB or DSN: rollups are written to a database table"))
+	}
+	if cfg.Ledger && cfg.DB == nil && cfg.DSN == "" {
+		errs = append(errs, errors.New("Ledger requires DB or DSN: the ledger is written to a database table"))
+	}
+	if cfg.EnrichConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("EnrichConcurrency must not be negative (got %d)", cfg.EnrichConcurrency))
+	}
+	if cfg.EnrichTimeout < 0 {
+		errs = append(errs, fmt.Errorf("EnrichTimeout must not be negative (got %s)", cfg.EnrichTimeout))
+	}
+	if cfg.OperationDeadline < 0 {
+		errs = append(errs, fmt.Errorf("OperationDeadline must not be negative (got %s)", cfg.OperationDeadline))
+	}
+	if cfg.ExitFlushTimeout < 0 {
+		errs = append(errs, fmt.Errorf("ExitFlushTimeout must not be negative (got %s)", cfg.ExitFlushTimeout))
+	}
+	if cfg.ExpectedRPS < 0 {
+		errs = append(errs, fmt.Errorf("ExpectedRPS must not be negative (got %d)", cfg.ExpectedRPS))
+	}
+	if cfg.Retention < 0 || cfg.RetentionInterval < 0 || cfg.RetentionBatchSize < 0 {
+		errs = append(errs, fmt.Errorf("Retention (%s), RetentionInterval (%s) and RetentionBatchSize (%d) must not be negative", cfg.Retention, cfg.RetentionInterval, cfg.RetentionBatchSize))
+	}
+	if cfg.TableName != "" && !validIdentifier(cfg.TableName) {
+		errs = append(errs, fmt.Errorf("TableName %q may only contain letters, digits and underscores", cfg.TableName))
+	}
+	if cfg.TableSchema != "" && !validIdentifier(cfg.TableSchema) {
+		errs = append(errs, fmt.Errorf("TableSchema %q may only contain letters, digits and underscores", cfg.TableSchema))
+	}
+	if cfg.PartitionByDay && cfg.Sink != nil {
+		errs = append(errs, errors.New("PartitionByDay applies to the built-in DB sink; leave Sink empty to use it"))
+	}
+	if cfg.Retention > 0 && cfg.DB == nil && (cfg.DSN == "" || cfg.Sink != nil) {
+		errs = append(errs, errors.New("Retention requires DB: steps written to a custom Sink must be expired by that store"))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("trace: invalid config: %w", errors.Join(errs...))
+	}
+
+	// 플러시 간격 동안 쌓이는 예상 Step 수보다 버퍼가 작으면 드롭 발생
+	if cfg.ExpectedRPS > 0 {
+		expected := int(float64(cfg.ExpectedRPS) * cfg.FlushInterval.Seconds())
+		if cfg.BufferSize < expected {
+			warnings = append(warnings, fmt.Sprintf("BufferSize %d is below ExpectedRPS %d x FlushInterval %s = %d steps; steps will be dropped between flushes unless batches fill first", cfg.BufferSize, cfg.ExpectedRPS, cfg.FlushInterval, expected))
+		}
+	}
+	if cfg.FlushJitter >= cfg.FlushInterval {
+		warnings = append(warnings, fmt.Sprintf("FlushJitter %s is not smaller than FlushInterval %s; flushes may be delayed by up to twice the interval", cfg.FlushJitter, cfg.FlushInterval))
+	}
+
+	return warnings, nil
+}
+
+// logConfigWarnings - 설정 경고 출력
+func logConfigWarnings(warnings []string) {
+	for _, w := range warnings {
+		log.Printf("trace config warning: %s", w)
+	}
+}
@@ -0,0 +1,172 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	defaults := DefaultConfig()
+	tests := []struct {
+		name   string
+		cfg    Config
+		verify func(t *testing.T, cfg Config)
+	}{
+		{"zero config uses defaults", Config{}, func(t *testing.T, cfg Config) {
+			if cfg.FlushInterval != defaults.FlushInterval || cfg.BatchSize != defaults.BatchSize || cfg.BufferSize != defaults.BufferSize ||
+				cfg.MaxOpenConn != defaults.MaxOpenConn || cfg.MaxIdleConn != defaults.MaxIdleConn || cfg.ConnMaxLifetime != defaults.ConnMaxLifetime {
+				t.Errorf("got %+v", cfg)
+			}
+			if cfg.IPHashRotation != defaultIPHashRotation || cfg.ErrorRateWindow != defaultErrorRateWindow || cfg.ErrorRateMinRequests != defaultErrorRateMinRequests ||
+				cfg.EnrichConcurrency != defaultEnrichConcurrency || cfg.EnrichTimeout != defaultEnrichTimeout ||
+				cfg.OperationDeadline != defaultOperationDeadline || cfg.ExitFlushTimeout != defaultExitFlushTimeout {
+				t.Errorf("optional defaults not applied: %+v", cfg)
+			}
+		}},
+		{"small BufferSize lowers BatchSize", Config{BufferSize: 50}, func(t *testing.T, cfg Config) {
+			if cfg.BatchSize != 50 || cfg.BufferSize != 50 {
+				t.Errorf("BatchSize %d, BufferSize %d, want 50 and 50", cfg.BatchSize, cfg.BufferSize)
+			}
+		}},
+		{"large BufferSize keeps default BatchSize", Config{BufferSize: 5000}, func(t *testing.T, cfg Config) {
+			if cfg.BatchSize != defaults.BatchSize || cfg.BufferSize != 5000 {
+				t.Errorf("BatchSize %d, BufferSize %d", cfg.BatchSize, cfg.BufferSize)
+			}
+		}},
+		{"negative BufferSize leaves BatchSize at least 1", Config{BufferSize: -1}, func(t *testing.T, cfg Config) {
+			if cfg.BatchSize != 1 || cfg.BufferSize != -1 {
+				t.Errorf("BatchSize %d, BufferSize %d, want 1 and -1", cfg.BatchSize, cfg.BufferSize)
+			}
+		}},
+		{"large BatchSize raises BufferSize", Config{BatchSize: 2000}, func(t *testing.T, cfg Config) {
+			if cfg.BatchSize != 2000 || cfg.BufferSize != 2000 {
+				t.Errorf("BatchSize %d, BufferSize %d, want 2000 and 2000", cfg.BatchSize, cfg.BufferSize)
+			}
+		}},
+		{"small BatchSize keeps default BufferSize", Config{BatchSize: 10}, func(t *testing.T, cfg Config) {
+			if cfg.BatchSize != 10 || cfg.BufferSize != defaults.BufferSize {
+				t.Errorf("BatchSize %d, BufferSize %d", cfg.BatchSize, cfg.BufferSize)
+			}
+		}},
+		{"both sizes kept", Config{BatchSize: 1000, BufferSize: 100}, func(t *testing.T, cfg Config) {
+			if cfg.BatchSize != 1000 || cfg.BufferSize != 100 {
+				t.Errorf("BatchSize %d, BufferSize %d, want them unchanged", cfg.BatchSize, cfg.BufferSize)
+			}
+		}},
+		{"separate connection keeps its own pool default", Config{SeparateConnection: true}, func(t *testing.T, cfg Config) {
+			if cfg.MaxOpenConn != 0 {
+				t.Errorf("MaxOpenConn = %d, want 0", cfg.MaxOpenConn)
+			}
+		}},
+		{"negative values are left for validation", Config{FlushInterval: -time.Second, MaxIdleConn: -1}, func(t *testing.T, cfg Config) {
+			if cfg.FlushInterval != -time.Second || cfg.MaxIdleConn != -1 {
+				t.Errorf("got FlushInterval %s, MaxIdleConn %d", cfg.FlushInterval, cfg.MaxIdleConn)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.verify(t, applyDefaults(tt.cfg))
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	db := &gorm.DB{}
+	tests := []struct {
+		name string
+		// 기본값을 채운 유효한 설정(DB 사용)에 적용할 변경 (applyDefaults 전에 적용)
+		modify func(cfg *Config)
+		// 에러 또는 경고에 포함되어야 하는 문자열 (빈 값이면 없어야 함)
+		wantErr, wantWarning string
+	}{
+		{"valid", func(cfg *Config) {}, "", ""},
+		{"no sink", func(cfg *Config) { cfg.DB = nil }, "no sink configured", ""},
+		{"DSN with separate connection", func(cfg *Config) { cfg.DB, cfg.DSN, cfg.SeparateConnection = nil, "file::memory:", true }, "SeparateConnection is not needed with DSN", ""},
+		{"negative FlushInterval", func(cfg *Config) { cfg.FlushInterval = -time.Second }, "FlushInterval must be positive (got -1s)", ""},
+		{"negative BatchSize", func(cfg *Config) { cfg.BatchSize = -1 }, "BatchSize must be positive (got -1)", ""},
+		{"negative BufferSize", func(cfg *Config) { cfg.BufferSize = -1 }, "BufferSize must be positive (got -1)", ""},
+		{"negative pool settings", func(cfg *Config) { cfg.MaxOpenConn = -1 }, "MaxOpenConn (-1), MaxIdleConn (5) and ConnMaxLifetime (1h0m0s) must not be negative", ""},
+		{"BatchSize above BufferSize", func(cfg *Config) { cfg.BatchSize, cfg.BufferSize = 1000, 100 }, "BatchSize (1000) must not exceed BufferSize (100)", ""},
+		{"negative FlushJitter", func(cfg *Config) { cfg.FlushJitter = -time.Second }, "FlushJitter must not be negative", ""},
+		{"negative AlignFlushTo", func(cfg *Config) { cfg.AlignFlushTo = -time.Second }, "AlignFlushTo must not be negative", ""},
+		{"negative ordering shards", func(cfg *Config) { cfg.OrderingShards = -1 }, "OrderingShards (-1) and ShardBufferSize (0) must not be negative", ""},
+		{"BlockWithTimeout without timeout", func(cfg *Config) { cfg.Overflow = BlockWithTimeout(0) }, "BlockWithTimeout requires a positive timeout", ""},
+		{"negative spill settings", func(cfg *Config) { cfg.SpillMaxBytes = -1 }, "SpillMaxBytes (-1) and SpillReplayInterval (0s) must not be negative", ""},
+		{"health utilization above 1", func(cfg *Config) { cfg.HealthMaxBufferUtilization = 1.5 }, "HealthMaxBufferUtilization must be between 0 and 1 (got 1.5)", ""},
+		{"health utilization below 0", func(cfg *Config) { cfg.HealthMaxBufferUtilization = -0.1 }, "HealthMaxBufferUtilization must be between 0 and 1", ""},
+		{"unknown IP anonymization", func(cfg *Config) { cfg.IPAnonymization = IPHash + 1 }, "is not supported; use IPKeep, IPTruncate or IPHash", ""},
+		{"both encryption keys", func(cfg *Config) {
+			cfg.EncryptionKey = make([]byte, 32)
+			cfg.EncryptionKeyFunc = func() ([]byte, error) { return nil, nil }
+		}, "set either EncryptionKey or EncryptionKeyFunc, not both", ""},
+		{"negative IPHashRotation", func(cfg *Config) { cfg.IPHashRotation = -time.Hour }, "IPHashRotation must not be negative", ""},
+		{"negative error rate window", func(cfg *Config) { cfg.ErrorRateMinRequests = -1 }, "ErrorRateWindow (1m0s) and ErrorRateMinRequests (-1) must not be negative", ""},
+		{"error rate threshold above 1", func(cfg *Config) { cfg.ErrorRateThreshold = 2 }, "ErrorRateThreshold must be between 0 and 1 (got 2)", ""},
+		{"rollups without DB", func(cfg *Config) { cfg.DB, cfg.Sink, cfg.Rollups = nil, nopSink{}, true }, "Rollups requires DB or DSN", ""},
+		{"ledger without DB", func(cfg *Config) { cfg.DB, cfg.Sink, cfg.Ledger = nil, nopSink{}, true }, "Ledger requires DB or DSN", ""},
+		{"negative EnrichConcurrency", func(cfg *Config) { cfg.EnrichConcurrency = -1 }, "EnrichConcurrency must not be negative", ""},
+		{"negative EnrichTimeout", func(cfg *Config) { cfg.EnrichTimeout = -time.Second }, "EnrichTimeout must not be negative", ""},
+		{"negative OperationDeadline", func(cfg *Config) { cfg.OperationDeadline = -time.Second }, "OperationDeadline must not be negative", ""},
+		{"negative ExitFlushTimeout", func(cfg *Config) { cfg.ExitFlushTimeout = -time.Second }, "ExitFlushTimeout must not be negative", ""},
+		{"negative ExpectedRPS", func(cfg *Config) { cfg.ExpectedRPS = -1 }, "ExpectedRPS must not be negative", ""},
+		{"negative retention", func(cfg *Config) { cfg.RetentionBatchSize = -1 }, "Retention (0s), RetentionInterval (0s) and RetentionBatchSize (-1) must not be negative", ""},
+		{"invalid table name", func(cfg *Config) { cfg.TableName = "steps; drop" }, `TableName "steps; drop" may only contain`, ""},
+		{"invalid table schema", func(cfg *Config) { cfg.TableSchema = "public.x" }, `TableSchema "public.x" may only contain`, ""},
+		{"partitioning a custom sink", func(cfg *Config) { cfg.Sink, cfg.PartitionByDay = nopSink{}, true }, "PartitionByDay applies to the built-in DB sink", ""},
+		{"retention without DB", func(cfg *Config) { cfg.DB, cfg.Sink, cfg.Retention = nil, nopSink{}, time.Hour }, "Retention requires DB", ""},
+		{"retention with a DSN and a custom sink", func(cfg *Config) {
+			cfg.DB, cfg.DSN, cfg.Sink, cfg.Retention = nil, "file::memory:", nopSink{}, time.Hour
+		}, "Retention requires DB", ""},
+		{"retention with a DSN", func(cfg *Config) { cfg.DB, cfg.DSN, cfg.Retention = nil, "file::memory:", time.Hour }, "", ""},
+
+		// 경고 기준: ExpectedRPS x FlushInterval이 BufferSize를 넘을 때만
+		{"buffer below expected steps", func(cfg *Config) { cfg.ExpectedRPS, cfg.BufferSize = 1000, 1000 }, "", "BufferSize 1000 is below ExpectedRPS 1000 x FlushInterval 5s = 5000 steps"},
+		{"buffer equal to expected steps", func(cfg *Config) { cfg.ExpectedRPS, cfg.BufferSize = 200, 1000 }, "", ""},
+		{"buffer one below expected steps", func(cfg *Config) { cfg.ExpectedRPS, cfg.BufferSize = 200, 999 }, "", "= 1000 steps"},
+		{"sub-second flush interval", func(cfg *Config) {
+			cfg.ExpectedRPS, cfg.FlushInterval, cfg.BufferSize = 1000, 500*time.Millisecond, 400
+		}, "", "ExpectedRPS 1000 x FlushInterval 500ms = 500 steps"},
+		{"jitter equal to interval", func(cfg *Config) { cfg.FlushJitter = 5 * time.Second }, "", "FlushJitter 5s is not smaller than FlushInterval 5s"},
+		{"jitter below interval", func(cfg *Config) { cfg.FlushJitter = 4 * time.Second }, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{DB: db}
+			tt.modify(&cfg)
+			warnings, err := validateConfig(applyDefaults(cfg))
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			case err != nil && warnings != nil:
+				t.Fatalf("warnings %q returned with an error", warnings)
+			}
+
+			joined := strings.Join(warnings, "\n")
+			if tt.wantWarning == "" && len(warnings) > 0 {
+				t.Fatalf("unexpected warnings: %q", warnings)
+			}
+			if tt.wantWarning != "" && !strings.Contains(joined, tt.wantWarning) {
+				t.Fatalf("warnings = %q, want one containing %q", warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestValidateConfigReportsEveryError(t *testing.T) {
+	_, err := validateConfig(applyDefaults(Config{BatchSize: 1000, BufferSize: 100, FlushInterval: -1, ExpectedRPS: -1}))
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+	for _, want := range []string{"no sink configured", "FlushInterval must be positive", "BatchSize (1000) must not exceed BufferSize (100)", "ExpectedRPS must not be negative"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
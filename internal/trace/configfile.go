@@ -0,0 +1,245 @@
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// DefaultConfig - 문서화된 기본값으로 채운 설정 (DB, Sink, DSN은 비어 있음)
+func DefaultConfig() Config {
+	return Config{
+		FlushInterval:   5 * time.Second,
+		BatchSize:       100,
+		BufferSize:      1000,
+		MaxOpenConn:     10,
+		MaxIdleConn:     5,
+		ConnMaxLifetime: time.Hour,
+	}
+}
+
+// configOverrides - 환경 변수와 YAML 파일에서 읽은 값 (nil이면 기본값 유지)
+type configOverrides struct {
+	Name              *string        `yaml:"name"`
+	DSN               *string        `yaml:"dsn"`
+	FlushInterval     *time.Duration `yaml:"flush_interval"`
+	BatchSize         *int           `yaml:"batch_size"`
+	BufferSize        *int           `yaml:"buffer_size"`
+	MaxOpenConn       *int           `yaml:"max_open_conns"`
+	MaxIdleConn       *int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime   *time.Duration `yaml:"conn_max_lifetime"`
+	SampleRate        *float64       `yaml:"sample_rate"`
+	SlowThreshold     *time.Duration `yaml:"slow_threshold"`
+	SkipPaths         []string       `yaml:"skip_paths"`
+	Retention         *time.Duration `yaml:"retention"`
+	RetentionInterval *time.Duration `yaml:"retention_interval"`
+	TableName         *string        `yaml:"table_name"`
+	SpillPath         *string        `yaml:"spill_path"`
+	SnapshotPath      *string        `yaml:"shutdown_snapshot_path"`
+	ServiceName       *string        `yaml:"service_name"`
+	Environment       *string        `yaml:"environment"`
+	Version           *string        `yaml:"version"`
+	KubernetesMeta    *bool          `yaml:"kubernetes_metadata"`
+	TraceIDSecret     *string        `yaml:"trace_id_secret"`
+}
+
+func (o configOverrides) apply(cfg *Config) {
+	setIf(&cfg.Name, o.Name)
+	setIf(&cfg.DSN, o.DSN)
+	setIf(&cfg.FlushInterval, o.FlushInterval)
+	setIf(&cfg.BatchSize, o.BatchSize)
+	setIf(&cfg.BufferSize, o.BufferSize)
+	setIf(&cfg.MaxOpenConn, o.MaxOpenConn)
+	setIf(&cfg.MaxIdleConn, o.MaxIdleConn)
+	setIf(&cfg.ConnMaxLifetime, o.ConnMaxLifetime)
+	setIf(&cfg.Retention, o.Retention)
+	setIf(&cfg.RetentionInterval, o.RetentionInterval)
+	setIf(&cfg.TableName, o.TableName)
+	setIf(&cfg.SpillPath, o.SpillPath)
+	setIf(&cfg.ShutdownSnapshotPath, o.SnapshotPath)
+	setIf(&cfg.ServiceName, o.ServiceName)
+	setIf(&cfg.Environment, o.Environment)
+	setIf(&cfg.Version, o.Version)
+	setIf(&cfg.KubernetesMetadata, o.KubernetesMeta)
+	if o.TraceIDSecret != nil {
+		cfg.TraceIDSecret = []byte(*o.TraceIDSecret)
+	}
+	if o.SampleRate != nil {
+		cfg.Runtime.SampleRate = o.SampleRate
+	}
+	if o.SlowThreshold != nil {
+		cfg.Runtime.SlowThreshold = o.SlowThreshold
+	}
+	if o.SkipPaths != nil {
+		cfg.Runtime.SkipPaths = o.SkipPaths
+	}
+}
+
+func setIf[T any](dst *T, value *T) {
+	if value != nil {
+		*dst = *value
+	}
+}
+
+// ConfigFromEnv - DefaultConfig에 TRACE_ 환경 변수 값을 적용한 설정 반환
+// 설정하지 않은 변수는 기본값을 유지하며, 형식이 잘못된 값은 모두 모아 에러로 반환한다
+//
+//	TRACE_NAME, TRACE_DSN, TRACE_FLUSH_INTERVAL, TRACE_BATCH_SIZE, TRACE_BUFFER_SIZE,
+//	TRACE_MAX_OPEN_CONNS, TRACE_MAX_IDLE_CONNS, TRACE_CONN_MAX_LIFETIME,
+//	TRACE_SAMPLE_RATE, TRACE_SLOW_THRESHOLD, TRACE_SKIP_PATHS (쉼표로 구분),
+//	TRACE_RETENTION, TRACE_RETENTION_INTERVAL, TRACE_TABLE_NAME, TRACE_SPILL_PATH,
+//	TRACE_SERVICE_NAME, TRACE_ENVIRONMENT, TRACE_VERSION, TRACE_KUBERNETES_METADATA, TRACE_ID_SECRET
+func ConfigFromEnv() (Config, error) {
+	var o configOverrides
+	var errs []error
+	str := func(name string, dst **string) {
+		if v, ok := os.LookupEnv(name); ok {
+			*dst = &v
+		}
+	}
+	parse := func(name string, fn func(string) error) {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			if err := fn(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s=%q: %w", name, v, err))
+			}
+		}
+	}
+	duration := func(name string, dst **time.Duration) {
+		parse(name, func(v string) error {
+			d, err := time.ParseDuration(v)
+			*dst = &d
+			return err
+		})
+	}
+	integer := func(name string, dst **int) {
+		parse(name, func(v string) error {
+			n, err := strconv.Atoi(v)
+			*dst = &n
+			return err
+		})
+	}
+
+	str("TRACE_NAME", &o.Name)
+	str("TRACE_DSN", &o.DSN)
+	duration("TRACE_FLUSH_INTERVAL", &o.FlushInterval)
+	integer("TRACE_BATCH_SIZE", &o.BatchSize)
+	integer("TRACE_BUFFER_SIZE", &o.BufferSize)
+	integer("TRACE_MAX_OPEN_CONNS", &o.MaxOpenConn)
+	integer("TRACE_MAX_IDLE_CONNS", &o.MaxIdleConn)
+	duration("TRACE_CONN_MAX_LIFETIME", &o.ConnMaxLifetime)
+	parse("TRACE_SAMPLE_RATE", func(v string) error {
+		rate, err := strconv.ParseFloat(v, 64)
+		o.SampleRate = &rate
+		return err
+	})
+	duration("TRACE_SLOW_THRESHOLD", &o.SlowThreshold)
+	parse("TRACE_SKIP_PATHS", func(v string) error {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				o.SkipPaths = append(o.SkipPaths, p)
+			}
+		}
+		return nil
+	})
+	duration("TRACE_RETENTION", &o.Retention)
+	duration("TRACE_RETENTION_INTERVAL", &o.RetentionInterval)
+	str("TRACE_TABLE_NAME", &o.TableName)
+	str("TRACE_SPILL_PATH", &o.SpillPath)
+	str("TRACE_SHUTDOWN_SNAPSHOT_PATH", &o.SnapshotPath)
+	str("TRACE_SERVICE_NAME", &o.ServiceName)
+	str("TRACE_ENVIRONMENT", &o.Environment)
+	str("TRACE_VERSION", &o.Version)
+	parse("TRACE_KUBERNETES_METADATA", func(v string) error {
+		enabled, err := strconv.ParseBool(v)
+		o.KubernetesMeta = &enabled
+		return err
+	})
+	str("TRACE_ID_SECRET", &o.TraceIDSecret)
+
+	cfg := DefaultConfig()
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("trace: invalid environment: %w", errors.Join(errs...))
+	}
+	o.apply(&cfg)
+	return cfg, nil
+}
+
+// ConfigFromFile - DefaultConfig에 YAML 파일 값을 적용한 설정 반환
+// 파일의 ${VAR} 형식은 환경 변수 값으로 바꾸며 (DSN 비밀번호 등), 알 수 없는 키는 오타로 보고 에러를 반환한다
+//
+//	dsn: postgres://trace:${TRACE_DB_PASSWORD}@db:5432/trace
+//	flush_interval: 5s
+//	batch_size: 200
+//	buffer_size: 5000
+//	sample_rate: 0.1
+//	retention: 720h
+func ConfigFromFile(path string) (Config, error) {
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	var o configOverrides
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(os.ExpandEnv(string(data)))))
+	dec.KnownFields(true)
+	if err := dec.Decode(&o); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("trace: invalid config file %s: %w", path, err)
+	}
+	o.apply(&cfg)
+	return cfg, nil
+}
+
+var (
+	dialectorsMu sync.RWMutex
+	dialectors   = make(map[string]func(dsn string) gorm.Dialector)
+)
+
+// RegisterDialector - DSN 스킴(예: "postgres", "sqlite")에 사용할 GORM 드라이버 등록
+// open은 스킴을 포함한 DSN 전체를 받는다 (드라이버 의존성을 이 패키지에 추가하지 않기 위해 애플리케이션이 등록)
+//
+//	trace.RegisterDialector("sqlite", func(dsn string) gorm.Dialector {
+//		return sqlite.Open(strings.TrimPrefix(dsn, "sqlite://"))
+//	})
+func RegisterDialector(scheme string, open func(dsn string) gorm.Dialector) {
+	dialectorsMu.Lock()
+	defer dialectorsMu.Unlock()
+	dialectors[scheme] = open
+}
+
+// OpenDSN - RegisterDialector로 등록한 드라이버로 DSN에 연결
+func OpenDSN(dsn string) (*gorm.DB, error) {
+	scheme, _, ok := strings.Cut(dsn, ":")
+	if !ok {
+		return nil, fmt.Errorf("trace: DSN %q has no scheme", redactDSN(dsn))
+	}
+	dialectorsMu.RLock()
+	open := dialectors[scheme]
+	dialectorsMu.RUnlock()
+	if open == nil {
+		return nil, fmt.Errorf("trace: no dialector registered for DSN scheme %q (call trace.RegisterDialector)", scheme)
+	}
+	return gorm.Open(open(dsn), &gorm.Config{})
+}
+
+// redactDSN - 에러 메시지에 넣을 DSN (비밀번호 제거)
+func redactDSN(dsn string) string {
+	scheme, rest, ok := strings.Cut(dsn, "://")
+	if !ok {
+		return dsn
+	}
+	if userinfo, host, ok := strings.Cut(rest, "@"); ok {
+		user, _, _ := strings.Cut(userinfo, ":")
+		return scheme + "://" + user + ":***@" + host
+	}
+	return dsn
+}
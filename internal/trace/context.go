@@ -0,0 +1,43 @@
+package trace
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+type traceIDContextKey struct{}
+
+type userIDContextKey struct{}
+
+// ContextWithTraceID - Trace ID를 담은 하위 컨텍스트 반환
+// 미들웨어는 c.Request.Context()에 자동으로 기록하므로 백그라운드 작업 등에 Trace ID를 넘길 때 사용한다
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey{}, traceID)
+}
+
+// ContextWithUserID - 사용자 ID를 담은 하위 컨텍스트 반환
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDContextKey{}, userID)
+}
+
+// FromContext - 컨텍스트의 Trace ID 반환 (없으면 false)
+// gin.Context를 넘기면 c.Request.Context()에서 찾는다
+func FromContext(ctx context.Context) (string, bool) {
+	traceID, ok := requestContext(ctx).Value(traceIDContextKey{}).(string)
+	return traceID, ok && traceID != ""
+}
+
+// UserIDFromContext - 컨텍스트의 사용자 ID 반환 (없으면 false)
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := requestContext(ctx).Value(userIDContextKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// requestContext - gin.Context는 Value가 요청 컨텍스트까지 찾지 않으므로 요청 컨텍스트로 바꿔서 사용
+func requestContext(ctx context.Context) context.Context {
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		return c.Request.Context()
+	}
+	return ctx
+}
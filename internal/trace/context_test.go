@@ -0,0 +1,153 @@
+package trace_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// contextState - 핸들러가 볼 수 있는 trace 상태
+type contextState struct {
+	keys          map[string]any
+	header        string
+	traceID       string
+	userID        string
+	requestHasIDs bool
+}
+
+func captureState(c *gin.Context) contextState {
+	keys := make(map[string]any, len(c.Keys))
+	for key, value := range c.Keys {
+		keys[key] = value
+	}
+	traceID, okTrace := trace.FromContext(c.Request.Context())
+	userID, okUser := trace.UserIDFromContext(c.Request.Context())
+	return contextState{
+		keys:          keys,
+		header:        c.Writer.Header().Get("X-Trace-ID"),
+		traceID:       traceID,
+		userID:        userID,
+		requestHasIDs: okTrace && okUser,
+	}
+}
+
+func TestSetupContextMatchesMiddleware(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+
+	var real contextState
+	r := gin.New()
+	r.Use(tracer.Middleware(
+		trace.WithTenantIDExtractor(func(c *gin.Context) string { return "tenant-1" }),
+		trace.WithSessionIDExtractor(func(c *gin.Context) string { return "session-1" }),
+	))
+	r.GET("/orders", func(c *gin.Context) {
+		real = captureState(c)
+		c.Status(200)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders?user_id=user-1&access_token=token", nil))
+	if real.keys == nil {
+		t.Fatal("handler did not run")
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/orders", nil)
+	tracetest.SetupContext(c,
+		tracetest.WithTraceID(real.keys[trace.TraceIDKey].(string)),
+		tracetest.WithSpanID(real.keys[trace.SpanIDKey].(string)),
+		tracetest.WithUserID("user-1"),
+		tracetest.WithTenantID("tenant-1"),
+		tracetest.WithSessionID("session-1"),
+	)
+	tracetest.AssertContext(t, c)
+	assertSameState(t, real, captureState(c))
+}
+
+func TestSetupContextMatchesMiddlewareForSessionOnlyRequest(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+
+	// 사용자 ID와 토큰 없이 세션 ID만으로 수집하는 비로그인 요청
+	var real contextState
+	r := gin.New()
+	r.Use(tracer.Middleware(
+		trace.WithSessionIDExtractor(func(c *gin.Context) string { return "session-1" }),
+	))
+	r.GET("/cart", func(c *gin.Context) {
+		tracetest.AssertContext(t, c)
+		real = captureState(c)
+		c.Status(200)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/cart", nil))
+	if real.keys == nil {
+		t.Fatal("handler did not run")
+	}
+	if real.keys[trace.UserIDKey] != "" || real.keys[trace.SessionIDKey] != "session-1" {
+		t.Fatalf("middleware keys = %+v, want an empty user ID and the session ID", real.keys)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/cart", nil)
+	tracetest.SetupContext(c,
+		tracetest.WithTraceID(real.keys[trace.TraceIDKey].(string)),
+		tracetest.WithSpanID(real.keys[trace.SpanIDKey].(string)),
+		tracetest.WithUserID(""),
+		tracetest.WithSessionID("session-1"),
+	)
+	tracetest.AssertContext(t, c)
+	assertSameState(t, real, captureState(c))
+}
+
+// assertSameState - 미들웨어와 SetupContext가 같은 키와 값을 설정했는지 비교
+func assertSameState(t *testing.T, real, fake contextState) {
+	t.Helper()
+	for key := range real.keys {
+		if _, ok := fake.keys[key]; !ok {
+			t.Errorf("SetupContext does not set %q", key)
+		}
+	}
+	for key := range fake.keys {
+		if _, ok := real.keys[key]; !ok {
+			t.Errorf("SetupContext sets %q which the middleware does not", key)
+		}
+	}
+	if !reflect.DeepEqual(real, fake) {
+		t.Fatalf("context differs:\nmiddleware:   %+v\nSetupContext: %+v", real, fake)
+	}
+}
+
+func TestSetMetadataIsStoredInExtra(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.Use(tracer.Middleware())
+	r.GET("/orders", func(c *gin.Context) {
+		trace.SetMetadata(c, "order_id", "o-1")
+		c.Status(200)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders?user_id=user-1&access_token=token", nil))
+	stopTracer(t, tracer)
+
+	result, err := tracer.Store().Query(context.Background(), trace.QueryFilter{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Steps) != 1 || result.Steps[0].Extra != `{"order_id":"o-1"}` {
+		t.Fatalf("steps = %+v, want one step with order_id in Extra", result.Steps)
+	}
+}
@@ -0,0 +1,115 @@
+package trace
+
+import (
+	"embed"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+//go:embed ui/index.html
+var dashboardFS embed.FS
+
+// 대시보드 엔드포인트 표의 기본 조회 구간과 개수
+const (
+	defaultDashboardWindow = time.Hour
+	defaultDashboardTop    = 20
+)
+
+// 조회 API가 허용하는 window, n (요청한 값 이상인 가장 작은 값으로 올려 캐시 키 수를 제한, 가장 큰 값을 넘으면 가장 큰 값)
+var (
+	dashboardWindows = []time.Duration{5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+	dashboardTops    = []int{5, 10, 20, 50, 100}
+)
+
+// DashboardRoutes - 내장 웹 대시보드 등록
+//
+//	GET /                  대시보드 화면 (최근 trace, 경로별 p50/p95/p99와 에러율, trace별 waterfall)
+//	GET /api/slowest       SlowestEndpoints (window=1h, n=20, by=p95|max)
+//	GET /api/...           APIRoutes와 같은 조회 API (/api/top 포함)
+//
+// 화면과 API(실시간 tail 포함) 모두 WithAdminAuth, WithAPIAuth로 설정한 인증을 거친다
+func DashboardRoutes(r *gin.RouterGroup, db *gorm.DB, opts ...APIOption) {
+	config := &APIConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if len(config.Auth) == 0 {
+		log.Printf("trace dashboard mounted at %s without authentication", r.BasePath())
+	}
+
+	g := r.Group("", config.Auth...)
+	g.GET("/", func(c *gin.Context) {
+		page, err := dashboardFS.ReadFile("ui/index.html")
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Header("Cache-Control", "no-cache")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	})
+
+	store := config.store(db)
+	api := g.Group("/api")
+	mountAPI(api, store, config.tracer(), config.HostResolver)
+	api.GET("/slowest", func(c *gin.Context) {
+		window, n, ok := dashboardWindow(c)
+		if !ok {
+			return
+		}
+		by := SlowestByP95
+		switch v := c.Query("by"); v {
+		case "", "p95":
+		case "max":
+			by = SlowestByMax
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid by: " + strconv.Quote(v) + " (p95 or max)"})
+			return
+		}
+
+		slowest, err := store().SlowestEndpoints(c.Request.Context(), window, n, by)
+		if err != nil {
+			log.Printf("trace dashboard query failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query slowest endpoints"})
+			return
+		}
+		c.JSON(http.StatusOK, slowest)
+	})
+}
+
+// dashboardWindow - window, n 쿼리 파라미터를 dashboardWindows, dashboardTops 중 하나로 올림 (잘못된 값이면 400으로 응답하고 false 반환)
+func dashboardWindow(c *gin.Context) (time.Duration, int, bool) {
+	window := defaultDashboardWindow
+	if v := c.Query("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window: " + strconv.Quote(v)})
+			return 0, 0, false
+		}
+		window = roundUp(dashboardWindows, d)
+	}
+	n := defaultDashboardTop
+	if v := c.Query("n"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid n: " + strconv.Quote(v)})
+			return 0, 0, false
+		}
+		n = roundUp(dashboardTops, parsed)
+	}
+	return window, n, true
+}
+
+// roundUp - 오름차순 allowed에서 v 이상인 가장 작은 값 (없으면 가장 큰 값)
+func roundUp[T int | time.Duration](allowed []T, v T) T {
+	for _, a := range allowed {
+		if a >= v {
+			return a
+		}
+	}
+	return allowed[len(allowed)-1]
+}
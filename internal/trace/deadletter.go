@@ -0,0 +1,145 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// DeadLetterHandler 재시도 후에도 저장하지 못한 배치를 전달받는 함수
+// err는 마지막 저장 시도의 에러이며, steps는 함수가 반환된 뒤에도 계속 사용할 수 있다
+type DeadLetterHandler func(steps []Step, err error)
+
+// DeadLetterFile - Step을 path에 dead-letter 파일 형식으로 이어 쓰는 핸들러 (DeadLetterPath를 설정하면 사용)
+// 파일은 ReadDLQFile로 읽어 다시 저장할 수 있다
+func DeadLetterFile(path string) DeadLetterHandler {
+	var mu sync.Mutex
+	return func(steps []Step, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if writeErr := appendDLQFile(path, steps); writeErr != nil {
+			log.Printf("trace dead-letter: failed to write %d steps to %s: %v (flush error: %v)", len(steps), path, writeErr, err)
+			return
+		}
+		log.Printf("trace dead-letter: wrote %d steps to %s: %v", len(steps), path, err)
+	}
+}
+
+// appendDLQFile - dead-letter 파일 끝에 Step 기록 (파일이 없거나 비어 있으면 헤더부터 기록)
+func appendDLQFile(path string, steps []Step) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w := NewDLQAppender(f)
+	if info.Size() == 0 {
+		w = NewDLQWriter(f)
+	}
+	for _, step := range steps {
+		if err := w.Write(step); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// deadLetterHandler - 설정의 dead-letter 핸들러 (없으면 DeadLetterPath에 기록하고, 경로도 없으면 로그만 남김)
+// 작업 디렉터리에 파일을 몰래 만들지 않도록 기본 경로는 두지 않는다
+func deadLetterHandler(cfg Config) DeadLetterHandler {
+	if cfg.DeadLetterHandler != nil {
+		return cfg.DeadLetterHandler
+	}
+	if cfg.DeadLetterPath != "" {
+		return DeadLetterFile(cfg.DeadLetterPath)
+	}
+	return logDeadLetters
+}
+
+// logDeadLetters - 저장하지 못한 Step 수와 에러만 기록하는 핸들러 (DeadLetterHandler와 DeadLetterPath가 모두 없을 때)
+func logDeadLetters(steps []Step, err error) {
+	log.Printf("trace dead-letter: dropped %d steps (set DeadLetterPath or DeadLetterHandler to keep them): %v", len(steps), err)
+}
+
+// deadLetter - 저장하지 못한 Step을 dead-letter 핸들러로 전달 (핸들러 panic은 기록만 함)
+func (t *Tracer) deadLetter(steps []Step, err error) {
+	if len(steps) == 0 {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in trace dead-letter handler: %v", r)
+		}
+	}()
+	t.deadLettered.Add(int64(len(steps)))
+	t.ledger.deadLettered(steps)
+	t.deadLetters(append([]Step(nil), steps...), fmt.Errorf("trace: %w", err))
+}
+
+// writeFallback - 재시도 후에도 저장하지 못한 배치를 FallbackSink에 저장 (저장했으면 true)
+// 원래 배치는 spill 파일과 dead-letter에 그대로 넘길 수 있도록 복사본에 경로 태그를 기록한다
+func (t *Tracer) writeFallback(logs []Step) bool {
+	if t.cfg.FallbackSink == nil {
+		return false
+	}
+	batch := append([]Step(nil), logs...)
+	stampProvenance(batch, "fallback")
+	if err := t.cfg.FallbackSink.Write(context.Background(), batch); err != nil {
+		log.Printf("trace fallback sink: failed to write %d steps: %v", len(batch), err)
+		return false
+	}
+	t.storedSteps.Add(int64(len(batch)))
+	log.Printf("trace fallback sink: wrote %d steps", len(batch))
+	return true
+}
+
+// ReplayDLQFile - dead-letter 파일(DeadLetterPath, ShutdownSnapshotPath)의 Step을 BatchSize씩 Sink에 다시 저장
+// 다시 저장한 Step은 Provenance에 "dlq"를 더하며, 저장에 실패하면 그때까지 저장한 Step 수와 에러를 반환한다
+// 같은 파일을 두 번 넣으면 중복 저장되므로 모두 저장했으면 파일을 지우거나 옮겨야 한다
+func (t *Tracer) ReplayDLQFile(ctx context.Context, r io.Reader) (int, error) {
+	if t == nil {
+		return 0, ErrNotRunning
+	}
+	stored := 0
+	batch := make([]Step, 0, t.cfg.BatchSize)
+	write := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		stampProvenance(batch, "dlq")
+		if err := t.flushWithRetry(batch, 1); err != nil {
+			return err
+		}
+		stored += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	report, err := ReadDLQFile(r, func(step Step) error {
+		batch = append(batch, step)
+		if len(batch) < t.cfg.BatchSize {
+			return nil
+		}
+		return write()
+	})
+	if err == nil {
+		err = write()
+	}
+	if err != nil {
+		return stored, fmt.Errorf("trace: replayed %d dead-letter steps: %w", stored, err)
+	}
+	if !report.Valid() {
+		log.Printf("trace dead-letter file was damaged: replayed %d steps, skipped %d corrupt records", stored, report.Corrupt)
+	}
+	return stored, nil
+}
@@ -0,0 +1,41 @@
+package trace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeadLetterHandlerWritesOnlyToConfiguredPath(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	steps := []Step{{TraceID: "t", Path: "/x", Method: "GET"}}
+	deadLetterHandler(Config{})(steps, errors.New("sink down"))
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("default dead-letter handler created %v in the working directory", entries)
+	}
+
+	path := filepath.Join(dir, "explicit.dlq")
+	deadLetterHandler(Config{DeadLetterPath: path})(steps, errors.New("sink down"))
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []Step
+	if _, err := ReadDLQFile(f, func(s Step) error { got = append(got, s); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].TraceID != "t" {
+		t.Fatalf("ReadDLQFile = %+v, want the dead-lettered step", got)
+	}
+}
@@ -0,0 +1,314 @@
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 디버그 캡처 제한 (동시에 활성화할 수 있는 캡처 수, 최대 기간, 캡처 중 저장할 본문 크기)
+const (
+	maxDebugCaptures        = 16
+	maxDebugCaptureDuration = 24 * time.Hour
+	debugCaptureBodyBytes   = 64 << 10
+)
+
+// ErrTooManyDebugCaptures 활성 디버그 캡처가 이미 최대 개수일 때 StartDebugCapture가 반환하는 에러
+var ErrTooManyDebugCaptures = fmt.Errorf("trace: at most %d debug captures can be active", maxDebugCaptures)
+
+// DebugCaptureSpec 디버그 캡처 설정 (StartDebugCapture)
+// JSON에서 Duration은 POST /captures 요청과 같은 "15m" 형식 문자열이다
+type DebugCaptureSpec struct {
+	UserID        string        `json:"user_id"`
+	Duration      time.Duration `json:"duration"`       // 이 시간이 지나면 자동 종료 (최대 24시간)
+	CaptureBodies bool          `json:"capture_bodies"` // true면 요청/응답 본문도 저장 (최대 64KB)
+	MaxRequests   int           `json:"max_requests"`   // 이만큼 저장하면 자동 종료 (0이면 Duration까지)
+}
+
+// DebugCapture 활성 디버그 캡처 상태
+type DebugCapture struct {
+	ID string `json:"id"`
+	DebugCaptureSpec
+	Requests  int       `json:"requests"` // 지금까지 캡처한 요청 수
+	StartedAt time.Time `json:"started_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// debugCaptureSpecJSON - DebugCaptureSpec의 JSON 표현 (Duration을 time.ParseDuration 형식 문자열로)
+type debugCaptureSpecJSON struct {
+	UserID        string `json:"user_id"`
+	Duration      string `json:"duration"`
+	CaptureBodies bool   `json:"capture_bodies"`
+	MaxRequests   int    `json:"max_requests"`
+}
+
+func (s DebugCaptureSpec) toJSON() debugCaptureSpecJSON {
+	return debugCaptureSpecJSON{
+		UserID:        s.UserID,
+		Duration:      s.Duration.String(),
+		CaptureBodies: s.CaptureBodies,
+		MaxRequests:   s.MaxRequests,
+	}
+}
+
+// MarshalJSON - Duration을 "15m0s" 같은 문자열로 인코딩
+func (s DebugCaptureSpec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+// UnmarshalJSON - Duration을 "15m" 같은 문자열로 디코딩 (빈 문자열이면 0)
+func (s *DebugCaptureSpec) UnmarshalJSON(data []byte) error {
+	var raw debugCaptureSpecJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var duration time.Duration
+	if raw.Duration != "" {
+		d, err := time.ParseDuration(raw.Duration)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		duration = d
+	}
+	*s = DebugCaptureSpec{
+		UserID:        raw.UserID,
+		Duration:      duration,
+		CaptureBodies: raw.CaptureBodies,
+		MaxRequests:   raw.MaxRequests,
+	}
+	return nil
+}
+
+// debugCaptureJSON - DebugCapture의 JSON 표현 (내장한 DebugCaptureSpec의 메서드가 나머지 필드를 가리지 않도록 따로 정의)
+type debugCaptureJSON struct {
+	ID string `json:"id"`
+	debugCaptureSpecJSON
+	Requests  int       `json:"requests"`
+	StartedAt time.Time `json:"started_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MarshalJSON - DebugCaptureSpec과 같이 Duration을 문자열로 인코딩
+func (d DebugCapture) MarshalJSON() ([]byte, error) {
+	return json.Marshal(debugCaptureJSON{
+		ID:                   d.ID,
+		debugCaptureSpecJSON: d.DebugCaptureSpec.toJSON(),
+		Requests:             d.Requests,
+		StartedAt:            d.StartedAt,
+		ExpiresAt:            d.ExpiresAt,
+	})
+}
+
+// UnmarshalJSON - MarshalJSON으로 인코딩한 캡처 디코딩
+func (d *DebugCapture) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ID        string    `json:"id"`
+		Requests  int       `json:"requests"`
+		StartedAt time.Time `json:"started_at"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var spec DebugCaptureSpec
+	if err := spec.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*d = DebugCapture{ID: raw.ID, DebugCaptureSpec: spec, Requests: raw.Requests, StartedAt: raw.StartedAt, ExpiresAt: raw.ExpiresAt}
+	return nil
+}
+
+// debugCaptures - Tracer의 활성 디버그 캡처 (사용자 ID별 하나)
+type debugCaptures struct {
+	active atomic.Int32 // 활성 캡처 수 (캡처가 없으면 요청마다 잠그지 않음)
+
+	mu       sync.Mutex
+	captures map[string]*DebugCapture // 사용자 ID → 캡처
+}
+
+// StartDebugCapture - 사용자 한 명의 요청을 샘플링과 관계없이 헤더, 쿼리, 본문까지 저장하는 임시 캡처 시작
+// Duration이 지나거나 MaxRequests만큼 저장하면 자동으로 원래 설정으로 돌아가며, 저장한 Step의 CaptureID로 모아 조회할 수 있다
+// 같은 사용자의 캡처가 이미 있으면 새 설정으로 교체한다
+func (t *Tracer) StartDebugCapture(spec DebugCaptureSpec) (string, error) {
+	if t == nil {
+		return "", ErrNotRunning
+	}
+	switch {
+	case spec.UserID == "":
+		return "", errors.New("trace: debug capture requires a UserID")
+	case spec.Duration <= 0 || spec.Duration > maxDebugCaptureDuration:
+		return "", fmt.Errorf("trace: debug capture Duration must be between 0 and %s (got %s)", maxDebugCaptureDuration, spec.Duration)
+	case spec.MaxRequests < 0:
+		return "", fmt.Errorf("trace: debug capture MaxRequests must not be negative (got %d)", spec.MaxRequests)
+	}
+
+	var b [8]byte
+	rand.Read(b[:])
+	now := time.Now()
+	capture := &DebugCapture{
+		ID:               hex.EncodeToString(b[:]),
+		DebugCaptureSpec: spec,
+		StartedAt:        now,
+		ExpiresAt:        now.Add(spec.Duration),
+	}
+
+	d := &t.captures
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expire(now)
+	if _, replaced := d.captures[spec.UserID]; !replaced && len(d.captures) >= maxDebugCaptures {
+		return "", ErrTooManyDebugCaptures
+	}
+	if d.captures == nil {
+		d.captures = make(map[string]*DebugCapture)
+	}
+	d.captures[spec.UserID] = capture
+	d.active.Store(int32(len(d.captures)))
+	return capture.ID, nil
+}
+
+// StartDebugCapture - 기본 Tracer에서 디버그 캡처 시작 (Start 전이면 ErrNotRunning)
+func StartDebugCapture(spec DebugCaptureSpec) (string, error) {
+	return defaultTracer.Load().StartDebugCapture(spec)
+}
+
+// DebugCaptures - 활성 디버그 캡처 목록 (시작한 순)
+func (t *Tracer) DebugCaptures() []DebugCapture {
+	if t == nil {
+		return nil
+	}
+	d := &t.captures
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expire(time.Now())
+	list := make([]DebugCapture, 0, len(d.captures))
+	for _, capture := range d.captures {
+		list = append(list, *capture)
+	}
+	slices.SortFunc(list, func(a, b DebugCapture) int { return a.StartedAt.Compare(b.StartedAt) })
+	return list
+}
+
+// CancelDebugCapture - 디버그 캡처 종료 (없거나 이미 끝났으면 false)
+func (t *Tracer) CancelDebugCapture(id string) bool {
+	if t == nil {
+		return false
+	}
+	d := &t.captures
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for userID, capture := range d.captures {
+		if capture.ID == id {
+			d.remove(userID)
+			return true
+		}
+	}
+	return false
+}
+
+// claim - userID의 활성 캡처가 있으면 요청 하나를 캡처한 것으로 세고 캡처 ID와 본문 저장 여부 반환 (없으면 빈 값)
+// MaxRequests번째 요청이면 캡처를 종료한다
+func (d *debugCaptures) claim(userID string, now time.Time) (id string, bodies bool) {
+	if d.active.Load() == 0 || userID == "" {
+		return "", false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	capture := d.captures[userID]
+	if capture == nil {
+		return "", false
+	}
+	if !now.Before(capture.ExpiresAt) {
+		d.remove(userID)
+		return "", false
+	}
+	capture.Requests++
+	if capture.MaxRequests > 0 && capture.Requests >= capture.MaxRequests {
+		d.remove(userID)
+	}
+	return capture.ID, capture.CaptureBodies
+}
+
+// claimDebugCapture - 사용자의 활성 디버그 캡처 확인 (Tracer가 없으면 빈 값)
+func (t *Tracer) claimDebugCapture(userID string) (id string, bodies bool) {
+	if t == nil {
+		return "", false
+	}
+	return t.captures.claim(userID, time.Now())
+}
+
+func (d *debugCaptures) expire(now time.Time) {
+	for userID, capture := range d.captures {
+		if !now.Before(capture.ExpiresAt) {
+			d.remove(userID)
+		}
+	}
+}
+
+func (d *debugCaptures) remove(userID string) {
+	delete(d.captures, userID)
+	d.active.Store(int32(len(d.captures)))
+}
+
+// debugCaptureConfig - 디버그 캡처 요청에 적용할 설정 (쿼리, 모든 헤더, CaptureBodies면 본문까지 저장)
+// 미들웨어 설정을 복사하므로 다른 요청에는 영향이 없고, 인증 헤더와 RedactQueryKeys는 그대로 마스킹한다
+func debugCaptureConfig(config *MiddlewareConfig, bodies bool) *MiddlewareConfig {
+	debug := *config
+	if !debug.CaptureQuery {
+		// WithQueryCapture 없이도 토큰 등 민감한 키는 마스킹
+		debug.CaptureQuery, debug.RedactQueryKeys = true, alwaysRedactedQueryKeys
+	}
+	debug.captureAllHeaders = true
+	if bodies {
+		debug.MaxRequestBodyBytes = max(debug.MaxRequestBodyBytes, debugCaptureBodyBytes)
+		debug.MaxResponseBodyBytes = max(debug.MaxResponseBodyBytes, debugCaptureBodyBytes)
+	}
+	return &debug
+}
+
+// mountDebugCaptures - 디버그 캡처 관리 API 등록 (APIRoutes, DashboardRoutes에 포함되며 같은 인증을 거침)
+//
+//	GET    /captures      활성 캡처 목록
+//	POST   /captures      캡처 시작 ({"user_id": "...", "duration": "15m", "capture_bodies": true, "max_requests": 100})
+//	DELETE /captures/:id  캡처 종료
+func mountDebugCaptures(g *gin.RouterGroup, tracer func() *Tracer) {
+	g.GET("/captures", func(c *gin.Context) {
+		captures := tracer().DebugCaptures()
+		if captures == nil {
+			captures = []DebugCapture{}
+		}
+		c.JSON(http.StatusOK, captures)
+	})
+	g.POST("/captures", func(c *gin.Context) {
+		var spec DebugCaptureSpec
+		if err := c.ShouldBindJSON(&spec); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body: " + err.Error()})
+			return
+		}
+		id, err := tracer().StartDebugCapture(spec)
+		switch {
+		case errors.Is(err, ErrNotRunning):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusCreated, gin.H{"id": id})
+		}
+	})
+	g.DELETE("/captures/:id", func(c *gin.Context) {
+		if !tracer().CancelDebugCapture(c.Param("id")) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "capture not found"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,202 @@
+package trace_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// sampledOutServer - 샘플링 비율 0으로 평소에는 아무것도 저장하지 않는 서버
+func sampledOutServer(t *testing.T) (*gin.Engine, *trace.Tracer) {
+	t.Helper()
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(tracer.Middleware(trace.WithSampleRate(0)))
+	r.POST("/orders/:id", func(c *gin.Context) {
+		c.GetRawData()
+		c.Header("X-Order-State", "paid")
+		c.String(http.StatusOK, "order ok")
+	})
+	return r, tracer
+}
+
+func postOrder(r *gin.Engine, userID string) {
+	req := httptest.NewRequest("POST", "/orders/42?user_id="+userID+"&access_token=token&coupon=SPRING", strings.NewReader(`{"qty":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Client", "ios")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func storedSteps(t *testing.T, tracer *trace.Tracer, filter trace.QueryFilter) []trace.Step {
+	t.Helper()
+	result, err := tracer.Store().Query(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Steps
+}
+
+func TestDebugCaptureRecordsOnlyTheUser(t *testing.T) {
+	r, tracer := sampledOutServer(t)
+	id, err := tracer.StartDebugCapture(trace.DebugCaptureSpec{UserID: "user-1", Duration: time.Minute, CaptureBodies: true, MaxRequests: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		postOrder(r, "user-1")
+		postOrder(r, "user-2")
+	}
+	if active := tracer.DebugCaptures(); len(active) != 0 {
+		t.Fatalf("capture still active after MaxRequests: %+v", active)
+	}
+	stopTracer(t, tracer)
+
+	if other := storedSteps(t, tracer, trace.QueryFilter{UserID: "user-2"}); len(other) != 0 {
+		t.Fatalf("non-matching user stored %d steps", len(other))
+	}
+	steps := storedSteps(t, tracer, trace.QueryFilter{CaptureID: id})
+	if len(steps) != 2 {
+		t.Fatalf("captured %d steps, want MaxRequests (2)", len(steps))
+	}
+	step := steps[0]
+	if step.UserID != "user-1" || step.RequestBody != `{"qty":2}` || step.ResponseBody != "order ok" {
+		t.Errorf("bodies not captured: %+v", step)
+	}
+	if !strings.Contains(step.Query, "coupon=SPRING") || strings.Contains(step.Query, "access_token=token") || !strings.Contains(step.Params, `"id":"42"`) {
+		t.Errorf("query/params not captured: query %q params %q", step.Query, step.Params)
+	}
+	var headers struct {
+		Request  map[string]string `json:"request"`
+		Response map[string]string `json:"response"`
+	}
+	if err := json.Unmarshal([]byte(step.Headers), &headers); err != nil {
+		t.Fatalf("headers %q: %v", step.Headers, err)
+	}
+	if headers.Request["X-Client"] != "ios" || headers.Response["X-Order-State"] != "paid" {
+		t.Errorf("headers not captured: %+v", headers)
+	}
+	if auth := headers.Request["Authorization"]; !strings.HasPrefix(auth, "hmac:") {
+		t.Errorf("Authorization stored as %q, want a hash", auth)
+	}
+}
+
+func TestDebugCaptureExpiresByDuration(t *testing.T) {
+	r, tracer := sampledOutServer(t)
+	id, err := tracer.StartDebugCapture(trace.DebugCaptureSpec{UserID: "user-1", Duration: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	postOrder(r, "user-1")
+	time.Sleep(60 * time.Millisecond)
+	postOrder(r, "user-1")
+	if active := tracer.DebugCaptures(); len(active) != 0 {
+		t.Fatalf("capture still active after Duration: %+v", active)
+	}
+	stopTracer(t, tracer)
+
+	steps := storedSteps(t, tracer, trace.QueryFilter{UserID: "user-1"})
+	if len(steps) != 1 || steps[0].CaptureID != id || steps[0].RequestBody != "" {
+		t.Fatalf("stored %+v, want one captured step without bodies", steps)
+	}
+}
+
+func TestDebugCaptureValidation(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+
+	for _, spec := range []trace.DebugCaptureSpec{
+		{Duration: time.Minute},
+		{UserID: "u", Duration: 0},
+		{UserID: "u", Duration: 48 * time.Hour},
+		{UserID: "u", Duration: time.Minute, MaxRequests: -1},
+	} {
+		if _, err := tracer.StartDebugCapture(spec); err == nil {
+			t.Errorf("StartDebugCapture(%+v) succeeded", spec)
+		}
+	}
+	for i := range 16 {
+		if _, err := tracer.StartDebugCapture(trace.DebugCaptureSpec{UserID: string(rune('a' + i)), Duration: time.Minute}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tracer.StartDebugCapture(trace.DebugCaptureSpec{UserID: "one-too-many", Duration: time.Minute}); err != trace.ErrTooManyDebugCaptures {
+		t.Fatalf("err = %v, want ErrTooManyDebugCaptures", err)
+	}
+}
+
+func TestDebugCaptureAPI(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+	r := gin.New()
+	trace.APIRoutes(r.Group("/debug"), nil, trace.WithAPIStore(tracer.Store()), trace.WithAPITracer(tracer), trace.WithAPIAuth(func(c *gin.Context) {}))
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+	w := do("POST", "/debug/captures", `{"user_id":"user-1","duration":"15m","max_requests":10}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start: %d %s", w.Code, w.Body)
+	}
+	var started struct{ ID string }
+	json.Unmarshal(w.Body.Bytes(), &started)
+
+	list := do("GET", "/debug/captures", "").Body.Bytes()
+	// 목록의 duration은 POST와 같은 문자열 형식
+	var raw []map[string]any
+	json.Unmarshal(list, &raw)
+	if len(raw) != 1 || raw[0]["duration"] != "15m0s" {
+		t.Fatalf("raw list = %s, want duration \"15m0s\"", list)
+	}
+	var listed []trace.DebugCapture
+	if err := json.Unmarshal(list, &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != started.ID || listed[0].UserID != "user-1" || listed[0].MaxRequests != 10 ||
+		listed[0].Duration != 15*time.Minute || listed[0].ExpiresAt.Sub(listed[0].StartedAt) != 15*time.Minute {
+		t.Fatalf("list = %+v", listed)
+	}
+	// 목록 항목을 그대로 다시 POST하면 같은 설정으로 캡처를 교체
+	again, _ := json.Marshal(listed[0].DebugCaptureSpec)
+	if w := do("POST", "/debug/captures", string(again)); w.Code != http.StatusCreated {
+		t.Fatalf("restart from listed spec %s: %d %s", again, w.Code, w.Body)
+	}
+	if active := tracer.DebugCaptures(); len(active) != 1 || active[0].Duration != 15*time.Minute || active[0].MaxRequests != 10 {
+		t.Fatalf("restarted capture = %+v", active)
+	}
+	json.Unmarshal(do("GET", "/debug/captures", "").Body.Bytes(), &listed)
+	started.ID = listed[0].ID
+	if w := do("DELETE", "/debug/captures/"+started.ID, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("cancel: %d", w.Code)
+	}
+	if w := do("DELETE", "/debug/captures/"+started.ID, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("second cancel: %d", w.Code)
+	}
+	if w := do("POST", "/debug/captures", `{"user_id":"user-1","duration":"soon"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid duration: %d", w.Code)
+	}
+	if active := tracer.DebugCaptures(); len(active) != 0 {
+		t.Fatalf("active after cancel: %+v", active)
+	}
+}
@@ -0,0 +1,168 @@
+package trace
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 외부 HTTP 호출 span 이름의 접두사 (NewTransport: "HTTP GET api.example.com")
+const outboundSpanPrefix = "HTTP "
+
+// 출처 서비스 이름이 없는 Step의 노드 ID (Config.ServiceName을 설정하지 않은 서비스)
+const unknownService = "unknown"
+
+// Graph 서비스 의존 관계 그래프 (DependencyMap, force-directed 그래프 UI에 그대로 전달할 수 있는 노드와 간선)
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode 그래프 노드 (호출한 서비스 또는 호출 대상 호스트)
+type GraphNode struct {
+	ID      string `json:"id"`
+	Service bool   `json:"service"` // 외부 호출을 기록한 서비스면 true (호출 대상으로만 나타나면 false)
+}
+
+// GraphEdge 출처 서비스에서 대상 호스트로의 호출 통계
+type GraphEdge struct {
+	Source    string  `json:"source"`
+	Target    string  `json:"target"`
+	Calls     int64   `json:"calls"`
+	Errors    int64   `json:"errors"`     // 5xx 응답 또는 전송 에러
+	ErrorRate float64 `json:"error_rate"` // Errors / Calls (0~1)
+	P95Ms     int64   `json:"p95_ms"`
+}
+
+// DependencyOption DependencyMap 옵션
+type DependencyOption func(*dependencyConfig)
+
+type dependencyConfig struct {
+	resolveHost func(host string) string
+}
+
+// WithHostResolver 호출 대상 호스트를 그래프 노드 이름으로 바꾸는 함수 설정 (예: Pod IP → 서비스 이름)
+// 포트를 뗀 소문자 호스트가 전달되며, 빈 문자열을 반환하면 호스트를 그대로 사용한다
+func WithHostResolver(resolve func(host string) string) DependencyOption {
+	return func(config *dependencyConfig) {
+		config.resolveHost = resolve
+	}
+}
+
+// DependencyMap - [from, to) 구간의 외부 호출 span(NewTransport)을 (출처 서비스, 대상 호스트)별로 모아 의존 관계 그래프 반환
+// 기본 Tracer의 테이블 구성으로 조회한다
+func DependencyMap(ctx context.Context, db *gorm.DB, from, to time.Time, opts ...DependencyOption) (Graph, error) {
+	return storeFor(db).DependencyMap(ctx, from, to, opts...)
+}
+
+// DependencyMap - [from, to) 구간의 외부 호출 span을 (출처 서비스, 대상 호스트)별로 모아 의존 관계 그래프 반환
+// 간선은 호출 수가 많은 순, 노드는 이름순이다
+func (s *Store) DependencyMap(ctx context.Context, from, to time.Time, opts ...DependencyOption) (Graph, error) {
+	if from.IsZero() || to.IsZero() {
+		return Graph{}, errors.New("trace: DependencyMap requires from and to")
+	}
+	config := &dependencyConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	steps, err := s.stepsFrom(ctx, from, to)
+	if err != nil {
+		return Graph{}, err
+	}
+	rows, err := steps().
+		Select("COALESCE(service_name, ''), span_name, status_code, latency_ms, COALESCE(error, '')").
+		Where("span_name LIKE ?", outboundSpanPrefix+"%").
+		Where("created_at >= ? AND created_at < ?", from.Unix(), to.Unix()).
+		Rows()
+	if err != nil {
+		return Graph{}, err
+	}
+	defer rows.Close()
+
+	type edgeKey struct{ source, target string }
+	type edgeCalls struct {
+		errors    int64
+		latencies []int64
+	}
+	edges := make(map[edgeKey]*edgeCalls)
+	for rows.Next() {
+		var source, spanName, callErr string
+		var status int
+		var latency int64
+		if err := rows.Scan(&source, &spanName, &status, &latency, &callErr); err != nil {
+			return Graph{}, err
+		}
+		target := outboundHost(spanName, config.resolveHost)
+		if target == "" {
+			continue
+		}
+		if source == "" {
+			source = unknownService
+		}
+		k := edgeKey{source, target}
+		e := edges[k]
+		if e == nil {
+			e = &edgeCalls{}
+			edges[k] = e
+		}
+		if status >= 500 || callErr != "" {
+			e.errors++
+		}
+		e.latencies = append(e.latencies, latency)
+	}
+	if err := rows.Err(); err != nil {
+		return Graph{}, err
+	}
+
+	graph := Graph{Nodes: []GraphNode{}, Edges: make([]GraphEdge, 0, len(edges))}
+	services := make(map[string]bool)
+	for k, e := range edges {
+		slices.Sort(e.latencies)
+		calls := int64(len(e.latencies))
+		graph.Edges = append(graph.Edges, GraphEdge{
+			Source:    k.source,
+			Target:    k.target,
+			Calls:     calls,
+			Errors:    e.errors,
+			ErrorRate: float64(e.errors) / float64(calls),
+			P95Ms:     e.latencies[int(float64(calls-1)*0.95)],
+		})
+		services[k.source] = true
+		if _, ok := services[k.target]; !ok {
+			services[k.target] = false
+		}
+	}
+	for id, service := range services {
+		graph.Nodes = append(graph.Nodes, GraphNode{ID: id, Service: service})
+	}
+	slices.SortFunc(graph.Nodes, func(a, b GraphNode) int { return strings.Compare(a.ID, b.ID) })
+	slices.SortFunc(graph.Edges, func(a, b GraphEdge) int {
+		if c := cmp.Compare(b.Calls, a.Calls); c != 0 {
+			return c
+		}
+		return cmp.Or(strings.Compare(a.Source, b.Source), strings.Compare(a.Target, b.Target))
+	})
+	return graph, nil
+}
+
+// outboundHost - 외부 호출 span 이름의 대상 호스트 (포트를 떼고 소문자로 바꾼 뒤 resolve 적용)
+func outboundHost(spanName string, resolve func(string) string) string {
+	_, host, _ := strings.Cut(strings.TrimPrefix(spanName, outboundSpanPrefix), " ")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if resolve != nil && host != "" {
+		if name := resolve(host); name != "" {
+			return name
+		}
+	}
+	return host
+}
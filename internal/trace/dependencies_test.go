@@ -0,0 +1,152 @@
+package trace_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// seedOutboundCalls - 세 서비스(gateway, orders, payments)가 서로와 외부 PG사를 호출한 span 저장
+// orders의 Pod IP(10.0.1.x)는 resolver로 서비스 이름이 된다
+func seedOutboundCalls(t *testing.T, db *gorm.DB, now time.Time) {
+	t.Helper()
+	type call struct {
+		source, host string
+		status       int
+		latencyMs    int64
+		err          string
+	}
+	var calls []call
+	for i := range 20 {
+		// gateway → orders: Pod 두 개로 나뉘어 호출, 20개 중 2개 5xx, 응답 시간 1~20ms
+		status := http.StatusOK
+		if i < 2 {
+			status = http.StatusBadGateway
+		}
+		pod := []string{"10.0.1.7:8080", "10.0.1.8:8080"}[i%2]
+		calls = append(calls, call{"gateway", pod, status, int64(i + 1), ""})
+	}
+	for i := range 10 {
+		// orders → payments: 10개 모두 성공, 응답 시간 10~100ms
+		calls = append(calls, call{"orders", "payments.internal:443", http.StatusOK, int64((i + 1) * 10), ""})
+	}
+	for i := range 4 {
+		// payments → 외부 PG사: 4개 중 1개 연결 에러
+		call := call{"payments", "API.PG.example.com", http.StatusOK, 200, ""}
+		if i == 0 {
+			call.status, call.err = 0, "dial tcp: i/o timeout"
+		}
+		calls = append(calls, call)
+	}
+
+	for i, c := range calls {
+		step := trace.Step{
+			TraceID:     "trace",
+			Path:        "/checkout",
+			ServiceName: c.source,
+			StatusCode:  c.status,
+			LatencyMs:   c.latencyMs,
+			Error:       c.err,
+			SpanName:    "HTTP POST " + c.host,
+			CreatedAt:   now.Unix(),
+			Seq:         int64(i),
+		}
+		if err := db.Create(&step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 요청 Step과 구간 밖의 호출은 집계하지 않음
+	for _, step := range []trace.Step{
+		{TraceID: "trace", Path: "/checkout", ServiceName: "gateway", StatusCode: 200, CreatedAt: now.Unix()},
+		{TraceID: "old", ServiceName: "gateway", SpanName: "HTTP GET legacy:80", CreatedAt: now.Add(-2 * time.Hour).Unix()},
+	} {
+		if err := db.Create(&step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func resolvePods(host string) string {
+	if strings.HasPrefix(host, "10.0.1.") {
+		return "orders"
+	}
+	return ""
+}
+
+func TestDependencyMap(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	now := time.Now()
+	seedOutboundCalls(t, db, now)
+
+	graph, err := trace.DependencyMap(context.Background(), db, now.Add(-time.Hour), now.Add(time.Minute), trace.WithHostResolver(resolvePods))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []trace.GraphEdge{
+		{Source: "gateway", Target: "orders", Calls: 20, Errors: 2, ErrorRate: 0.1, P95Ms: 19},
+		{Source: "orders", Target: "payments.internal", Calls: 10, Errors: 0, ErrorRate: 0, P95Ms: 90},
+		{Source: "payments", Target: "api.pg.example.com", Calls: 4, Errors: 1, ErrorRate: 0.25, P95Ms: 200},
+	}
+	if len(graph.Edges) != len(want) {
+		t.Fatalf("edges = %+v, want %+v", graph.Edges, want)
+	}
+	for i := range want {
+		if graph.Edges[i] != want[i] {
+			t.Errorf("edge %d = %+v, want %+v", i, graph.Edges[i], want[i])
+		}
+	}
+	wantNodes := []trace.GraphNode{
+		{ID: "api.pg.example.com"},
+		{ID: "gateway", Service: true},
+		{ID: "orders", Service: true},
+		{ID: "payments", Service: true},
+		{ID: "payments.internal"},
+	}
+	if len(graph.Nodes) != len(wantNodes) {
+		t.Fatalf("nodes = %+v, want %+v", graph.Nodes, wantNodes)
+	}
+	for i := range wantNodes {
+		if graph.Nodes[i] != wantNodes[i] {
+			t.Errorf("node %d = %+v, want %+v", i, graph.Nodes[i], wantNodes[i])
+		}
+	}
+
+	// resolver 없이는 포트만 뗀 Pod IP가 노드가 됨
+	graph, err = trace.DependencyMap(context.Background(), db, now.Add(-time.Hour), now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Edges) != 4 || graph.Edges[0].Target != "10.0.1.7" || graph.Edges[0].Calls != 10 {
+		t.Fatalf("edges without resolver = %+v", graph.Edges)
+	}
+}
+
+func TestDependenciesAPI(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	seedOutboundCalls(t, db, time.Now())
+	r := gin.New()
+	trace.APIRoutes(r.Group("/debug"), db, trace.WithAPIHostResolver(resolvePods), trace.WithAPIAuth(func(c *gin.Context) {}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/dependencies", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var graph trace.Graph
+	if err := json.Unmarshal(w.Body.Bytes(), &graph); err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Nodes) != 5 || len(graph.Edges) != 3 || graph.Edges[0].Target != "orders" {
+		t.Fatalf("graph = %+v", graph)
+	}
+}
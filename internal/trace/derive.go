@@ -0,0 +1,77 @@
+package trace
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"unsafe"
+
+	"github.com/gin-gonic/gin"
+)
+
+// middlewares - newMiddleware로 만든 핸들러의 설정 (Derive, Explain에서 조회)
+// 키는 핸들러 클로저의 주소이며, 값이 핸들러를 참조하므로 등록한 주소가 다른 함수에 재사용되지 않는다
+// 미들웨어는 보통 시작할 때 한 번 만들므로 등록한 설정은 삭제하지 않는다
+var middlewares sync.Map // map[unsafe.Pointer]registeredMiddleware
+
+type registeredMiddleware struct {
+	handler gin.HandlerFunc
+	config  *MiddlewareConfig
+}
+
+// handlerKey - 핸들러 클로저의 주소 (같은 핸들러를 복사한 값은 같은 주소)
+func handlerKey(handler gin.HandlerFunc) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&handler))
+}
+
+// registerMiddleware - configOf로 조회할 수 있도록 핸들러의 설정 등록
+func registerMiddleware(handler gin.HandlerFunc, config *MiddlewareConfig) gin.HandlerFunc {
+	middlewares.Store(handlerKey(handler), registeredMiddleware{handler: handler, config: config})
+	return handler
+}
+
+// Clone - 설정의 깊은 복사본 반환 (슬라이스와 맵도 복사, 적응형 샘플링 집계는 새로 시작)
+func (config *MiddlewareConfig) Clone() *MiddlewareConfig {
+	clone := *config
+	clone.TrustedProxies = slices.Clone(config.TrustedProxies)
+	clone.Labels = maps.Clone(config.Labels)
+	clone.CaptureHeaders = slices.Clone(config.CaptureHeaders)
+	clone.RedactHeaders = slices.Clone(config.RedactHeaders)
+	clone.HashParams = slices.Clone(config.HashParams)
+	clone.ExcludeParams = slices.Clone(config.ExcludeParams)
+	clone.RedactQueryKeys = slices.Clone(config.RedactQueryKeys)
+	clone.Redactors = slices.Clone(config.Redactors)
+	clone.RouteRules = cloneRouteRules(config.RouteRules)
+	if config.adaptive != nil {
+		// 파생된 미들웨어는 처리량을 따로 집계
+		clone.adaptive = newAdaptiveSampler(config.AdaptiveMaxPerSecond)
+	}
+	return &clone
+}
+
+// Derive - 기존 미들웨어의 설정을 복사한 뒤 overrides를 적용한 새 미들웨어 반환
+// base의 설정은 변경되지 않으며, base는 MiddlewareWithConfig(또는 Middleware)로 만든 핸들러여야 한다
+func Derive(base gin.HandlerFunc, overrides ...MiddlewareOption) gin.HandlerFunc {
+	config := configOf(base)
+	if config == nil {
+		panic("trace: Derive requires a handler created by MiddlewareWithConfig")
+	}
+
+	derived := config.Clone()
+	for _, option := range overrides {
+		option(derived)
+	}
+	return newMiddleware(derived)
+}
+
+// configOf - 미들웨어 핸들러의 설정 조회 (trace 미들웨어가 아니면 nil)
+func configOf(handler gin.HandlerFunc) *MiddlewareConfig {
+	if handler == nil {
+		return nil
+	}
+	registered, ok := middlewares.Load(handlerKey(handler))
+	if !ok {
+		return nil
+	}
+	return registered.(registeredMiddleware).config
+}
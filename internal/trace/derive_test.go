@@ -0,0 +1,138 @@
+package trace_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestDeriveConcurrentVariantsAreIsolated(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 사용자별로 실행된 redactor
+	var mu sync.Mutex
+	ran := map[string][]string{}
+	redactor := func(name string) func(*trace.Step) *trace.Step {
+		return func(step *trace.Step) *trace.Step {
+			mu.Lock()
+			defer mu.Unlock()
+			ran[step.UserID] = append(ran[step.UserID], name)
+			return step
+		}
+	}
+
+	base := tracer.Middleware(
+		trace.WithRouteParams(nil, nil),
+		trace.WithQueryCapture(),
+		trace.WithRouteRules(trace.RouteRule{Pattern: "/orders/*", StoreColumns: []string{"team", "query"}}),
+		trace.WithRedactor(redactor("base")),
+		trace.WithStaticLabels(map[string]string{"team": "base"}),
+	)
+	overrides := [][]trace.MiddlewareOption{
+		{trace.WithSampleRate(0)},
+		// 같은 패턴의 규칙을 덮어써도 base의 규칙은 그대로
+		{trace.WithRouteRules(trace.RouteRule{Pattern: "/orders/*", StoreColumns: []string{"team", "params"}})},
+		// base의 redactor 슬라이스에 추가해도 base에는 추가되지 않음
+		{trace.WithRedactor(redactor("v2")), trace.WithStaticLabels(map[string]string{"team": "v2"})},
+	}
+
+	// 세 변형을 동시에 만들면서 base로 요청 처리
+	variants := make([]gin.HandlerFunc, len(overrides))
+	baseEngine := gin.New()
+	baseEngine.GET("/orders/:id", base, func(c *gin.Context) { c.Status(http.StatusOK) })
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, opts := range overrides {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			variants[i] = trace.Derive(base, opts...)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-start
+		for range 20 {
+			baseEngine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders/1?user_id=warmup&access_token=a", nil))
+		}
+	}()
+	close(start)
+	wg.Wait()
+
+	for i, handler := range append([]gin.HandlerFunc{base}, variants...) {
+		user := []string{"base", "v0", "v1", "v2"}[i]
+		r := gin.New()
+		r.GET("/orders/:id", handler, func(c *gin.Context) { c.Status(http.StatusOK) })
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders/7?page=2&user_id="+user+"&access_token=a", nil))
+	}
+	stopTracer(t, tracer)
+
+	steps := map[string]trace.Step{}
+	for _, step := range storedSteps(t, tracer, trace.QueryFilter{RequestsOnly: true}) {
+		steps[step.UserID] = step
+	}
+	tests := []struct {
+		user       string
+		stored     bool
+		team       string
+		query      bool // query를 저장하고 params를 비움 (아니면 반대)
+		redactors  []string
+		sampleRate float64
+	}{
+		{"base", true, "base", true, []string{"base"}, 1},
+		{"v0", false, "", false, nil, 0},
+		{"v1", true, "base", false, []string{"base"}, 1},
+		{"v2", true, "v2", true, []string{"base", "v2"}, 1},
+	}
+	for i, tt := range tests {
+		step, ok := steps[tt.user]
+		if ok != tt.stored {
+			t.Errorf("%s: stored = %v, want %v", tt.user, ok, tt.stored)
+			continue
+		}
+		if !slices.Equal(ran[tt.user], tt.redactors) {
+			t.Errorf("%s: redactors ran %v, want %v", tt.user, ran[tt.user], tt.redactors)
+		}
+		handler := base
+		if i > 0 {
+			handler = variants[i-1]
+		}
+		e, err := trace.Explain(handler, "/orders/:id")
+		if err != nil || e.SampleRate != tt.sampleRate {
+			t.Errorf("%s: Explain = %+v, %v, want sample rate %v", tt.user, e, err, tt.sampleRate)
+		}
+		if !tt.stored {
+			continue
+		}
+		if step.Team != tt.team {
+			t.Errorf("%s: team = %q, want %q", tt.user, step.Team, tt.team)
+		}
+		if (step.Query != "") != tt.query || (step.Params != "") == tt.query {
+			t.Errorf("%s: query = %q, params = %q, want only the %s column", tt.user, step.Query, step.Params, map[bool]string{true: "query", false: "params"}[tt.query])
+		}
+	}
+	if len(ran["warmup"]) != 20 || slices.Contains(ran["warmup"], "v2") {
+		t.Errorf("base redactors during Derive = %v, want 20 base runs", ran["warmup"])
+	}
+}
+
+func TestDeriveRequiresTraceMiddleware(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Derive accepted a handler that is not a trace middleware")
+		}
+	}()
+	trace.Derive(func(c *gin.Context) { c.Status(http.StatusTeapot) })
+}
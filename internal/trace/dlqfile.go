@@ -0,0 +1,203 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Dead-letter / 스냅샷 파일 형식 (버전 1)
+//
+//	헤더:   magic "TRDL" (4바이트) | version (1바이트)
+//	레코드: sync "\xd1\xf0\x5e\x7a" (4바이트) | length uint32 BE | crc32 uint32 BE (IEEE, payload 기준) | payload (Step JSON)
+//
+// 레코드마다 sync 마커를 두어 손상된 레코드를 건너뛰고 다음 레코드부터 다시 읽을 수 있다.
+// sync 마커는 올바른 UTF-8이 아니므로 JSON payload 안에는 나타나지 않는다.
+const (
+	dlqMagic   = "TRDL"
+	dlqVersion = 1
+
+	dlqHeaderSize       = len(dlqMagic) + 1
+	dlqRecordHeaderSize = 12
+
+	// 레코드 하나의 최대 크기 (손상된 length 값으로 인한 과도한 할당 방지)
+	dlqMaxRecordSize = 16 << 20
+)
+
+var dlqSync = []byte{0xd1, 0xf0, 0x5e, 0x7a}
+
+// ErrNotDLQFile 파일 헤더가 dead-letter 형식이 아닌 경우
+var ErrNotDLQFile = errors.New("trace: not a dead-letter file")
+
+// FileReport dead-letter 파일 검사 결과
+type FileReport struct {
+	Version       int   `json:"version"`
+	Records       int   `json:"records"`        // 정상 레코드 수
+	Corrupt       int   `json:"corrupt"`        // CRC 불일치 등으로 건너뛴 레코드 수
+	SkippedBytes  int64 `json:"skipped_bytes"`  // 복구 과정에서 건너뛴 바이트 수
+	TruncatedTail bool  `json:"truncated_tail"` // 마지막 레코드가 잘려 있는지 여부
+}
+
+// Valid - 손상 없이 모든 레코드를 읽었는지 여부
+func (r FileReport) Valid() bool {
+	return r.Corrupt == 0 && r.SkippedBytes == 0 && !r.TruncatedTail
+}
+
+// DLQWriter dead-letter 파일 작성기
+type DLQWriter struct {
+	w           io.Writer
+	wroteHeader bool
+}
+
+// NewDLQWriter - 새 파일에 쓰는 작성기 생성 (첫 쓰기 시 헤더 기록)
+func NewDLQWriter(w io.Writer) *DLQWriter {
+	return &DLQWriter{w: w}
+}
+
+// NewDLQAppender - 이미 헤더가 기록된 파일 뒤에 이어 쓰는 작성기 생성
+func NewDLQAppender(w io.Writer) *DLQWriter {
+	return &DLQWriter{w: w, wroteHeader: true}
+}
+
+// Write - Step 하나를 레코드로 기록
+func (dw *DLQWriter) Write(step Step) error {
+	payload, err := json.Marshal(step)
+	if err != nil {
+		return err
+	}
+	return dw.writeRecord(payload)
+}
+
+func (dw *DLQWriter) writeRecord(payload []byte) error {
+	if !dw.wroteHeader {
+		if _, err := dw.w.Write(append([]byte(dlqMagic), dlqVersion)); err != nil {
+			return err
+		}
+		dw.wroteHeader = true
+	}
+
+	record := make([]byte, 0, len(dlqSync)+8+len(payload))
+	record = append(record, dlqSync...)
+	record = binary.BigEndian.AppendUint32(record, uint32(len(payload)))
+	record = binary.BigEndian.AppendUint32(record, crc32.ChecksumIEEE(payload))
+	record = append(record, payload...)
+	_, err := dw.w.Write(record)
+	return err
+}
+
+// ReadDLQFile - 파일의 모든 정상 레코드를 fn으로 전달
+// 손상된 레코드는 중단하지 않고 건너뛰며 결과 보고서에 집계한다
+func ReadDLQFile(r io.Reader, fn func(Step) error) (FileReport, error) {
+	return scanDLQ(r, func(payload []byte) error {
+		var step Step
+		if err := json.Unmarshal(payload, &step); err != nil {
+			return errCorruptPayload
+		}
+		return fn(step)
+	})
+}
+
+// VerifyDLQFile - 파일을 끝까지 읽어 정상/손상 레코드 수 보고
+func VerifyDLQFile(r io.Reader) (FileReport, error) {
+	return ReadDLQFile(r, func(Step) error { return nil })
+}
+
+// RepairDLQFile - 손상된 파일에서 정상 레코드만 골라 새 파일로 기록
+func RepairDLQFile(r io.Reader, w io.Writer) (FileReport, error) {
+	dw := NewDLQWriter(w)
+	report, err := ReadDLQFile(r, dw.Write)
+	if err != nil {
+		return report, err
+	}
+	if !dw.wroteHeader {
+		// 정상 레코드가 없어도 빈 파일이 아닌 유효한 헤더를 남긴다
+		_, err = w.Write(append([]byte(dlqMagic), dlqVersion))
+	}
+	return report, err
+}
+
+var errCorruptPayload = errors.New("corrupt payload")
+
+// scanDLQ - 레코드 단위로 payload를 읽는다
+// 파일 전체를 메모리에 읽으므로 spill 파일 크기 제한과 함께 사용한다
+func scanDLQ(r io.Reader, fn func(payload []byte) error) (FileReport, error) {
+	var report FileReport
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return report, err
+	}
+	if len(data) < dlqHeaderSize || string(data[:len(dlqMagic)]) != dlqMagic {
+		return report, ErrNotDLQFile
+	}
+	report.Version = int(data[len(dlqMagic)])
+	if report.Version != dlqVersion {
+		return report, fmt.Errorf("trace: unsupported dead-letter file version %d", report.Version)
+	}
+
+	pos := dlqHeaderSize
+	for pos < len(data) {
+		rest := data[pos:]
+		if !bytes.HasPrefix(rest, dlqSync) {
+			// 다음 sync 마커까지 건너뜀
+			next := bytes.Index(rest, dlqSync)
+			if next < 0 {
+				report.SkippedBytes += int64(len(rest))
+				report.TruncatedTail = true
+				break
+			}
+			report.SkippedBytes += int64(next)
+			pos += next
+			continue
+		}
+
+		if len(rest) < dlqRecordHeaderSize {
+			report.SkippedBytes += int64(len(rest))
+			report.TruncatedTail = true
+			break
+		}
+
+		length := int(binary.BigEndian.Uint32(rest[4:8]))
+		sum := binary.BigEndian.Uint32(rest[8:12])
+		if length > dlqMaxRecordSize {
+			report.Corrupt++
+			pos += len(dlqSync)
+			continue
+		}
+		if len(rest) < dlqRecordHeaderSize+length {
+			// 마지막 레코드가 잘렸거나 length가 손상된 경우, 뒤에 다른 레코드가 있는지 확인
+			if next := bytes.Index(rest[len(dlqSync):], dlqSync); next >= 0 {
+				report.Corrupt++
+				pos += len(dlqSync) + next
+				continue
+			}
+			report.SkippedBytes += int64(len(rest))
+			report.TruncatedTail = true
+			break
+		}
+
+		payload := rest[dlqRecordHeaderSize : dlqRecordHeaderSize+length]
+		if crc32.ChecksumIEEE(payload) != sum {
+			report.Corrupt++
+			pos += len(dlqSync)
+			continue
+		}
+
+		if err := fn(payload); err != nil {
+			if errors.Is(err, errCorruptPayload) {
+				report.Corrupt++
+				pos += dlqRecordHeaderSize + length
+				continue
+			}
+			return report, err
+		}
+		report.Records++
+		pos += dlqRecordHeaderSize + length
+	}
+
+	return report, nil
+}
@@ -0,0 +1,114 @@
+package trace_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"trace/internal/trace"
+)
+
+// dlqFixture - Step 세 개를 기록한 파일과 각 레코드가 끝나는 위치
+func dlqFixture(t *testing.T) (data []byte, steps []trace.Step, ends []int) {
+	t.Helper()
+	steps = []trace.Step{
+		{TraceID: "t1", UserID: "alice", Path: "/orders", Method: "GET", StatusCode: 200, Seq: 1},
+		{TraceID: "t1", UserID: "alice", Path: "/orders/:id", Method: "POST", StatusCode: 201, Seq: 2, Extra: `{"note":"multi\nline"}`},
+		{TraceID: "t2", UserID: "bob", Path: "/payments", Method: "GET", StatusCode: 500, Seq: 1},
+	}
+	var buf bytes.Buffer
+	w := trace.NewDLQWriter(&buf)
+	for _, step := range steps {
+		if err := w.Write(step); err != nil {
+			t.Fatal(err)
+		}
+		ends = append(ends, buf.Len())
+	}
+	return buf.Bytes(), steps, ends
+}
+
+func readDLQ(data []byte) ([]trace.Step, trace.FileReport, error) {
+	var got []trace.Step
+	report, err := trace.ReadDLQFile(bytes.NewReader(data), func(s trace.Step) error {
+		got = append(got, s)
+		return nil
+	})
+	return got, report, err
+}
+
+func sameSteps(got, want []trace.Step) bool {
+	return len(got) == len(want) && (len(got) == 0 || reflect.DeepEqual(got, want))
+}
+
+func TestDLQFileTruncatedAtEveryOffset(t *testing.T) {
+	data, steps, ends := dlqFixture(t)
+
+	for cut := 0; cut <= len(data); cut++ {
+		got, report, err := readDLQ(data[:cut])
+		if cut < 5 {
+			if !errors.Is(err, trace.ErrNotDLQFile) {
+				t.Fatalf("cut %d: err = %v, want ErrNotDLQFile", cut, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("cut %d: %v", cut, err)
+		}
+		complete := 0
+		for _, end := range ends {
+			if end <= cut {
+				complete++
+			}
+		}
+		if !sameSteps(got, steps[:complete]) {
+			t.Fatalf("cut %d: read %d steps, want the first %d intact", cut, len(got), complete)
+		}
+		atBoundary := cut == 5 || (complete > 0 && ends[complete-1] == cut)
+		if report.Valid() != atBoundary || report.TruncatedTail == atBoundary {
+			t.Fatalf("cut %d: report %+v, want truncated tail only between records", cut, report)
+		}
+	}
+}
+
+func TestDLQFileSurvivesEveryBitFlip(t *testing.T) {
+	data, steps, ends := dlqFixture(t)
+	starts := append([]int{5}, ends[:len(ends)-1]...)
+
+	for pos := range data {
+		for bit := 0; bit < 8; bit++ {
+			flipped := bytes.Clone(data)
+			flipped[pos] ^= 1 << bit
+
+			got, report, err := readDLQ(flipped)
+			if pos < 5 {
+				if err == nil {
+					t.Fatalf("flip %d.%d in the header: read without error", pos, bit)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("flip %d.%d: %v", pos, bit, err)
+			}
+			damaged := 0
+			for i := range starts {
+				if pos >= starts[i] && pos < ends[i] {
+					damaged = i
+				}
+			}
+			want := append(append([]trace.Step(nil), steps[:damaged]...), steps[damaged+1:]...)
+			if !sameSteps(got, want) || report.Valid() {
+				t.Fatalf("flip %d.%d in record %d: read %d steps (report %+v), want the other %d intact", pos, bit, damaged, len(got), report, len(want))
+			}
+
+			var repaired bytes.Buffer
+			if _, err := trace.RepairDLQFile(bytes.NewReader(flipped), &repaired); err != nil {
+				t.Fatalf("flip %d.%d: repair: %v", pos, bit, err)
+			}
+			fixed, report, err := readDLQ(repaired.Bytes())
+			if err != nil || !report.Valid() || !sameSteps(fixed, want) {
+				t.Fatalf("flip %d.%d: repaired file read %d steps, report %+v, err %v", pos, bit, len(fixed), report, err)
+			}
+		}
+	}
+}
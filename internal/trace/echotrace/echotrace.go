@@ -0,0 +1,36 @@
+// echotrace 패키지 - Echo용 trace 미들웨어
+package echotrace
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"trace/internal/trace"
+)
+
+// Middleware - Echo용 기본 미들웨어
+func Middleware() echo.MiddlewareFunc {
+	return MiddlewareWithConfig()
+}
+
+// MiddlewareWithConfig - Gin 미들웨어와 같은 옵션, 같은 파이프라인을 사용하는 Echo 미들웨어
+// Step.Path에는 Echo 라우트 패턴(/users/:id)이 기록된다
+// 핸들러가 반환한 에러는 응답 상태 코드가 Step에 남도록 이 미들웨어 안에서 Echo의 HTTPErrorHandler로 처리한다
+func MiddlewareWithConfig(options ...trace.MiddlewareOption) echo.MiddlewareFunc {
+	middleware := trace.HTTPMiddlewareWithConfig(options...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				trace.SetHTTPRoute(r, c.Path())
+				c.SetRequest(r)
+				c.SetResponse(echo.NewResponse(w, c.Echo()))
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+			})).ServeHTTP(c.Response(), c.Request())
+			return nil
+		}
+	}
+}
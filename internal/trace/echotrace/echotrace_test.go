@@ -0,0 +1,61 @@
+package echotrace_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+
+	"trace/internal/trace"
+	"trace/internal/trace/echotrace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestMiddlewareRecordsRouteTemplateAndErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Use(echotrace.MiddlewareWithConfig(trace.WithTracer(tracer)))
+	e.GET("/users/:id", func(c echo.Context) error {
+		if c.Param("id") == "missing" {
+			return echo.NewHTTPError(http.StatusNotFound, "no such user")
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	for _, id := range []string{"1", "missing"} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest("GET", "/users/"+id+"?user_id=user-1&access_token=token", nil))
+		if w.Header().Get("X-Trace-ID") == "" {
+			t.Fatalf("/users/%s: no trace ID header", id)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := tracer.Store().Query(context.Background(), trace.QueryFilter{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[int]bool{}
+	for _, step := range result.Steps {
+		if step.Path != "/users/:id" {
+			t.Errorf("Path = %q, want the route template", step.Path)
+		}
+		statuses[step.StatusCode] = true
+	}
+	if len(result.Steps) != 2 || !statuses[200] || !statuses[404] {
+		t.Fatalf("steps = %+v, want 200 and 404", result.Steps)
+	}
+}
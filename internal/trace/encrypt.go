@@ -0,0 +1,129 @@
+package trace
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// 암호화한 컬럼 값 접두사 (접두사가 없는 값은 암호화 전에 저장된 평문으로 본다)
+const encryptedPrefix = "enc:v1:"
+
+// columnCipher - UserID, IP, Extra 컬럼 AES-GCM 암호화
+// 같은 값은 같은 암호문이 되도록 nonce를 값의 HMAC으로 만든다 (user_id 조회와 DeleteUserData를 위해 필요)
+// 따라서 두 행의 값이 같은지는 드러나지만 값 자체는 키 없이 알 수 없다
+type columnCipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+func newColumnCipher(key []byte) (*columnCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("trace: invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("trace: invalid encryption key: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("trace column nonce"))
+	return &columnCipher{aead: aead, nonceKey: mac.Sum(nil)}, nil
+}
+
+// encrypt - column을 추가 인증 데이터로 사용하여 암호화 (다른 컬럼으로 옮긴 값은 복호화되지 않음)
+func (c *columnCipher) encrypt(column, value string) string {
+	if c == nil || value == "" || strings.HasPrefix(value, encryptedPrefix) {
+		return value
+	}
+	mac := hmac.New(sha256.New, c.nonceKey)
+	mac.Write([]byte(column))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	nonce := mac.Sum(nil)[:c.aead.NonceSize()]
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), []byte(column))
+	return encryptedPrefix + base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+func (c *columnCipher) decrypt(column, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if c == nil || !ok {
+		return value, nil
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("trace: encrypted value is too short")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, []byte(column))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// encryptStep - 저장 전에 민감한 컬럼 암호화
+func (c *columnCipher) encryptStep(step *Step) {
+	if c == nil {
+		return
+	}
+	step.UserID = c.encrypt("user_id", step.UserID)
+	step.IP = c.encrypt("ip", step.IP)
+	step.Extra = c.encrypt("extra", step.Extra)
+}
+
+// decryptSteps - 조회한 Step 복호화 (다른 키로 암호화된 값은 그대로 두고 로그를 남김)
+func (c *columnCipher) decryptSteps(steps []Step) {
+	if c == nil {
+		return
+	}
+	failed := 0
+	for i := range steps {
+		for _, field := range []struct {
+			column string
+			value  *string
+		}{
+			{"user_id", &steps[i].UserID},
+			{"ip", &steps[i].IP},
+			{"extra", &steps[i].Extra},
+		} {
+			plain, err := c.decrypt(field.column, *field.value)
+			if err != nil {
+				failed++
+				continue
+			}
+			*field.value = plain
+		}
+	}
+	if failed > 0 {
+		log.Printf("trace: failed to decrypt %d column values (encrypted with a different key?)", failed)
+	}
+}
+
+// encryptionCipher - Config의 키 또는 키 함수로 암호화 설정 생성 (암호화를 사용하지 않으면 nil)
+func encryptionCipher(cfg Config) (*columnCipher, error) {
+	key := cfg.EncryptionKey
+	if cfg.EncryptionKeyFunc != nil {
+		var err error
+		if key, err = cfg.EncryptionKeyFunc(); err != nil {
+			return nil, fmt.Errorf("trace: failed to get encryption key: %w", err)
+		}
+		if len(key) == 0 {
+			return nil, errors.New("trace: EncryptionKeyFunc returned an empty key")
+		}
+	}
+	if len(key) == 0 {
+		return nil, nil
+	}
+	return newColumnCipher(key)
+}
@@ -0,0 +1,272 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EndpointSummary 엔드포인트별 요약 통계
+type EndpointSummary struct {
+	Path      string  `json:"path"`
+	Method    string  `json:"method"`
+	Count     int64   `json:"count"`
+	P50Ms     int64   `json:"p50_ms"`
+	P95Ms     int64   `json:"p95_ms"`
+	P99Ms     int64   `json:"p99_ms"`
+	ErrorRate float64 `json:"error_rate"` // 5xx 비율 (0~1)
+
+	// 저장하지 않은 요청 (WithCountSkipped, Rollups를 사용할 때만 집계, 백분위 없음)
+	Uncollected          int64   `json:"uncollected,omitempty"`
+	UncollectedAvgMs     float64 `json:"uncollected_avg_ms,omitempty"`
+	UncollectedErrorRate float64 `json:"uncollected_error_rate,omitempty"`
+}
+
+const (
+	// 기본 TopEndpoints 캐시 유지 시간
+	defaultTopEndpointsCacheTTL = 30 * time.Second
+	// TopEndpoints 조회 제한 시간 (조회는 호출한 요청의 ctx와 분리되어 실행됨)
+	topEndpointsQueryTimeout = 30 * time.Second
+	// Store 하나가 캐시하는 최대 (window, n) 조합 수 (넘으면 가장 먼저 만료될 결과부터 버림)
+	maxTopCacheEntries = 64
+)
+
+// topEndpointsCacheTTL - cfg의 TopEndpoints 캐시 유지 시간 (0이면 기본값)
+func topEndpointsCacheTTL(cfg Config) time.Duration {
+	if cfg.TopEndpointsCacheTTL > 0 {
+		return cfg.TopEndpointsCacheTTL
+	}
+	return defaultTopEndpointsCacheTTL
+}
+
+// TopEndpoints - 최근 window 동안 요청 수 기준 상위 n개 엔드포인트 반환
+// 결과는 Store(Tracer, NewStore)마다 (DB, 테이블, window, n) 단위로 캐시되며, 동시에 들어온 같은 요청은 한 번만 조회한다
+// 패키지 함수는 기본 Tracer의 캐시를 사용하고, 기본 Tracer가 DB에 저장하지 않으면 캐시하지 않는다
+// Rollups를 사용하면 원본 Step 대신 롤업 테이블을 조회하며, 이때 백분위는 히스토그램 버킷의 상한이다
+// WithCountSkipped로 센 저장하지 않은 요청은 롤업 테이블에만 있으므로 Rollups를 사용할 때만 Uncollected에 채우고 순위에 포함한다
+func TopEndpoints(ctx context.Context, db *gorm.DB, window time.Duration, n int) ([]EndpointSummary, error) {
+	return storeFor(db).TopEndpoints(ctx, window, n)
+}
+
+// TopEndpoints - 최근 window 동안 요청 수 기준 상위 n개 엔드포인트 반환
+func (s *Store) TopEndpoints(ctx context.Context, window time.Duration, n int) ([]EndpointSummary, error) {
+	load := func(ctx context.Context) ([]EndpointSummary, error) {
+		since := time.Now().Add(-window)
+		if s.rollups {
+			return s.topFromRollups(ctx, since, n)
+		}
+		return s.queryTopEndpoints(ctx, since, n)
+	}
+	if s.top == nil {
+		ctx, cancel := context.WithTimeout(ctx, topEndpointsQueryTimeout)
+		defer cancel()
+		return load(ctx)
+	}
+	key := topCacheKey{db: s.db.Config, tables: s.tables, rollups: s.rollups, window: window, n: n}
+	return s.top.get(ctx, key, load)
+}
+
+// topFromRollups - 롤업 테이블에서 상위 n개 엔드포인트 계산 (엔드포인트마다 백분위 쿼리를 보내지 않음)
+// 분 단위로 집계하므로 since가 속한 분 전체를 포함한다
+func (s *Store) topFromRollups(ctx context.Context, since time.Time, n int) ([]EndpointSummary, error) {
+	rollups, err := s.summarizeRollups(ctx, since.Truncate(time.Minute), time.Now().Add(time.Minute), n)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]EndpointSummary, 0, len(rollups))
+	for _, r := range rollups {
+		summaries = append(summaries, EndpointSummary{
+			Path:      r.Path,
+			Method:    r.Method,
+			Count:     r.Count,
+			P50Ms:     r.P50Ms,
+			P95Ms:     r.P95Ms,
+			P99Ms:     r.P99Ms,
+			ErrorRate: r.ErrorRate,
+
+			Uncollected:          r.Uncollected,
+			UncollectedAvgMs:     r.UncollectedAvgMs,
+			UncollectedErrorRate: r.UncollectedErrorRate,
+		})
+	}
+	return summaries, nil
+}
+
+func (s *Store) queryTopEndpoints(ctx context.Context, since time.Time, n int) ([]EndpointSummary, error) {
+	type row struct {
+		Path   string
+		Method string
+		Count  int64
+		Errors int64
+	}
+
+	steps, err := s.stepsFrom(ctx, since, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []row
+	err = steps().
+		Where(requestStepsOnly).
+		Select("path, method, COUNT(*) AS count, SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) AS errors").
+		Where("created_at >= ?", since.Unix()).
+		Group("path, method").
+		Order("count DESC").
+		Limit(n).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]EndpointSummary, 0, len(rows))
+	for _, r := range rows {
+		scope := func() *gorm.DB {
+			return steps().
+				Where(requestStepsOnly).
+				Where("created_at >= ? AND path = ? AND method = ?", since.Unix(), r.Path, r.Method)
+		}
+		summary := EndpointSummary{
+			Path:      r.Path,
+			Method:    r.Method,
+			Count:     r.Count,
+			ErrorRate: float64(r.Errors) / float64(r.Count),
+		}
+		for _, pct := range []struct {
+			p   float64
+			dst *int64
+		}{{0.50, &summary.P50Ms}, {0.95, &summary.P95Ms}, {0.99, &summary.P99Ms}} {
+			if *pct.dst, err = latencyPercentile(scope, r.Count, pct.p); err != nil {
+				return nil, err
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// latencyPercentile - 정렬 후 OFFSET으로 백분위 값 조회 (DB 종류와 무관하게 동작)
+// scope는 호출할 때마다 새 조회 조건을 만들어야 한다
+func latencyPercentile(scope func() *gorm.DB, count int64, p float64) (int64, error) {
+	if count == 0 {
+		return 0, nil
+	}
+
+	var latencies []int64
+	err := scope().
+		Order("latency_ms").
+		Offset(int(float64(count-1)*p)).
+		Limit(1).
+		Pluck("latency_ms", &latencies).Error
+	if err != nil || len(latencies) == 0 {
+		return 0, err
+	}
+	return latencies[0], nil
+}
+
+// topCacheKey - 같은 DB와 테이블의 같은 조회만 결과를 공유한다
+type topCacheKey struct {
+	db      *gorm.Config // 같은 DB의 세션은 Config를 공유한다
+	tables  stepTables
+	rollups bool
+	window  time.Duration
+	n       int
+}
+
+type topCacheEntry struct {
+	summaries []EndpointSummary
+	expiresAt time.Time
+}
+
+// topCall - 진행 중인 조회 (single-flight)
+type topCall struct {
+	done      chan struct{}
+	summaries []EndpointSummary
+	err       error
+}
+
+// topEndpointsCache - TTL 캐시와 single-flight 중복 제거 (Store마다 하나, 최대 maxTopCacheEntries개)
+type topEndpointsCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	now      func() time.Time
+	entries  map[topCacheKey]topCacheEntry
+	inflight map[topCacheKey]*topCall
+}
+
+func newTopEndpointsCache(ttl time.Duration) *topEndpointsCache {
+	return &topEndpointsCache{
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[topCacheKey]topCacheEntry),
+		inflight: make(map[topCacheKey]*topCall),
+	}
+}
+
+// get - 캐시된 결과 반환, 없으면 조회 (같은 key의 조회가 진행 중이면 그 결과를 기다림)
+// 조회는 처음 호출한 요청의 ctx와 분리된 고루틴에서 실행되므로 그 요청이 취소되어도 기다리던 호출자는 결과를 받고,
+// 각 호출자는 자신의 ctx가 끝나면 결과를 기다리지 않고 반환한다
+func (tc *topEndpointsCache) get(ctx context.Context, key topCacheKey, load func(context.Context) ([]EndpointSummary, error)) ([]EndpointSummary, error) {
+	tc.mu.Lock()
+	if entry, ok := tc.entries[key]; ok && tc.now().Before(entry.expiresAt) {
+		tc.mu.Unlock()
+		return entry.summaries, nil
+	}
+	call, ok := tc.inflight[key]
+	if !ok {
+		call = &topCall{done: make(chan struct{})}
+		tc.inflight[key] = call
+		go tc.load(context.WithoutCancel(ctx), key, call, load)
+	}
+	tc.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.summaries, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// load - 조회 후 결과를 캐시하고 기다리는 호출자에게 알림 (load가 panic해도 진행 중 표시를 지우고 에러로 전달)
+func (tc *topEndpointsCache) load(ctx context.Context, key topCacheKey, call *topCall, load func(context.Context) ([]EndpointSummary, error)) {
+	ctx, cancel := context.WithTimeout(ctx, topEndpointsQueryTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in trace top endpoints query: %v", r)
+			call.summaries, call.err = nil, fmt.Errorf("trace: top endpoints query panicked: %v", r)
+		}
+		tc.mu.Lock()
+		delete(tc.inflight, key)
+		if call.err == nil && tc.ttl > 0 {
+			now := tc.now()
+			tc.evict(now)
+			tc.entries[key] = topCacheEntry{summaries: call.summaries, expiresAt: now.Add(tc.ttl)}
+		}
+		tc.mu.Unlock()
+		close(call.done)
+	}()
+	call.summaries, call.err = load(ctx)
+}
+
+// evict - 만료된 결과를 지우고, 그래도 가득 차 있으면 가장 먼저 만료될 결과를 지움 (tc.mu를 잡은 상태에서 호출)
+func (tc *topEndpointsCache) evict(now time.Time) {
+	var oldest topCacheKey
+	var oldestAt time.Time
+	for key, entry := range tc.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(tc.entries, key)
+			continue
+		}
+		if oldestAt.IsZero() || entry.expiresAt.Before(oldestAt) {
+			oldest, oldestAt = key, entry.expiresAt
+		}
+	}
+	if len(tc.entries) >= maxTopCacheEntries {
+		delete(tc.entries, oldest)
+	}
+}
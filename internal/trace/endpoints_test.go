@@ -0,0 +1,278 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTopEndpointsCacheSingleFlight(t *testing.T) {
+	tc := newTopEndpointsCache(time.Minute)
+	release := make(chan struct{})
+	var loads atomic.Int32
+	load := func(ctx context.Context) ([]EndpointSummary, error) {
+		loads.Add(1)
+		<-release
+		return []EndpointSummary{{Path: "/orders", Count: 3}}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]EndpointSummary, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = tc.get(context.Background(), topCacheKey{n: 1}, load)
+		}()
+	}
+	waitInflight(t, tc)
+	time.Sleep(10 * time.Millisecond) // 나머지 호출자가 진행 중인 조회에 합류할 시간
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("load ran %d times, want 1", n)
+	}
+	for i, r := range results {
+		if len(r) != 1 || r[0].Path != "/orders" {
+			t.Fatalf("caller %d got %+v", i, r)
+		}
+	}
+}
+
+func TestTopEndpointsCacheWaitersOutliveFirstCaller(t *testing.T) {
+	tc := newTopEndpointsCache(time.Minute)
+	release := make(chan struct{})
+	loadErr := make(chan error, 1)
+	load := func(ctx context.Context) ([]EndpointSummary, error) {
+		<-release
+		loadErr <- ctx.Err()
+		return []EndpointSummary{{Path: "/a"}}, nil
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := tc.get(first, topCacheKey{n: 2}, load)
+		firstDone <- err
+	}()
+	waitInflight(t, tc)
+
+	second := make(chan []EndpointSummary, 1)
+	go func() {
+		r, _ := tc.get(context.Background(), topCacheKey{n: 2}, load)
+		second <- r
+	}()
+
+	cancel()
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller got %v, want context.Canceled", err)
+	}
+	close(release)
+	if err := <-loadErr; err != nil {
+		t.Fatalf("load ran with the first caller's cancellation: %v", err)
+	}
+	if r := <-second; len(r) != 1 || r[0].Path != "/a" {
+		t.Fatalf("waiting caller got %+v, want the loaded result", r)
+	}
+}
+
+func TestTopEndpointsCachePanicReleasesWaiters(t *testing.T) {
+	tc := newTopEndpointsCache(time.Minute)
+	key := topCacheKey{n: 3}
+	_, err := tc.get(context.Background(), key, func(context.Context) ([]EndpointSummary, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("panicking load returned no error")
+	}
+	r, err := tc.get(context.Background(), key, func(context.Context) ([]EndpointSummary, error) {
+		return []EndpointSummary{{Path: "/ok"}}, nil
+	})
+	if err != nil || len(r) != 1 {
+		t.Fatalf("load after a panic = %+v, %v; want a fresh query", r, err)
+	}
+}
+
+func TestTopEndpointsCacheTTL(t *testing.T) {
+	tc := newTopEndpointsCache(30 * time.Second)
+	now := time.Unix(1_700_000_000, 0)
+	tc.now = func() time.Time { return now }
+
+	var loads int
+	load := func(context.Context) ([]EndpointSummary, error) {
+		loads++
+		return nil, nil
+	}
+	get := func() {
+		if _, err := tc.get(context.Background(), topCacheKey{n: 4}, load); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get()
+	now = now.Add(29 * time.Second)
+	get()
+	if loads != 1 {
+		t.Fatalf("loads = %d within the TTL, want 1", loads)
+	}
+	now = now.Add(time.Second)
+	get()
+	if loads != 2 {
+		t.Fatalf("loads = %d after the TTL, want 2", loads)
+	}
+	if _, err := tc.get(context.Background(), topCacheKey{n: 5}, load); err != nil || loads != 3 {
+		t.Fatalf("a different key reused the cached result (loads = %d)", loads)
+	}
+}
+
+func TestTopEndpointsCacheEvictsExpiredAndCapsEntries(t *testing.T) {
+	tc := newTopEndpointsCache(time.Minute)
+	now := time.Unix(1_700_000_000, 0)
+	tc.now = func() time.Time { return now }
+	load := func(context.Context) ([]EndpointSummary, error) { return nil, nil }
+
+	for n := range maxTopCacheEntries * 2 {
+		if _, err := tc.get(context.Background(), topCacheKey{n: n}, load); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Millisecond)
+	}
+	if len(tc.entries) != maxTopCacheEntries {
+		t.Fatalf("cache holds %d entries, want at most %d", len(tc.entries), maxTopCacheEntries)
+	}
+	// 가장 먼저 만료될 결과부터 버림
+	if _, ok := tc.entries[topCacheKey{n: 0}]; ok {
+		t.Fatal("oldest entry was kept over the cap")
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := tc.get(context.Background(), topCacheKey{n: -1}, load); err != nil {
+		t.Fatal(err)
+	}
+	if len(tc.entries) != 1 {
+		t.Fatalf("cache holds %d entries after all others expired, want 1", len(tc.entries))
+	}
+}
+
+// newTopTestDB - Step 테이블을 만든 메모리 SQLite DB
+func newTopTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGormSink(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestTopEndpointsCacheIsPerStore(t *testing.T) {
+	db := newTopTestDB(t)
+	a, err := NewStore(db, Config{TopEndpointsCacheTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewStore(db, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.top == b.top || a.top.ttl != time.Hour || b.top.ttl != defaultTopEndpointsCacheTTL {
+		t.Fatalf("stores share a cache or ignore their TTL: a=%v b=%v", a.top.ttl, b.top.ttl)
+	}
+	if _, err := a.TopEndpoints(context.Background(), time.Hour, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	// 다른 Tracer를 만들어도 이미 캐시한 결과와 TTL은 그대로
+	other, err := New(Config{DB: db, TopEndpointsCacheTTL: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Stop(context.Background())
+	if len(a.top.entries) != 1 || a.top.ttl != time.Hour {
+		t.Fatalf("creating another tracer changed this store's cache: %d entries, TTL %v", len(a.top.entries), a.top.ttl)
+	}
+	if other.Store().top == a.top || other.Store().top.ttl != time.Second {
+		t.Fatalf("tracer store cache = %p (TTL %v), want its own with a 1s TTL", other.Store().top, other.Store().top.ttl)
+	}
+}
+
+func TestTopRouteRoundsWindowAndN(t *testing.T) {
+	store, err := NewStore(newTopTestDB(t), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	APIRoutes(r.Group("/debug"), nil, WithAPIStore(store), WithAPIAuth(func(c *gin.Context) {}))
+
+	tests := []struct {
+		query  string
+		status int
+		window time.Duration
+		n      int
+	}{
+		{"", http.StatusOK, time.Hour, 20},
+		{"?window=7m&n=30", http.StatusOK, 15 * time.Minute, 50},
+		{"?window=8m&n=31", http.StatusOK, 15 * time.Minute, 50},
+		{"?window=720h&n=1000", http.StatusOK, 7 * 24 * time.Hour, 100},
+		{"?window=1h&n=3", http.StatusOK, time.Hour, 5},
+		{"?window=soon", http.StatusBadRequest, 0, 0},
+		{"?n=-1", http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/top"+tt.query, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET /top%s: status %d, want %d", tt.query, w.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		key := topCacheKey{db: store.db.Config, tables: store.tables, window: tt.window, n: tt.n}
+		if _, ok := store.top.entries[key]; !ok {
+			t.Errorf("GET /top%s did not query window=%v n=%d", tt.query, tt.window, tt.n)
+		}
+	}
+	// 서로 다른 값이 허용 목록으로 모여 캐시 항목이 늘지 않음
+	if len(store.top.entries) != 4 {
+		t.Errorf("cache holds %d entries, want 4", len(store.top.entries))
+	}
+	// 기존 경로도 같은 응답
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/endpoints", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /endpoints: status %d, want 200", w.Code)
+	}
+}
+
+// waitInflight - 조회가 시작될 때까지 대기
+func waitInflight(t *testing.T, tc *topEndpointsCache) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tc.mu.Lock()
+		n := len(tc.inflight)
+		tc.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("load never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
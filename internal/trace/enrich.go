@@ -0,0 +1,112 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Enricher 기본값
+const (
+	defaultEnrichConcurrency = 4
+	defaultEnrichTimeout     = 5 * time.Second
+)
+
+// Enricher 저장 직전에 워커에서 Step에 정보를 더하는 단계 (Config.Enrichers)
+// 요청 처리 중이 아니라 저장 고루틴에서 실행되므로 UA 분석, 사용자 등급 조회처럼 오래 걸리는 작업에 사용한다
+// 에러를 반환해도 Step은 그대로 저장되며, ctx가 끝나면 가능한 빨리 반환해야 한다
+type Enricher interface {
+	Enrich(ctx context.Context, step *Step) error
+}
+
+// EnricherFunc 함수를 Enricher로 사용
+type EnricherFunc func(ctx context.Context, step *Step) error
+
+func (f EnricherFunc) Enrich(ctx context.Context, step *Step) error {
+	return f(ctx, step)
+}
+
+// LimitConcurrency - enricher의 동시 실행 수를 n으로 제한 (모든 배치와 Tracer에서 공유)
+// 외부 서비스 호출처럼 동시 요청 수에 제한이 있는 Enricher에 사용한다
+func LimitConcurrency(enricher Enricher, n int) Enricher {
+	sem := make(chan struct{}, max(n, 1))
+	return EnricherFunc(func(ctx context.Context, step *Step) error {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-sem }()
+		return enricher.Enrich(ctx, step)
+	})
+}
+
+// enrich - 배치의 Step에 Enricher를 순서대로 적용 (EnrichConcurrency개의 Step을 동시에 처리)
+// 배치 전체에 EnrichTimeout을 적용하며, 시간이 지나면 남은 Step은 그대로 저장한다
+func (t *Tracer) enrich(logs []Step) {
+	if len(t.cfg.Enrichers) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), t.cfg.EnrichTimeout)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		failed  atomic.Int64
+		skipped int
+	)
+	sem := make(chan struct{}, t.cfg.EnrichConcurrency)
+	for i := range logs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			skipped = len(logs) - i
+			break
+		}
+		wg.Add(1)
+		go func(step *Step) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			for _, enricher := range t.cfg.Enrichers {
+				if err := runEnricher(ctx, enricher, step); err != nil {
+					failed.Add(1)
+				}
+			}
+		}(&logs[i])
+	}
+	wg.Wait()
+
+	if n := failed.Load(); n > 0 {
+		log.Printf("trace: %d enricher calls failed", n)
+	}
+	if skipped > 0 {
+		log.Printf("trace: enrichment timed out after %s, %d steps stored without enrichment", t.cfg.EnrichTimeout, skipped)
+	}
+}
+
+func runEnricher(ctx context.Context, enricher Enricher, step *Step) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in trace enricher: %v", r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return enricher.Enrich(ctx, step)
+}
+
+// prepare - 저장 전에 워커에서 수행하는 단계 (지역 정보, Enricher, 암호화 순)
+// Enricher가 평문 값을 사용하고 추가한 값도 암호화되도록 암호화는 마지막에 한다
+func (t *Tracer) prepare(logs []Step) {
+	t.enrichGeo(logs)
+	t.enrich(logs)
+	for i := range logs {
+		t.cipher.encryptStep(&logs[i])
+	}
+}
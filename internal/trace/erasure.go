@@ -0,0 +1,101 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeleteUserData - 기본 Tracer의 DB에서 사용자의 모든 Step을 삭제 (개인정보 삭제 요청 처리용)
+// Start 전이면 ErrNotRunning을 반환한다
+func DeleteUserData(ctx context.Context, userID string) (int64, error) {
+	t := defaultTracer.Load()
+	if t == nil {
+		return 0, ErrNotRunning
+	}
+	return t.DeleteUserData(ctx, userID)
+}
+
+// DeleteUserData - DB에서 사용자의 모든 Step(하위 span 포함)을 RetentionBatchSize씩 트랜잭션으로 나누어 삭제
+// PartitionByDay를 사용하면 모든 일별 테이블에서 삭제하며, 중간에 실패하면 그때까지 삭제한 수와 에러를 반환한다
+// 호출 시점에 버퍼에 있던 Step은 이후에 저장될 수 있고, spill/dead-letter 파일과 사용자 정의 Sink는 대상이 아니다
+func (t *Tracer) DeleteUserData(ctx context.Context, userID string) (int64, error) {
+	if userID == "" {
+		return 0, errors.New("trace: DeleteUserData requires a user ID")
+	}
+	db := t.cfg.DB
+	if db == nil {
+		return 0, errors.New("trace: DeleteUserData requires DB: steps written to a custom Sink must be erased in that store")
+	}
+
+	tables := []string{t.tables.base}
+	if t.tables.daily {
+		parts, err := t.tables.partitions(db.WithContext(ctx))
+		if err != nil {
+			return 0, err
+		}
+		tables = tables[:0]
+		for _, p := range parts {
+			tables = append(tables, p.name)
+		}
+	}
+
+	batchSize := t.cfg.RetentionBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRetentionBatchSize
+	}
+	var total int64
+	for _, table := range tables {
+		n, err := deleteUserBatches(ctx, db, table, t.cipher.encrypt("user_id", userID), batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// deleteUserBatches - table에서 사용자의 Step을 오래된 순으로 batchSize씩 삭제 (배치마다 트랜잭션 하나)
+// 같은 초에 생성된 Step이 많으면 배치가 batchSize보다 커질 수 있다 (deleteBatches와 같은 created_at 경계 사용)
+func deleteUserBatches(ctx context.Context, db *gorm.DB, table, userID string, batchSize int) (int64, error) {
+	var total int64
+	for {
+		var bounds []int64
+		err := db.WithContext(ctx).
+			Table(table).
+			Where("user_id = ?", userID).
+			Order("created_at").
+			Offset(batchSize-1).
+			Limit(1).
+			Pluck("created_at", &bounds).Error
+		if err != nil {
+			return total, err
+		}
+
+		err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			scope := tx.Table(table).Where("user_id = ?", userID)
+			if len(bounds) > 0 {
+				scope = scope.Where("created_at <= ?", bounds[0])
+			}
+			result := scope.Delete(&Step{})
+			total += result.RowsAffected
+			return result.Error
+		})
+		if err != nil {
+			return total, err
+		}
+
+		// 마지막 배치
+		if len(bounds) == 0 {
+			return total, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-time.After(retentionBatchPause):
+		}
+	}
+}
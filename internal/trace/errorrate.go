@@ -0,0 +1,159 @@
+package trace
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 경로별 에러율 기본값
+const (
+	defaultErrorRateWindow      = time.Minute
+	defaultErrorRateMinRequests = 20
+	// 윈도를 나누는 구간 수 (윈도가 1분이면 10초 단위로 오래된 구간을 버림)
+	errorRateSlots = 6
+	// 추적할 최대 경로 수 (경로에 ID가 들어가 무한히 늘어나는 경우 대비, 넘으면 새 경로는 무시)
+	maxErrorRateRoutes = 1000
+)
+
+// RouteErrorRate 경로의 최근 ErrorRateWindow 동안 에러율 (5xx 비율)
+// 담당 팀 라벨(Labeled, WithStaticLabels의 team)이 다른 요청은 같은 경로라도 따로 집계한다
+type RouteErrorRate struct {
+	Route     string  `json:"route"` // "GET /api/users"
+	Team      string  `json:"team,omitempty"`
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"` // 0~1
+	// ErrorRateThreshold를 넘은 상태인지 여부
+	Exceeded bool `json:"exceeded"`
+}
+
+type errorRateSlot struct {
+	index    int64 // 구간 번호 (시각 / 구간 길이)
+	requests int64
+	errors   int64
+}
+
+// errorRateKey - 에러율을 집계하는 경로와 담당 팀
+type errorRateKey struct {
+	route, team string
+}
+
+type routeWindow struct {
+	slots    [errorRateSlots]errorRateSlot
+	exceeded bool
+}
+
+// errorRates - 경로별 슬라이딩 윈도 에러율
+type errorRates struct {
+	slot        time.Duration
+	threshold   float64
+	minRequests int64
+
+	mu     sync.Mutex
+	routes map[errorRateKey]*routeWindow
+}
+
+func newErrorRates(cfg Config) *errorRates {
+	return &errorRates{
+		slot:        max(cfg.ErrorRateWindow/errorRateSlots, time.Millisecond),
+		threshold:   cfg.ErrorRateThreshold,
+		minRequests: int64(cfg.ErrorRateMinRequests),
+		routes:      make(map[errorRateKey]*routeWindow),
+	}
+}
+
+// totals - 현재 구간 기준 윈도 안의 요청 수와 에러 수
+func (w *routeWindow) totals(current int64) (requests, errors int64) {
+	for _, s := range w.slots {
+		if s.index > current-errorRateSlots {
+			requests += s.requests
+			errors += s.errors
+		}
+	}
+	return requests, errors
+}
+
+// record - 요청 결과를 기록하고, 에러율이 처음 기준을 넘었으면 알릴 값과 true 반환
+func (r *errorRates) record(key errorRateKey, failed bool, now time.Time) (RouteErrorRate, bool) {
+	current := now.UnixNano() / int64(r.slot)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w := r.routes[key]
+	if w == nil {
+		if len(r.routes) >= maxErrorRateRoutes {
+			return RouteErrorRate{}, false
+		}
+		w = &routeWindow{}
+		r.routes[key] = w
+	}
+	s := &w.slots[current%errorRateSlots]
+	if s.index != current {
+		*s = errorRateSlot{index: current}
+	}
+	s.requests++
+	if failed {
+		s.errors++
+	}
+
+	if r.threshold <= 0 {
+		return RouteErrorRate{}, false
+	}
+	requests, errors := w.totals(current)
+	rate := float64(errors) / float64(requests)
+	exceeded := requests >= r.minRequests && rate >= r.threshold
+	notify := exceeded && !w.exceeded
+	w.exceeded = exceeded
+	return RouteErrorRate{Route: key.route, Team: key.team, Requests: requests, Errors: errors, ErrorRate: rate, Exceeded: exceeded}, notify
+}
+
+// snapshot - 윈도 안에 요청이 있는 경로의 에러율 (에러율이 높은 순)
+func (r *errorRates) snapshot(now time.Time) []RouteErrorRate {
+	current := now.UnixNano() / int64(r.slot)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rates []RouteErrorRate
+	for key, w := range r.routes {
+		requests, errors := w.totals(current)
+		if requests == 0 {
+			// 윈도가 지난 경로는 정리
+			delete(r.routes, key)
+			continue
+		}
+		rates = append(rates, RouteErrorRate{
+			Route:     key.route,
+			Team:      key.team,
+			Requests:  requests,
+			Errors:    errors,
+			ErrorRate: float64(errors) / float64(requests),
+			Exceeded:  w.exceeded,
+		})
+	}
+	slices.SortFunc(rates, func(a, b RouteErrorRate) int {
+		if a.ErrorRate != b.ErrorRate {
+			if a.ErrorRate > b.ErrorRate {
+				return -1
+			}
+			return 1
+		}
+		if n := strings.Compare(a.Route, b.Route); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Team, b.Team)
+	})
+	return rates
+}
+
+// recordErrorRate - 요청 Step의 결과를 경로별 에러율에 반영 (하위 span 제외)
+func (t *Tracer) recordErrorRate(step Step) {
+	if step.SpanName != "" {
+		return
+	}
+	rate, notify := t.errorRates.record(errorRateKey{step.Method + " " + step.Path, step.Team}, step.StatusCode >= 500, time.Now())
+	if notify && t.cfg.OnErrorRateExceeded != nil {
+		callHook("OnErrorRateExceeded", func() { t.cfg.OnErrorRateExceeded(rate) })
+	}
+}
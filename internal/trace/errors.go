@@ -0,0 +1,46 @@
+package trace
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Step.Error 최대 길이
+const maxErrorLen = 1024
+
+// handlerErrors - 핸들러가 c.Error로 남긴 에러 메시지와 종류 반환
+// 메시지는 "; "로, 종류는 중복 없이 ","로 이어 붙인다 (예: "bind,private")
+func handlerErrors(c *gin.Context) (string, string) {
+	if len(c.Errors) == 0 {
+		return "", ""
+	}
+
+	messages := make([]string, 0, len(c.Errors))
+	var types []string
+	for _, e := range c.Errors {
+		messages = append(messages, e.Error())
+		name := errorTypeName(e.Type)
+		if !slices.Contains(types, name) {
+			types = append(types, name)
+		}
+	}
+
+	return truncateUTF8(strings.Join(messages, "; "), maxErrorLen), strings.Join(types, ",")
+}
+
+func errorTypeName(t gin.ErrorType) string {
+	switch t {
+	case gin.ErrorTypeBind:
+		return "bind"
+	case gin.ErrorTypeRender:
+		return "render"
+	case gin.ErrorTypePrivate:
+		return "private"
+	case gin.ErrorTypePublic:
+		return "public"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,332 @@
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// 한 번에 읽어 복호화하고 기록하는 Step 수
+const exportBatchSize = 500
+
+// 기본 셀 최대 길이 (Excel 셀 최대 32767자, 넘으면 잘라 내고 csvTruncatedSuffix를 붙임)
+const (
+	maxCSVCellBytes    = 32767
+	csvTruncatedSuffix = "...[truncated]"
+)
+
+// Excel이 UTF-8로 인식하도록 파일 앞에 붙이는 BOM (WithBOM)
+const utf8BOM = "\ufeff"
+
+// ExportFormat Export 출력 형식
+type ExportFormat string
+
+const (
+	// ExportCSV 첫 줄이 컬럼 이름인 CSV
+	ExportCSV ExportFormat = "csv"
+	// ExportNDJSON 한 줄에 Step 하나씩 JSON 객체
+	ExportNDJSON ExportFormat = "ndjson"
+	// ExportParquet Parquet 파일 (비압축, pandas/DuckDB에서 바로 읽을 수 있음)
+	ExportParquet ExportFormat = "parquet"
+)
+
+// ExportOptions Export 설정 (ExportOption으로 변경)
+type ExportOptions struct {
+	// true면 CSV에서 =, +, -, @, 탭, CR로 시작하는 값 앞에 '를 붙여 스프레드시트 수식으로 실행되지 않게 함 (기본 true)
+	ExcelSafe bool
+	// CSV와 등록한 형식(RegisterExportFormat)의 문자열 셀 최대 바이트 수 (기본 32767, 넘으면 "...[truncated]"로 끝나도록 자름)
+	MaxCellBytes int
+	// true면 CSV 앞에 UTF-8 BOM 기록 (Excel에서 한글 등이 깨지지 않음)
+	BOM bool
+}
+
+// ExportOption 함수형 옵션 타입
+type ExportOption func(*ExportOptions)
+
+// WithExcelSafe CSV 수식 이스케이프 여부 설정 (기본 true, 스프레드시트로 열지 않는 파이프라인에서만 끔)
+func WithExcelSafe(safe bool) ExportOption {
+	return func(opts *ExportOptions) {
+		opts.ExcelSafe = safe
+	}
+}
+
+// WithMaxCellBytes 문자열 셀 최대 바이트 수 설정 (0 이하이면 기본 32767, 잘린 표시를 붙일 수 있도록 최소 32)
+func WithMaxCellBytes(n int) ExportOption {
+	return func(opts *ExportOptions) {
+		if n <= 0 {
+			n = maxCSVCellBytes
+		}
+		opts.MaxCellBytes = max(n, 32)
+	}
+}
+
+// WithBOM CSV 앞에 UTF-8 BOM 기록
+func WithBOM() ExportOption {
+	return func(opts *ExportOptions) {
+		opts.BOM = true
+	}
+}
+
+// ExportWriter RegisterExportFormat으로 등록한 형식의 기록기
+type ExportWriter interface {
+	// WriteRow - Step 하나의 컬럼 값 기록 (컬럼 순서, string, int64, bool), values는 다시 사용되므로 보관하려면 복사해야 한다
+	WriteRow(values []any) error
+	// Close - 남은 내용을 기록하고 형식을 마무리 (w는 닫지 않음)
+	Close() error
+}
+
+var (
+	exportFormatsMu sync.RWMutex
+	exportFormats   = map[ExportFormat]func(w io.Writer, columns []string, opts ExportOptions) (ExportWriter, error){}
+)
+
+// RegisterExportFormat - Export가 지원할 형식 등록 (xlsx 하위 패키지처럼 의존성이 필요한 형식을 import로 추가)
+// columns는 테이블 컬럼 이름이며, 문자열 값은 MaxCellBytes로 자른 뒤 전달한다 (ExcelSafe 이스케이프는 CSV에만 적용)
+func RegisterExportFormat(format ExportFormat, newWriter func(w io.Writer, columns []string, opts ExportOptions) (ExportWriter, error)) {
+	exportFormatsMu.Lock()
+	defer exportFormatsMu.Unlock()
+	exportFormats[format] = newWriter
+}
+
+// exportColumn 내보낼 Step 컬럼
+type exportColumn struct {
+	name  string // DB 컬럼 이름 (예: trace_id)
+	index []int  // Step 필드 위치
+	kind  reflect.Kind
+}
+
+func (c exportColumn) value(step *Step) reflect.Value {
+	return reflect.ValueOf(step).Elem().FieldByIndex(c.index)
+}
+
+// exportColumns - 테이블과 같은 이름과 순서의 Step 컬럼 목록
+func exportColumns(db *gorm.DB) ([]exportColumn, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&Step{}); err != nil {
+		return nil, err
+	}
+	columns := make([]exportColumn, 0, len(stmt.Schema.Fields))
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		columns = append(columns, exportColumn{name: field.DBName, index: field.StructField.Index, kind: field.FieldType.Kind()})
+	}
+	return columns, nil
+}
+
+// stepEncoder 형식별 Step 기록
+type stepEncoder interface {
+	encode(steps []Step) error
+	close() error
+}
+
+// Export - 기본 Tracer가 저장한 Step 중 조건에 맞는 Step을 오래된 순으로 w에 기록 (pandas, DuckDB 등에서 오프라인 분석용)
+// 기본 Tracer가 없거나 사용자 정의 Sink에 저장하면 ErrNotRunning을 반환하며, 다른 DB는 Store.Export로 내보낸다
+func Export(ctx context.Context, filter QueryFilter, format ExportFormat, w io.Writer, opts ...ExportOption) error {
+	store := defaultTracer.Load().Store()
+	if store == nil {
+		return ErrNotRunning
+	}
+	return store.Export(ctx, filter, format, w, opts...)
+}
+
+// Export - 조건에 맞는 Step을 오래된 순으로 w에 기록
+// 모든 형식에서 컬럼 이름은 테이블 컬럼 이름(trace_id, created_at 등)을 사용하며, 암호화한 컬럼은 복호화해서 기록한다
+// filter.Limit이 0이면 조건에 맞는 Step을 모두 기록하며, 결과를 메모리에 모두 올리지 않고 나누어 읽는다
+func (s *Store) Export(ctx context.Context, filter QueryFilter, format ExportFormat, w io.Writer, opts ...ExportOption) error {
+	options := ExportOptions{ExcelSafe: true, MaxCellBytes: maxCSVCellBytes}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	columns, err := exportColumns(s.db)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	var enc stepEncoder
+	switch format {
+	case ExportCSV:
+		if options.BOM {
+			bw.WriteString(utf8BOM)
+		}
+		enc = newCSVEncoder(bw, columns, options)
+	case ExportNDJSON:
+		enc = &ndjsonEncoder{w: bw, columns: columns}
+	case ExportParquet:
+		enc = newParquetEncoder(bw, columns)
+	default:
+		exportFormatsMu.RLock()
+		newWriter := exportFormats[format]
+		exportFormatsMu.RUnlock()
+		if newWriter == nil {
+			return fmt.Errorf("trace: unknown export format %q (use csv, ndjson, parquet or a format added with RegisterExportFormat)", format)
+		}
+		if enc, err = newRowEncoder(bw, columns, options, newWriter); err != nil {
+			return err
+		}
+	}
+
+	if err := s.forEachPage(ctx, filter, exportBatchSize, enc.encode); err != nil {
+		return err
+	}
+	if err := enc.close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []exportColumn
+	opts    ExportOptions
+	record  []string
+	header  bool
+}
+
+func newCSVEncoder(w io.Writer, columns []exportColumn, opts ExportOptions) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w), columns: columns, opts: opts, record: make([]string, len(columns))}
+}
+
+func (e *csvEncoder) encode(steps []Step) error {
+	if !e.header {
+		// Step이 없어도 컬럼 이름은 기록
+		for i, c := range e.columns {
+			e.record[i] = c.name
+		}
+		if err := e.w.Write(e.record); err != nil {
+			return err
+		}
+		e.header = true
+	}
+	for i := range steps {
+		for j, c := range e.columns {
+			v := c.value(&steps[i])
+			switch c.kind {
+			case reflect.String:
+				e.record[j] = csvCell(v.String(), e.opts)
+			case reflect.Bool:
+				e.record[j] = strconv.FormatBool(v.Bool())
+			default:
+				e.record[j] = strconv.FormatInt(v.Int(), 10)
+			}
+		}
+		if err := e.w.Write(e.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// csvCell - ExcelSafe면 스프레드시트에서 수식으로 실행되지 않도록 =, +, -, @, 탭, CR로 시작하는 값 앞에 '를 붙이고 길이 제한
+// 경로, User-Agent, 헤더 등 요청에서 온 값이 그대로 기록되므로 CSV 인젝션을 막는다
+func csvCell(s string, opts ExportOptions) string {
+	if opts.ExcelSafe && s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		s = "'" + s
+	}
+	return truncateCell(s, opts.MaxCellBytes)
+}
+
+// truncateCell - maxBytes를 넘는 값을 UTF-8 문자 경계에서 잘라 csvTruncatedSuffix를 붙임
+func truncateCell(s string, maxBytes int) string {
+	if len(s) > maxBytes {
+		s = truncateUTF8(s, maxBytes-len(csvTruncatedSuffix)) + csvTruncatedSuffix
+	}
+	return s
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonEncoder struct {
+	w       io.Writer
+	columns []exportColumn
+	line    []byte
+}
+
+func (e *ndjsonEncoder) encode(steps []Step) error {
+	for i := range steps {
+		// 컬럼 순서를 유지하도록 직접 구성
+		e.line = append(e.line[:0], '{')
+		for j, c := range e.columns {
+			if j > 0 {
+				e.line = append(e.line, ',')
+			}
+			// 컬럼 이름은 영문자, 숫자, 밑줄만 사용하므로 이스케이프하지 않음
+			e.line = append(append(append(e.line, '"'), c.name...), '"', ':')
+			v := c.value(&steps[i])
+			switch c.kind {
+			case reflect.String:
+				b, err := json.Marshal(v.String())
+				if err != nil {
+					return err
+				}
+				e.line = append(e.line, b...)
+			case reflect.Bool:
+				e.line = strconv.AppendBool(e.line, v.Bool())
+			default:
+				e.line = strconv.AppendInt(e.line, v.Int(), 10)
+			}
+		}
+		e.line = append(e.line, '}', '\n')
+		if _, err := e.w.Write(e.line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ndjsonEncoder) close() error { return nil }
+
+// rowEncoder - 등록한 형식의 ExportWriter에 Step을 컬럼 값으로 전달
+type rowEncoder struct {
+	w       ExportWriter
+	columns []exportColumn
+	opts    ExportOptions
+	values  []any
+}
+
+func newRowEncoder(w io.Writer, columns []exportColumn, opts ExportOptions, newWriter func(io.Writer, []string, ExportOptions) (ExportWriter, error)) (*rowEncoder, error) {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	writer, err := newWriter(w, names, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &rowEncoder{w: writer, columns: columns, opts: opts, values: make([]any, len(columns))}, nil
+}
+
+func (e *rowEncoder) encode(steps []Step) error {
+	for i := range steps {
+		for j, c := range e.columns {
+			v := c.value(&steps[i])
+			switch c.kind {
+			case reflect.String:
+				e.values[j] = truncateCell(v.String(), e.opts.MaxCellBytes)
+			case reflect.Bool:
+				e.values[j] = v.Bool()
+			default:
+				e.values[j] = v.Int()
+			}
+		}
+		if err := e.w.WriteRow(e.values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *rowEncoder) close() error { return e.w.Close() }
@@ -0,0 +1,123 @@
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestExportCSVNeutralizesFormulas(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	hostile := []string{
+		"=HYPERLINK(\"http://evil\",\"x\")",
+		"+1+1",
+		"-2+3",
+		"@SUM(A1)",
+		"line1\nline2, \"quoted\"",
+		strings.Repeat("가", 20000),
+	}
+	now := time.Now().Unix()
+	for i, value := range hostile {
+		step := trace.Step{TraceID: "t", Path: "/x", Method: "GET", StatusCode: 200, UserAgent: value, CreatedAt: now, Seq: int64(i)}
+		if err := db.Create(&step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := trace.NewStore(db, trace.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Export(context.Background(), trace.QueryFilter{}, trace.ExportCSV, &buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("exported CSV does not parse: %v", err)
+	}
+	column := -1
+	for i, name := range records[0] {
+		if name == "user_agent" {
+			column = i
+		}
+	}
+	if column < 0 || len(records) != len(hostile)+1 {
+		t.Fatalf("got %d records with header %v", len(records), records[0])
+	}
+
+	for i, value := range hostile {
+		got := records[i+1][column]
+		switch {
+		case strings.ContainsRune("=+-@", rune(value[0])):
+			if got != "'"+value {
+				t.Errorf("cell %q exported as %q, want leading apostrophe", value, got)
+			}
+		case len(value) > 32767:
+			if len(got) > 32767 || !strings.HasSuffix(got, "...[truncated]") {
+				t.Errorf("long cell exported with %d bytes, want at most 32767 ending in ...[truncated]", len(got))
+			}
+		default:
+			if got != value {
+				t.Errorf("cell exported as %q, want %q", got, value)
+			}
+		}
+	}
+}
+
+func TestExportCSVOptions(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	values := []string{"=1+2", "\tcmd|' /C calc'!A0", "plain \"quoted\"\nnext", strings.Repeat("b", 100)}
+	now := time.Now().Unix()
+	for i, value := range values {
+		step := trace.Step{TraceID: "t", Path: "/x", UserAgent: value, CreatedAt: now, Seq: int64(i)}
+		if err := db.Create(&step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := trace.NewStore(db, trace.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	export := func(opts ...trace.ExportOption) []string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := store.Export(context.Background(), trace.QueryFilter{}, trace.ExportCSV, &buf, opts...); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("exported CSV does not parse: %v", err)
+		}
+		column := slices.Index(records[0], "user_agent")
+		cells := make([]string, 0, len(records)-1)
+		for _, record := range records[1:] {
+			cells = append(cells, record[column])
+		}
+		return cells
+	}
+
+	safe := export(trace.WithMaxCellBytes(40))
+	want := []string{"'=1+2", "'\tcmd|' /C calc'!A0", "plain \"quoted\"\nnext", strings.Repeat("b", 26) + "...[truncated]"}
+	if !slices.Equal(safe, want) {
+		t.Errorf("ExcelSafe cells = %q, want %q", safe, want)
+	}
+	if raw := export(trace.WithExcelSafe(false)); !slices.Equal(raw, values) {
+		t.Errorf("raw cells = %q, want %q", raw, values)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Export(context.Background(), trace.QueryFilter{}, trace.ExportCSV, &buf, trace.WithBOM()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "\ufefftrace_id,") {
+		t.Fatalf("CSV with BOM starts with %q", buf.String()[:12])
+	}
+}
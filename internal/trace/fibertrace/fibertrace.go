@@ -0,0 +1,86 @@
+// fibertrace 패키지 - Fiber(fasthttp)용 trace 미들웨어
+package fibertrace
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"trace/internal/trace"
+)
+
+// Middleware - Fiber용 기본 미들웨어
+func Middleware() fiber.Handler {
+	return MiddlewareWithConfig()
+}
+
+// MiddlewareWithConfig - Gin 미들웨어와 같은 옵션, 같은 파이프라인을 사용하는 Fiber 미들웨어
+// 추출 함수가 쓰는 *http.Request는 요청마다 헤더만 복사해 만들고, 응답은 fasthttp가 그대로 보낸다
+// Step.Path에는 Fiber 라우트 패턴(/users/:id)이 기록되고, 핸들러가 반환한 에러는 응답 상태 코드가 Step에 남도록
+// 이 미들웨어 안에서 앱의 ErrorHandler로 처리한다
+// 핸들러는 c.Body()로 본문을 읽으므로 WithBodyCapture는 응답 본문만 저장한다
+func MiddlewareWithConfig(options ...trace.MiddlewareOption) fiber.Handler {
+	middleware := trace.HTTPMiddlewareWithConfig(options...)
+
+	return func(c *fiber.Ctx) error {
+		r, err := newRequest(c)
+		if err != nil {
+			return err
+		}
+
+		var handlerErr error
+		middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// 미들웨어가 기록한 응답 헤더(X-Trace-ID 등)
+			for key, values := range w.Header() {
+				for _, value := range values {
+					c.Response().Header.Add(key, value)
+				}
+			}
+			// 하위 span과 GORM 플러그인 등이 c.UserContext()로 요청 컨텍스트를 사용할 수 있도록 전달
+			c.SetUserContext(r.Context())
+			if err := c.Next(); err != nil {
+				handlerErr = c.App().Config().ErrorHandler(c, err)
+			}
+
+			trace.SetHTTPRoute(r, c.Route().Path)
+			w.WriteHeader(c.Response().StatusCode())
+			if !c.Response().IsBodyStream() {
+				// 응답 크기와 본문 저장을 위해 trace 미들웨어의 writer에만 기록 (전송은 fasthttp가 담당)
+				_, _ = w.Write(c.Response().Body())
+			}
+		})).ServeHTTP(&discardWriter{header: http.Header{}}, r)
+		return handlerErr
+	}
+}
+
+// newRequest - 추출 함수와 Step에 사용할 *http.Request 생성
+// fasthttp는 요청이 끝나면 버퍼를 재사용하므로 비동기로 저장되는 Step에 남는 값은 모두 복사한다
+func newRequest(c *fiber.Ctx) (*http.Request, error) {
+	req := c.Request()
+	r, err := http.NewRequestWithContext(c.UserContext(), string(req.Header.Method()), string(req.RequestURI()), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	r.Host = string(req.Host())
+	r.RemoteAddr = c.Context().RemoteAddr().String()
+	r.ContentLength = int64(len(req.Body()))
+	req.Header.VisitAll(func(key, value []byte) {
+		r.Header.Add(string(key), string(value))
+	})
+	return r, nil
+}
+
+// discardWriter trace 미들웨어에 전달하는 응답 writer (상태 코드와 본문은 fasthttp 응답이 원본)
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardWriter) WriteHeader(int) {}
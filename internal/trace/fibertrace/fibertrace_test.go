@@ -0,0 +1,75 @@
+package fibertrace_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+
+	"trace/internal/trace"
+	"trace/internal/trace/fibertrace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestMiddlewareRecordsRouteTemplateStatusAndSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(fibertrace.MiddlewareWithConfig(trace.WithTracer(tracer)))
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "missing" {
+			return fiber.NewError(http.StatusNotFound, "no such user")
+		}
+		time.Sleep(20 * time.Millisecond)
+		span := trace.StartSpanFromContext(c.UserContext(), "load user")
+		span.End()
+		return c.SendString("hello")
+	})
+
+	for _, id := range []string{"1", "missing"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/users/"+id+"?user_id=user-1&access_token=token", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.Header.Get("X-Trace-ID") == "" {
+			t.Fatalf("/users/%s: no trace ID header", id)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := tracer.Store().Query(context.Background(), trace.QueryFilter{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ok, missing, spans int
+	for _, step := range result.Steps {
+		switch {
+		case step.SpanName == "load user":
+			spans++
+		case step.Path != "/users/:id":
+			t.Errorf("Path = %q, want the route template", step.Path)
+		case step.StatusCode == 200 && step.ResponseBytes == 5 && step.LatencyMs >= 20:
+			ok++
+		case step.StatusCode == 404:
+			missing++
+		default:
+			t.Errorf("unexpected step %+v", step)
+		}
+	}
+	if ok != 1 || missing != 1 || spans != 1 {
+		t.Fatalf("steps = %+v, want a 200 step with its span and a 404 step", result.Steps)
+	}
+}
@@ -0,0 +1,51 @@
+package trace
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Step.Extra 최대 크기 (넘으면 기록하지 않음)
+const maxExtraBytes = 4096
+
+// WithFieldsExtractor 요청마다 Step.Extra(JSON)에 기록할 필드 추출 함수 설정
+// 핸들러 실행 후 호출되므로 핸들러가 c.Set으로 남긴 값(주문 ID, 기능 플래그 등)도 사용할 수 있다
+func WithFieldsExtractor(extractor func(c *gin.Context) map[string]any) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.FieldsExtractor = extractor
+	}
+}
+
+// extraFields - SetMetadata로 추가한 값과 추출한 필드를 JSON으로 인코딩 (필드가 없거나 인코딩할 수 없으면 빈 값)
+// 같은 키는 추출 함수의 값을 사용한다
+func extraFields(c *gin.Context, extractor func(c *gin.Context) map[string]any) string {
+	fields := map[string]any{}
+	if metadata, ok := c.Get(MetadataKey); ok {
+		if m, ok := metadata.(map[string]any); ok {
+			for key, value := range m {
+				fields[key] = value
+			}
+		}
+	}
+	if extractor != nil {
+		for key, value := range extractor(c) {
+			fields[key] = value
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		log.Printf("failed to encode trace fields: %v", err)
+		return ""
+	}
+	if len(encoded) > maxExtraBytes {
+		log.Printf("trace fields too large: %d bytes (max %d), path=%s", len(encoded), maxExtraBytes, c.Request.URL.Path)
+		return ""
+	}
+	return string(encoded)
+}
@@ -0,0 +1,108 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	ossignal "os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 기본 Config.ExitFlushTimeout
+const defaultExitFlushTimeout = 5 * time.Second
+
+// ExitFlushTimeout이 지난 뒤 스냅샷 기록(ShutdownSnapshotPath)과 Sink 종료를 기다리는 시간
+const exitFlushGrace = time.Second
+
+// 시그널을 다시 보낸 뒤 프로세스가 끝나지 않으면 직접 종료하기까지 기다리는 시간
+const reraiseWait = time.Second
+
+// InstallSignalHandler - 종료 시그널을 받으면 기본 Tracer의 남은 Step을 저장한 뒤 같은 시그널로 다시 종료
+// signals가 없으면 SIGINT, SIGTERM을 처리하며, 저장은 ExitFlushTimeout(기본 5초)을 넘지 않는다
+// 저장 후 시그널 처리를 기본 동작으로 되돌리고 시그널을 다시 보내므로 종료 코드는 처리하지 않았을 때와 같다
+// 애플리케이션이 직접 시그널을 받아 Stop을 호출한다면 사용하지 않는다 (다시 보낸 시그널로 정상 종료 처리가 중단됨)
+// 반환한 함수를 호출하면 시그널 처리를 해제한다
+func InstallSignalHandler(signals ...os.Signal) (uninstall func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	ossignal.Notify(ch, signals...)
+	go func() {
+		select {
+		case sig := <-ch:
+			if err := flushOnExit(); err != nil {
+				log.Printf("trace: flush on %s: %v", sig, err)
+			}
+			ossignal.Reset(signals...)
+			reraise(sig)
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ossignal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// FlushOnPanic - panic이 나면 기본 Tracer의 남은 Step을 저장한 뒤 같은 값으로 다시 panic 하는 함수 반환
+// 저장은 ExitFlushTimeout(기본 5초)을 넘지 않으며, panic이 없으면 아무것도 하지 않는다
+// recover는 defer로 실행한 함수에서만 동작하므로 반환한 함수를 그대로 defer 한다
+// 다른 고루틴의 panic에는 main의 defer가 실행되지 않으므로 직접 시작한 고루틴에서도 defer 한다
+//
+//	func main() {
+//		defer trace.FlushOnPanic()()
+//		...
+//	}
+func FlushOnPanic() func() {
+	return func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if err := flushOnExit(); err != nil {
+			log.Printf("trace: flush on panic: %v", err)
+		}
+		panic(r)
+	}
+}
+
+// flushOnExit - 기본 Tracer를 Stop하여 남은 Step 저장 (Stop이 이미 호출되었으면 그 결과를 기다림)
+// ExitFlushTimeout이 지나면 Stop이 버퍼에 남은 Step을 스냅샷으로 기록하고 Sink를 닫을 시간(exitFlushGrace)만 더 기다린다
+func flushOnExit() error {
+	t := defaultTracer.Load()
+	if t == nil {
+		return nil
+	}
+	timeout := t.cfg.ExitFlushTimeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() { result <- t.Stop(ctx) }()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout + exitFlushGrace):
+		return fmt.Errorf("trace: stop did not return within %s", timeout+exitFlushGrace)
+	}
+}
+
+// reraise - 기본 동작으로 되돌린 시그널을 다시 보냄 (프로세스가 끝나지 않으면 128+시그널 번호로 종료)
+func reraise(sig os.Signal) {
+	code := 1
+	if num, ok := sig.(syscall.Signal); ok {
+		code = 128 + int(num)
+	}
+	if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+		time.Sleep(reraiseWait)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,142 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"trace/internal/trace"
+)
+
+// 하위 프로세스로 실행할 때 전달하는 종료 방식과 SQLite 파일 경로 (runCrashChild)
+var (
+	crashMode = flag.String("trace.crash", "", "run as a crashing child process: panic, signal, stopped-panic or blocked-panic")
+	crashDB   = flag.String("trace.crash-db", "", "SQLite file the crashing child process writes to")
+)
+
+// crashSteps - 하위 프로세스가 종료 전에 버퍼에 넣는 Step 수 (Checkpoint 4개와 Finish)
+const crashSteps = 5
+
+// hangingSink - Write가 끝나지 않는 Sink (저장 제한 시간 확인용)
+type hangingSink struct{}
+
+func (hangingSink) Write(ctx context.Context, steps []trace.Step) error { select {} }
+func (hangingSink) Close() error                                        { return nil }
+
+func openCrashDB(t testing.TB, path string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// runCrashChild - FlushInterval이 길어 버퍼에만 있는 Step을 남긴 채 비정상 종료하는 하위 프로세스 본문
+func runCrashChild(t *testing.T) {
+	cfg := trace.Config{DB: openCrashDB(t, *crashDB), FlushInterval: time.Hour, ExitFlushTimeout: 2 * time.Second}
+	if *crashMode == "blocked-panic" {
+		cfg = trace.Config{Sink: hangingSink{}, BatchSize: 1, ExitFlushTimeout: 200 * time.Millisecond}
+	}
+	if err := trace.Start(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if *crashMode == "signal" {
+		trace.InstallSignalHandler(syscall.SIGTERM)
+	}
+
+	op := trace.StartOperation(context.Background(), "nightly-export")
+	for i := range crashSteps - 1 {
+		op.Checkpoint(fmt.Sprintf("batch-%d", i), float64(i)/crashSteps)
+	}
+	op.Finish(nil)
+
+	switch *crashMode {
+	case "signal":
+		p, _ := os.FindProcess(os.Getpid())
+		p.Signal(syscall.SIGTERM)
+		time.Sleep(10 * time.Second)
+		t.Fatal("process survived SIGTERM")
+	case "stopped-panic":
+		trace.Stop(context.Background())
+	}
+	// HTTP 요청이 아닌 작업 고루틴의 panic
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer trace.FlushOnPanic()()
+		panic("worker crashed")
+	}()
+	<-done
+	t.Fatal("process survived the panic")
+}
+
+func TestFlushOnFatalExit(t *testing.T) {
+	if *crashMode != "" {
+		runCrashChild(t)
+		return
+	}
+	tests := []struct {
+		mode       string
+		wantOutput string
+		wantSteps  int
+	}{
+		{"panic", "panic: worker crashed", crashSteps},
+		{"stopped-panic", "panic: worker crashed", crashSteps},
+		{"signal", "", crashSteps},
+		{"blocked-panic", "panic: worker crashed", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			if tt.mode == "signal" && runtime.GOOS == "windows" {
+				t.Skip("SIGTERM cannot be sent on windows")
+			}
+			path := filepath.Join(t.TempDir(), "trace.db")
+			cmd := exec.Command(os.Args[0], "-test.run=^TestFlushOnFatalExit$", "-trace.crash="+tt.mode, "-trace.crash-db="+path)
+			start := time.Now()
+			out, err := cmd.CombinedOutput()
+			elapsed := time.Since(start)
+
+			var exit *exec.ExitError
+			if !errors.As(err, &exit) {
+				t.Fatalf("child exited with %v, want a crash:\n%s", err, out)
+			}
+			if !strings.Contains(string(out), tt.wantOutput) {
+				t.Fatalf("child output does not contain %q:\n%s", tt.wantOutput, out)
+			}
+			if tt.mode == "signal" {
+				if status, ok := exit.Sys().(syscall.WaitStatus); ok && (!status.Signaled() || status.Signal() != syscall.SIGTERM) {
+					t.Fatalf("child exit status = %v, want killed by SIGTERM after the flush", status)
+				}
+			}
+			// 저장이 끝나지 않아도 ExitFlushTimeout과 종료 대기 시간 안에 종료
+			if elapsed > 5*time.Second {
+				t.Fatalf("child took %s to exit", elapsed)
+			}
+			if tt.wantSteps < 0 {
+				return
+			}
+
+			var count int64
+			if err := openCrashDB(t, path).Model(&trace.Step{}).Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if count != int64(tt.wantSteps) {
+				t.Fatalf("stored %d steps before exit, want %d", count, tt.wantSteps)
+			}
+		})
+	}
+}
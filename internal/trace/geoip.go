@@ -0,0 +1,108 @@
+package trace
+
+import (
+	"log"
+	"net"
+	"net/netip"
+)
+
+// GeoLocation 클라이언트 IP의 지역 정보
+type GeoLocation struct {
+	Country string // 국가 ISO 코드 (예: "KR")
+	Region  string // 지역 (시/도, 주 등)
+	City    string // 도시
+}
+
+// GeoResolver IP를 지역 정보로 변환 (Config.GeoResolver)
+// 저장 고루틴에서 배치마다 호출되므로 요청 처리 시간에는 영향이 없지만, 느리면 저장이 그만큼 늦어진다
+type GeoResolver interface {
+	Lookup(ip netip.Addr) (GeoLocation, error)
+}
+
+// GeoResolverFunc 함수를 GeoResolver로 사용
+type GeoResolverFunc func(ip netip.Addr) (GeoLocation, error)
+
+func (f GeoResolverFunc) Lookup(ip netip.Addr) (GeoLocation, error) {
+	return f(ip)
+}
+
+// MaxMindReader MaxMind DB 조회 인터페이스 (github.com/oschwald/maxminddb-golang의 *maxminddb.Reader)
+type MaxMindReader interface {
+	Lookup(ip net.IP, result any) error
+}
+
+// maxMindCity - GeoIP2/GeoLite2 City 데이터베이스 레코드 중 사용하는 필드
+type maxMindCity struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// MaxMindResolver - GeoIP2/GeoLite2 City 데이터베이스로 조회하는 GeoResolver (지역과 도시는 영문 이름)
+// 데이터베이스 파일을 열고 닫는 것은 호출자가 관리한다
+func MaxMindResolver(reader MaxMindReader) GeoResolver {
+	return GeoResolverFunc(func(ip netip.Addr) (GeoLocation, error) {
+		var record maxMindCity
+		if err := reader.Lookup(net.IP(ip.AsSlice()), &record); err != nil {
+			return GeoLocation{}, err
+		}
+		loc := GeoLocation{
+			Country: record.Country.ISOCode,
+			City:    record.City.Names["en"],
+		}
+		if len(record.Subdivisions) > 0 {
+			loc.Region = record.Subdivisions[0].Names["en"]
+		}
+		return loc, nil
+	})
+}
+
+// enrichGeo - 배치의 Step에 지역 정보 기록 (익명화 전 IP 사용, 배치 안에서 같은 IP는 한 번만 조회)
+// 조회에 실패한 Step은 지역 정보 없이 저장한다
+func (t *Tracer) enrichGeo(logs []Step) {
+	if t.cfg.GeoResolver == nil {
+		return
+	}
+	resolved := make(map[string]GeoLocation)
+	failed := 0
+	for i := range logs {
+		step := &logs[i]
+		ip := step.geoIP
+		step.geoIP = ""
+		if ip == "" || step.Country != "" || step.Region != "" || step.City != "" {
+			continue
+		}
+		loc, ok := resolved[ip]
+		if !ok {
+			var err error
+			if loc, err = t.lookupGeo(ip); err != nil {
+				failed++
+			}
+			resolved[ip] = loc
+		}
+		step.Country, step.Region, step.City = loc.Country, loc.Region, loc.City
+	}
+	if failed > 0 {
+		log.Printf("trace: failed to resolve location of %d client IPs", failed)
+	}
+}
+
+func (t *Tracer) lookupGeo(ip string) (loc GeoLocation, err error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return GeoLocation{}, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in trace geo resolver: %v", r)
+			loc = GeoLocation{}
+		}
+	}()
+	return t.cfg.GeoResolver.Lookup(addr.Unmap())
+}
@@ -0,0 +1,253 @@
+package trace
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SQL 문 최대 기록 길이
+const maxSQLLen = 1024
+
+const (
+	gormSpanKey  = "trace:span"
+	gormStartKey = "trace:start"
+)
+
+// 요청마다 기록하는 느린 쿼리 기본 개수 (WithSlowQueries)
+const defaultSlowQueries = 3
+
+// GormPlugin SQL 쿼리를 현재 요청의 하위 span으로 기록하는 GORM 플러그인
+// 쿼리 컨텍스트가 trace 미들웨어를 거친 요청 컨텍스트일 때만 기록한다 (db.WithContext(c.Request.Context()))
+type GormPlugin struct {
+	// 0보다 크면 쿼리마다 span을 만들지 않고 요청에서 가장 느린 쿼리 N개만 요청 Step의 Extra에 기록
+	SlowQueries int
+}
+
+// GormOption 함수형 옵션 타입
+type GormOption func(*GormPlugin)
+
+// WithSlowQueries 쿼리마다 span을 만드는 대신 요청별로 가장 느린 쿼리 k개(0 이하면 3개)만
+// 요청 Step의 Extra "slow_queries"에 기록 (SQL 문, 걸린 시간, 영향받은 행 수)
+func WithSlowQueries(k int) GormOption {
+	return func(p *GormPlugin) {
+		if k <= 0 {
+			k = defaultSlowQueries
+		}
+		p.SlowQueries = k
+	}
+}
+
+// NewGormPlugin - GORM 플러그인 생성
+//
+//	db.Use(trace.NewGormPlugin())
+func NewGormPlugin(opts ...GormOption) *GormPlugin {
+	p := &GormPlugin{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Name - gorm.Plugin 구현
+func (p *GormPlugin) Name() string {
+	return "trace"
+}
+
+// Initialize - 생성/조회/수정/삭제/Row/Raw 작업 앞뒤에 콜백 등록
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	start, end := startGormSpan, endGormSpan
+	if p.SlowQueries > 0 {
+		start, end = startGormTimer, recordSlowQuery(p.SlowQueries)
+	}
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("trace:before_create", start("create")),
+		cb.Create().After("gorm:create").Register("trace:after_create", end),
+		cb.Query().Before("gorm:query").Register("trace:before_query", start("query")),
+		cb.Query().After("gorm:query").Register("trace:after_query", end),
+		cb.Update().Before("gorm:update").Register("trace:before_update", start("update")),
+		cb.Update().After("gorm:update").Register("trace:after_update", end),
+		cb.Delete().Before("gorm:delete").Register("trace:before_delete", start("delete")),
+		cb.Delete().After("gorm:delete").Register("trace:after_delete", end),
+		cb.Row().Before("gorm:row").Register("trace:before_row", start("row")),
+		cb.Row().After("gorm:row").Register("trace:after_row", end),
+		cb.Raw().Before("gorm:raw").Register("trace:before_raw", start("raw")),
+		cb.Raw().After("gorm:raw").Register("trace:after_raw", end),
+	)
+}
+
+// startGormSpan - 요청 컨텍스트가 있으면 하위 span 시작 (trace 내부 저장 쿼리는 컨텍스트가 없어 기록되지 않음)
+func startGormSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+		span := StartSpanFromContext(db.Statement.Context, "SQL "+operation)
+		if span.rec == nil {
+			return
+		}
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+// endGormSpan - 테이블, SQL 문, 영향받은 행 수를 Extra에 기록하고 span 종료
+func endGormSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, _ := value.(*Span)
+	if span == nil {
+		return
+	}
+
+	if db.Statement.Table != "" {
+		span.step.SpanName += " " + db.Statement.Table
+	}
+	detail, _ := json.Marshal(map[string]any{
+		"table": db.Statement.Table,
+		"sql":   gormSQL(db),
+		"rows":  db.RowsAffected,
+	})
+	span.step.Extra = string(detail)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.SetError(db.Error)
+	}
+	span.End()
+}
+
+// gormSQL - 바인딩 값과 SQL 문 안의 리터럴을 제외한 SQL 문
+func gormSQL(db *gorm.DB) string {
+	return truncateUTF8(sanitizeSQL(db.Statement.SQL.String()), maxSQLLen)
+}
+
+// startGormTimer - 요청 컨텍스트가 있으면 쿼리 시작 시각 기록
+func startGormTimer(string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil || requestSpans(db.Statement.Context) == nil {
+			return
+		}
+		db.InstanceSet(gormStartKey, time.Now())
+	}
+}
+
+// recordSlowQuery - 걸린 시간이 요청의 느린 쿼리 k개 안에 들면 기록
+func recordSlowQuery(k int) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, _ := value.(time.Time)
+		rec := requestSpans(db.Statement.Context)
+		if rec == nil {
+			return
+		}
+		elapsed := time.Since(start)
+		rec.addSlowQuery(k, SlowQuery{
+			SQL:        gormSQL(db),
+			Table:      db.Statement.Table,
+			DurationMs: float64(elapsed.Microseconds()) / 1000,
+			Rows:       db.RowsAffected,
+			duration:   elapsed,
+		})
+	}
+}
+
+// SlowQuery 요청 Step의 Extra "slow_queries"에 기록되는 쿼리 하나
+type SlowQuery struct {
+	SQL        string  `json:"sql"` // 바인딩 값과 리터럴은 ?로 바뀐다
+	Table      string  `json:"table,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	Rows       int64   `json:"rows"`
+
+	duration time.Duration
+}
+
+// addSlowQuery - 요청의 느린 쿼리 k개 유지 (k개를 넘으면 가장 빠른 쿼리를 교체하므로 메모리는 k개로 고정)
+func (r *spanRecorder) addSlowQuery(k int, q SlowQuery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.slowQueries) < k {
+		r.slowQueries = append(r.slowQueries, q)
+		return
+	}
+	fastest := 0
+	for i := range r.slowQueries {
+		if r.slowQueries[i].duration < r.slowQueries[fastest].duration {
+			fastest = i
+		}
+	}
+	if q.duration > r.slowQueries[fastest].duration {
+		r.slowQueries[fastest] = q
+	}
+}
+
+// takeSlowQueries - 기록한 느린 쿼리를 느린 순으로 반환
+func (r *spanRecorder) takeSlowQueries() []SlowQuery {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queries := r.slowQueries
+	r.slowQueries = nil
+	slices.SortFunc(queries, func(a, b SlowQuery) int {
+		return cmp.Compare(b.duration, a.duration)
+	})
+	return queries
+}
+
+// sanitizeSQL - 문자열, 숫자 리터럴을 ?로 바꿈 (Raw, Where 문자열에 값을 직접 넣은 경우)
+// 식별자 안의 숫자(table1)와 큰따옴표, 백틱으로 감싼 식별자는 그대로 둔다
+func sanitizeSQL(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'':
+			// '' 이스케이프를 포함한 문자열 리터럴
+			for i++; i < len(sql); i++ {
+				if sql[i] == '\'' {
+					if i+1 < len(sql) && sql[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			b.WriteByte('?')
+		case c == '"' || c == '`':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				b.WriteString(sql[i:])
+				return b.String()
+			}
+			b.WriteString(sql[i : i+end+2])
+			i += end + 1
+		case isDigit(c) && (i == 0 || !isIdentChar(sql[i-1])):
+			for i+1 < len(sql) && (isDigit(sql[i+1]) || sql[i+1] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || c == '$'
+}
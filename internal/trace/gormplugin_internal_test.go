@@ -0,0 +1,17 @@
+package trace
+
+import "testing"
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM orders WHERE user_id = 'alice' AND amount > 1500": "SELECT * FROM orders WHERE user_id = ? AND amount > ?",
+		"SELECT * FROM t WHERE name = 'O''Brien' LIMIT 10":               "SELECT * FROM t WHERE name = ? LIMIT ?",
+		"SELECT * FROM table1 WHERE `col2` = 3.5":                        "SELECT * FROM table1 WHERE `col2` = ?",
+		`SELECT "it's" FROM t WHERE id = ?`:                              `SELECT "it's" FROM t WHERE id = ?`,
+	}
+	for in, want := range tests {
+		if got := sanitizeSQL(in); got != want {
+			t.Errorf("sanitizeSQL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
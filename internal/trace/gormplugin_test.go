@@ -0,0 +1,93 @@
+package trace_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+type gormOrder struct {
+	ID     uint
+	UserID string
+	Amount int
+}
+
+// appDB - 쿼리 전에 db.Set("sleep", d)만큼 기다리는 콜백을 등록한 애플리케이션 DB
+func appDB(t *testing.T, opts ...trace.GormOption) *gorm.DB {
+	t.Helper()
+	db := tracetest.NewTempDB(t)
+	if err := db.AutoMigrate(&gormOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(trace.NewGormPlugin(opts...)); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Callback().Query().After("trace:before_query").Before("gorm:query").Register("test:sleep", func(db *gorm.DB) {
+		if d, ok := db.Get("sleep"); ok {
+			time.Sleep(d.(time.Duration))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestGormPluginRecordsSlowestQueries(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := appDB(t, trace.WithSlowQueries(2))
+
+	r := gin.New()
+	r.Use(tracer.Middleware())
+	r.GET("/orders", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var orders []gormOrder
+		db.WithContext(ctx).Set("sleep", 30*time.Millisecond).Where("user_id = 'alice'").Find(&orders)
+		db.WithContext(ctx).Set("sleep", 1*time.Millisecond).Where("amount > 100").Find(&orders)
+		db.WithContext(ctx).Set("sleep", 20*time.Millisecond).Where("amount = 4242").Find(&orders)
+		db.WithContext(ctx).Find(&orders)
+		c.Status(200)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders?user_id=user-1&access_token=token", nil))
+	stopTracer(t, tracer)
+
+	result, err := tracer.Store().Query(context.Background(), trace.QueryFilter{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Steps) != 1 {
+		t.Fatalf("got %d steps, want only the request step", len(result.Steps))
+	}
+	var extra struct {
+		SlowQueries []trace.SlowQuery `json:"slow_queries"`
+	}
+	if err := json.Unmarshal([]byte(result.Steps[0].Extra), &extra); err != nil {
+		t.Fatalf("extra %q: %v", result.Steps[0].Extra, err)
+	}
+	if len(extra.SlowQueries) != 2 {
+		t.Fatalf("slow_queries = %+v, want 2 entries", extra.SlowQueries)
+	}
+	if !strings.Contains(extra.SlowQueries[0].SQL, "user_id = ?") || !strings.Contains(extra.SlowQueries[1].SQL, "amount = ?") {
+		t.Errorf("slow_queries not ordered slowest first: %+v", extra.SlowQueries)
+	}
+	for _, q := range extra.SlowQueries {
+		if strings.Contains(q.SQL, "alice") || strings.Contains(q.SQL, "4242") {
+			t.Errorf("literal leaked into %q", q.SQL)
+		}
+		if q.Table != "gorm_orders" || q.DurationMs < 20 {
+			t.Errorf("unexpected entry %+v", q)
+		}
+	}
+}
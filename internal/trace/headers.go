@@ -0,0 +1,118 @@
+package trace
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 저장할 헤더 값 최대 길이
+const maxHeaderValueLen = 256
+
+// allowlist에 있어도 항상 해시로 저장하는 헤더
+var alwaysRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// WithHeaderCapture 요청/응답 헤더 저장 설정
+// allowlist의 헤더는 값 그대로, redact의 헤더는 HMAC 해시(TraceIDSecret 사용)로 Step.Headers에 기록된다
+// Authorization, Cookie 등 인증 헤더는 allowlist에 있어도 해시로 기록된다
+// 해시는 배포 내에서 같은 값이면 같으므로 원문 없이도 같은 토큰의 요청을 묶어 볼 수 있다
+func WithHeaderCapture(allowlist []string, redact []string) MiddlewareOption {
+	canonical := func(names []string) []string {
+		out := make([]string, 0, len(names))
+		for _, name := range names {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" && !slices.Contains(out, name) {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	allow, masked := canonical(allowlist), canonical(redact)
+
+	return func(config *MiddlewareConfig) {
+		config.CaptureHeaders = allow
+		config.RedactHeaders = masked
+	}
+}
+
+// capturedHeaders Step.Headers JSON 형식
+type capturedHeaders struct {
+	Request  map[string]string `json:"request,omitempty"`
+	Response map[string]string `json:"response,omitempty"`
+}
+
+// captureHeaders - 설정된 요청/응답 헤더를 JSON으로 인코딩 (설정이 없거나 해당 헤더가 없으면 빈 값)
+func captureHeaders(c *gin.Context, config *MiddlewareConfig, tracer *Tracer) string {
+	if len(config.CaptureHeaders) == 0 && len(config.RedactHeaders) == 0 && !config.captureAllHeaders {
+		return ""
+	}
+
+	secret := tracer.secret()
+	captured := capturedHeaders{
+		Request:  pickHeaders(c.Request.Header, config, secret),
+		Response: pickHeaders(c.Writer.Header(), config, secret),
+	}
+	if captured.Request == nil && captured.Response == nil {
+		return ""
+	}
+
+	encoded, err := json.Marshal(captured)
+	if err != nil {
+		log.Printf("failed to encode trace headers: %v", err)
+		return ""
+	}
+	return string(encoded)
+}
+
+func pickHeaders(header http.Header, config *MiddlewareConfig, secret []byte) map[string]string {
+	var picked map[string]string
+	add := func(name, value string) {
+		if picked == nil {
+			picked = make(map[string]string)
+		}
+		picked[name] = value
+	}
+
+	for _, name := range config.RedactHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			add(name, redactHeaderValue(strings.Join(values, ", "), secret))
+		}
+	}
+	names := config.CaptureHeaders
+	if config.captureAllHeaders {
+		names = slices.Sorted(maps.Keys(header))
+	}
+	for _, name := range names {
+		if _, redacted := picked[name]; redacted {
+			continue
+		}
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		value := strings.Join(values, ", ")
+		if slices.Contains(alwaysRedactedHeaders, name) {
+			add(name, redactHeaderValue(value, secret))
+			continue
+		}
+		if len(value) > maxHeaderValueLen {
+			value = value[:maxHeaderValueLen]
+		}
+		add(name, value)
+	}
+	return picked
+}
+
+// redactHeaderValue - 민감한 헤더 값을 HMAC 해시로 대체
+func redactHeaderValue(value string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,88 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 헬스 체크 기본값
+const (
+	defaultHealthMaxBufferUtilization = 0.9
+	healthCheckTimeout                = 2 * time.Second
+)
+
+// ErrNotRunning Tracer가 시작되지 않았거나 이미 중지된 경우
+var ErrNotRunning = errors.New("trace: not running")
+
+// Pinger 연결 상태를 확인할 수 있는 Sink (Healthy에서 사용, 구현하지 않은 Sink는 확인하지 않음)
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping - DB 연결 확인
+func (s *GormSink) Ping(ctx context.Context) error {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Healthy - 파이프라인이 정상인지 확인
+// 워커가 모두 실행 중인지, 버퍼 사용률이 HealthMaxBufferUtilization 이하인지, Sink가 Pinger면 Ping에 응답하는지 검사한다
+func (t *Tracer) Healthy(ctx context.Context) error {
+	if t == nil || !t.isRunning() {
+		return ErrNotRunning
+	}
+	if live, started := t.liveWorkers.Load(), t.startedWorkers.Load(); live < started {
+		return fmt.Errorf("trace: %d of %d workers stopped", started-live, started)
+	}
+
+	limit := t.cfg.HealthMaxBufferUtilization
+	if limit <= 0 {
+		limit = defaultHealthMaxBufferUtilization
+	}
+	if depth, capacity := t.bufferUsage(); capacity > 0 && float64(depth)/float64(capacity) > limit {
+		return fmt.Errorf("trace: buffer is %d/%d full (limit %.0f%%)", depth, capacity, limit*100)
+	}
+
+	if p, ok := t.cfg.Sink.(Pinger); ok {
+		if err := p.Ping(ctx); err != nil {
+			return fmt.Errorf("trace: sink ping failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// Healthy - 기본 Tracer가 정상인지 확인 (Start 전이면 ErrNotRunning)
+func Healthy(ctx context.Context) error {
+	return defaultTracer.Load().Healthy(ctx)
+}
+
+// HealthHandler - 기본 Tracer의 Healthy 결과를 반환하는 Gin 핸들러 (정상이면 200, 아니면 503)
+// Sink 장애에도 실패하므로 Kubernetes에서는 livenessProbe보다 readinessProbe에 사용한다
+func HealthHandler() gin.HandlerFunc {
+	return healthHandler(defaultTracer.Load)
+}
+
+// HealthHandler - 이 Tracer의 Healthy 결과를 반환하는 Gin 핸들러
+func (t *Tracer) HealthHandler() gin.HandlerFunc {
+	return healthHandler(func() *Tracer { return t })
+}
+
+func healthHandler(tracer func() *Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+		defer cancel()
+		if err := tracer().Healthy(ctx); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
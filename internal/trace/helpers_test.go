@@ -0,0 +1,38 @@
+package trace_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// serve - tracer의 미들웨어로 path 요청을 한 번 처리 (사용자 ID와 토큰은 쿼리 파라미터로 전달)
+func serve(t *testing.T, tracer *trace.Tracer, path string) {
+	t.Helper()
+	r := gin.New()
+	r.Use(tracer.Middleware())
+	route, _, _ := strings.Cut(path, "?")
+	r.GET(route, func(c *gin.Context) { c.Status(200) })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+}
+
+func stopTracer(t *testing.T, tracer *trace.Tracer) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+}
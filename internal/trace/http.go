@@ -0,0 +1,74 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// net/http 핸들러의 라우트 경로를 Step.Path로 전달하는 키
+const httpRouteKey = "trace_http_route"
+
+// httpRouteHolder next가 SetHTTPRoute로 알려 준 라우트 패턴
+type httpRouteHolder struct {
+	route string
+}
+
+type httpRouteHolderKey struct{}
+
+// SetHTTPRoute - net/http 미들웨어 뒤에서 라우팅하는 프레임워크(Echo 등)의 라우트 패턴을 Step.Path로 전달
+// HTTPMiddlewareWithConfig가 처리하는 요청(또는 그 하위 요청)이 아니면 아무것도 하지 않는다
+func SetHTTPRoute(r *http.Request, route string) {
+	if holder, ok := r.Context().Value(httpRouteHolderKey{}).(*httpRouteHolder); ok {
+		holder.route = route
+	}
+}
+
+// HTTPMiddleware - net/http용 기본 미들웨어 (chi, gorilla/mux, http.ServeMux 등)
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return HTTPMiddlewareWithConfig()(next)
+}
+
+// HTTPMiddlewareWithConfig - Gin 미들웨어와 같은 옵션을 사용하는 net/http 미들웨어
+// 추출 함수와 필터는 요청마다 만들어지는 gin.Context로 호출되므로 c.Query, c.GetHeader 등을 그대로 쓸 수 있다
+func HTTPMiddlewareWithConfig(options ...MiddlewareOption) func(http.Handler) http.Handler {
+	middleware := MiddlewareWithConfig(options...)
+
+	return func(next http.Handler) http.Handler {
+		// 라우트가 없는 엔진의 NoRoute 체인으로 trace 미들웨어 뒤에 next를 실행
+		engine := gin.New()
+		engine.Use(middleware)
+		engine.NoRoute(func(c *gin.Context) {
+			// NoRoute의 기본 404 대신 next가 정한 상태 코드를 사용
+			c.Writer.WriteHeader(http.StatusOK)
+			holder := &httpRouteHolder{}
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), httpRouteHolderKey{}, holder))
+			next.ServeHTTP(c.Writer, c.Request)
+			route := holder.route
+			if route == "" {
+				route = httpRoute(c.Request)
+			}
+			c.Set(httpRouteKey, route)
+			c.Params = httpParams(c.Request)
+		})
+		return engine
+	}
+}
+
+// httpRoute - Go 1.22+ ServeMux 패턴(예: "GET /users/{id}")의 경로 부분, 없으면 요청 경로
+func httpRoute(r *http.Request) string {
+	if i := strings.IndexByte(r.Pattern, '/'); i >= 0 {
+		return r.Pattern[i:]
+	}
+	return r.URL.Path
+}
+
+// routePath - Step.Path에 기록할 라우트 경로
+func routePath(c *gin.Context) string {
+	if path := c.FullPath(); path != "" {
+		return path
+	}
+	return c.GetString(httpRouteKey)
+}
@@ -0,0 +1,43 @@
+package trace_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestHTTPMiddlewareRecordsRouteTemplate(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /mux/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	// 미들웨어 뒤에서 라우팅하는 프레임워크 (Echo 등)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		trace.SetHTTPRoute(r.WithContext(context.WithValue(r.Context(), struct{}{}, 1)), "/framework/:id")
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := trace.HTTPMiddlewareWithConfig(trace.WithTracer(tracer))(mux)
+	for _, path := range []string{"/mux/1", "/framework/2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path+"?user_id=user-1&access_token=token", nil))
+	}
+	stopTracer(t, tracer)
+
+	result, err := tracer.Store().Query(context.Background(), trace.QueryFilter{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, step := range result.Steps {
+		got[step.Path] = step.StatusCode
+	}
+	if len(got) != 2 || got["/mux/{id}"] != 200 || got["/framework/:id"] != 404 {
+		t.Fatalf("paths = %v, want /mux/{id} and /framework/:id", got)
+	}
+}
@@ -0,0 +1,113 @@
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UUIDGenerator - 요청마다 새 UUIDv4를 만드는 TraceIDGenerator
+// 기본 생성기는 같은 사용자와 토큰이면 같은 ID가 되므로 요청 하나하나를 구분해야 할 때 사용한다
+func UUIDGenerator() func(userID, token string) string {
+	return func(string, string) string {
+		var b [16]byte
+		rand.Read(b[:])
+		b[6] = b[6]&0x0f | 0x40 // 버전 4
+		b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+		return formatUUID(b)
+	}
+}
+
+// UUIDv7Generator - 생성 시각(밀리초)으로 정렬되는 UUIDv7을 만드는 TraceIDGenerator
+// 앞 48비트가 Unix 밀리초이므로 ID 순서가 대략 요청 순서와 같고 DB 인덱스에도 순서대로 쌓인다
+func UUIDv7Generator() func(userID, token string) string {
+	return func(string, string) string {
+		var b [16]byte
+		rand.Read(b[6:])
+		putMillis(b[:], time.Now())
+		b[6] = b[6]&0x0f | 0x70 // 버전 7
+		b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+		return formatUUID(b)
+	}
+}
+
+func formatUUID(b [16]byte) string {
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// putMillis - b의 앞 6바이트에 t의 Unix 밀리초 기록 (big endian)
+func putMillis(b []byte, t time.Time) {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(b[:6], ms[2:])
+}
+
+// ULID Crockford base32 문자
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator - 26자리 ULID를 만드는 TraceIDGenerator (생성 시각으로 정렬, 대소문자 구분 없음)
+// 같은 밀리초에 만든 ID는 랜덤 부분을 1씩 늘려 생성기 안에서 항상 증가하는 순서를 보장한다
+func ULIDGenerator() func(userID, token string) string {
+	var (
+		mu   sync.Mutex
+		last [16]byte
+	)
+	return func(string, string) string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var b [16]byte
+		putMillis(b[:], time.Now())
+		if string(b[:6]) <= string(last[:6]) {
+			// 같은 밀리초(또는 시계가 뒤로 간 경우)면 이전 ID의 랜덤 부분을 증가
+			b = last
+			for i := 15; i >= 6; i-- {
+				b[i]++
+				if b[i] != 0 {
+					break
+				}
+			}
+		} else {
+			rand.Read(b[6:])
+		}
+		last = b
+		return encodeULID(b)
+	}
+}
+
+// encodeULID - 128비트를 26자리 Crockford base32로 인코딩 (앞 2비트는 0으로 채운 130비트)
+func encodeULID(b [16]byte) string {
+	var out [26]byte
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	for i := 25; i >= 0; i-- {
+		out[i] = ulidAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// decodeULID - 26자리 ULID를 128비트로 변환 (ULID 형식이 아니면 false)
+func decodeULID(s string) ([16]byte, bool) {
+	var b [16]byte
+	if len(s) != 26 || s[0] > '7' {
+		return b, false
+	}
+	var hi, lo uint64
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(ulidAlphabet, s[i])
+		if v < 0 {
+			return b, false
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+	binary.BigEndian.PutUint64(b[:8], hi)
+	binary.BigEndian.PutUint64(b[8:], lo)
+	return b, true
+}
@@ -0,0 +1,313 @@
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Import 제한
+const (
+	importBatchSize = 500
+	// ImportResult.Errors에 남길 최대 에러 수
+	maxImportErrors = 10
+	// 한 줄 최대 길이 (넘는 줄은 건너뜀)
+	maxImportLineBytes = 1 << 20
+)
+
+// ImportFormat 가져올 접근 로그 형식
+type ImportFormat string
+
+const (
+	// ImportCombined Apache/nginx combined 로그 형식 (referer, user agent가 없는 common 형식도 허용)
+	//
+	//	127.0.0.1 - frank [10/Oct/2024:13:55:36 +0900] "GET /api/users?id=1 HTTP/1.1" 200 2326 "-" "curl/8.4.0"
+	//
+	// 줄 끝에 nginx $request_time(초)을 추가한 형식이면 지연 시간으로 사용한다
+	ImportCombined ImportFormat = "combined"
+	// ImportJSONLines 한 줄에 JSON 객체 하나 (WithAccessLog(AccessLogJSON) 출력과 nginx JSON 로그의 주요 키 지원)
+	ImportJSONLines ImportFormat = "json"
+)
+
+// ImportOptions 접근 로그 가져오기 설정
+type ImportOptions struct {
+	Format ImportFormat
+	// 이 시각 이후의 로그는 건너뜀 (미들웨어로 이미 수집한 구간과 겹치지 않도록, 0이면 모두 가져옴)
+	Until time.Time
+	// 가져온 Step에 기록할 서비스 이름과 배포 환경 (Config.ServiceName, Config.Environment와 같게 두면 함께 조회됨)
+	ServiceName string
+	Environment string
+}
+
+// ImportResult 가져오기 결과
+type ImportResult struct {
+	Lines    int `json:"lines"`    // 읽은 줄 수 (빈 줄 제외)
+	Imported int `json:"imported"` // 저장한 Step 수
+	Skipped  int `json:"skipped"`  // 형식이 맞지 않거나 Until 이후라 건너뛴 줄 수
+	// 형식 에러 앞부분 (최대 10개, "줄 번호: 에러")
+	Errors []string `json:"errors,omitempty"`
+}
+
+// importProvenance 가져온 Step의 Provenance (미들웨어가 수집한 Step과 구분)
+const importProvenance = "import"
+
+// Import - 기존 접근 로그를 읽어 Step 테이블에 과거 Step으로 저장 (도입 전 기간의 기준값 확보용)
+// 기본 Tracer와 같은 테이블에 저장하며, 암호화 키가 있으면 암호화해서 저장한다
+// 쿼리 문자열에는 토큰이 포함될 수 있으므로 경로만 저장하며, Trace ID가 없는 로그는 새 ID를 만든다
+// 같은 로그를 다시 가져오면 중복 저장되므로 구간이 겹치지 않게 Until을 지정하는 것이 좋다
+func Import(ctx context.Context, db *gorm.DB, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	return storeFor(db).Import(ctx, r, opts)
+}
+
+// Import - 기존 접근 로그를 읽어 이 Store의 테이블에 과거 Step으로 저장
+func (s *Store) Import(ctx context.Context, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	var parse func(line string) (Step, error)
+	switch opts.Format {
+	case ImportCombined:
+		parse = parseCombinedLine
+	case ImportJSONLines:
+		parse = parseJSONLine
+	default:
+		return ImportResult{}, fmt.Errorf("trace: unknown import format %q (use combined or json)", opts.Format)
+	}
+
+	sink := &GormSink{DB: s.db, Table: s.tables.base}
+	if s.tables.daily {
+		sink.partitions = &partitionWriter{tables: s.tables}
+	} else if err := s.db.WithContext(ctx).Table(s.tables.base).AutoMigrate(&Step{}); err != nil {
+		return ImportResult{}, err
+	}
+	newTraceID := UUIDGenerator()
+
+	var result ImportResult
+	batch := make([]Step, 0, importBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := sink.Write(ctx, batch); err != nil {
+			return err
+		}
+		result.Imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineBytes)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		result.Lines++
+		step, err := parse(line)
+		if err != nil {
+			result.Skipped++
+			if len(result.Errors) < maxImportErrors {
+				result.Errors = append(result.Errors, fmt.Sprintf("%d: %v", lineNo, err))
+			}
+			continue
+		}
+		if !opts.Until.IsZero() && step.CreatedAt >= opts.Until.Unix() {
+			result.Skipped++
+			continue
+		}
+
+		if step.TraceID == "" {
+			step.TraceID = newTraceID("", "")
+		}
+		step.ServiceName = opts.ServiceName
+		step.Environment = opts.Environment
+		step.Provenance = importProvenance
+		s.cipher.encryptStep(&step)
+		if batch = append(batch, step); len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = fmt.Errorf("line %d is longer than %d bytes", lineNo+1, maxImportLineBytes)
+		}
+		return result, errors.Join(flush(), err)
+	}
+	return result, flush()
+}
+
+// combinedLine - %h %l %u %t "%r" %>s %b ["%{Referer}i" "%{User-agent}i"] [$request_time]
+var combinedLine = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\S+)(?: "(?:[^"\\]|\\.)*" "((?:[^"\\]|\\.)*)")?(?: (\d+(?:\.\d+)?))?`)
+
+// combinedTimeLayout - %t 형식
+const combinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+func parseCombinedLine(line string) (Step, error) {
+	m := combinedLine.FindStringSubmatch(line)
+	if m == nil {
+		return Step{}, errors.New("not in combined log format")
+	}
+	at, err := time.Parse(combinedTimeLayout, m[3])
+	if err != nil {
+		return Step{}, fmt.Errorf("invalid time %q", m[3])
+	}
+	method, target, ok := parseRequestLine(m[4])
+	if !ok {
+		return Step{}, fmt.Errorf("invalid request line %q", m[4])
+	}
+	status, _ := strconv.Atoi(m[5])
+	step := Step{
+		Method:     method,
+		Path:       requestPath(target),
+		StatusCode: status,
+		IP:         m[1],
+		UserAgent:  unescapeLogValue(m[7]),
+		CreatedAt:  at.Unix(),
+	}
+	if m[2] != "-" {
+		step.UserID = m[2]
+	}
+	if m[6] != "-" {
+		step.ResponseBytes, _ = strconv.ParseInt(m[6], 10, 64)
+	}
+	if m[8] != "" {
+		seconds, _ := strconv.ParseFloat(m[8], 64)
+		step.LatencyMs = int64(seconds * 1000)
+	}
+	return step, nil
+}
+
+// parseRequestLine - "GET /path?x=1 HTTP/1.1"에서 메서드와 요청 대상 분리
+func parseRequestLine(request string) (method, target string, ok bool) {
+	fields := strings.Fields(request)
+	if len(fields) < 2 {
+		return "", "", false
+	}
+	return fields[0], fields[1], true
+}
+
+// requestPath - 요청 대상에서 쿼리 문자열을 제외한 경로 (절대 URL이면 경로만)
+func requestPath(target string) string {
+	target, _, _ = strings.Cut(target, "?")
+	if rest, ok := strings.CutPrefix(target, "http://"); ok {
+		target = rest
+	} else if rest, ok := strings.CutPrefix(target, "https://"); ok {
+		target = rest
+	} else {
+		return target
+	}
+	if i := strings.IndexByte(target, '/'); i >= 0 {
+		return target[i:]
+	}
+	return "/"
+}
+
+// unescapeLogValue - 접근 로그에서 이스케이프한 값 복원 ("-"는 빈 값)
+func unescapeLogValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(s)
+}
+
+// JSON 접근 로그에서 값을 찾을 키 (앞에 있는 키 우선)
+var (
+	jsonTimeKeys      = []string{"time", "timestamp", "time_iso8601", "@timestamp", "ts"}
+	jsonStatusKeys    = []string{"status", "status_code"}
+	jsonMethodKeys    = []string{"method", "request_method"}
+	jsonPathKeys      = []string{"path", "uri", "request_uri", "url"}
+	jsonIPKeys        = []string{"client_ip", "remote_addr", "ip"}
+	jsonUserAgentKeys = []string{"user_agent", "http_user_agent"}
+	jsonBytesKeys     = []string{"response_bytes", "body_bytes_sent", "bytes"}
+	jsonTraceIDKeys   = []string{"trace_id", "request_id"}
+	jsonUserIDKeys    = []string{"user_id", "remote_user"}
+	jsonErrorKeys     = []string{"error"}
+)
+
+func parseJSONLine(line string) (Step, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return Step{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	lookup := func(keys []string) string {
+		for _, key := range keys {
+			switch v := fields[key].(type) {
+			case string:
+				if v != "" && v != "-" {
+					return v
+				}
+			case json.Number:
+				return v.String()
+			}
+		}
+		return ""
+	}
+
+	at, err := parseLogTime(lookup(jsonTimeKeys))
+	if err != nil {
+		return Step{}, err
+	}
+	status, err := strconv.Atoi(lookup(jsonStatusKeys))
+	if err != nil {
+		return Step{}, errors.New("missing or invalid status")
+	}
+	method, path := lookup(jsonMethodKeys), lookup(jsonPathKeys)
+	if method == "" || path == "" {
+		// nginx의 $request ("GET /path HTTP/1.1")만 있는 경우
+		var ok bool
+		if method, path, ok = parseRequestLine(lookup([]string{"request"})); !ok {
+			return Step{}, errors.New("missing method or path")
+		}
+	}
+
+	step := Step{
+		TraceID:    lookup(jsonTraceIDKeys),
+		UserID:     lookup(jsonUserIDKeys),
+		Method:     method,
+		Path:       requestPath(path),
+		StatusCode: status,
+		IP:         lookup(jsonIPKeys),
+		UserAgent:  lookup(jsonUserAgentKeys),
+		Error:      lookup(jsonErrorKeys),
+		CreatedAt:  at.Unix(),
+	}
+	step.ResponseBytes, _ = strconv.ParseInt(lookup(jsonBytesKeys), 10, 64)
+	if v := lookup([]string{"latency_ms"}); v != "" {
+		ms, _ := strconv.ParseFloat(v, 64)
+		step.LatencyMs = int64(ms)
+	} else if v := lookup([]string{"request_time", "duration"}); v != "" {
+		// nginx $request_time 등 초 단위
+		seconds, _ := strconv.ParseFloat(v, 64)
+		step.LatencyMs = int64(seconds * 1000)
+	}
+	return step, nil
+}
+
+// parseLogTime - RFC3339, combined 로그 시각, Unix 초(소수 허용) 중 하나로 해석
+func parseLogTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("missing time")
+	}
+	for _, layout := range []string{time.RFC3339Nano, combinedTimeLayout} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(sec*float64(time.Second))), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
@@ -0,0 +1,124 @@
+package trace
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ForEachStep 기본 페이지 크기
+const defaultIteratePageSize = 500
+
+// ErrStopIteration ForEachStep의 fn이 반환하면 남은 Step을 읽지 않고 에러 없이 종료
+var ErrStopIteration = errors.New("trace: stop iteration")
+
+// iterateOptions ForEachStep 설정
+type iterateOptions struct {
+	pageSize int
+}
+
+// IterateOption ForEachStep 함수형 옵션 타입
+type IterateOption func(*iterateOptions)
+
+// WithPageSize 한 번에 읽는 Step 수 설정 (기본 500, 순회 중 메모리 사용량은 페이지 크기에 비례)
+func WithPageSize(n int) IterateOption {
+	return func(opts *iterateOptions) {
+		if n > 0 {
+			opts.pageSize = n
+		}
+	}
+}
+
+// ForEachStep - db에서 조건에 맞는 Step을 오래된 순으로 하나씩 fn에 전달 (기본 Tracer의 테이블 구성과 암호화 키 사용)
+func ForEachStep(ctx context.Context, db *gorm.DB, filter QueryFilter, fn func(Step) error, opts ...IterateOption) error {
+	return storeFor(db).ForEachStep(ctx, filter, fn, opts...)
+}
+
+// ForEachStep - 조건에 맞는 Step을 오래된 순으로 하나씩 fn에 전달
+// 결과를 메모리에 모두 올리지 않고 (created_at, seq) 키셋 페이지로 나누어 읽으므로 전체 행 수와 관계없이 메모리 사용량은 페이지 크기에 비례한다
+// fn이 ErrStopIteration을 반환하면 nil을, 다른 에러를 반환하면 그 에러를 반환하며, filter.Limit이 0이면 조건에 맞는 Step을 모두 전달한다
+func (s *Store) ForEachStep(ctx context.Context, filter QueryFilter, fn func(Step) error, opts ...IterateOption) error {
+	options := iterateOptions{pageSize: defaultIteratePageSize}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return s.forEachPage(ctx, filter, options.pageSize, func(steps []Step) error {
+		for i := range steps {
+			if err := fn(steps[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// forEachPage - 조건에 맞는 Step을 오래된 순으로 pageSize씩 복호화하여 fn에 전달 (filter.Offset, Limit 적용)
+func (s *Store) forEachPage(ctx context.Context, filter QueryFilter, pageSize int, fn func([]Step) error) error {
+	tx, err := s.filteredSteps(ctx, filter)
+	if err != nil {
+		return err
+	}
+	err = pageSteps(tx, pageSize, filter.Offset, filter.Limit, func(steps []Step) error {
+		s.cipher.decryptSteps(steps)
+		return fn(steps)
+	})
+	if errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+	return err
+}
+
+// pageSteps - query의 Step을 오래된 순으로 (created_at, seq) 키셋 페이지로 나누어 fn에 전달
+// seq는 프로세스마다 따로 증가하므로(가져온 Step은 0) 키가 같은 Step이 페이지 경계에 걸치면 이미 전달한 수만큼 건너뛴다
+// offset은 첫 페이지에만 적용하며, limit이 0이면 모두 전달한다
+func pageSteps(query *gorm.DB, pageSize, offset, limit int, fn func([]Step) error) error {
+	query = query.Session(&gorm.Session{})
+	var last Step
+	sameKey := 0 // last와 키가 같은 Step 중 이미 전달한 수
+	for first := true; ; first = false {
+		n := pageSize
+		if limit > 0 {
+			n = min(n, limit)
+		}
+		page := query.Order("created_at, seq, trace_id, span_id").Limit(n)
+		if first {
+			page = page.Offset(max(offset, 0))
+		} else {
+			// created_at 인덱스를 범위 조건으로 사용하도록 OR 앞에 created_at >= 조건을 둠
+			page = page.Where("created_at >= ? AND (created_at > ? OR seq >= ?)", last.CreatedAt, last.CreatedAt, last.Seq).Offset(sameKey)
+		}
+		var steps []Step
+		if err := page.Find(&steps).Error; err != nil {
+			return err
+		}
+		if len(steps) == 0 {
+			return nil
+		}
+
+		tail := steps[len(steps)-1]
+		same := 0
+		for i := len(steps) - 1; i >= 0 && steps[i].CreatedAt == tail.CreatedAt && steps[i].Seq == tail.Seq; i-- {
+			same++
+		}
+		if !first && tail.CreatedAt == last.CreatedAt && tail.Seq == last.Seq {
+			sameKey += same
+		} else {
+			sameKey = same
+		}
+		last = tail
+
+		if err := fn(steps); err != nil {
+			return err
+		}
+		if limit > 0 {
+			limit -= len(steps)
+			if limit == 0 {
+				return nil
+			}
+		}
+		if len(steps) < n {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestForEachStepBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("seeds 100k rows")
+	}
+	db := tracetest.NewTempDB(t)
+	const rows = 100_000
+	start := time.Now().Add(-time.Hour).Unix()
+	batch := make([]trace.Step, 0, 1000)
+	for i := range rows {
+		// 초마다 50개씩 저장되어 페이지 경계마다 created_at이 같은 Step이 걸침
+		batch = append(batch, trace.Step{TraceID: fmt.Sprintf("t%06d", i), Path: "/feed", UserAgent: "Mozilla/5.0", CreatedAt: start + int64(i/50), Seq: int64(i)})
+		if len(batch) == cap(batch) {
+			if err := db.CreateInBatches(batch, 200).Error; err != nil {
+				t.Fatal(err)
+			}
+			batch = batch[:0]
+		}
+	}
+	store, err := trace.NewStore(db, trace.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	var peak uint64
+	count := 0
+	err = store.ForEachStep(context.Background(), trace.QueryFilter{}, func(step trace.Step) error {
+		if want := fmt.Sprintf("t%06d", count); step.TraceID != want {
+			return fmt.Errorf("step %d is %s, want %s", count, step.TraceID, want)
+		}
+		count++
+		if count%10_000 == 0 {
+			runtime.GC()
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			peak = max(peak, m.HeapAlloc)
+		}
+		return nil
+	}, trace.WithPageSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != rows {
+		t.Fatalf("visited %d steps, want %d", count, rows)
+	}
+	// 10만 개를 슬라이스로 읽으면 100MB 이상, 페이지 하나는 수백 KB
+	if grown := int64(peak) - int64(before.HeapAlloc); grown > 8<<20 {
+		t.Fatalf("live heap grew by %d bytes while iterating, want it bounded by the page size", grown)
+	}
+}
+
+func TestForEachStepSameKeyAcrossPages(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	// 가져온 Step처럼 seq가 모두 0이고 같은 초에 생성된 Step
+	now := time.Now().Unix()
+	for i := range 23 {
+		step := trace.Step{TraceID: fmt.Sprintf("imported-%02d", i), Path: "/legacy", CreatedAt: now}
+		if err := db.Create(&step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	seen := map[string]int{}
+	err := trace.ForEachStep(context.Background(), db, trace.QueryFilter{}, func(step trace.Step) error {
+		seen[step.TraceID]++
+		return nil
+	}, trace.WithPageSize(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 23 {
+		t.Fatalf("visited %d distinct steps, want 23", len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Fatalf("step %s visited %d times", id, n)
+		}
+	}
+}
+
+func TestForEachStepStopAndLimit(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	now := time.Now().Unix()
+	for i := range 40 {
+		step := trace.Step{TraceID: fmt.Sprintf("t%02d", i), Path: "/x", CreatedAt: now + int64(i), Seq: int64(i)}
+		if err := db.Create(&step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := trace.NewStore(db, trace.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	err = store.ForEachStep(context.Background(), trace.QueryFilter{}, func(step trace.Step) error {
+		if calls++; calls == 12 {
+			return trace.ErrStopIteration
+		}
+		return nil
+	}, trace.WithPageSize(5))
+	if err != nil || calls != 12 {
+		t.Fatalf("ForEachStep = %v after %d calls, want nil after 12", err, calls)
+	}
+
+	failure := errors.New("sink unavailable")
+	if err := store.ForEachStep(context.Background(), trace.QueryFilter{}, func(trace.Step) error { return failure }); !errors.Is(err, failure) {
+		t.Fatalf("ForEachStep = %v, want the callback error", err)
+	}
+
+	var ids []string
+	err = store.ForEachStep(context.Background(), trace.QueryFilter{Offset: 3, Limit: 9}, func(step trace.Step) error {
+		ids = append(ids, step.TraceID)
+		return nil
+	}, trace.WithPageSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 9 || ids[0] != "t03" || ids[8] != "t11" {
+		t.Fatalf("offset 3 limit 9 visited %v", ids)
+	}
+}
@@ -0,0 +1,35 @@
+package trace
+
+import (
+	"os"
+	"strings"
+)
+
+// 서비스 계정 토큰과 함께 마운트되는 네임스페이스 파일
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// KubernetesMetadata Step에 기록할 Pod 정보 (Config.KubernetesMetadata)
+type KubernetesMetadata struct {
+	PodName   string `json:"pod_name,omitempty"`
+	NodeName  string `json:"node_name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// kubernetesMetadata - downward API로 주입한 환경 변수에서 Pod 정보 읽기 (New에서 한 번 호출)
+// POD_NAME이 없으면 호스트 이름(기본적으로 Pod 이름), POD_NAMESPACE가 없으면 서비스 계정의 네임스페이스 파일을 사용한다
+func kubernetesMetadata() KubernetesMetadata {
+	meta := KubernetesMetadata{
+		PodName:   os.Getenv("POD_NAME"),
+		NodeName:  os.Getenv("NODE_NAME"),
+		Namespace: os.Getenv("POD_NAMESPACE"),
+	}
+	if meta.PodName == "" {
+		meta.PodName, _ = os.Hostname()
+	}
+	if meta.Namespace == "" {
+		if data, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+			meta.Namespace = strings.TrimSpace(string(data))
+		}
+	}
+	return meta
+}
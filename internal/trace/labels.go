@@ -0,0 +1,185 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// 라벨 최대 개수
+	maxLabels = 16
+	// 라벨 키/값 최대 길이
+	maxLabelKeyLen   = 64
+	maxLabelValueLen = 256
+
+	// Team 컬럼에 함께 기록되는 라벨 키
+	teamLabel = "team"
+)
+
+// WithStaticLabels 고정 라벨 설정 (라우트 그룹의 담당 팀 등)
+// 키는 영문자, 숫자, '_', '-', '.'만 허용되며, 잘못된 라벨은 로그를 남기고 적용하지 않는다
+// 에러를 직접 처리하려면 ValidateLabels로 미리 검사하거나 Labeled를 사용한다
+func WithStaticLabels(labels map[string]string) MiddlewareOption {
+	err := ValidateLabels(labels)
+	if err != nil {
+		log.Printf("trace: ignoring static labels: %v", err)
+	}
+	labels = maps.Clone(labels)
+	return func(config *MiddlewareConfig) {
+		if err != nil {
+			config.labelsErr = err
+			return
+		}
+		config.setLabels(labels)
+	}
+}
+
+// withTeamLabel - 이미 설정한 라벨에 team 라벨을 더함
+func withTeamLabel(team string) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		labels := maps.Clone(config.Labels)
+		if labels == nil {
+			labels = make(map[string]string, 1)
+		}
+		labels[teamLabel] = team
+		if err := ValidateLabels(labels); err != nil {
+			config.labelsErr = err
+			return
+		}
+		config.setLabels(labels)
+	}
+}
+
+// setLabels - 라벨과 Step에 기록할 JSON 설정
+func (config *MiddlewareConfig) setLabels(labels map[string]string) {
+	encoded, _ := json.Marshal(labels) // map[string]string은 항상 인코딩 가능
+	config.Labels = labels
+	config.encodedLabels = string(encoded)
+}
+
+// Labeled - 담당 팀 라벨이 붙은 미들웨어 생성 (opts의 WithStaticLabels 라벨에 team 라벨을 더함)
+// team이나 라벨이 잘못되었으면 에러를 반환한다
+func Labeled(team string, opts ...MiddlewareOption) (gin.HandlerFunc, error) {
+	options := make([]MiddlewareOption, 0, len(opts)+1)
+	options = append(options, opts...)
+	options = append(options, withTeamLabel(team))
+
+	config := newMiddlewareConfig(options...)
+	if config.labelsErr != nil {
+		return nil, config.labelsErr
+	}
+	return newMiddleware(config), nil
+}
+
+// ValidateLabels - 라벨 개수, 키 문자와 길이, 값 길이 검사
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("trace: too many labels (%d > %d)", len(labels), maxLabels)
+	}
+	for key, value := range labels {
+		if key == "" || len(key) > maxLabelKeyLen {
+			return fmt.Errorf("trace: invalid label key length %q", key)
+		}
+		for _, r := range key {
+			if !isLabelKeyRune(r) {
+				return fmt.Errorf("trace: invalid character %q in label key %q", r, key)
+			}
+		}
+		if len(value) > maxLabelValueLen {
+			return fmt.Errorf("trace: label %q value too long (%d > %d)", key, len(value), maxLabelValueLen)
+		}
+	}
+	return nil
+}
+
+func isLabelKeyRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		r == '_' || r == '-' || r == '.'
+}
+
+// TeamSummary 팀별 요약 통계
+type TeamSummary struct {
+	Team      string  `json:"team"`
+	Count     int64   `json:"count"`
+	ErrorRate float64 `json:"error_rate"` // 5xx 비율 (0~1)
+}
+
+// TeamSummaries - 최근 window 동안 팀별 요청 수와 에러율 반환 (라벨이 없는 요청은 빈 팀으로 집계)
+func TeamSummaries(ctx context.Context, db *gorm.DB, window time.Duration) ([]TeamSummary, error) {
+	return storeFor(db).TeamSummaries(ctx, window)
+}
+
+// TeamSummaries - 최근 window 동안 팀별 요청 수와 에러율 (요청이 많은 순)
+func (s *Store) TeamSummaries(ctx context.Context, window time.Duration) ([]TeamSummary, error) {
+	type row struct {
+		Team   string
+		Count  int64
+		Errors int64
+	}
+
+	since := time.Now().Add(-window)
+	steps, err := s.stepsFrom(ctx, since, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []row
+	err = steps().
+		Where(requestStepsOnly).
+		Select("team, COUNT(*) AS count, SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) AS errors").
+		Where("created_at >= ?", since.Unix()).
+		Group("team").
+		Order("count DESC, team").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]TeamSummary, 0, len(rows))
+	for _, r := range rows {
+		summaries = append(summaries, TeamSummary{
+			Team:      r.Team,
+			Count:     r.Count,
+			ErrorRate: float64(r.Errors) / float64(r.Count),
+		})
+	}
+	return summaries, nil
+}
+
+// TeamErrorBudget 팀별 가용성 목표(SLO) 대비 에러 버짓 사용량
+type TeamErrorBudget struct {
+	TeamSummary
+	SLO float64 `json:"slo"` // 예: 0.999
+	// 허용 에러율(1 - SLO) 대비 에러율 (1 이상이면 버짓 소진)
+	Consumed  float64 `json:"consumed"`
+	Exhausted bool    `json:"exhausted"`
+}
+
+// TeamErrorBudgets - 최근 window 동안 팀별 에러 버짓 사용량 (slo는 0과 1 사이, 요청이 많은 순)
+func TeamErrorBudgets(ctx context.Context, db *gorm.DB, window time.Duration, slo float64) ([]TeamErrorBudget, error) {
+	return storeFor(db).TeamErrorBudgets(ctx, window, slo)
+}
+
+// TeamErrorBudgets - 최근 window 동안 팀별 에러 버짓 사용량
+func (s *Store) TeamErrorBudgets(ctx context.Context, window time.Duration, slo float64) ([]TeamErrorBudget, error) {
+	if slo <= 0 || slo >= 1 {
+		return nil, fmt.Errorf("trace: SLO must be between 0 and 1 (got %g)", slo)
+	}
+	summaries, err := s.TeamSummaries(ctx, window)
+	if err != nil {
+		return nil, err
+	}
+	budgets := make([]TeamErrorBudget, 0, len(summaries))
+	for _, summary := range summaries {
+		consumed := summary.ErrorRate / (1 - slo)
+		budgets = append(budgets, TeamErrorBudget{TeamSummary: summary, SLO: slo, Consumed: consumed, Exhausted: consumed >= 1})
+	}
+	return budgets, nil
+}
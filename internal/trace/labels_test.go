@@ -0,0 +1,194 @@
+package trace_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestLabeledTeamsAreAggregatedSeparately(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	var mu sync.Mutex
+	var alerts []trace.RouteErrorRate
+	tracer, err := trace.New(trace.Config{
+		DB:                   db,
+		ErrorRateThreshold:   0.5,
+		ErrorRateMinRequests: 4,
+		OnErrorRateExceeded: func(rate trace.RouteErrorRate) {
+			mu.Lock()
+			defer mu.Unlock()
+			alerts = append(alerts, rate)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 같은 경로를 두 팀이 각자의 라우트 그룹으로 제공 (예: 두 서비스가 같은 저장소 사용)
+	engine := func(team string, status int) *gin.Engine {
+		labeled, err := trace.Labeled(team, trace.WithTracer(tracer), trace.WithStaticLabels(map[string]string{"tier": "critical"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := gin.New()
+		g := r.Group("/api", labeled)
+		g.GET("/checkout", func(c *gin.Context) { c.Status(status) })
+		return r
+	}
+	payments, orders := engine("payments", http.StatusServiceUnavailable), engine("orders", http.StatusOK)
+	for range 6 {
+		payments.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/checkout?user_id=u&access_token=a", nil))
+	}
+	for range 10 {
+		orders.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/checkout?user_id=u&access_token=a", nil))
+	}
+
+	// 에러율 알림은 팀별로 집계되어 orders의 정상 요청이 payments의 에러율을 낮추지 않음
+	routes := tracer.Stats().Routes
+	stopTracer(t, tracer)
+	if len(alerts) != 1 || alerts[0].Team != "payments" || alerts[0].Route != "GET /api/checkout" || alerts[0].ErrorRate != 1 {
+		t.Fatalf("alerts = %+v, want one for payments", alerts)
+	}
+	if len(routes) != 2 || routes[0].Team != "payments" || routes[0].Requests != 6 || routes[1].Team != "orders" || routes[1].Requests != 10 || routes[1].Exceeded {
+		t.Fatalf("Stats().Routes = %+v, want payments and orders split", routes)
+	}
+
+	// 저장된 Step의 라벨 (WithStaticLabels의 라벨에 team을 더함)
+	for _, step := range storedSteps(t, tracer, trace.QueryFilter{RequestsOnly: true}) {
+		var labels map[string]string
+		if err := json.Unmarshal([]byte(step.Labels), &labels); err != nil || labels["team"] != step.Team || labels["tier"] != "critical" {
+			t.Fatalf("step labels = %q (team %q), want team and tier", step.Labels, step.Team)
+		}
+	}
+
+	ctx := context.Background()
+	// 방금 저장한 Step(같은 초)까지 포함
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Second)
+	summaries, err := trace.TeamSummaries(ctx, db, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 2 || summaries[0] != (trace.TeamSummary{Team: "orders", Count: 10}) || summaries[1] != (trace.TeamSummary{Team: "payments", Count: 6, ErrorRate: 1}) {
+		t.Fatalf("TeamSummaries = %+v", summaries)
+	}
+
+	rows, err := trace.Aggregate(ctx, db, trace.AggQuery{From: from, To: to, GroupByTeam: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Team != "orders" || rows[0].Count != 10 || rows[0].ErrorRate != 0 || rows[1].Team != "payments" || rows[1].Count != 6 || rows[1].ErrorRate != 1 {
+		t.Fatalf("Aggregate by team = %+v", rows)
+	}
+	rows, err = trace.Aggregate(ctx, db, trace.AggQuery{From: from, To: to, Team: "payments"})
+	if err != nil || len(rows) != 1 || rows[0].Count != 6 || rows[0].Team != "" {
+		t.Fatalf("Aggregate for payments = %+v, %v", rows, err)
+	}
+	rows, err = trace.Aggregate(ctx, db, trace.AggQuery{From: from, To: to})
+	if err != nil || len(rows) != 1 || rows[0].Count != 16 {
+		t.Fatalf("Aggregate without teams = %+v, %v", rows, err)
+	}
+
+	budgets, err := trace.TeamErrorBudgets(ctx, db, time.Hour, 0.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(budgets) != 2 || budgets[0].Team != "orders" || budgets[0].Exhausted || budgets[1].Team != "payments" || !budgets[1].Exhausted {
+		t.Fatalf("TeamErrorBudgets = %+v", budgets)
+	}
+
+	// 조회 API
+	r := gin.New()
+	trace.APIRoutes(r.Group("/debug"), db, trace.WithAPIAuth(func(c *gin.Context) {}))
+	for _, tt := range []struct {
+		target string
+		code   int
+		want   string
+	}{
+		{"/debug/teams?window=1h", http.StatusOK, `"team":"payments","count":6,"error_rate":1`},
+		{"/debug/teams/error-budget?slo=0.5", http.StatusOK, `"team":"payments","count":6,"error_rate":1,"slo":0.5,"consumed":2,"exhausted":true`},
+		{"/debug/teams/error-budget?slo=1", http.StatusBadRequest, "invalid slo"},
+		{"/debug/aggregate?group_by=team&to=" + strconv.FormatInt(to.Unix(), 10), http.StatusOK, `"team":"orders"`},
+		{"/debug/aggregate?group_by=tier", http.StatusBadRequest, "invalid group_by"},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("GET %s = %d %s, want %d containing %s", tt.target, w.Code, w.Body, tt.code, tt.want)
+		}
+	}
+}
+
+func TestLabelValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		team   string
+		labels map[string]string
+	}{
+		{"empty key", "payments", map[string]string{"": "x"}},
+		{"key charset", "payments", map[string]string{"owner team": "x"}},
+		{"long value", strings.Repeat("t", 257), nil},
+		{"too many labels", "payments", func() map[string]string {
+			labels := map[string]string{}
+			for _, k := range strings.Split("a b c d e f g h i j k l m n o p", " ") {
+				labels[k] = "x"
+			}
+			return labels
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []trace.MiddlewareOption
+			if tt.labels != nil {
+				opts = append(opts, trace.WithStaticLabels(tt.labels))
+			}
+			if handler, err := trace.Labeled(tt.team, opts...); err == nil || handler != nil {
+				t.Fatalf("Labeled(%q, %v) = %v, want an error", tt.team, tt.labels, err)
+			}
+		})
+	}
+	if err := trace.ValidateLabels(map[string]string{"team": "payments", "tier.level-1_a": "x"}); err != nil {
+		t.Fatalf("ValidateLabels rejected valid labels: %v", err)
+	}
+
+	// 잘못된 라벨은 panic 없이 무시
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(tracer.Middleware(trace.WithStaticLabels(map[string]string{"bad key": "x"})))
+	r.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders?user_id=u&access_token=a", nil))
+	stopTracer(t, tracer)
+	if steps := storedSteps(t, tracer, trace.QueryFilter{}); len(steps) != 1 || steps[0].Labels != "" {
+		t.Fatalf("steps = %+v, want one step without labels", steps)
+	}
+}
+
+func TestLabeledDoesNotModifyOptions(t *testing.T) {
+	// 여유 용량이 있는 슬라이스를 넘겨도 뒤쪽 원소를 덮어쓰지 않음
+	spare := trace.WithSampleRate(0.5)
+	opts := append(make([]trace.MiddlewareOption, 0, 2), trace.WithSampleRate(1), spare)[:1]
+	if _, err := trace.Labeled("payments", opts...); err != nil {
+		t.Fatal(err)
+	}
+	if opts[:2][1] == nil {
+		t.Fatal("Labeled cleared the caller's spare option")
+	}
+	config := &trace.MiddlewareConfig{}
+	opts[:2][1](config)
+	if config.SampleRate != 0.5 {
+		t.Fatal("Labeled appended into the caller's options slice")
+	}
+}
@@ -0,0 +1,105 @@
+package trace_test
+
+import (
+	"bufio"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"trace/internal/trace"
+)
+
+// gateSink - gate가 닫힐 때까지 Write를 멈추고 저장한 Step을 기록하는 Sink
+type gateSink struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	rows []trace.Step
+}
+
+func (s *gateSink) Write(ctx context.Context, steps []trace.Step) error {
+	<-s.gate
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = append(s.rows, steps...)
+	return nil
+}
+
+func (s *gateSink) Close() error { return nil }
+
+func (s *gateSink) lagOf(path string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range s.rows {
+		if row.Path == path {
+			return row.PipelineLagMs, true
+		}
+	}
+	return 0, false
+}
+
+func waitStored(t *testing.T, tracer *trace.Tracer, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for tracer.Stats().Stored < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("stored %d steps, want %d", tracer.Stats().Stored, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPipelineLagWithPausedSink(t *testing.T) {
+	const pause = 300 * time.Millisecond
+	sink := &gateSink{gate: make(chan struct{})}
+	// 샤드 하나에서 순서대로 저장하므로 Sink가 멈추면 뒤의 Step은 샤드 버퍼에서 기다린다
+	tracer, err := trace.New(trace.Config{Sink: sink, BatchSize: 1, FlushInterval: 10 * time.Millisecond, PerUserOrdering: true, OrderingShards: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+
+	// 첫 Step은 저장 직전에 Sink가 멈추고, 그동안 들어온 Step은 멈춘 시간만큼 늦게 저장된다
+	for _, path := range []string{"/blocked", "/delayed1", "/delayed2"} {
+		serve(t, tracer, path+"?user_id=u&access_token=a")
+	}
+	time.Sleep(pause)
+	close(sink.gate)
+	waitStored(t, tracer, 3)
+
+	serve(t, tracer, "/after?user_id=u&access_token=a")
+	waitStored(t, tracer, 4)
+
+	for _, path := range []string{"/delayed1", "/delayed2"} {
+		if lag, ok := sink.lagOf(path); !ok || lag < (pause-50*time.Millisecond).Milliseconds() {
+			t.Errorf("%s lag = %dms, want at least the %s pause", path, lag, pause)
+		}
+	}
+	if lag, ok := sink.lagOf("/after"); !ok || lag >= (pause/2).Milliseconds() {
+		t.Errorf("/after lag = %dms, want well under the %s pause", lag, pause)
+	}
+
+	// 히스토그램은 저장 완료 시각 기준이므로 Sink 안에서 멈춘 첫 Step도 늦게 저장된 것으로 집계한다
+	stats := tracer.Stats()
+	if stats.PipelineLag.Count != 4 || stats.PipelineLag.P50Ms < pause.Milliseconds() || stats.ReplayedPipelineLag.Count != 0 {
+		t.Fatalf("Stats lag = %+v / replayed %+v, want 4 live steps, 3 of them delayed by the pause", stats.PipelineLag, stats.ReplayedPipelineLag)
+	}
+
+	w := httptest.NewRecorder()
+	tracer.MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	var live, replayed bool
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		switch scanner.Text() {
+		case `trace_pipeline_lag_seconds_count{provenance="live"} 4`:
+			live = true
+		case `trace_pipeline_lag_seconds_count{provenance="replayed"} 0`:
+			replayed = true
+		}
+	}
+	if !live || !replayed {
+		t.Fatalf("metrics missing pipeline lag counts split by provenance:\n%s", strings.TrimSpace(w.Body.String()))
+	}
+}
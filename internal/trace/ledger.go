@@ -0,0 +1,269 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LedgerEntry 분 단위, 출처(서비스 이름)별 기대 Step 수와 버린 이유별 수 (Config.Ledger, trace_ledger 테이블)
+// 여러 인스턴스가 같은 행에 더하므로 모든 값은 누적 합계다
+type LedgerEntry struct {
+	Minute            int64  `gorm:"primaryKey;autoIncrement:false"` // 분 시작 시각 (Unix 초)
+	Source            string `gorm:"primaryKey;size:255"`            // Step의 ServiceName
+	Expected          int64  // 파이프라인에 넘긴 요청 Step 수 (과부하로 제외한 Step 포함)
+	DroppedBufferFull int64  // 버퍼가 가득 차 버린 Step 수
+	DroppedOverload   int64  // 적응형 샘플링이 과부하로 제외한 Step 수
+	DeadLettered      int64  // 재시도와 spill 후에도 저장하지 못해 dead-letter로 보낸 Step 수
+}
+
+// ledgerTable - Step 테이블 이름에 맞춘 장부 테이블 이름 (steps → trace_ledger, svc_steps → svc_trace_ledger)
+func (t stepTables) ledgerTable() string {
+	if prefix, ok := strings.CutSuffix(t.base, defaultStepTable); ok {
+		return prefix + "trace_ledger"
+	}
+	return t.base + "_ledger"
+}
+
+type ledgerKey struct {
+	minute int64
+	source string
+}
+
+// ledger - 저장 전까지 메모리에 모아 두는 장부 증가분 (분, 출처별 행 하나)
+type ledger struct {
+	db    *gorm.DB
+	table string
+
+	mu      sync.Mutex
+	entries map[ledgerKey]*LedgerEntry
+}
+
+func newLedger(db *gorm.DB, table string) (*ledger, error) {
+	if err := db.Table(table).AutoMigrate(&LedgerEntry{}); err != nil {
+		return nil, err
+	}
+	return &ledger{db: db, table: table, entries: make(map[ledgerKey]*LedgerEntry)}, nil
+}
+
+// add - step이 속한 분과 출처의 증가분에 update 적용 (Ledger가 꺼져 있으면 무시)
+func (l *ledger) add(step Step, update func(*LedgerEntry)) {
+	if l == nil || step.SpanName != "" {
+		return
+	}
+	k := ledgerKey{step.CreatedAt - step.CreatedAt%60, step.ServiceName}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := l.entries[k]
+	if e == nil {
+		e = &LedgerEntry{Minute: k.minute, Source: k.source}
+		l.entries[k] = e
+	}
+	update(e)
+}
+
+func (l *ledger) expect(step Step) {
+	l.add(step, func(e *LedgerEntry) { e.Expected++ })
+}
+
+func (l *ledger) dropped(step Step, reason DropReason) {
+	l.add(step, func(e *LedgerEntry) {
+		switch reason {
+		case DropBufferFull:
+			e.DroppedBufferFull++
+		case DropOverload:
+			// 미들웨어가 버퍼에 넣기 전에 제외하므로 기대 수에도 더함
+			e.Expected++
+			e.DroppedOverload++
+		}
+	})
+}
+
+func (l *ledger) deadLettered(steps []Step) {
+	for _, step := range steps {
+		l.add(step, func(e *LedgerEntry) { e.DeadLettered++ })
+	}
+}
+
+// take - 모아 둔 증가분을 꺼냄
+func (l *ledger) take() map[ledgerKey]*LedgerEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := l.entries
+	l.entries = make(map[ledgerKey]*LedgerEntry)
+	return entries
+}
+
+// restore - 저장하지 못한 증가분을 다음 저장 때 다시 시도하도록 되돌림
+func (l *ledger) restore(entries map[ledgerKey]*LedgerEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, e := range entries {
+		cur := l.entries[k]
+		if cur == nil {
+			l.entries[k] = e
+			continue
+		}
+		cur.Expected += e.Expected
+		cur.DroppedBufferFull += e.DroppedBufferFull
+		cur.DroppedOverload += e.DroppedOverload
+		cur.DeadLettered += e.DeadLettered
+	}
+}
+
+// flush - 모아 둔 증가분을 Step과 같은 방식(최대 3회, 시도마다 대기 시간 증가)으로 재시도하며 장부 테이블에 더함
+// 끝내 실패하면 증가분을 메모리에 되돌려 다음 flush(다음 배치 저장이나 Stop)에서 다시 시도한다
+func (l *ledger) flush() error {
+	if l == nil {
+		return nil
+	}
+	entries := l.take()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	const maxRetries = 3
+	var err error
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if err = l.write(entries); err == nil {
+			return nil
+		}
+		if attempt < maxRetries {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	l.restore(entries)
+	return fmt.Errorf("failed to write %d ledger rows after %d attempts: %w", len(entries), maxRetries, err)
+}
+
+func (l *ledger) write(entries map[ledgerKey]*LedgerEntry) error {
+	// ON CONFLICT의 SET에서 기존 행은 테이블 이름으로 참조 (PostgreSQL에서 excluded와 구분)
+	target := l.table[strings.LastIndexByte(l.table, '.')+1:]
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		for _, e := range entries {
+			err := tx.Table(l.table).Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "minute"}, {Name: "source"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"expected":            gorm.Expr(target+".expected + ?", e.Expected),
+					"dropped_buffer_full": gorm.Expr(target+".dropped_buffer_full + ?", e.DroppedBufferFull),
+					"dropped_overload":    gorm.Expr(target+".dropped_overload + ?", e.DroppedOverload),
+					"dead_lettered":       gorm.Expr(target+".dead_lettered + ?", e.DeadLettered),
+				}),
+			}).Create(e).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// flushLedger - 배치 저장 후 장부 증가분 저장 (실패는 로그만 남기고 다음 저장에서 다시 시도)
+func (t *Tracer) flushLedger() {
+	if err := t.ledger.flush(); err != nil {
+		log.Printf("trace: %v", err)
+	}
+}
+
+// Discrepancy 장부의 기대 Step 수와 실제 저장된 Step 수가 다른 분, 출처
+type Discrepancy struct {
+	Minute   time.Time `json:"minute"`
+	Source   string    `json:"source"`
+	Expected int64     `json:"expected"`
+	Stored   int64     `json:"stored"`
+	Missing  int64     `json:"missing"` // Expected - Stored (중복 저장이면 음수)
+	// 버린 이유별 Step 수 (buffer_full, overload, dead_letter)
+	Dropped map[string]int64 `json:"dropped"`
+	// 버린 이유로 설명되지 않는 차이 (0이 아니면 알 수 없는 경로로 유실되거나 중복 저장됨)
+	Unexplained int64 `json:"unexplained"`
+}
+
+// Reconcile - [from, to) 구간의 장부(trace_ledger)와 저장된 요청 Step 수를 분, 출처별로 비교하여 차이가 있는 구간 반환 (시간순)
+// spill 파일에 남아 아직 다시 저장하지 않은 Step도 차이로 보고되므로 재전송이 끝난 뒤 실행한다
+func Reconcile(ctx context.Context, db *gorm.DB, from, to time.Time) ([]Discrepancy, error) {
+	return storeFor(db).Reconcile(ctx, from, to)
+}
+
+// Reconcile - [from, to) 구간의 장부와 저장된 요청 Step 수를 분, 출처별로 비교하여 차이가 있는 구간 반환
+func (s *Store) Reconcile(ctx context.Context, from, to time.Time) ([]Discrepancy, error) {
+	if from.IsZero() || to.IsZero() {
+		return nil, errors.New("trace: Reconcile requires from and to")
+	}
+	var entries []LedgerEntry
+	err := s.db.WithContext(ctx).
+		Table(s.tables.ledgerTable()).
+		Where("minute >= ? AND minute < ?", from.Unix(), to.Unix()).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := s.stepsFrom(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		Minute int64
+		Source string
+		Stored int64
+	}
+	err = steps().
+		Select("created_at - created_at % 60 AS minute, COALESCE(service_name, '') AS source, COUNT(*) AS stored").
+		Where(requestStepsOnly).
+		Where("created_at >= ? AND created_at < ?", from.Unix(), to.Unix()).
+		Group("created_at - created_at % 60, COALESCE(service_name, '')").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[ledgerKey]*Discrepancy)
+	bucket := func(k ledgerKey) *Discrepancy {
+		d := buckets[k]
+		if d == nil {
+			d = &Discrepancy{Minute: time.Unix(k.minute, 0).UTC(), Source: k.source}
+			buckets[k] = d
+		}
+		return d
+	}
+	for _, e := range entries {
+		d := bucket(ledgerKey{e.Minute, e.Source})
+		d.Expected = e.Expected
+		d.Dropped = map[string]int64{
+			DropBufferFull.String(): e.DroppedBufferFull,
+			DropOverload.String():   e.DroppedOverload,
+			"dead_letter":           e.DeadLettered,
+		}
+	}
+	for _, c := range counts {
+		bucket(ledgerKey{c.Minute, c.Source}).Stored = c.Stored
+	}
+
+	var discrepancies []Discrepancy
+	for _, d := range buckets {
+		if d.Expected == d.Stored {
+			continue
+		}
+		d.Missing = d.Expected - d.Stored
+		d.Unexplained = d.Missing
+		for _, n := range d.Dropped {
+			d.Unexplained -= n
+		}
+		discrepancies = append(discrepancies, *d)
+	}
+	slices.SortFunc(discrepancies, func(a, b Discrepancy) int {
+		if c := a.Minute.Compare(b.Minute); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Source, b.Source)
+	})
+	return discrepancies, nil
+}
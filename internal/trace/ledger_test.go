@@ -0,0 +1,109 @@
+package trace_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// gatedGormSink - gate가 닫힐 때까지 Write를 멈춘 뒤 DB에 저장하는 Sink
+type gatedGormSink struct {
+	gate chan struct{}
+	sink *trace.GormSink
+}
+
+func (s *gatedGormSink) Write(ctx context.Context, steps []trace.Step) error {
+	<-s.gate
+	return s.sink.Write(ctx, steps)
+}
+
+func (s *gatedGormSink) Close() error { return nil }
+
+func reconcileAll(t *testing.T, db *gorm.DB, from time.Time) []trace.Discrepancy {
+	t.Helper()
+	discrepancies, err := trace.Reconcile(context.Background(), db, from.Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return discrepancies
+}
+
+func TestReconcileReportsDroppedSteps(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	gormSink, err := trace.NewGormSink(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &gatedGormSink{gate: make(chan struct{}), sink: gormSink}
+	// 샤드 하나가 Sink에서 멈춘 동안 샤드 버퍼(2)를 넘는 Step은 버려진다
+	tracer, err := trace.New(trace.Config{
+		DB: db, Sink: sink, Ledger: true, ServiceName: "orders",
+		BatchSize: 1, FlushInterval: time.Hour, PerUserOrdering: true, OrderingShards: 1, ShardBufferSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	const requests = 10
+	for i := range requests {
+		serve(t, tracer, fmt.Sprintf("/r%d?user_id=user-1&access_token=token", i))
+	}
+	close(sink.gate)
+	if err := tracer.Stop(context.Background()); err == nil {
+		t.Fatal("Stop reported no lost steps")
+	}
+	dropped := tracer.DroppedSteps()
+	if dropped == 0 {
+		t.Fatal("no steps were dropped")
+	}
+
+	var expected, missing, bufferFull int64
+	for _, d := range reconcileAll(t, db, start) {
+		if d.Source != "orders" || d.Unexplained != 0 {
+			t.Errorf("unexpected discrepancy %+v", d)
+		}
+		expected += d.Expected
+		missing += d.Missing
+		bufferFull += d.Dropped["buffer_full"]
+	}
+	if missing != dropped || bufferFull != dropped {
+		t.Fatalf("missing %d, buffer_full %d, want both to equal the %d dropped steps", missing, bufferFull, dropped)
+	}
+	if expected > requests || expected < dropped {
+		t.Fatalf("expected %d steps, want at most %d", expected, requests)
+	}
+}
+
+func TestReconcileWithoutLoss(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	tracer, err := trace.New(trace.Config{DB: db, Ledger: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := range 5 {
+		serve(t, tracer, fmt.Sprintf("/r%d?user_id=user-1&access_token=token", i))
+	}
+	stopTracer(t, tracer)
+
+	if d := reconcileAll(t, db, start); len(d) != 0 {
+		t.Fatalf("discrepancies without drops: %+v", d)
+	}
+	var ledger []trace.LedgerEntry
+	if err := db.Table("trace_ledger").Find(&ledger).Error; err != nil {
+		t.Fatal(err)
+	}
+	var expected int64
+	for _, e := range ledger {
+		expected += e.Expected
+	}
+	if expected != 5 {
+		t.Fatalf("ledger expected %d steps, want 5", expected)
+	}
+}
@@ -0,0 +1,105 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrAlreadyStarted Stop 없이 Start를 다시 호출한 경우
+var ErrAlreadyStarted = errors.New("trace: already started")
+
+// runWorker - 종료를 기다릴 수 있도록 워커 고루틴 시작
+func (t *Tracer) runWorker(ch <-chan Step, schedule flushSchedule, batchSize int, flushFn func([]Step)) {
+	t.workersWG.Add(1)
+	t.startedWorkers.Add(1)
+	t.liveWorkers.Add(1)
+	go func() {
+		defer t.workersWG.Done()
+		defer t.liveWorkers.Add(-1)
+		startWorker(t.clock, ch, schedule, batchSize, flushFn)
+	}()
+}
+
+// Stop - 기본 Tracer의 버퍼를 닫고 남은 Step을 모두 저장한 뒤 종료
+// Start 전에 호출하면 아무것도 하지 않으며, 다시 호출하면 첫 Stop의 결과를 반환한다
+func Stop(ctx context.Context) error {
+	t := defaultTracer.Load()
+	if t == nil {
+		return nil
+	}
+	return t.Stop(ctx)
+}
+
+// Stop - 버퍼를 닫고 남은 Step을 모두 저장한 뒤 종료
+// ctx가 끝나기 전에 저장이 끝나지 않거나, 실행 중 버리거나 저장하지 못한 Step이 있으면 에러를 반환한다
+// ctx가 끝나도 spill 파일과 Sink는 닫으며, 아직 저장하지 못한 Step은 이후 저장에 실패한다
+// ShutdownSnapshotPath가 있으면 이때 버퍼에 남은 Step을 스냅샷 파일에 기록한다
+// 다시 호출하면 첫 Stop이 끝나기를 기다려 같은 에러를 반환한다
+func (t *Tracer) Stop(ctx context.Context) error {
+	t.mu.Lock()
+	if t.stopped != nil {
+		stopped := t.stopped
+		t.mu.Unlock()
+		select {
+		case <-stopped:
+			return t.stopErr
+		case <-ctx.Done():
+			return fmt.Errorf("trace: stop did not finish draining: %w", ctx.Err())
+		}
+	}
+	t.stopped = make(chan struct{})
+	defer close(t.stopped)
+	t.running = false
+	t.stopJanitor()
+	t.stopReplayer()
+	if t.shards != nil {
+		t.shards.close()
+	} else {
+		close(t.buffer)
+	}
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		// 워커가 남은 Step을 flush한 뒤 종료하므로 워커를 먼저 기다린다
+		t.workersWG.Wait()
+		t.flushWG.Wait()
+		close(done)
+	}()
+
+	var errs []error
+	drained := true
+	select {
+	case <-done:
+	case <-ctx.Done():
+		drained = false
+		errs = append(errs, fmt.Errorf("trace: stop did not finish draining: %w", ctx.Err()))
+	}
+	if !drained {
+		if err := t.writeShutdownSnapshot(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Sink가 연결을 닫기 전에 남은 장부 증가분 저장
+	if err := t.ledger.flush(); err != nil {
+		errs = append(errs, fmt.Errorf("trace: %w", err))
+	}
+	// 배치 저장 없이 모인 저장하지 않은 요청 수(WithCountSkipped)를 롤업 테이블에 더함
+	t.rollup(nil)
+	if t.spill != nil {
+		if err := t.spill.close(); err != nil {
+			errs = append(errs, fmt.Errorf("trace: failed to close spill file: %w", err))
+		}
+	}
+	if err := t.cfg.Sink.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("trace: failed to close sink: %w", err))
+	}
+
+	if dropped, failed := t.droppedSteps.Load(), t.failedSteps.Load(); drained && (dropped > 0 || failed > 0) {
+		errs = append(errs, fmt.Errorf("trace: lost %d steps (dropped: %d, failed to store: %d)", dropped+failed, dropped, failed))
+	}
+	t.stopErr = errors.Join(errs...)
+	return t.stopErr
+}
@@ -0,0 +1,96 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"trace/internal/trace"
+)
+
+// blockingSink - release가 닫힐 때까지 Write를 막는 Sink
+type blockingSink struct {
+	release chan struct{}
+	closed  atomic.Int32
+}
+
+func (s *blockingSink) Write(ctx context.Context, steps []trace.Step) error {
+	<-s.release
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	s.closed.Add(1)
+	return nil
+}
+
+func TestStopClosesSinkOnTimeoutAndRepeatsFirstError(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	defer close(sink.release)
+	tracer, err := trace.New(trace.Config{Sink: sink, FlushInterval: time.Hour, BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, tracer, "/slow?user_id=u&access_token=x")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	first := tracer.Stop(ctx)
+	if !errors.Is(first, context.DeadlineExceeded) {
+		t.Fatalf("Stop = %v, want deadline exceeded while the sink is blocked", first)
+	}
+	if n := sink.closed.Load(); n != 1 {
+		t.Fatalf("sink closed %d times after a timed-out Stop, want 1", n)
+	}
+
+	second := tracer.Stop(context.Background())
+	if second == nil || second.Error() != first.Error() {
+		t.Fatalf("second Stop = %v, want the first error %v", second, first)
+	}
+	if n := sink.closed.Load(); n != 1 {
+		t.Fatalf("sink closed %d times after repeated Stop, want 1", n)
+	}
+}
+
+func TestStopTimeoutWritesShutdownSnapshot(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	defer close(sink.release)
+	path := filepath.Join(t.TempDir(), "shutdown.dlq")
+	tracer, err := trace.New(trace.Config{Sink: sink, FlushInterval: time.Hour, BatchSize: 1, ShutdownSnapshotPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const requests = 5
+	for i := range requests {
+		serve(t, tracer, fmt.Sprintf("/r%d?user_id=u&access_token=x", i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := tracer.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop = %v, want deadline exceeded", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var paths []string
+	report, err := trace.ReadDLQFile(f, func(s trace.Step) error {
+		paths = append(paths, s.Path)
+		return nil
+	})
+	if err != nil || !report.Valid() {
+		t.Fatalf("snapshot report %+v, err %v", report, err)
+	}
+	// Sink에서 막힌 배치와 버퍼에 남은 Step 모두 스냅샷에 남는다
+	if len(paths) != requests {
+		t.Fatalf("snapshot has %d steps (%v), want all %d unsaved steps", len(paths), paths, requests)
+	}
+}
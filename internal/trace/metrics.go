@@ -0,0 +1,177 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// histogram - Prometheus 히스토그램과 같은 누적 버킷 (단위: 초)
+type histogram struct {
+	bounds  []float64
+	buckets []atomic.Int64 // bounds[i] 이하인 관측 수 (누적 아님)
+	count   atomic.Int64
+	sumNs   atomic.Int64
+}
+
+func newHistogram(bounds ...float64) *histogram {
+	return &histogram{bounds: bounds, buckets: make([]atomic.Int64, len(bounds))}
+}
+
+// newFlushDurationHistogram - sink.Write 소요 시간 히스토그램
+func newFlushDurationHistogram() *histogram {
+	return newHistogram(0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10)
+}
+
+// newPipelineLagHistogram - 버퍼 진입부터 저장까지 걸린 시간 히스토그램 (FlushInterval 검증과 장애 후 적체 확인용)
+func newPipelineLagHistogram() *histogram {
+	return newHistogram(0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600)
+}
+
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	for i, bound := range h.bounds {
+		if seconds <= bound {
+			h.buckets[i].Add(1)
+			break
+		}
+	}
+	h.count.Add(1)
+	h.sumNs.Add(int64(d))
+}
+
+// summary - 관측 수, 평균과 백분위 (백분위는 버킷 상한, 마지막 버킷을 넘으면 마지막 상한)
+func (h *histogram) summary() LagStats {
+	count := h.count.Load()
+	if count == 0 {
+		return LagStats{}
+	}
+	stats := LagStats{
+		Count: count,
+		AvgMs: float64(h.sumNs.Load()) / float64(count) / float64(time.Millisecond),
+	}
+	counts := make([]int64, len(h.buckets))
+	for i := range h.buckets {
+		counts[i] = h.buckets[i].Load()
+	}
+	for _, pct := range []struct {
+		p   float64
+		dst *int64
+	}{{0.50, &stats.P50Ms}, {0.95, &stats.P95Ms}, {0.99, &stats.P99Ms}} {
+		rank := int64(float64(count-1) * pct.p)
+		*pct.dst = int64(h.bounds[len(h.bounds)-1] * 1000)
+		var seen int64
+		for i, n := range counts {
+			if seen += n; seen > rank {
+				*pct.dst = int64(h.bounds[i] * 1000)
+				break
+			}
+		}
+	}
+	return stats
+}
+
+// bufferDepth - 버퍼와 샤드에서 저장을 기다리는 Step 수
+func (t *Tracer) bufferDepth() int {
+	depth, _ := t.bufferUsage()
+	return depth
+}
+
+// bufferUsage - 버퍼와 샤드에서 저장을 기다리는 Step 수와 전체 크기 (중지 후에는 0)
+func (t *Tracer) bufferUsage() (depth, capacity int) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.running {
+		return 0, 0
+	}
+	if t.shards != nil {
+		for _, q := range t.shards.queues {
+			depth += len(q)
+			capacity += cap(q)
+		}
+		return depth, capacity
+	}
+	return len(t.buffer), cap(t.buffer)
+}
+
+// metricsHandler - 요청 시점의 Tracer 지표를 노출하는 핸들러 (Tracer가 없으면 노출하지 않음)
+func metricsHandler(tracer func() *Tracer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		t := tracer()
+		if t == nil {
+			return
+		}
+		bw := bufio.NewWriter(w)
+		t.writeMetrics(bw)
+		bw.Flush()
+	})
+}
+
+func (t *Tracer) writeMetrics(w *bufio.Writer) {
+	// Config.Name이 있으면 모든 지표에 tracer 라벨을 붙여 여러 Tracer의 지표를 구분
+	var label string
+	if t.cfg.Name != "" {
+		label = "tracer=" + strconv.Quote(t.cfg.Name)
+	}
+	labels := func(extra string) string {
+		switch {
+		case label == "" && extra == "":
+			return ""
+		case label == "":
+			return "{" + extra + "}"
+		case extra == "":
+			return "{" + label + "}"
+		}
+		return "{" + label + "," + extra + "}"
+	}
+
+	counter := func(name, help string, value int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s%s %d\n", name, help, name, name, labels(""), value)
+	}
+	counter("trace_steps_received_total", "Steps accepted into the trace pipeline.", t.stepsReceived.Load())
+	counter("trace_steps_dropped_total", "Steps dropped because the buffer was full.", t.droppedSteps.Load())
+	counter("trace_steps_failed_total", "Steps that could not be stored after retries.", t.failedSteps.Load())
+	counter("trace_flush_attempts_total", "Sink write attempts, including retries.", t.flushAttempts.Load())
+	counter("trace_flush_failures_total", "Sink write attempts that returned an error.", t.flushFailures.Load())
+	counter("trace_retention_deleted_total", "Steps deleted by the retention cleanup.", t.retentionDeleted.Load())
+	counter("trace_steps_spilled_total", "Steps written to the spill file.", t.spilledSteps.Load())
+	counter("trace_steps_replayed_total", "Steps stored from the spill file.", t.replayedSteps.Load())
+	counter("trace_steps_dead_lettered_total", "Steps passed to the dead-letter handler.", t.deadLettered.Load())
+
+	fmt.Fprintf(w, "# HELP trace_buffer_depth Steps waiting in the buffer.\n# TYPE trace_buffer_depth gauge\ntrace_buffer_depth%s %d\n", labels(""), t.bufferDepth())
+
+	// series - 히스토그램 하나의 버킷, 합계, 개수 (extra는 추가 라벨)
+	series := func(name string, h *histogram, extra string) {
+		join := func(l string) string {
+			if extra == "" {
+				return l
+			}
+			if l == "" {
+				return extra
+			}
+			return extra + "," + l
+		}
+		var cumulative int64
+		for i, bound := range h.bounds {
+			cumulative += h.buckets[i].Load()
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(join("le=\""+strconv.FormatFloat(bound, 'g', -1, 64)+"\"")), cumulative)
+		}
+		count := h.count.Load()
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(join(`le="+Inf"`)), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, labels(join("")), strconv.FormatFloat(time.Duration(h.sumNs.Load()).Seconds(), 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, labels(join("")), count)
+	}
+
+	const name = "trace_flush_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Duration of sink write attempts.\n# TYPE %s histogram\n", name, name)
+	series(name, t.flushDuration, "")
+
+	const lag = "trace_pipeline_lag_seconds"
+	fmt.Fprintf(w, "# HELP %s Time from buffer entry to a successful sink write, by provenance (replayed: stored from the spill file).\n# TYPE %s histogram\n", lag, lag)
+	series(lag, t.pipelineLag, `provenance="live"`)
+	series(lag, t.replayedLag, `provenance="replayed"`)
+}
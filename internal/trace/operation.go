@@ -0,0 +1,132 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 기본 Config.OperationDeadline
+const defaultOperationDeadline = time.Hour
+
+// 작업 Step의 Phase 값
+const (
+	PhaseCheckpoint = "checkpoint" // 진행 상황 (Checkpoint)
+	PhaseFinished   = "finished"   // 정상 종료 또는 에러로 종료 (Finish)
+	PhaseAbandoned  = "abandoned"  // OperationDeadline까지 Finish를 호출하지 않음
+)
+
+// Operation 배치 작업 등 오래 걸리는 작업 하나 (StartOperation)
+// 여러 고루틴에서 함께 사용할 수 있다
+type Operation struct {
+	tracer *Tracer
+	step   Step // 작업 Step의 공통 값 (Trace ID, span ID, 작업 이름)
+	start  time.Time
+	timer  *time.Timer
+
+	mu    sync.Mutex
+	ended bool
+}
+
+// StartOperation - 기본 Tracer로 작업 시작 (StartOperation 참고, Start 전이면 아무것도 기록하지 않는 작업 반환)
+func StartOperation(ctx context.Context, name string) *Operation {
+	return defaultTracer.Load().StartOperation(ctx, name)
+}
+
+// StartOperation - 오래 걸리는 작업 시작
+// Checkpoint마다 진행 상황을 하위 Step(Phase "checkpoint")으로 바로 저장하고, Finish하면 작업 Step(Phase "finished")을 저장한다
+// OperationDeadline까지 Finish를 호출하지 않으면(작업 고루틴이 panic으로 끝난 경우 등) Phase "abandoned"인 작업 Step을 대신 저장한다
+// ctx에 Trace ID(요청 컨텍스트, ContextWithTraceID)가 있으면 같은 trace로, 없으면 새 Trace ID로 기록한다
+func (t *Tracer) StartOperation(ctx context.Context, name string) *Operation {
+	if t == nil {
+		return &Operation{ended: true}
+	}
+	traceID, ok := FromContext(ctx)
+	if !ok {
+		traceID = newSpanID() + newSpanID()
+	}
+	userID, _ := UserIDFromContext(ctx)
+	op := &Operation{
+		tracer: t,
+		step: Step{
+			TraceID: traceID,
+			UserID:  userID,
+			Path:    name,
+			SpanID:  newSpanID(),
+		},
+		start: time.Now(),
+	}
+	// 기한이 매우 짧아도 timer를 설정한 뒤에 abandon이 실행되도록 잠금
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.timer = time.AfterFunc(t.cfg.OperationDeadline, op.abandon)
+	return op
+}
+
+// Checkpoint - 진행 상황 기록 (progress는 0~1, 작업이 끝난 뒤에는 무시)
+// label은 하위 Step의 SpanName으로, progress는 Extra({"progress": 0.4})로 저장된다
+func (op *Operation) Checkpoint(label string, progress float64) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if op.ended {
+		return
+	}
+	step := op.step
+	step.ParentSpanID, step.SpanID = op.step.SpanID, newSpanID()
+	step.SpanName = label
+	step.Phase = PhaseCheckpoint
+	extra, _ := json.Marshal(map[string]float64{"progress": min(max(progress, 0), 1)})
+	step.Extra = string(extra)
+	op.emit(step)
+}
+
+// Finish - 작업 종료 기록 (err가 있으면 상태 코드 500과 에러 메시지 저장, 두 번째 호출부터는 무시)
+// panic에도 기록되도록 작업 고루틴에서 defer로 호출한다
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			op.Finish(fmt.Errorf("panic: %v", r))
+//			panic(r)
+//		}
+//	}()
+func (op *Operation) Finish(err error) {
+	status, message := http.StatusOK, ""
+	if err != nil {
+		status, message = http.StatusInternalServerError, err.Error()
+	}
+	op.end(PhaseFinished, status, message)
+}
+
+// abandon - OperationDeadline까지 Finish를 호출하지 않은 작업 종료
+func (op *Operation) abandon() {
+	op.end(PhaseAbandoned, http.StatusInternalServerError, fmt.Sprintf("operation not finished within %s", op.tracer.cfg.OperationDeadline))
+}
+
+func (op *Operation) end(phase string, status int, message string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if op.ended {
+		return
+	}
+	op.ended = true
+	op.timer.Stop()
+
+	step := op.step
+	step.Phase = phase
+	step.StatusCode = status
+	step.Error = truncateUTF8(message, maxErrorLen)
+	step.LatencyMs = time.Since(op.start).Milliseconds()
+	op.emit(step)
+}
+
+// emit - Step을 Tracer 버퍼에 넣음 (Tracer가 중지되었으면 버림)
+func (op *Operation) emit(step Step) {
+	now := time.Now()
+	step.CreatedAt = now.Unix()
+	step.EnqueuedAtNs = now.UnixNano()
+	step.enqueuedAt = now
+	op.tracer.enqueue(step)
+}
@@ -0,0 +1,125 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func newOperationTracer(t *testing.T, deadline time.Duration) *trace.Tracer {
+	t.Helper()
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t), OperationDeadline: deadline})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer
+}
+
+func TestOperationCheckpointsAndFinish(t *testing.T) {
+	tracer := newOperationTracer(t, time.Minute)
+	ctx := trace.ContextWithUserID(trace.ContextWithTraceID(context.Background(), "nightly-trace"), "ops")
+	op := tracer.StartOperation(ctx, "nightly-export")
+	op.Checkpoint("fetched", 0.25)
+	op.Checkpoint("converted", 0.75)
+	op.Finish(errors.New("upload failed"))
+	op.Finish(nil)
+	op.Checkpoint("late", 1)
+	stopTracer(t, tracer)
+
+	steps := storedSteps(t, tracer, trace.QueryFilter{TraceID: "nightly-trace"})
+	if len(steps) != 3 {
+		t.Fatalf("stored %d steps, want 2 checkpoints and 1 finish: %+v", len(steps), steps)
+	}
+	var finished trace.Step
+	checkpoints := map[string]trace.Step{}
+	for _, step := range steps {
+		if step.Path != "nightly-export" || step.UserID != "ops" {
+			t.Errorf("step not tied to the operation: %+v", step)
+		}
+		switch step.Phase {
+		case trace.PhaseCheckpoint:
+			checkpoints[step.SpanName] = step
+		case trace.PhaseFinished:
+			finished = step
+		default:
+			t.Errorf("unexpected phase %q", step.Phase)
+		}
+	}
+	if finished.StatusCode != http.StatusInternalServerError || finished.Error != "upload failed" || finished.SpanName != "" {
+		t.Fatalf("finish step = %+v", finished)
+	}
+	if c := checkpoints["converted"]; c.Extra != `{"progress":0.75}` || c.ParentSpanID != finished.SpanID {
+		t.Fatalf("checkpoint = %+v, want progress 0.75 under span %s", c, finished.SpanID)
+	}
+	if _, ok := checkpoints["late"]; ok {
+		t.Fatal("checkpoint after Finish was stored")
+	}
+}
+
+func TestOperationAbandoned(t *testing.T) {
+	tracer := newOperationTracer(t, 50*time.Millisecond)
+	op := tracer.StartOperation(context.Background(), "reindex")
+	done := make(chan struct{})
+	go func() {
+		// 작업 고루틴이 panic으로 끝나 Finish를 호출하지 못한 경우
+		defer close(done)
+		defer func() { recover() }()
+		op.Checkpoint("started", 0.1)
+		panic("worker crashed")
+	}()
+	<-done
+	time.Sleep(100 * time.Millisecond)
+	op.Finish(nil)
+	stopTracer(t, tracer)
+
+	steps := storedSteps(t, tracer, trace.QueryFilter{PathPrefix: "reindex", RequestsOnly: true})
+	if len(steps) != 1 {
+		t.Fatalf("stored %d terminal steps, want 1: %+v", len(steps), steps)
+	}
+	if step := steps[0]; step.Phase != trace.PhaseAbandoned || step.StatusCode != http.StatusInternalServerError || step.LatencyMs < 50 {
+		t.Fatalf("terminal step = %+v, want abandoned after the deadline", step)
+	}
+}
+
+func TestOperationConcurrentCheckpoints(t *testing.T) {
+	tracer := newOperationTracer(t, time.Minute)
+	op := tracer.StartOperation(context.Background(), "backfill")
+	const workers, perWorker = 8, 25
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				op.Checkpoint(fmt.Sprintf("worker-%d", w), float64(i)/perWorker)
+			}
+		}()
+	}
+	wg.Wait()
+	op.Finish(nil)
+	stopTracer(t, tracer)
+
+	result, err := tracer.Store().Query(context.Background(), trace.QueryFilter{PathPrefix: "backfill", Limit: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checkpoints, finished int
+	for _, step := range result.Steps {
+		switch step.Phase {
+		case trace.PhaseCheckpoint:
+			checkpoints++
+		case trace.PhaseFinished:
+			finished++
+		}
+	}
+	if checkpoints != workers*perWorker || finished != 1 {
+		t.Fatalf("stored %d checkpoints and %d finish steps, want %d and 1", checkpoints, finished, workers*perWorker)
+	}
+}
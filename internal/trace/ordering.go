@@ -0,0 +1,81 @@
+package trace
+
+import (
+	"hash/fnv"
+)
+
+// 기본 샤드 수
+const defaultOrderingShards = 8
+
+// orderedShards - 사용자 ID 해시로 나눈 순차 저장 큐
+// 한 사용자의 Step은 항상 같은 샤드로 들어가고, 샤드는 배치를 하나씩 동기적으로 저장한다
+type orderedShards struct {
+	queues   []chan Step
+	overflow OverflowPolicy
+	drop     func(Step)
+}
+
+func (t *Tracer) startShards(schedule flushSchedule) {
+	cfg := t.cfg
+	n := cfg.OrderingShards
+	if n <= 0 {
+		n = defaultOrderingShards
+	}
+	size := cfg.ShardBufferSize
+	if size <= 0 {
+		size = max(cfg.BufferSize/n, 1)
+	}
+
+	s := &orderedShards{
+		queues:   make([]chan Step, n),
+		overflow: cfg.Overflow,
+		drop:     t.overflowed,
+	}
+	for i := range s.queues {
+		s.queues[i] = make(chan Step, size)
+		// 다음 배치를 읽기 전에 저장을 마치도록 동기적으로 저장
+		t.runWorker(s.queues[i], schedule, min(cfg.BatchSize, size), t.flushBatch)
+	}
+	t.shards = s
+}
+
+func (s *orderedShards) enqueue(step Step) {
+	queue := s.queues[shardIndex(step.UserID, len(s.queues))]
+	s.overflow.push(queue, step, s.drop)
+}
+
+func shardIndex(userID string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	return int(h.Sum32() % uint32(n))
+}
+
+// ShardDepths - 기본 Tracer의 PerUserOrdering 샤드별 대기 중인 Step 수 (비활성화 시 nil)
+func ShardDepths() []int {
+	return defaultTracer.Load().ShardDepths()
+}
+
+// ShardDepths - PerUserOrdering 샤드별 대기 중인 Step 수 (비활성화 시 nil)
+func (t *Tracer) ShardDepths() []int {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	s := t.shards
+	t.mu.RUnlock()
+	if s == nil {
+		return nil
+	}
+	depths := make([]int, len(s.queues))
+	for i, q := range s.queues {
+		depths[i] = len(q)
+	}
+	return depths
+}
+
+// close - 샤드 큐를 닫아 워커가 남은 Step을 저장하고 종료하도록 함
+func (s *orderedShards) close() {
+	for _, q := range s.queues {
+		close(q)
+	}
+}
@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// orderSink - 저장한 순서대로 Step을 기록하고 Write마다 무작위로 지연하는 Sink
+type orderSink struct {
+	mu   sync.Mutex
+	rng  *rand.Rand
+	rows []Step
+}
+
+func (s *orderSink) Write(ctx context.Context, steps []Step) error {
+	s.mu.Lock()
+	delay := time.Duration(s.rng.Intn(3)) * time.Millisecond
+	s.mu.Unlock()
+	// 잠금 밖에서 지연하여 다른 샤드의 저장이 앞지를 수 있도록 함
+	time.Sleep(delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = append(s.rows, steps...)
+	return nil
+}
+
+func (s *orderSink) Close() error { return nil }
+
+func TestPerUserOrderingWithInterleavedUsers(t *testing.T) {
+	const shards, perUser = 4, 50
+	// 서로 다른 샤드로 가는 두 사용자
+	users := []string{"alice"}
+	for i := 0; len(users) < 2; i++ {
+		if user := fmt.Sprintf("user-%d", i); shardIndex(user, shards) != shardIndex(users[0], shards) {
+			users = append(users, user)
+		}
+	}
+
+	sink := &orderSink{rng: rand.New(rand.NewSource(1))}
+	tracer, err := New(Config{
+		Sink:            sink,
+		BatchSize:       3,
+		FlushInterval:   time.Millisecond,
+		PerUserOrdering: true,
+		OrderingShards:  shards,
+		ShardBufferSize: perUser * 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depths := tracer.ShardDepths(); len(depths) != shards {
+		t.Fatalf("ShardDepths = %v, want %d shards", depths, shards)
+	}
+
+	r := gin.New()
+	r.Use(tracer.Middleware())
+	// Step.Path는 라우트 패턴이므로 요청 순서를 경로로 구분할 수 있게 번호마다 라우트를 등록
+	for i := range perUser {
+		r.GET(fmt.Sprintf("/steps/%d", i), func(c *gin.Context) { c.Status(200) })
+	}
+
+	// 사용자마다 요청을 순서대로 보내되 두 사용자의 요청은 동시에 섞여 들어감
+	var wg sync.WaitGroup
+	for _, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perUser {
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", fmt.Sprintf("/steps/%d?user_id=%s&access_token=a", i, user), nil))
+			}
+		}()
+	}
+	wg.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 사용자별로는 Seq와 요청 순서대로 저장됨
+	last := map[string]Step{}
+	count := map[string]int{}
+	for _, row := range sink.rows {
+		if want := fmt.Sprintf("/steps/%d", count[row.UserID]); row.Path != want {
+			t.Fatalf("%s: stored %s at position %d, want %s", row.UserID, row.Path, count[row.UserID], want)
+		}
+		if prev, ok := last[row.UserID]; ok && row.Seq <= prev.Seq {
+			t.Fatalf("%s: Seq %d stored after %d", row.UserID, row.Seq, prev.Seq)
+		}
+		last[row.UserID] = row
+		count[row.UserID]++
+	}
+	for _, user := range users {
+		if count[user] != perUser {
+			t.Fatalf("%s: stored %d steps, want %d", user, count[user], perUser)
+		}
+	}
+
+	// 전체 저장 순서는 Seq 순서와 다를 수 있음 (샤드끼리는 병렬로 저장)
+	interleaved := false
+	for i := 1; i < len(sink.rows); i++ {
+		if sink.rows[i].UserID != sink.rows[i-1].UserID {
+			interleaved = true
+		}
+	}
+	t.Logf("users %v, rows interleaved = %v", users, interleaved)
+}
@@ -0,0 +1,125 @@
+package trace
+
+import (
+	"fmt"
+	"time"
+)
+
+// OverflowPolicy 버퍼가 가득 찼을 때의 처리 방식 (DropNewest, DropOldest, Block, BlockWithTimeout)
+type OverflowPolicy struct {
+	mode    overflowMode
+	timeout time.Duration
+}
+
+type overflowMode int
+
+const (
+	overflowDropNewest overflowMode = iota
+	overflowDropOldest
+	overflowBlock
+	overflowBlockTimeout
+)
+
+var (
+	// DropNewest 새 Step을 버림 (기본값, 요청 처리를 막지 않음)
+	DropNewest = OverflowPolicy{mode: overflowDropNewest}
+	// DropOldest 버퍼에서 가장 오래된 Step을 버리고 새 Step을 넣음 (최근 Step 우선)
+	DropOldest = OverflowPolicy{mode: overflowDropOldest}
+	// Block 버퍼에 자리가 날 때까지 요청 처리를 대기 (저장소가 느리면 응답도 느려짐)
+	Block = OverflowPolicy{mode: overflowBlock}
+)
+
+// BlockWithTimeout 버퍼에 자리가 날 때까지 최대 timeout 동안 대기한 뒤 새 Step을 버림
+func BlockWithTimeout(timeout time.Duration) OverflowPolicy {
+	return OverflowPolicy{mode: overflowBlockTimeout, timeout: timeout}
+}
+
+func (p OverflowPolicy) String() string {
+	switch p.mode {
+	case overflowDropOldest:
+		return "drop_oldest"
+	case overflowBlock:
+		return "block"
+	case overflowBlockTimeout:
+		return fmt.Sprintf("block_with_timeout(%s)", p.timeout)
+	default:
+		return "drop_newest"
+	}
+}
+
+// push - 정책에 따라 ch에 step을 넣고, 넣지 못하거나 밀려난 Step은 drop으로 전달
+func (p OverflowPolicy) push(ch chan Step, step Step, drop func(Step)) {
+	select {
+	case ch <- step:
+		return
+	default:
+	}
+
+	switch p.mode {
+	case overflowBlock:
+		ch <- step
+	case overflowBlockTimeout:
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+		select {
+		case ch <- step:
+		case <-timer.C:
+			drop(step)
+		}
+	case overflowDropOldest:
+		// 워커가 동시에 꺼내 갈 수 있으므로 넣을 때까지 반복
+		for {
+			select {
+			case oldest := <-ch:
+				drop(oldest)
+			default:
+			}
+			select {
+			case ch <- step:
+				return
+			default:
+			}
+		}
+	default:
+		drop(step)
+	}
+}
+
+// DropReason Step을 버린 이유 (Config.OnDrop)
+type DropReason int
+
+const (
+	// DropBufferFull 버퍼가 가득 차 Overflow 정책에 따라 버림 (SpillPath가 있으면 spill 파일도 가득 찬 경우)
+	DropBufferFull DropReason = iota + 1
+	// DropOverload 적응형 샘플링(WithAdaptiveSampling)이 과부하 보호를 위해 제외함
+	// 본문, 헤더 등 저장 직전에 채우는 값은 비어 있다
+	DropOverload
+)
+
+func (r DropReason) String() string {
+	switch r {
+	case DropBufferFull:
+		return "buffer_full"
+	case DropOverload:
+		return "overload"
+	default:
+		return fmt.Sprintf("DropReason(%d)", int(r))
+	}
+}
+
+// drop - OnDrop 콜백 호출
+func (t *Tracer) drop(step Step, reason DropReason) {
+	if t == nil {
+		return
+	}
+	if t.ledger != nil {
+		// 과부하로 제외한 Step은 버퍼에 넣기 전이라 서비스 이름이 비어 있다
+		stamped := step
+		t.stamp(&stamped)
+		t.ledger.dropped(stamped, reason)
+	}
+	if t.cfg.OnDrop == nil {
+		return
+	}
+	callHook("OnDrop", func() { t.cfg.OnDrop(step, reason) })
+}
@@ -0,0 +1,90 @@
+package trace
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PanicMode 핸들러 panic 처리 방식
+type PanicMode int
+
+const (
+	// PanicPropagate panic을 처리하지 않음 (기본값, Step이 기록되지 않음)
+	PanicPropagate PanicMode = iota
+	// PanicRecover panic을 복구하고 500으로 응답 (gin.Recovery 대체)
+	PanicRecover
+	// PanicRecordAndRepanic Step을 기록한 뒤 다시 panic (앞단의 gin.Recovery 등이 응답)
+	PanicRecordAndRepanic
+)
+
+const (
+	// Step.Panic 최대 길이
+	maxPanicLen = 1024
+	// Step.PanicStack 최대 길이
+	maxPanicStackLen = 4096
+)
+
+// WithPanicRecovery 핸들러 panic 처리 방식 설정
+// 복구한 panic은 값과 스택 트레이스 앞부분이 Step에 기록되고 상태 코드는 500으로 저장된다
+func WithPanicRecovery(mode PanicMode) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.PanicMode = mode
+	}
+}
+
+// recoveredPanic 핸들러에서 복구한 panic
+type recoveredPanic struct {
+	value any
+	stack string
+}
+
+// message - Step.Panic에 기록할 panic 값
+func (p *recoveredPanic) message() string {
+	return truncateUTF8(fmt.Sprint(p.value), maxPanicLen)
+}
+
+// next - 설정에 따라 panic을 복구하며 다음 핸들러 실행
+func next(c *gin.Context, mode PanicMode) (p *recoveredPanic) {
+	if mode == PanicPropagate {
+		c.Next()
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			p = &recoveredPanic{value: r, stack: truncateUTF8(string(debug.Stack()), maxPanicStackLen)}
+		}
+	}()
+	c.Next()
+	return nil
+}
+
+// finishPanic - Step 기록 후 panic 처리 마무리 (응답 또는 다시 panic)
+func finishPanic(c *gin.Context, mode PanicMode, p *recoveredPanic) {
+	if p == nil {
+		return
+	}
+	// 연결을 끊기 위한 panic은 net/http가 처리하도록 그대로 전달
+	if mode == PanicRecordAndRepanic || p.value == http.ErrAbortHandler {
+		panic(p.value)
+	}
+
+	log.Printf("panic recovered: %v\n%s", p.value, p.stack)
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	c.AbortWithStatus(http.StatusInternalServerError)
+}
+
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,79 @@
+package trace
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithRouteParams 라우트 파라미터 저장 방식 설정 (기본: 모든 파라미터를 값 그대로 Step.Params에 저장)
+// hash의 파라미터는 HMAC 해시(TraceIDSecret 사용)로, exclude의 파라미터는 저장하지 않는다
+// 해시는 배포 내에서 같은 값이면 같으므로 원문 없이도 같은 리소스의 요청을 묶어 볼 수 있다
+func WithRouteParams(hash []string, exclude []string) MiddlewareOption {
+	hashed, excluded := slices.Clone(hash), slices.Clone(exclude)
+	return func(config *MiddlewareConfig) {
+		config.HashParams = hashed
+		config.ExcludeParams = excluded
+	}
+}
+
+// captureParams - 라우트 파라미터를 JSON 객체로 인코딩 (파라미터가 없으면 빈 값)
+func captureParams(c *gin.Context, config *MiddlewareConfig, tracer *Tracer) string {
+	if len(c.Params) == 0 {
+		return ""
+	}
+
+	params := make(map[string]string, len(c.Params))
+	for _, p := range c.Params {
+		switch {
+		case slices.Contains(config.ExcludeParams, p.Key):
+		case slices.Contains(config.HashParams, p.Key):
+			params[p.Key] = redactHeaderValue(p.Value, tracer.secret())
+		default:
+			params[p.Key] = p.Value
+		}
+	}
+	if len(params) == 0 {
+		return ""
+	}
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		log.Printf("failed to encode trace route params: %v", err)
+		return ""
+	}
+	return string(encoded)
+}
+
+// httpParams - Go 1.22+ ServeMux 패턴(예: "GET /users/{id}")의 와일드카드 값
+func httpParams(r *http.Request) gin.Params {
+	var params gin.Params
+	pattern := r.Pattern
+	for {
+		start := strings.IndexByte(pattern, '{')
+		if start < 0 {
+			return params
+		}
+		end := strings.IndexByte(pattern[start:], '}')
+		if end < 0 {
+			return params
+		}
+		name := strings.TrimSuffix(pattern[start+1:start+end], "...")
+		pattern = pattern[start+end+1:]
+		if name == "" || name == "$" {
+			continue
+		}
+		params = append(params, gin.Param{Key: name, Value: r.PathValue(name)})
+	}
+}
+
+// paramFragment - Step.Params JSON에서 name=value를 찾기 위한 LIKE 패턴 조각
+func paramFragment(name, value string) string {
+	key, _ := json.Marshal(name)
+	val, _ := json.Marshal(value)
+	return escapeLike(string(key) + ":" + string(val))
+}
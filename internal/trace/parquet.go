@@ -0,0 +1,286 @@
+package trace
+
+import (
+	"encoding/binary"
+	"io"
+	"reflect"
+)
+
+// Parquet 행 그룹 크기 (행 그룹마다 Step을 메모리에 모아 컬럼 단위로 기록)
+const parquetRowGroupSize = 10000
+
+// Parquet 형식 상수 (parquet-format의 parquet.thrift)
+const (
+	parquetMagic = "PAR1"
+
+	parquetBoolean   = 0 // Type
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0 // FieldRepetitionType
+	parquetUTF8     = 0 // ConvertedType
+	parquetPlain    = 0 // Encoding
+	parquetRLE      = 3
+	parquetDataPage = 0 // PageType
+)
+
+// Thrift compact protocol 타입
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// parquetEncoder - 의존성 없이 Parquet 파일을 기록하는 최소 구현
+// 모든 컬럼은 REQUIRED이고 PLAIN 인코딩, 비압축이며, 행 그룹마다 컬럼별 데이터 페이지 하나를 기록한다
+type parquetEncoder struct {
+	w       io.Writer
+	columns []exportColumn
+	offset  int64 // 지금까지 기록한 바이트 수 (컬럼 청크 위치)
+	pending []Step
+	groups  []parquetRowGroup
+	rows    int64
+	err     error
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+	bytes  int64
+}
+
+type parquetChunk struct {
+	offset int64 // 데이터 페이지 헤더 위치
+	size   int64 // 페이지 헤더 포함 크기
+}
+
+func newParquetEncoder(w io.Writer, columns []exportColumn) *parquetEncoder {
+	return &parquetEncoder{w: w, columns: columns}
+}
+
+func (e *parquetEncoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+	e.offset += int64(len(b))
+}
+
+func (e *parquetEncoder) encode(steps []Step) error {
+	if e.offset == 0 {
+		e.write([]byte(parquetMagic))
+	}
+	for len(steps) > 0 {
+		n := min(len(steps), parquetRowGroupSize-len(e.pending))
+		e.pending = append(e.pending, steps[:n]...)
+		steps = steps[n:]
+		if len(e.pending) == parquetRowGroupSize {
+			e.flushRowGroup()
+		}
+	}
+	return e.err
+}
+
+func parquetType(kind reflect.Kind) int32 {
+	switch kind {
+	case reflect.String:
+		return parquetByteArray
+	case reflect.Bool:
+		return parquetBoolean
+	default:
+		return parquetInt64
+	}
+}
+
+// flushRowGroup - 모아 둔 Step을 컬럼별 데이터 페이지로 기록
+func (e *parquetEncoder) flushRowGroup() {
+	if len(e.pending) == 0 {
+		return
+	}
+	group := parquetRowGroup{rows: int64(len(e.pending))}
+	var data []byte
+	for _, c := range e.columns {
+		// PLAIN 인코딩 (REQUIRED 컬럼이므로 definition/repetition level 없음)
+		data = data[:0]
+		switch c.kind {
+		case reflect.String:
+			for i := range e.pending {
+				s := c.value(&e.pending[i]).String()
+				data = binary.LittleEndian.AppendUint32(data, uint32(len(s)))
+				data = append(data, s...)
+			}
+		case reflect.Bool:
+			// 값 하나에 1비트, 하위 비트부터 채움
+			data = append(data, make([]byte, (len(e.pending)+7)/8)...)
+			for i := range e.pending {
+				if c.value(&e.pending[i]).Bool() {
+					data[i/8] |= 1 << (i % 8)
+				}
+			}
+		default:
+			for i := range e.pending {
+				data = binary.LittleEndian.AppendUint64(data, uint64(c.value(&e.pending[i]).Int()))
+			}
+		}
+
+		var header thriftWriter
+		header.begin()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.structField(5) // DataPageHeader
+		header.i32(1, int32(len(e.pending)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.end()
+		header.end()
+
+		chunk := parquetChunk{offset: e.offset, size: int64(len(header.buf) + len(data))}
+		e.write(header.buf)
+		e.write(data)
+		group.chunks = append(group.chunks, chunk)
+		group.bytes += chunk.size
+	}
+	e.groups = append(e.groups, group)
+	e.rows += group.rows
+	e.pending = e.pending[:0]
+}
+
+// close - 남은 행 그룹과 스키마, 행 그룹 위치를 담은 footer(FileMetaData) 기록
+func (e *parquetEncoder) close() error {
+	if e.offset == 0 {
+		e.write([]byte(parquetMagic))
+	}
+	e.flushRowGroup()
+
+	var meta thriftWriter
+	meta.begin()
+	meta.i32(1, 1) // version
+	meta.list(2, thriftStruct, len(e.columns)+1)
+	meta.begin() // 루트 스키마
+	meta.binary(4, "step")
+	meta.i32(5, int32(len(e.columns)))
+	meta.end()
+	for _, c := range e.columns {
+		meta.begin()
+		meta.i32(1, parquetType(c.kind))
+		meta.i32(3, parquetRequired)
+		meta.binary(4, c.name)
+		if c.kind == reflect.String {
+			meta.i32(6, parquetUTF8)
+		}
+		meta.end()
+	}
+	meta.i64(3, e.rows)
+	meta.list(4, thriftStruct, len(e.groups))
+	for _, g := range e.groups {
+		meta.begin()
+		meta.list(1, thriftStruct, len(g.chunks))
+		for i, chunk := range g.chunks {
+			meta.begin()
+			meta.i64(2, chunk.offset)
+			meta.structField(3) // ColumnMetaData
+			meta.i32(1, parquetType(e.columns[i].kind))
+			meta.list(2, thriftI32, 2)
+			meta.zigzag(parquetPlain)
+			meta.zigzag(parquetRLE)
+			meta.list(3, thriftBinary, 1)
+			meta.bytes(e.columns[i].name)
+			meta.i32(4, 0) // UNCOMPRESSED
+			meta.i64(5, g.rows)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.end()
+			meta.end()
+		}
+		meta.i64(2, g.bytes)
+		meta.i64(3, g.rows)
+		meta.end()
+	}
+	meta.binary(6, "go-trace-middleware")
+	meta.end()
+
+	e.write(meta.buf)
+	e.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta.buf))))
+	e.write([]byte(parquetMagic))
+	return e.err
+}
+
+// thriftWriter - Parquet 메타데이터용 Thrift compact protocol 인코더 (필요한 타입만 지원)
+type thriftWriter struct {
+	buf   []byte
+	last  int16   // 현재 구조체에서 마지막으로 기록한 필드 ID
+	stack []int16 // 바깥 구조체의 last
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (t *thriftWriter) bytes(s string) {
+	t.varint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// field - 필드 헤더 (이전 필드 ID와의 차이가 1~15면 한 바이트에 함께 기록)
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.zigzag(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.bytes(s)
+}
+
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elem)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|elem)
+	t.varint(uint64(n))
+}
+
+// begin - 구조체 시작 (최상위 구조체나 리스트 원소)
+func (t *thriftWriter) begin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+// structField - 구조체 타입 필드 시작
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// end - 구조체 끝 (STOP)
+func (t *thriftWriter) end() {
+	t.buf = append(t.buf, 0)
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
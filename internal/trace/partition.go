@@ -0,0 +1,289 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Step 기본 테이블 이름
+const defaultStepTable = "steps"
+
+// 일별 테이블 이름의 날짜 형식 (예: steps_20240601)
+const partitionDateLayout = "20060102"
+
+// stepTables - Step 테이블 구성 (Tracer와 Store가 각자 보관, 조회 API와 보관 기간 정리가 사용)
+type stepTables struct {
+	base  string
+	daily bool // true면 생성일(UTC)별 테이블에 저장
+}
+
+// qualifiedTableName - 설정의 Step 테이블 이름 (스키마가 있으면 "schema.table")
+func qualifiedTableName(cfg Config) string {
+	table := cfg.TableName
+	if table == "" {
+		table = defaultStepTable
+	}
+	if cfg.TableSchema != "" {
+		return cfg.TableSchema + "." + table
+	}
+	return table
+}
+
+// validIdentifier - 테이블/스키마 이름으로 허용하는 문자 (조회 시 SQL에 그대로 들어가므로 제한)
+func validIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateTableNames - TableName, TableSchema 검사
+func validateTableNames(cfg Config) error {
+	if cfg.TableName != "" && !validIdentifier(cfg.TableName) {
+		return fmt.Errorf("trace: TableName %q may only contain letters, digits and underscores", cfg.TableName)
+	}
+	if cfg.TableSchema != "" && !validIdentifier(cfg.TableSchema) {
+		return fmt.Errorf("trace: TableSchema %q may only contain letters, digits and underscores", cfg.TableSchema)
+	}
+	return nil
+}
+
+// partitionName - Step 생성 시각이 속한 일별 테이블 이름
+func (t stepTables) partitionName(createdAt int64) string {
+	return t.base + "_" + time.Unix(createdAt, 0).UTC().Format(partitionDateLayout)
+}
+
+// partition 일별 테이블
+type partition struct {
+	name string
+	day  time.Time // 테이블이 담는 날짜의 00:00 UTC
+}
+
+// partitions - DB에 있는 일별 테이블을 날짜순으로 반환
+// 테이블 목록에는 스키마가 붙지 않으므로 스키마를 뺀 이름으로 비교하고, 반환하는 이름에는 스키마를 붙인다
+func (t stepTables) partitions(db *gorm.DB) ([]partition, error) {
+	schema, base, qualified := strings.Cut(t.base, ".")
+	if !qualified {
+		schema, base = "", t.base
+	}
+	var tables []string
+	var err error
+	if schema != "" {
+		// GetTables는 현재 스키마의 테이블만 반환한다
+		err = db.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = ?", schema).Scan(&tables).Error
+	} else {
+		tables, err = db.Migrator().GetTables()
+	}
+	if err != nil {
+		return nil, err
+	}
+	prefix := base + "_"
+	var parts []partition
+	for _, name := range tables {
+		suffix, ok := strings.CutPrefix(name, prefix)
+		if !ok || len(suffix) != len(partitionDateLayout) {
+			continue
+		}
+		day, err := time.Parse(partitionDateLayout, suffix)
+		if err != nil {
+			continue
+		}
+		if schema != "" {
+			name = schema + "." + name
+		}
+		parts = append(parts, partition{name: name, day: day})
+	}
+	// GetTables는 정렬을 보장하지 않는다
+	slices.SortFunc(parts, func(a, b partition) int { return a.day.Compare(b.day) })
+	return parts, nil
+}
+
+var (
+	stepColumnsOnce sync.Once
+	stepColumns     []string
+)
+
+// stepColumnNames - Step 모델의 컬럼 목록
+func stepColumnNames(db *gorm.DB) []string {
+	stepColumnsOnce.Do(func() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(&Step{}); err == nil {
+			stepColumns = stmt.Schema.DBNames
+		}
+	})
+	return stepColumns
+}
+
+// stepColumnList - Step의 컬럼 목록 (SELECT *에 의존하지 않고 이 바이너리가 아는 컬럼만 조회)
+func stepColumnList(db *gorm.DB) string {
+	return strings.Join(stepColumnNames(db), ", ")
+}
+
+// 테이블 컬럼 목록 캐시 유지 시간 (새 버전 writer가 컬럼을 추가하면 이 시간 안에 조회에 반영)
+const tableColumnsTTL = time.Minute
+
+type tableColumnsKey struct {
+	config *gorm.Config // 같은 DB의 세션은 Config를 공유한다
+	table  string
+}
+
+type tableColumnsEntry struct {
+	columns map[string]bool
+	loaded  time.Time
+}
+
+var tableColumnsCache sync.Map // tableColumnsKey -> tableColumnsEntry
+
+// existingColumns - table에 실제로 있는 컬럼 (tableColumnsTTL 동안 캐시)
+func existingColumns(db *gorm.DB, table string) (map[string]bool, error) {
+	key := tableColumnsKey{config: db.Config, table: table}
+	if v, ok := tableColumnsCache.Load(key); ok {
+		if entry := v.(tableColumnsEntry); time.Since(entry.loaded) < tableColumnsTTL {
+			return entry.columns, nil
+		}
+	}
+	columnTypes, err := db.Table(table).Migrator().ColumnTypes(&Step{})
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool, len(columnTypes))
+	for _, ct := range columnTypes {
+		columns[ct.Name()] = true
+	}
+	tableColumnsCache.Store(key, tableColumnsEntry{columns: columns, loaded: time.Now()})
+	return columns, nil
+}
+
+// selectColumns - Step 컬럼 중 tables에 모두 있는 컬럼 목록
+// 구버전 writer가 만든 테이블에 없는 신규 컬럼은 조회하지 않으므로 해당 필드는 0 값이 된다
+// 컬럼을 하나도 찾지 못하면(테이블이 없는 경우 등) 모델의 전체 목록을 반환하여 DB 에러를 그대로 전달한다
+func selectColumns(db *gorm.DB, tables ...string) (string, error) {
+	names := stepColumnNames(db)
+	present := slices.Clone(names)
+	for _, table := range tables {
+		columns, err := existingColumns(db, table)
+		if err != nil {
+			return "", err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		present = slices.DeleteFunc(present, func(name string) bool { return !columns[name] })
+	}
+	if len(present) == 0 {
+		return stepColumnList(db), nil
+	}
+	return strings.Join(present, ", "), nil
+}
+
+// stepsFrom - [from, to) 구간의 Step을 조회할 쿼리 생성 함수 반환
+// 일별 테이블을 사용하면 구간에 걸친 테이블을 UNION ALL로 묶은 서브쿼리를 조회하며, from/to가 0이면 해당 방향으로 제한하지 않는다
+// 반환된 함수는 호출할 때마다 새 조회 조건을 만든다 (테이블 목록은 한 번만 조회)
+func (s *Store) stepsFrom(ctx context.Context, from, to time.Time) (func() *gorm.DB, error) {
+	db, tables := s.db, s.tables
+	if !tables.daily {
+		return func() *gorm.DB {
+			return db.WithContext(ctx).Model(&Step{}).Table(tables.base)
+		}, nil
+	}
+
+	parts, err := tables.partitions(db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var selected []string
+	for _, p := range parts {
+		if !from.IsZero() && !p.day.Add(24*time.Hour).After(from) {
+			continue
+		}
+		if !to.IsZero() && !p.day.Before(to) {
+			continue
+		}
+		selected = append(selected, p.name)
+	}
+
+	scanned := selected
+	if len(scanned) == 0 && len(parts) > 0 {
+		scanned = []string{parts[0].name}
+	}
+	columns, err := selectColumns(db.WithContext(ctx), scanned...)
+	if err != nil {
+		return nil, err
+	}
+	var union string
+	switch {
+	case len(selected) > 0:
+		queries := make([]string, len(selected))
+		for i, name := range selected {
+			queries[i] = "SELECT " + columns + " FROM " + name
+		}
+		union = strings.Join(queries, " UNION ALL ")
+	case len(parts) > 0:
+		// 구간에 해당하는 테이블이 없으면 빈 결과
+		union = "SELECT " + columns + " FROM " + parts[0].name + " WHERE 1 = 0"
+	default:
+		// 아직 저장된 Step이 없으면 기본 테이블 조회 (AutoMigrate 전이면 DB 에러)
+		union = "SELECT " + columns + " FROM " + tables.base
+	}
+	return func() *gorm.DB {
+		return db.WithContext(ctx).Model(&Step{}).Table("(" + union + ") AS steps")
+	}, nil
+}
+
+// partitionWriter - 일별 테이블 생성 및 저장 (GormSink에서 사용)
+type partitionWriter struct {
+	tables  stepTables
+	created sync.Map // 이미 생성(마이그레이션)한 테이블 이름
+}
+
+// migrateExisting - 기존 일별 테이블에 새 컬럼 추가 (UNION 조회 시 컬럼이 일치해야 함)
+func (w *partitionWriter) migrateExisting(db *gorm.DB) error {
+	parts, err := w.tables.partitions(db)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if err := db.Table(p.name).AutoMigrate(&Step{}); err != nil {
+			return err
+		}
+		w.created.Store(p.name, true)
+	}
+	return nil
+}
+
+// write - Step을 생성일별로 나누어 각 테이블에 저장 (없으면 생성)
+func (w *partitionWriter) write(ctx context.Context, db *gorm.DB, steps []Step) error {
+	groups := make(map[string][]Step)
+	var order []string
+	for _, step := range steps {
+		name := w.tables.partitionName(step.CreatedAt)
+		if _, ok := groups[name]; !ok {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], step)
+	}
+
+	for _, name := range order {
+		if _, ok := w.created.Load(name); !ok {
+			if err := db.WithContext(ctx).Table(name).AutoMigrate(&Step{}); err != nil {
+				return err
+			}
+			w.created.Store(name, true)
+		}
+		if err := db.WithContext(ctx).Table(name).CreateInBatches(groups[name], gormInsertBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
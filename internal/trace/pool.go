@@ -0,0 +1,70 @@
+package trace
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// 커넥션 풀 사용 방식 (EffectiveConfig에 노출)
+const (
+	poolModeShared   = "shared"   // 애플리케이션 풀을 그대로 사용 (설정 변경 없음)
+	poolModeManaged  = "managed"  // 애플리케이션 풀을 함께 사용하며 풀 설정을 적용
+	poolModeSeparate = "separate" // trace 전용 커넥션 풀 사용
+)
+
+// 전용 커넥션 풀의 기본 최대 연결 수
+const defaultSeparateMaxOpenConn = 2
+
+func poolMode(cfg Config) string {
+	switch {
+	case cfg.SeparateConnection:
+		return poolModeSeparate
+	case cfg.ManagePool:
+		return poolModeManaged
+	default:
+		return poolModeShared
+	}
+}
+
+// writerDB - 설정에 따라 Step 저장에 사용할 DB 준비
+// SeparateConnection이면 같은 Dialector로 새 풀을 열어 애플리케이션 연결을 소모하지 않는다
+func writerDB(cfg Config) (*gorm.DB, error) {
+	switch poolMode(cfg) {
+	case poolModeSeparate:
+		db, err := gorm.Open(cfg.DB.Dialector, &gorm.Config{Logger: cfg.DB.Logger})
+		if err != nil {
+			return nil, err
+		}
+		if db.ConnPool == cfg.DB.ConnPool {
+			// 기존 *sql.DB로 만든 Dialector는 같은 풀을 재사용하므로 분리할 수 없다
+			return nil, errors.New("trace: SeparateConnection requires a dialector opened from a DSN, not an existing connection")
+		}
+		maxOpen := cfg.MaxOpenConn
+		if maxOpen <= 0 {
+			maxOpen = defaultSeparateMaxOpenConn
+		}
+		if err := applyPoolSettings(db, maxOpen, cfg.MaxIdleConn, cfg); err != nil {
+			return nil, err
+		}
+		return db, nil
+	case poolModeManaged:
+		if err := applyPoolSettings(cfg.DB, cfg.MaxOpenConn, cfg.MaxIdleConn, cfg); err != nil {
+			return nil, err
+		}
+		return cfg.DB, nil
+	default:
+		return cfg.DB, nil
+	}
+}
+
+func applyPoolSettings(db *gorm.DB, maxOpen, maxIdle int, cfg Config) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(maxOpen)
+	sqlDB.SetMaxIdleConns(maxIdle)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return nil
+}
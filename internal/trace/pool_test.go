@@ -0,0 +1,77 @@
+package trace_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestSharedPoolSettings(t *testing.T) {
+	tests := []struct {
+		name              string
+		managePool        bool
+		mode              string
+		maxOpen, idleKept int
+	}{
+		// 애플리케이션이 설정한 풀 (최대 37개, 유휴 1개)을 그대로 둠
+		{"shared", false, "shared", 37, 1},
+		// ManagePool이면 trace 설정 (최대 3개, 유휴 3개)을 적용
+		{"managed", true, "managed", 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := tracetest.NewTempDB(t)
+			sqlDB, err := db.DB()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sqlDB.SetMaxOpenConns(37)
+			sqlDB.SetMaxIdleConns(1)
+			sqlDB.SetConnMaxLifetime(time.Hour)
+
+			tracer, err := trace.New(trace.Config{
+				DB:              db,
+				MaxOpenConn:     3,
+				MaxIdleConn:     3,
+				ConnMaxLifetime: time.Minute,
+				ManagePool:      tt.managePool,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range 5 {
+				serve(t, tracer, "/orders?user_id=u&access_token=a")
+			}
+			if mode := tracer.EffectiveConfig().PoolMode; mode != tt.mode {
+				t.Errorf("PoolMode = %q, want %q", mode, tt.mode)
+			}
+			stopTracer(t, tracer)
+
+			if got := sqlDB.Stats().MaxOpenConnections; got != tt.maxOpen {
+				t.Errorf("MaxOpenConnections = %d, want %d", got, tt.maxOpen)
+			}
+			// 연결 3개를 동시에 쓰고 반납하면 유휴 연결 한도만큼만 남음
+			ctx := context.Background()
+			var conns []*sql.Conn
+			for range 3 {
+				conn, err := sqlDB.Conn(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				conns = append(conns, conn)
+			}
+			for _, conn := range conns {
+				if err := conn.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if idle := sqlDB.Stats().Idle; idle != tt.idleKept {
+				t.Errorf("idle connections = %d, want %d", idle, tt.idleKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// flakySink - 처음 failures번은 실패하고 이후에는 sink에 저장하는 Sink (failures가 음수면 항상 실패)
+type flakySink struct {
+	sink     trace.Sink
+	mu       sync.Mutex
+	failures int
+}
+
+func (s *flakySink) Write(ctx context.Context, steps []trace.Step) error {
+	s.mu.Lock()
+	fail := s.failures != 0
+	if s.failures > 0 {
+		s.failures--
+	}
+	s.mu.Unlock()
+	if fail {
+		return errors.New("sink down")
+	}
+	return s.sink.Write(ctx, steps)
+}
+
+func (s *flakySink) Close() error { return nil }
+
+// provenances - db에서 provenance 조건에 맞는 Step의 Provenance 목록
+func provenances(t *testing.T, db *gorm.DB, provenance string) []string {
+	t.Helper()
+	result, err := trace.Query(context.Background(), db, trace.QueryFilter{Provenance: provenance})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, step := range result.Steps {
+		got = append(got, step.Provenance)
+	}
+	return got
+}
+
+func TestProvenanceOfEachWritePath(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	gormSink, err := trace.NewGormSink(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 정상 경로
+	live, err := trace.New(trace.Config{Sink: gormSink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, live, "/live?user_id=u&access_token=a")
+	stopTracer(t, live)
+
+	// 첫 저장에 실패하고 재시도로 저장
+	retried, err := trace.New(trace.Config{Sink: &flakySink{sink: gormSink, failures: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, retried, "/retry?user_id=u&access_token=a")
+	stopTracer(t, retried)
+
+	// 재시도까지 모두 실패해 보조 Sink에 저장
+	fallback, err := trace.New(trace.Config{Sink: &flakySink{sink: gormSink, failures: -1}, FallbackSink: gormSink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, fallback, "/fallback?user_id=u&access_token=a")
+	stopTracer(t, fallback)
+
+	// dead-letter 파일에서 다시 저장
+	var dlq bytes.Buffer
+	dw := trace.NewDLQWriter(&dlq)
+	for _, path := range []string{"/dlq/1", "/dlq/2", "/dlq/3"} {
+		if err := dw.Write(trace.Step{TraceID: "dead", UserID: "u", Path: path, Method: "GET", Provenance: "retry:2"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replayer, err := trace.New(trace.Config{Sink: gormSink, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := replayer.ReplayDLQFile(context.Background(), &dlq)
+	if err != nil || n != 3 {
+		t.Fatalf("ReplayDLQFile = %d, %v, want 3 steps", n, err)
+	}
+	stopTracer(t, replayer)
+
+	tests := []struct {
+		provenance string
+		want       []string
+	}{
+		{"live", []string{""}},
+		{"retry", []string{"retry:2,dlq", "retry:2,dlq", "retry:2,dlq", "retry:2,fallback", "retry:1"}},
+		{"fallback", []string{"retry:2,fallback"}},
+		{"dlq", []string{"retry:2,dlq", "retry:2,dlq", "retry:2,dlq"}},
+		{"spill", nil},
+		{"", []string{"retry:2,dlq", "retry:2,dlq", "retry:2,dlq", "retry:2,fallback", "retry:1", ""}},
+	}
+	for _, tt := range tests {
+		got := provenances(t, db, tt.provenance)
+		if len(got) != len(tt.want) {
+			t.Errorf("provenance %q: got %q, want %q", tt.provenance, got, tt.want)
+			continue
+		}
+		// 같은 초에 저장되어 순서가 정해지지 않으므로 개수로 비교
+		count := map[string]int{}
+		for _, p := range got {
+			count[p]++
+		}
+		for _, p := range tt.want {
+			count[p]--
+		}
+		for p, n := range count {
+			if n != 0 {
+				t.Errorf("provenance %q: got %q, want %q (mismatch on %q)", tt.provenance, got, tt.want, p)
+				break
+			}
+		}
+	}
+
+	// 조회 API의 provenance 파라미터
+	r := gin.New()
+	trace.APIRoutes(r.Group("/debug"), db, trace.WithAPIAuth(func(c *gin.Context) {}))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/traces?provenance=fallback", nil))
+	var result trace.QueryResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("GET /traces?provenance=fallback: %d %s", w.Code, w.Body)
+	}
+	if len(result.Steps) != 1 || result.Steps[0].Path != "/fallback" {
+		t.Fatalf("GET /traces?provenance=fallback = %+v, want the /fallback step", result.Steps)
+	}
+}
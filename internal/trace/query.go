@@ -0,0 +1,178 @@
+package trace
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Query 페이지 크기 기본값과 최댓값
+const (
+	defaultQueryLimit = 50
+	maxQueryLimit     = 1000
+)
+
+// QueryFilter 저장된 Step 조회 조건 (빈 값인 조건은 적용하지 않음)
+type QueryFilter struct {
+	UserID      string        `json:"user_id,omitempty"`
+	TenantID    string        `json:"tenant_id,omitempty"`
+	SessionID   string        `json:"session_id,omitempty"`
+	Country     string        `json:"country,omitempty"` // 국가 ISO 코드 (Config.GeoResolver)
+	ServiceName string        `json:"service_name,omitempty"`
+	Environment string        `json:"environment,omitempty"`
+	Version     string        `json:"version,omitempty"`
+	TraceID     string        `json:"trace_id,omitempty"`
+	CaptureID   string        `json:"capture_id,omitempty"` // 디버그 캡처 ID (StartDebugCapture)
+	PathPrefix  string        `json:"path_prefix,omitempty"`
+	MinStatus   int           `json:"min_status,omitempty"`  // 이상
+	MaxStatus   int           `json:"max_status,omitempty"`  // 이하
+	MinLatency  time.Duration `json:"min_latency,omitempty"` // 이상
+	From        time.Time     `json:"from,omitempty"`        // 이상
+	To          time.Time     `json:"to,omitempty"`          // 미만
+	// 라우트 파라미터 값이 모두 일치하는 Step만 조회 (예: {"id": "123"}, 해시로 저장한 파라미터는 해시 값으로 조회)
+	Params map[string]string `json:"params,omitempty"`
+	// true면 StartSpan 등으로 기록한 하위 span을 제외하고 요청 Step만 조회
+	RequestsOnly bool `json:"requests_only,omitempty"`
+	// 저장까지 거친 경로 태그 이름 (retry, spill, dlq, fallback, import), "live"면 태그 없이 정상 경로로 저장한 Step
+	Provenance string `json:"provenance,omitempty"`
+
+	Limit  int `json:"limit,omitempty"` // 기본 50, 최대 1000
+	Offset int `json:"offset,omitempty"`
+}
+
+// QueryResult 조회 결과 한 페이지
+type QueryResult struct {
+	Steps []Step `json:"steps"`
+	// 다음 페이지가 있으면 true (NextOffset으로 이어서 조회)
+	HasMore    bool `json:"has_more"`
+	NextOffset int  `json:"next_offset,omitempty"`
+}
+
+// Query - db에서 조건에 맞는 Step을 최신순으로 조회 (기본 Tracer의 테이블 구성과 암호화 키 사용)
+func Query(ctx context.Context, db *gorm.DB, filter QueryFilter) (QueryResult, error) {
+	return storeFor(db).Query(ctx, filter)
+}
+
+// Query - 조건에 맞는 Step을 최신순으로 조회
+func (s *Store) Query(ctx context.Context, filter QueryFilter) (QueryResult, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	limit = min(limit, maxQueryLimit)
+	offset := max(filter.Offset, 0)
+
+	tx, err := s.filteredSteps(ctx, filter)
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	// 다음 페이지 여부를 알기 위해 하나 더 조회
+	var steps []Step
+	err = tx.Order("created_at DESC, seq DESC").
+		Limit(limit + 1).
+		Offset(offset).
+		Find(&steps).Error
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	s.cipher.decryptSteps(steps)
+	result := QueryResult{Steps: steps}
+	if len(steps) > limit {
+		result.Steps = steps[:limit]
+		result.HasMore = true
+		result.NextOffset = offset + limit
+	}
+	return result, nil
+}
+
+// filteredSteps - QueryFilter의 조건(Limit, Offset 제외)을 적용한 Step 조회 쿼리
+func (s *Store) filteredSteps(ctx context.Context, filter QueryFilter) (*gorm.DB, error) {
+	scope, err := s.stepsFrom(ctx, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+	tx := scope()
+	if !s.tables.daily {
+		// 일별 테이블의 UNION은 이미 컬럼을 나열하므로 단일 테이블만 지정
+		columns, err := selectColumns(s.db.WithContext(ctx), s.tables.base)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Select(columns)
+	}
+	if filter.UserID != "" {
+		tx = tx.Where("user_id = ?", s.cipher.encrypt("user_id", filter.UserID))
+	}
+	if filter.TenantID != "" {
+		tx = tx.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.SessionID != "" {
+		tx = tx.Where("session_id = ?", filter.SessionID)
+	}
+	if filter.Country != "" {
+		tx = tx.Where("country = ?", filter.Country)
+	}
+	if filter.ServiceName != "" {
+		tx = tx.Where("service_name = ?", filter.ServiceName)
+	}
+	if filter.Environment != "" {
+		tx = tx.Where("environment = ?", filter.Environment)
+	}
+	if filter.Version != "" {
+		tx = tx.Where("version = ?", filter.Version)
+	}
+	if filter.TraceID != "" {
+		tx = tx.Where("trace_id = ?", filter.TraceID)
+	}
+	if filter.CaptureID != "" {
+		tx = tx.Where("capture_id = ?", filter.CaptureID)
+	}
+	if filter.Provenance != "" {
+		tx = whereProvenance(tx, filter.Provenance)
+	}
+	if filter.PathPrefix != "" {
+		tx = tx.Where("path LIKE ? ESCAPE '!'", escapeLike(filter.PathPrefix)+"%")
+	}
+	if filter.MinStatus > 0 {
+		tx = tx.Where("status_code >= ?", filter.MinStatus)
+	}
+	if filter.MaxStatus > 0 {
+		tx = tx.Where("status_code <= ?", filter.MaxStatus)
+	}
+	if filter.MinLatency > 0 {
+		tx = tx.Where("latency_ms >= ?", filter.MinLatency.Milliseconds())
+	}
+	if !filter.From.IsZero() {
+		tx = tx.Where("created_at >= ?", filter.From.Unix())
+	}
+	if !filter.To.IsZero() {
+		tx = tx.Where("created_at < ?", filter.To.Unix())
+	}
+	for name, value := range filter.Params {
+		tx = tx.Where("params LIKE ? ESCAPE '!'", "%"+paramFragment(name, value)+"%")
+	}
+	if filter.RequestsOnly {
+		tx = tx.Where(requestStepsOnly)
+	}
+	return tx, nil
+}
+
+// whereProvenance - 쉼표로 구분된 Provenance에 name 태그("retry:2"처럼 값이 붙은 태그 포함)가 있는 Step만 조회
+func whereProvenance(tx *gorm.DB, name string) *gorm.DB {
+	if name == "live" {
+		return tx.Where("(provenance = '' OR provenance IS NULL)")
+	}
+	tag := escapeLike(name)
+	return tx.Where("(provenance = ? OR provenance LIKE ? ESCAPE '!' OR provenance LIKE ? ESCAPE '!' OR provenance LIKE ? ESCAPE '!' OR provenance LIKE ? ESCAPE '!' OR provenance LIKE ? ESCAPE '!')",
+		name, tag+":%", tag+",%", "%,"+tag, "%,"+tag+":%", "%,"+tag+",%")
+}
+
+// escapeLike - LIKE 패턴의 특수 문자 이스케이프
+// MySQL은 문자열 안의 '\'를 이스케이프로 해석하므로 DB 종류와 무관한 '!'를 사용한다
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,62 @@
+package trace
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// 저장할 쿼리 문자열 최대 길이
+const maxQueryStringLen = 2048
+
+// 마스킹한 쿼리 값
+const redactedQueryValue = "REDACTED"
+
+// redact에 없어도 항상 마스킹하는 쿼리 키 (기본 토큰 추출 함수가 읽는 키 포함)
+var alwaysRedactedQueryKeys = []string{"access_token", "token", "password", "secret", "api_key"}
+
+// WithQueryCapture 요청 쿼리 문자열 저장 설정 (기본 비활성화)
+// redact의 키(대소문자 무시)는 값을 REDACTED로 바꾸어 Step.Query에 기록된다
+// access_token, token, password, secret, api_key는 redact에 없어도 항상 마스킹된다
+// 잘못된 형식을 확인할 수 있도록 나머지 부분은 원문 그대로 저장하며 최대 길이를 넘으면 잘린다
+func WithQueryCapture(redact ...string) MiddlewareOption {
+	keys := slices.Clone(alwaysRedactedQueryKeys)
+	for _, key := range redact {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return func(config *MiddlewareConfig) {
+		config.CaptureQuery = true
+		config.RedactQueryKeys = keys
+	}
+}
+
+// captureQuery - 마스킹한 쿼리 문자열 (설정이 없거나 쿼리가 없으면 빈 값)
+func captureQuery(rawQuery string, config *MiddlewareConfig) string {
+	if !config.CaptureQuery || rawQuery == "" {
+		return ""
+	}
+
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		rawKey, _, hasValue := strings.Cut(pair, "=")
+		if !hasValue {
+			continue
+		}
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		if slices.Contains(config.RedactQueryKeys, strings.ToLower(key)) {
+			pairs[i] = rawKey + "=" + redactedQueryValue
+		}
+	}
+
+	query := strings.Join(pairs, "&")
+	if len(query) > maxQueryStringLen {
+		query = query[:maxQueryStringLen] + truncatedBodySuffix
+	}
+	return query
+}
@@ -0,0 +1,111 @@
+package trace
+
+import (
+	"log"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// WithRedactor Step이 버퍼에 들어가기 전에 적용할 함수 (하위 span 포함, 지정한 순서대로 적용)
+// 반환한 Step이 저장되며 nil을 반환하면 저장하지 않는다. panic이 발생해도 원본이 저장되지 않도록 버린다
+// 여러 번 지정하면 모두 적용되며, 내장 redactor(MaskEmails, HashTokens, StripQueryParams)와 함께 쓸 수 있다
+func WithRedactor(redactors ...func(*Step) *Step) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		// Derive로 복사한 설정과 배열을 공유하지 않도록 새 슬라이스에 추가
+		config.Redactors = slices.Concat(config.Redactors, redactors)
+	}
+}
+
+// redact - 설정된 redactor를 순서대로 적용 (nil이면 저장하지 않음)
+func (config *MiddlewareConfig) redact(step *Step) (redacted *Step) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in trace redactor, step dropped: %v", r)
+			redacted = nil
+		}
+	}()
+	for _, fn := range config.Redactors {
+		if step = fn(step); step == nil {
+			return nil
+		}
+	}
+	return step
+}
+
+// textFields - 개인정보가 들어갈 수 있는 Step의 문자열 필드
+func textFields(step *Step) []*string {
+	return []*string{
+		&step.UserID, &step.Params, &step.Query, &step.Extra, &step.RequestBody, &step.ResponseBody,
+		&step.Headers, &step.Error, &step.Panic,
+	}
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+// MaskEmails - 문자열 필드(UserID, Query, 본문, 헤더, 에러 등)의 이메일 주소를 첫 글자와 도메인만 남기고 마스킹
+// 예: john.doe@example.com → j***@example.com
+func MaskEmails() func(*Step) *Step {
+	return func(step *Step) *Step {
+		for _, field := range textFields(step) {
+			*field = emailPattern.ReplaceAllStringFunc(*field, func(email string) string {
+				local, domain, _ := strings.Cut(email, "@")
+				return local[:1] + "***@" + domain
+			})
+		}
+		return step
+	}
+}
+
+var (
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)([A-Za-z0-9._~+/-]+=*)`)
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+)
+
+// HashTokens - 문자열 필드의 Bearer 토큰과 JWT를 HMAC 해시(hmac:...)로 대체
+// 같은 토큰은 같은 해시가 되므로 원문 없이도 같은 토큰의 요청을 묶어 볼 수 있다 (secret이 비어 있으면 프로세스별 랜덤 키)
+func HashTokens(secret []byte) func(*Step) *Step {
+	key := func() []byte {
+		if len(secret) > 0 {
+			return secret
+		}
+		return traceSecret()
+	}
+	return func(step *Step) *Step {
+		for _, field := range textFields(step) {
+			value := bearerPattern.ReplaceAllStringFunc(*field, func(match string) string {
+				parts := bearerPattern.FindStringSubmatch(match)
+				return parts[1] + redactHeaderValue(parts[2], key())
+			})
+			*field = jwtPattern.ReplaceAllStringFunc(value, func(token string) string {
+				return redactHeaderValue(token, key())
+			})
+		}
+		return step
+	}
+}
+
+// StripQueryParams - Step.Query에서 keys(대소문자 무시)를 키와 값 모두 제거
+func StripQueryParams(keys ...string) func(*Step) *Step {
+	stripped := make([]string, 0, len(keys))
+	for _, key := range keys {
+		stripped = append(stripped, strings.ToLower(key))
+	}
+	return func(step *Step) *Step {
+		if step.Query == "" {
+			return step
+		}
+		pairs := strings.Split(step.Query, "&")
+		pairs = slices.DeleteFunc(pairs, func(pair string) bool {
+			rawKey, _, _ := strings.Cut(pair, "=")
+			key, err := url.QueryUnescape(rawKey)
+			if err != nil {
+				key = rawKey
+			}
+			return slices.Contains(stripped, strings.ToLower(key))
+		})
+		step.Query = strings.Join(pairs, "&")
+		return step
+	}
+}
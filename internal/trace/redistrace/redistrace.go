@@ -0,0 +1,89 @@
+// redistrace 패키지 - go-redis 명령을 trace 하위 span으로 기록하는 Hook
+package redistrace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"trace/internal/trace"
+)
+
+// 키 패턴에서 *로 바꿀 숫자 구간 (user:42:cart -> user:*:cart)
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// Hook Redis 명령을 현재 요청의 하위 span("REDIS GET user:*:cart")으로 기록하는 go-redis Hook
+// 키에는 사용자 ID 등이 들어가므로 숫자 구간을 *로 바꾼 키 패턴만 기록하고, redis.Nil(캐시 미스)은 에러로 보지 않는다
+// trace 미들웨어가 수집하지 않는 요청의 컨텍스트면 아무것도 기록하지 않는다
+type Hook struct{}
+
+// NewHook - Hook 생성 (rdb.AddHook(redistrace.NewHook()))
+func NewHook() Hook {
+	return Hook{}
+}
+
+// DialHook - 연결은 기록하지 않음
+func (Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook - 명령 하나를 span으로 기록
+func (Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		span := trace.StartSpanFromContext(ctx, "REDIS "+commandName(cmd))
+		err := next(ctx, cmd)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			span.SetError(err)
+		}
+		span.End()
+		return err
+	}
+}
+
+// ProcessPipelineHook - 파이프라인(MULTI 포함) 전체를 span 하나로 기록 ("REDIS PIPELINE GET user:*:cart +2")
+func (Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		name := "REDIS PIPELINE"
+		if len(cmds) > 0 {
+			name += " " + commandName(cmds[0])
+		}
+		if len(cmds) > 1 {
+			name += fmt.Sprintf(" +%d", len(cmds)-1)
+		}
+		span := trace.StartSpanFromContext(ctx, name)
+		err := next(ctx, cmds)
+		// 파이프라인 자체는 성공해도 명령별 에러가 있으면 첫 에러를 span에 기록
+		spanErr := err
+		if spanErr == nil {
+			for _, cmd := range cmds {
+				if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+					spanErr = cmdErr
+					break
+				}
+			}
+		}
+		if spanErr != nil && !errors.Is(spanErr, redis.Nil) {
+			span.SetError(spanErr)
+		}
+		span.End()
+		return err
+	}
+}
+
+// commandName - 명령 이름과 첫 번째 키의 패턴
+func commandName(cmd redis.Cmder) string {
+	name := strings.ToUpper(cmd.Name())
+	if args := cmd.Args(); len(args) > 1 {
+		name += " " + KeyPattern(fmt.Sprint(args[1]))
+	}
+	return name
+}
+
+// KeyPattern - 키의 숫자 구간을 *로 바꾼 패턴 (user:42:cart -> user:*:cart)
+func KeyPattern(key string) string {
+	return digits.ReplaceAllString(key, "*")
+}
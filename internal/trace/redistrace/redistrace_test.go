@@ -0,0 +1,84 @@
+package redistrace_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"trace/internal/trace"
+	"trace/internal/trace/redistrace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestHookRecordsCommandsAsSpans(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook := redistrace.NewHook()
+	errDown := errors.New("connection refused")
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "set" {
+			return errDown
+		}
+		return redis.Nil
+	})
+	pipeline := hook.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+		cmds[1].SetErr(errDown)
+		return nil
+	})
+
+	r := gin.New()
+	r.Use(tracer.Middleware())
+	r.GET("/cart", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		_ = process(ctx, redis.NewStringCmd(ctx, "get", "user:42:cart"))
+		_ = process(ctx, redis.NewStatusCmd(ctx, "set", "user:42:cart", "x"))
+		_ = pipeline(ctx, []redis.Cmder{
+			redis.NewStringCmd(ctx, "get", "order:7"),
+			redis.NewStringCmd(ctx, "get", "order:8"),
+		})
+		c.Status(200)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/cart?user_id=user-1&access_token=token", nil))
+
+	// 수집하지 않는 컨텍스트에서는 아무것도 기록하지 않음
+	_ = process(context.Background(), redis.NewStringCmd(context.Background(), "get", "user:1"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := tracer.Store().Query(context.Background(), trace.QueryFilter{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := map[string]string{}
+	for _, step := range result.Steps {
+		if step.SpanName != "" {
+			spans[step.SpanName] = step.Error
+		}
+	}
+	want := map[string]string{
+		"REDIS GET user:*:cart":         "",
+		"REDIS SET user:*:cart":         "connection refused",
+		"REDIS PIPELINE GET order:* +1": "connection refused",
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("spans = %v, want %v", spans, want)
+	}
+	for name, wantErr := range want {
+		if got, ok := spans[name]; !ok || got != wantErr {
+			t.Errorf("span %q error = %q (recorded %v), want %q", name, got, ok, wantErr)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 보관 기간 정리 기본값
+const (
+	defaultRetentionInterval  = time.Hour
+	defaultRetentionBatchSize = 1000
+	// 배치 사이 대기 시간 (DB 부하 분산)
+	retentionBatchPause = 100 * time.Millisecond
+)
+
+// DeleteOlderThan - before 이전에 생성된 Step을 오래된 순으로 batchSize씩 나누어 삭제
+// archive가 있으면 각 배치를 먼저 archive에 저장하고, 저장에 실패하면 삭제하지 않고 중단한다
+// PartitionByDay를 사용하면 기간이 모두 지난 일별 테이블은 통째로 삭제(DROP)한다
+// 삭제한 Step 수는 기본 Tracer의 trace_retention_deleted_total 지표에 더한다
+func DeleteOlderThan(ctx context.Context, db *gorm.DB, before time.Time, batchSize int, archive Sink) (int64, error) {
+	deleted := new(atomic.Int64)
+	if t := defaultTracer.Load(); t != nil {
+		deleted = &t.retentionDeleted
+	}
+	return deleteExpired(ctx, db, storeFor(db).tables, before, batchSize, archive, deleted)
+}
+
+// deleteExpired - tables에서 before 이전 Step 삭제 (삭제할 때마다 deleted에 더함)
+func deleteExpired(ctx context.Context, db *gorm.DB, tables stepTables, before time.Time, batchSize int, archive Sink, deleted *atomic.Int64) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = defaultRetentionBatchSize
+	}
+	if !tables.daily {
+		return deleteBatches(ctx, db, tables.base, before.Unix(), batchSize, archive, deleted)
+	}
+
+	parts, err := tables.partitions(db.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, p := range parts {
+		if !p.day.Before(before) {
+			break
+		}
+		var n int64
+		if p.day.Add(24 * time.Hour).After(before) {
+			// before가 속한 날짜의 테이블은 행 단위로 삭제
+			n, err = deleteBatches(ctx, db, p.name, before.Unix(), batchSize, archive, deleted)
+		} else {
+			n, err = dropPartition(ctx, db, p.name, batchSize, archive, deleted)
+		}
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// deleteBatches - table에서 cutoff(Unix 초) 이전 Step을 batchSize씩 삭제
+// Step 테이블에는 기본 키가 없으므로 created_at 경계로 배치를 나누며, 같은 초에 생성된 Step이 많으면 삭제 배치가 batchSize보다 커질 수 있다
+func deleteBatches(ctx context.Context, db *gorm.DB, table string, cutoff int64, batchSize int, archive Sink, deleted *atomic.Int64) (int64, error) {
+	var total int64
+	for {
+		// 이번 배치의 마지막 created_at (오래된 순으로 batchSize번째 행)
+		var bounds []int64
+		err := db.WithContext(ctx).
+			Table(table).
+			Where("created_at < ?", cutoff).
+			Order("created_at").
+			Offset(batchSize-1).
+			Limit(1).
+			Pluck("created_at", &bounds).Error
+		if err != nil {
+			return total, err
+		}
+		bound := cutoff - 1
+		if len(bounds) > 0 {
+			bound = bounds[0]
+		}
+
+		if archive != nil {
+			columns, err := selectColumns(db.WithContext(ctx), table)
+			if err != nil {
+				return total, err
+			}
+			// 같은 초에 생성된 Step이 많아도 batchSize씩 나누어 저장
+			query := db.WithContext(ctx).Table(table).Select(columns).Where("created_at <= ?", bound)
+			err = pageSteps(query, batchSize, 0, 0, func(steps []Step) error {
+				return archiveSteps(ctx, archive, steps)
+			})
+			if err != nil {
+				return total, err
+			}
+		}
+
+		result := db.WithContext(ctx).Table(table).Where("created_at <= ?", bound).Delete(&Step{})
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		deleted.Add(result.RowsAffected)
+
+		// 마지막 배치
+		if len(bounds) == 0 {
+			return total, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-time.After(retentionBatchPause):
+		}
+	}
+}
+
+// dropPartition - 기간이 모두 지난 일별 테이블 삭제 (archive가 있으면 batchSize씩 먼저 저장)
+func dropPartition(ctx context.Context, db *gorm.DB, table string, batchSize int, archive Sink, deleted *atomic.Int64) (int64, error) {
+	var count int64
+	if err := db.WithContext(ctx).Table(table).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	if archive != nil {
+		columns, err := selectColumns(db.WithContext(ctx), table)
+		if err != nil {
+			return 0, err
+		}
+		err = pageSteps(db.WithContext(ctx).Table(table).Select(columns), batchSize, 0, 0, func(steps []Step) error {
+			return archiveSteps(ctx, archive, steps)
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := db.WithContext(ctx).Migrator().DropTable(table); err != nil {
+		return 0, err
+	}
+	deleted.Add(count)
+	return count, nil
+}
+
+func archiveSteps(ctx context.Context, archive Sink, steps []Step) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	if err := archive.Write(ctx, steps); err != nil {
+		return fmt.Errorf("trace: failed to archive %d steps: %w", len(steps), err)
+	}
+	return nil
+}
+
+// startJanitor - Retention이 설정된 경우 주기적으로 보관 기간이 지난 Step을 정리
+func (t *Tracer) startJanitor() {
+	cfg := t.cfg
+	if cfg.Retention <= 0 {
+		return
+	}
+	interval := cfg.RetentionInterval
+	if interval <= 0 {
+		interval = defaultRetentionInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.janitorCancel = cancel
+
+	t.workersWG.Add(1)
+	go func() {
+		defer t.workersWG.Done()
+		timer := t.clock.NewTimer(interval)
+		defer timer.Stop()
+		idle := 0
+		stored := t.storedSteps.Load()
+		for {
+			deleted, err := deleteExpired(ctx, cfg.DB, t.tables, t.clock.Now().Add(-cfg.Retention), cfg.RetentionBatchSize, cfg.RetentionArchive, &t.retentionDeleted)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("trace retention cleanup failed after deleting %d steps: %v", deleted, err)
+			}
+			if err == nil && deleted == 0 && t.storedSteps.Load() == stored {
+				idle++
+			} else {
+				idle = 0
+			}
+			stored = t.storedSteps.Load()
+
+			wait, wake := t.janitorWait(ctx, interval, idle)
+			timerC := timer.C()
+			if wait > 0 {
+				timer.Reset(wait)
+			} else {
+				timerC = nil
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-timerC:
+			case <-wake:
+				idle = 0
+			}
+		}
+	}()
+}
+
+// janitorWait - 다음 정리까지의 대기 시간 (0이면 wake 신호까지 대기)
+// 정리할 Step도 새로 저장된 Step도 없는 상태가 이어지면 남은 Step 중 가장 오래된 Step이 만료될 때까지,
+// 남은 Step이 없으면 다음 저장까지 깨어나지 않는다
+func (t *Tracer) janitorWait(ctx context.Context, interval time.Duration, idle int) (time.Duration, <-chan struct{}) {
+	if idle < idleIntervalsBeforePark {
+		return interval, nil
+	}
+	oldest, ok, err := oldestStep(ctx, t.cfg.DB, t.tables)
+	switch {
+	case err != nil:
+		return interval, nil
+	case !ok:
+		return 0, t.storedWake
+	}
+	return max(interval, time.Unix(oldest, 0).Add(t.cfg.Retention).Sub(t.clock.Now())), nil
+}
+
+// oldestStep - 가장 오래된 Step의 생성 시각 (Step이 없으면 ok=false)
+func oldestStep(ctx context.Context, db *gorm.DB, tables stepTables) (int64, bool, error) {
+	names := []string{tables.base}
+	if tables.daily {
+		parts, err := tables.partitions(db.WithContext(ctx))
+		if err != nil {
+			return 0, false, err
+		}
+		names = names[:0]
+		for _, p := range parts {
+			names = append(names, p.name)
+		}
+	}
+	for _, name := range names {
+		var oldest []int64
+		if err := db.WithContext(ctx).Table(name).Order("created_at").Limit(1).Pluck("created_at", &oldest).Error; err != nil {
+			return 0, false, err
+		}
+		if len(oldest) > 0 {
+			return oldest[0], true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// stopJanitor - janitor 종료 요청 (t.mu 보유 상태에서 호출)
+func (t *Tracer) stopJanitor() {
+	if t.janitorCancel != nil {
+		t.janitorCancel()
+		t.janitorCancel = nil
+	}
+}
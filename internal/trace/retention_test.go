@@ -0,0 +1,50 @@
+package trace
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestJanitorSleepsUntilOldestStepExpires(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:janitor?mode=memory"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Step{}); err != nil {
+		t.Fatal(err)
+	}
+	clk := newFakeClock()
+	tracer := &Tracer{
+		cfg:        Config{DB: db, Retention: 24 * time.Hour},
+		tables:     stepTables{base: defaultStepTable},
+		clock:      clk,
+		storedWake: make(chan struct{}, 1),
+	}
+	ctx := context.Background()
+	interval := time.Hour
+
+	if wait, wake := tracer.janitorWait(ctx, interval, 0); wait != interval || wake != nil {
+		t.Fatalf("active janitor waits %s, want %s", wait, interval)
+	}
+
+	// 남은 Step이 없으면 다음 저장까지 깨어나지 않는다
+	wait, wake := tracer.janitorWait(ctx, interval, idleIntervalsBeforePark)
+	if wait != 0 || wake == nil {
+		t.Fatalf("idle janitor on an empty table waits %s (wake %v), want to park until the next store", wait, wake != nil)
+	}
+
+	// 남은 Step이 있으면 가장 오래된 Step이 만료될 때까지 대기
+	created := clk.Now().Add(-2 * time.Hour)
+	if err := db.Create(&Step{TraceID: "old", CreatedAt: created.Unix()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	wait, wake = tracer.janitorWait(ctx, interval, idleIntervalsBeforePark)
+	if want := 22 * time.Hour; wait != want || wake != nil {
+		t.Fatalf("idle janitor waits %s, want %s until the oldest step expires", wait, want)
+	}
+}
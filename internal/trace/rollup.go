@@ -0,0 +1,233 @@
+package trace
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 롤업 지연 시간 히스토그램 버킷 상한 (밀리초, 마지막 버킷 LeInf는 그 이상 전체)
+var rollupBounds = [...]int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// StepRollup 경로와 분 단위 집계 (Config.Rollups, step_rollups 테이블)
+// 여러 인스턴스가 같은 행에 더하므로 모든 값은 누적 합계다
+type StepRollup struct {
+	Path         string `gorm:"primaryKey;size:255"`
+	Method       string `gorm:"primaryKey;size:16"`
+	Minute       int64  `gorm:"primaryKey;autoIncrement:false"` // 분 시작 시각 (Unix 초)
+	Requests     int64  // 요청 수
+	Errors       int64  // 5xx 응답 수
+	LatencySumMs int64  // 지연 시간 합계 (평균 계산용)
+
+	// 지연 시간 히스토그램 (각 버킷에 속한 요청 수, 누적 아님)
+	Le5     int64
+	Le10    int64
+	Le25    int64
+	Le50    int64
+	Le100   int64
+	Le250   int64
+	Le500   int64
+	Le1000  int64
+	Le2500  int64
+	Le5000  int64
+	Le10000 int64
+	LeInf   int64
+
+	// 필터, 식별자 없음, 샘플링으로 Step을 만들지 않은 요청 (WithCountSkipped, 히스토그램 없음)
+	Uncollected             int64 `gorm:"not null;default:0"`
+	UncollectedErrors       int64 `gorm:"not null;default:0"`
+	UncollectedLatencySumMs int64 `gorm:"not null;default:0"`
+}
+
+// buckets - 히스토그램 버킷 필드 (rollupBounds 순서, 마지막은 LeInf)
+func (r *StepRollup) buckets() []*int64 {
+	return []*int64{&r.Le5, &r.Le10, &r.Le25, &r.Le50, &r.Le100, &r.Le250, &r.Le500, &r.Le1000, &r.Le2500, &r.Le5000, &r.Le10000, &r.LeInf}
+}
+
+// bucketColumns - 히스토그램 버킷 컬럼 이름
+var bucketColumns = []string{"le5", "le10", "le25", "le50", "le100", "le250", "le500", "le1000", "le2500", "le5000", "le10000", "le_inf"}
+
+// rollupTable - Step 테이블 이름에 맞춘 롤업 테이블 이름 (steps → step_rollups, svc_steps → svc_step_rollups)
+func (t stepTables) rollupTable() string {
+	if prefix, ok := strings.CutSuffix(t.base, defaultStepTable); ok {
+		return prefix + "step_rollups"
+	}
+	return t.base + "_rollups"
+}
+
+// rollupKey - 롤업 행 (경로, 메서드, 분 시작 시각)
+type rollupKey struct {
+	path, method string
+	minute       int64
+}
+
+// migrateRollups - 롤업 테이블 생성 (New에서 Rollups가 true일 때 호출)
+func migrateRollups(db *gorm.DB, table string) error {
+	return db.Table(table).AutoMigrate(&StepRollup{})
+}
+
+// rollup - 저장에 성공한 배치를 경로와 분 단위로 모아 롤업 테이블에 더함 (요청 Step만, 하위 span 제외)
+// 그동안 모인 저장하지 않은 요청 수(WithCountSkipped)도 함께 더한다
+// 실패해도 이미 저장한 Step을 다시 저장하지 않도록 로그만 남기며, 저장하지 않은 요청 수는 다음 갱신에서 다시 시도한다
+func (t *Tracer) rollup(logs []Step) {
+	if t.rollupDB == nil {
+		return
+	}
+	rows := make(map[rollupKey]*StepRollup)
+	row := func(k rollupKey) *StepRollup {
+		r := rows[k]
+		if r == nil {
+			r = &StepRollup{Path: k.path, Method: k.method, Minute: k.minute}
+			rows[k] = r
+		}
+		return r
+	}
+	for _, step := range logs {
+		if step.SpanName != "" {
+			continue
+		}
+		r := row(rollupKey{step.Path, step.Method, step.CreatedAt - step.CreatedAt%60})
+		r.Requests++
+		if step.StatusCode >= 500 {
+			r.Errors++
+		}
+		r.LatencySumMs += step.LatencyMs
+		i := sort.Search(len(rollupBounds), func(i int) bool { return step.LatencyMs <= rollupBounds[i] })
+		*r.buckets()[i]++
+	}
+	uncollected := t.endpoints.takeUncollected()
+	for k, u := range uncollected {
+		r := row(k)
+		r.Uncollected, r.UncollectedErrors, r.UncollectedLatencySumMs = u.requests, u.errors, u.latencySumMs
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	table := t.tables.rollupTable()
+	// ON CONFLICT의 SET에서 기존 행은 테이블 이름으로 참조 (PostgreSQL에서 excluded와 구분)
+	target := table[strings.LastIndexByte(table, '.')+1:]
+	err := t.rollupDB.Transaction(func(tx *gorm.DB) error {
+		for _, r := range rows {
+			updates := map[string]any{
+				"requests":       gorm.Expr(target+".requests + ?", r.Requests),
+				"errors":         gorm.Expr(target+".errors + ?", r.Errors),
+				"latency_sum_ms": gorm.Expr(target+".latency_sum_ms + ?", r.LatencySumMs),
+			}
+			for i, column := range bucketColumns {
+				if n := *r.buckets()[i]; n > 0 {
+					updates[column] = gorm.Expr(target+"."+column+" + ?", n)
+				}
+			}
+			if r.Uncollected > 0 {
+				updates["uncollected"] = gorm.Expr(target+".uncollected + ?", r.Uncollected)
+				updates["uncollected_errors"] = gorm.Expr(target+".uncollected_errors + ?", r.UncollectedErrors)
+				updates["uncollected_latency_sum_ms"] = gorm.Expr(target+".uncollected_latency_sum_ms + ?", r.UncollectedLatencySumMs)
+			}
+			err := tx.Table(table).Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "path"}, {Name: "method"}, {Name: "minute"}},
+				DoUpdates: clause.Assignments(updates),
+			}).Create(r).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.endpoints.restoreUncollected(uncollected)
+		log.Printf("trace: failed to update rollups for %d steps: %v", len(logs), err)
+	}
+}
+
+// RollupSummary 롤업 테이블에서 계산한 경로별 요약
+// 백분위는 해당 요청이 속한 히스토그램 버킷의 상한이며, 10초를 넘는 값은 10000으로 표시한다
+type RollupSummary struct {
+	Path      string  `json:"path"`
+	Method    string  `json:"method"`
+	Count     int64   `json:"count"`
+	AvgMs     float64 `json:"avg_ms"`
+	P50Ms     int64   `json:"p50_ms"`
+	P95Ms     int64   `json:"p95_ms"`
+	P99Ms     int64   `json:"p99_ms"`
+	ErrorRate float64 `json:"error_rate"` // 5xx 비율 (0~1)
+
+	// 저장하지 않은 요청 (WithCountSkipped, 백분위 없음)
+	Uncollected          int64   `json:"uncollected,omitempty"`
+	UncollectedAvgMs     float64 `json:"uncollected_avg_ms,omitempty"`
+	UncollectedErrorRate float64 `json:"uncollected_error_rate,omitempty"`
+}
+
+// SummarizeRollups - [from, to) 구간의 롤업을 경로별로 합산 (저장하지 않은 요청을 포함한 요청 수가 많은 순)
+// 원본 Step을 읽지 않으므로 기간이 길어도 분 단위 행 수만큼만 읽는다
+func SummarizeRollups(ctx context.Context, db *gorm.DB, from, to time.Time) ([]RollupSummary, error) {
+	return storeFor(db).SummarizeRollups(ctx, from, to)
+}
+
+// SummarizeRollups - [from, to) 구간의 롤업을 경로별로 합산 (요청 수가 많은 순)
+func (s *Store) SummarizeRollups(ctx context.Context, from, to time.Time) ([]RollupSummary, error) {
+	return s.summarizeRollups(ctx, from, to, 0)
+}
+
+// summarizeRollups - limit이 0보다 크면 요청 수가 많은 limit개 경로만 합산
+func (s *Store) summarizeRollups(ctx context.Context, from, to time.Time, limit int) ([]RollupSummary, error) {
+	sums := []string{"path", "method", "SUM(requests) AS requests", "SUM(errors) AS errors", "SUM(latency_sum_ms) AS latency_sum_ms"}
+	for _, column := range bucketColumns {
+		sums = append(sums, "SUM("+column+") AS "+column)
+	}
+	for _, column := range []string{"uncollected", "uncollected_errors", "uncollected_latency_sum_ms"} {
+		sums = append(sums, "SUM("+column+") AS "+column)
+	}
+	tx := s.db.WithContext(ctx).
+		Table(s.tables.rollupTable()).
+		Select(strings.Join(sums, ", ")).
+		Where("minute >= ? AND minute < ?", from.Unix(), to.Unix()).
+		Group("path, method").
+		Order("SUM(requests) + SUM(uncollected) DESC")
+	if limit > 0 {
+		tx = tx.Limit(limit)
+	}
+	var rows []StepRollup
+	err := tx.Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]RollupSummary, 0, len(rows))
+	for _, r := range rows {
+		if r.Requests == 0 && r.Uncollected == 0 {
+			continue
+		}
+		summary := RollupSummary{Path: r.Path, Method: r.Method, Count: r.Requests, Uncollected: r.Uncollected}
+		if r.Uncollected > 0 {
+			summary.UncollectedAvgMs = float64(r.UncollectedLatencySumMs) / float64(r.Uncollected)
+			summary.UncollectedErrorRate = float64(r.UncollectedErrors) / float64(r.Uncollected)
+		}
+		if r.Requests == 0 {
+			summaries = append(summaries, summary)
+			continue
+		}
+		summary.AvgMs = float64(r.LatencySumMs) / float64(r.Requests)
+		summary.ErrorRate = float64(r.Errors) / float64(r.Requests)
+		for _, pct := range []struct {
+			p   float64
+			dst *int64
+		}{{0.50, &summary.P50Ms}, {0.95, &summary.P95Ms}, {0.99, &summary.P99Ms}} {
+			rank := int64(float64(r.Requests-1) * pct.p)
+			var seen int64
+			for i, n := range r.buckets() {
+				if seen += *n; seen > rank {
+					*pct.dst = rollupBounds[min(i, len(rollupBounds)-1)]
+					break
+				}
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
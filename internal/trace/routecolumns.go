@@ -0,0 +1,266 @@
+package trace
+
+import (
+	"cmp"
+	"errors"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storableColumns - RouteRule.StoreColumns로 저장 여부를 고를 수 있는 컬럼
+// 나머지 컬럼(Trace ID, 사용자 ID, 경로, 상태 코드, 응답 시간, 시각, 에러 등)은 항상 저장한다
+var storableColumns = map[string]func(*Step) *string{
+	"ip":            func(s *Step) *string { return &s.IP }, // 지역 정보(GeoResolver)도 조회하지 않음
+	"user_agent":    func(s *Step) *string { return &s.UserAgent },
+	"team":          func(s *Step) *string { return &s.Team },
+	"labels":        func(s *Step) *string { return &s.Labels },
+	"params":        func(s *Step) *string { return &s.Params },
+	"query":         func(s *Step) *string { return &s.Query },
+	"extra":         func(s *Step) *string { return &s.Extra }, // SetMetadata 값 포함
+	"request_body":  func(s *Step) *string { return &s.RequestBody },
+	"response_body": func(s *Step) *string { return &s.ResponseBody },
+	"headers":       func(s *Step) *string { return &s.Headers },
+}
+
+// RouteRule 라우트 패턴별 설정 (WithRouteRules, WithRouteSampling)
+// 패턴은 "/*"로 끝나면 접두사로, 나머지는 라우트 경로(c.FullPath)와 정확히 비교하며,
+// 설정한 항목마다 맞는 규칙 중 가장 긴 패턴을 사용한다 (SampleRate만 설정한 긴 규칙이 짧은 규칙의 StoreColumns를 가리지 않음)
+type RouteRule struct {
+	Pattern string `json:"pattern"`
+	// 저장할 요청 비율 (0~1, nil이면 WithSampleRate 비율)
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+	// 저장할 선택 컬럼 (ip, user_agent, team, labels, params, query, extra, request_body, response_body, headers)
+	// 목록에 없는 선택 컬럼은 버퍼에 넣기 전에 빈 문자열로 비우며 하위 span에도 적용한다 (nil이면 모두 저장, 빈 목록이면 모두 비움)
+	StoreColumns []string `json:"store_columns"`
+}
+
+// WithRouteRules 라우트 패턴별 샘플링 비율과 저장 컬럼 설정 (요청이 많은 라우트의 행 크기와 인덱스 부담 감소)
+// 같은 패턴의 규칙이 이미 있으면(WithRouteSampling 포함) 설정한 항목만 덮어쓰고, 알 수 없는 컬럼 이름은 로그를 남기고 무시한다
+func WithRouteRules(rules ...RouteRule) MiddlewareOption {
+	rules = cloneRouteRules(rules)
+	for _, rule := range rules {
+		for _, column := range rule.StoreColumns {
+			if storableColumns[column] == nil {
+				log.Printf("trace: ignoring unknown column %q in route rule for %q", column, rule.Pattern)
+			}
+		}
+	}
+	return func(config *MiddlewareConfig) {
+		config.RouteRules = mergeRouteRules(config.RouteRules, rules)
+		config.routeRules = compileRouteRules(config.RouteRules)
+	}
+}
+
+// cloneRouteRules - 호출자의 슬라이스와 공유하지 않는 복사본
+func cloneRouteRules(rules []RouteRule) []RouteRule {
+	if rules == nil {
+		return nil
+	}
+	clone := make([]RouteRule, len(rules))
+	for i, rule := range rules {
+		clone[i] = rule
+		if rule.SampleRate != nil {
+			rate := *rule.SampleRate
+			clone[i].SampleRate = &rate
+		}
+		clone[i].StoreColumns = slices.Clone(rule.StoreColumns)
+	}
+	return clone
+}
+
+// mergeRouteRules - existing에 add를 합친 새 목록 (같은 패턴이면 add에서 설정한 항목만 덮어씀)
+func mergeRouteRules(existing, add []RouteRule) []RouteRule {
+	merged := cloneRouteRules(existing)
+	for _, rule := range add {
+		i := slices.IndexFunc(merged, func(r RouteRule) bool { return r.Pattern == rule.Pattern })
+		if i < 0 {
+			merged = append(merged, rule)
+			continue
+		}
+		if rule.SampleRate != nil {
+			merged[i].SampleRate = rule.SampleRate
+		}
+		if rule.StoreColumns != nil {
+			merged[i].StoreColumns = rule.StoreColumns
+		}
+	}
+	return merged
+}
+
+// routeRule 비교용으로 준비한 RouteRule
+type routeRule struct {
+	routePattern
+	rule  RouteRule
+	rate  float64               // SampleRate를 0~1로 제한한 값
+	strip []func(*Step) *string // StoreColumns에 없어 비울 컬럼
+}
+
+// compileRouteRules - 긴 패턴부터 비교하도록 정렬한 규칙
+func compileRouteRules(rules []RouteRule) []routeRule {
+	compiled := make([]routeRule, 0, len(rules))
+	for _, rule := range rules {
+		r := routeRule{routePattern: parseRoutePattern(rule.Pattern), rule: rule}
+		if rule.SampleRate != nil {
+			r.rate = min(max(*rule.SampleRate, 0), 1)
+		}
+		if rule.StoreColumns != nil {
+			for _, column := range slices.Sorted(maps.Keys(storableColumns)) {
+				if !slices.Contains(rule.StoreColumns, column) {
+					r.strip = append(r.strip, storableColumns[column])
+				}
+			}
+		}
+		compiled = append(compiled, r)
+	}
+	sortRouteRules(compiled, func(rule routeRule) routePattern { return rule.routePattern })
+	return compiled
+}
+
+// matchRouteRule - route에 맞는 규칙 중 has를 만족하는 가장 긴 패턴의 규칙 (없으면 nil)
+func matchRouteRule(config *MiddlewareConfig, route string, has func(RouteRule) bool) *routeRule {
+	for i, rule := range config.routeRules {
+		if has(rule.rule) && rule.match(route) {
+			return &config.routeRules[i]
+		}
+	}
+	return nil
+}
+
+func hasSampleRate(rule RouteRule) bool   { return rule.SampleRate != nil }
+func hasStoreColumns(rule RouteRule) bool { return rule.StoreColumns != nil }
+
+// routeColumns - 라우트에 적용할 저장 컬럼 규칙 (없으면 nil)
+func routeColumns(config *MiddlewareConfig, route string) *routeRule {
+	return matchRouteRule(config, route, hasStoreColumns)
+}
+
+// apply - 규칙에서 제외한 컬럼을 비우고 비운 바이트 수 반환 (규칙이 없으면 그대로)
+func (r *routeRule) apply(step *Step) int64 {
+	if r == nil {
+		return 0
+	}
+	var blanked int64
+	for _, column := range r.strip {
+		field := column(step)
+		blanked += int64(len(*field))
+		*field = ""
+	}
+	return blanked
+}
+
+// StorageStats 저장 컬럼 규칙(RouteRule.StoreColumns)별로 선택 컬럼을 비운 요청 수와 줄인 바이트 수
+type StorageStats struct {
+	Pattern      string `json:"pattern"`
+	Steps        int64  `json:"steps"`         // 규칙을 적용한 요청 Step 수 (하위 span 제외)
+	BlankedBytes int64  `json:"blanked_bytes"` // 비운 선택 컬럼 값의 바이트 수
+}
+
+type storageCounter struct {
+	steps        atomic.Int64
+	blankedBytes atomic.Int64
+}
+
+// routeStorage - Tracer 시작 후 누적한 저장 컬럼 규칙별 집계 (패턴 수는 미들웨어 설정으로 제한됨)
+type routeStorage struct {
+	mu       sync.RWMutex
+	counters map[string]*storageCounter
+}
+
+// countStorage - 저장 컬럼 규칙을 적용한 요청 하나를 집계
+func (t *Tracer) countStorage(pattern string, blanked int64) {
+	if t == nil {
+		return
+	}
+	s := &t.storage
+	s.mu.RLock()
+	c := s.counters[pattern]
+	s.mu.RUnlock()
+	if c == nil {
+		s.mu.Lock()
+		if c = s.counters[pattern]; c == nil {
+			if s.counters == nil {
+				s.counters = make(map[string]*storageCounter)
+			}
+			c = &storageCounter{}
+			s.counters[pattern] = c
+		}
+		s.mu.Unlock()
+	}
+	c.steps.Add(1)
+	c.blankedBytes.Add(blanked)
+}
+
+// StorageStats - 저장 컬럼 규칙별로 비운 요청 수와 바이트 수 (줄인 바이트가 많은 순)
+func (t *Tracer) StorageStats() []StorageStats {
+	if t == nil {
+		return nil
+	}
+	s := &t.storage
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.counters) == 0 {
+		return nil
+	}
+	stats := make([]StorageStats, 0, len(s.counters))
+	for pattern, c := range s.counters {
+		stats = append(stats, StorageStats{Pattern: pattern, Steps: c.steps.Load(), BlankedBytes: c.blankedBytes.Load()})
+	}
+	slices.SortFunc(stats, func(a, b StorageStats) int {
+		if n := cmp.Compare(b.BlankedBytes, a.BlankedBytes); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Pattern, b.Pattern)
+	})
+	return stats
+}
+
+// RouteExplanation 미들웨어가 라우트에 적용하는 샘플링 비율과 저장 컬럼 (Explain)
+type RouteExplanation struct {
+	Route string `json:"route"`
+	// 저장할 요청 비율과 그 비율을 정한 규칙 (규칙이 없으면 nil, UpdateConfig의 SampleRate가 있으면 RuntimeSampleRate가 true)
+	SampleRate        float64    `json:"sample_rate"`
+	SampleRule        *RouteRule `json:"sample_rule,omitempty"`
+	RuntimeSampleRate bool       `json:"runtime_sample_rate,omitempty"`
+	// 저장하는 선택 컬럼과 비우는 선택 컬럼, 그렇게 정한 규칙 (규칙이 없으면 nil, 모두 저장)
+	StoreColumns []string   `json:"store_columns"`
+	BlankColumns []string   `json:"blank_columns,omitempty"`
+	ColumnRule   *RouteRule `json:"column_rule,omitempty"`
+}
+
+// Explain - 미들웨어 handler가 route(c.FullPath 형식, 예: "/orders/:id")에 적용하는 샘플링 비율과 저장 컬럼, 그렇게 정한 RouteRule
+// handler는 MiddlewareWithConfig(또는 Middleware, Derive)로 만든 핸들러여야 한다
+// 디버그 캡처 중인 사용자와 상위 서비스의 샘플링 결정(WithRespectUpstreamSampling)은 요청마다 달라 반영하지 않는다
+func Explain(handler gin.HandlerFunc, route string) (RouteExplanation, error) {
+	config := configOf(handler)
+	if config == nil {
+		return RouteExplanation{}, errors.New("trace: Explain requires a handler created by MiddlewareWithConfig")
+	}
+
+	e := RouteExplanation{Route: route, SampleRate: config.SampleRate}
+	if rule := matchRouteRule(config, route, hasSampleRate); rule != nil {
+		e.SampleRate = rule.rate
+		e.SampleRule = &cloneRouteRules([]RouteRule{rule.rule})[0]
+	}
+	if runtime := config.pipeline().runtimeSettings(); runtime != nil && runtime.config.SampleRate != nil {
+		e.SampleRate, e.RuntimeSampleRate = *runtime.config.SampleRate, true
+	}
+
+	e.StoreColumns = slices.Sorted(maps.Keys(storableColumns))
+	if rule := routeColumns(config, route); rule != nil {
+		e.ColumnRule = &cloneRouteRules([]RouteRule{rule.rule})[0]
+		e.StoreColumns = e.StoreColumns[:0]
+		for _, column := range slices.Sorted(maps.Keys(storableColumns)) {
+			if slices.Contains(rule.rule.StoreColumns, column) {
+				e.StoreColumns = append(e.StoreColumns, column)
+			} else {
+				e.BlankColumns = append(e.BlankColumns, column)
+			}
+		}
+	}
+	return e, nil
+}
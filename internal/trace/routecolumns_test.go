@@ -0,0 +1,147 @@
+package trace_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// storedWidth - 저장된 선택 컬럼의 총 바이트 수
+func storedWidth(t *testing.T, db *gorm.DB, path string) int64 {
+	t.Helper()
+	var width int64
+	err := db.Table("steps").
+		Select("COALESCE(SUM(LENGTH(ip) + LENGTH(user_agent) + LENGTH(params) + LENGTH(query) + LENGTH(extra) + LENGTH(headers)), 0)").
+		Where("path = ?", path).
+		Scan(&width).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return width
+}
+
+func TestRouteColumnsBlankExcludedFields(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	tracer, err := trace.New(trace.Config{DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(tracer.Middleware(
+		trace.WithRouteParams(nil, nil),
+		trace.WithQueryCapture(),
+		trace.WithHeaderCapture([]string{"X-Client"}, nil),
+		trace.WithRouteRules(trace.RouteRule{Pattern: "/hot/*", StoreColumns: []string{"params"}}),
+	))
+	handler := func(c *gin.Context) {
+		trace.SetMetadata(c, "cart", "large")
+		trace.StartSpan(c, "cache").End()
+		c.Status(http.StatusOK)
+	}
+	r.GET("/hot/:id", handler)
+	r.GET("/cold/:id", handler)
+
+	const requests = 5
+	for i := range requests {
+		for _, path := range []string{"/hot", "/cold"} {
+			req := httptest.NewRequest("GET", fmt.Sprintf("%s/%d?user_id=user-1&access_token=token&page=2", path, i), nil)
+			req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+			req.Header.Set("X-Client", "ios")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+	stopTracer(t, tracer)
+
+	hot := storedSteps(t, tracer, trace.QueryFilter{PathPrefix: "/hot"})
+	if len(hot) != 2*requests {
+		t.Fatalf("stored %d hot steps, want %d (requests and spans)", len(hot), 2*requests)
+	}
+	for _, step := range hot {
+		if step.IP != "" || step.UserAgent != "" || step.Query != "" || step.Extra != "" || step.Headers != "" {
+			t.Errorf("excluded columns stored: %+v", step)
+		}
+		if step.SpanName != "" {
+			continue
+		}
+		if step.Params == "" {
+			t.Errorf("kept column params blanked: %+v", step)
+		}
+		if step.TraceID == "" || step.UserID != "user-1" || step.StatusCode != http.StatusOK {
+			t.Errorf("required columns missing: %+v", step)
+		}
+	}
+	cold := storedSteps(t, tracer, trace.QueryFilter{PathPrefix: "/cold", RequestsOnly: true})
+	if len(cold) != requests || cold[0].UserAgent == "" || cold[0].Query == "" || cold[0].Headers == "" {
+		t.Fatalf("unmatched route lost columns: %+v", cold)
+	}
+
+	// 같은 요청 수에서 규칙을 적용한 라우트의 저장 크기 비교
+	hotWidth, coldWidth := storedWidth(t, db, "/hot/:id"), storedWidth(t, db, "/cold/:id")
+	t.Logf("optional columns: %d bytes with route columns, %d bytes without", hotWidth, coldWidth)
+	if hotWidth*4 > coldWidth {
+		t.Fatalf("route columns stored %d bytes, want well under %d", hotWidth, coldWidth)
+	}
+
+	// 규칙별로 비운 요청 수와 바이트 수 (요청 Step만 집계하므로 같은 요청의 /cold Step에 남은 제외 컬럼 크기와 같음)
+	var blanked int64
+	for _, step := range cold {
+		blanked += int64(len(step.IP) + len(step.UserAgent) + len(step.Query) + len(step.Extra) + len(step.Headers))
+	}
+	storage := tracer.Stats().Storage
+	if len(storage) != 1 || storage[0].Pattern != "/hot/*" || storage[0].Steps != requests || storage[0].BlankedBytes != blanked {
+		t.Fatalf("Stats().Storage = %+v, want %d /hot/* steps with %d bytes blanked", storage, requests, blanked)
+	}
+}
+
+func TestExplainShowsRouteRules(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+	middleware := tracer.Middleware(
+		trace.WithSampleRate(0.5),
+		trace.WithRouteRules(trace.RouteRule{Pattern: "/api/*", StoreColumns: []string{"query", "params"}}),
+		// 더 긴 패턴이지만 비율만 정하므로 /api/*의 저장 컬럼은 그대로 적용
+		trace.WithRouteSampling(map[string]float64{"/api/payments/*": 1}),
+	)
+
+	payments, err := trace.Explain(middleware, "/api/payments/:id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payments.SampleRate != 1 || payments.SampleRule == nil || payments.SampleRule.Pattern != "/api/payments/*" {
+		t.Errorf("payments sampling = %v by %+v, want 1 by /api/payments/*", payments.SampleRate, payments.SampleRule)
+	}
+	if payments.ColumnRule == nil || payments.ColumnRule.Pattern != "/api/*" ||
+		!slices.Equal(payments.StoreColumns, []string{"params", "query"}) || slices.Contains(payments.BlankColumns, "query") || !slices.Contains(payments.BlankColumns, "ip") {
+		t.Errorf("payments columns = %+v", payments)
+	}
+
+	other, err := trace.Explain(middleware, "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.SampleRate != 0.5 || other.SampleRule != nil || other.ColumnRule != nil || len(other.BlankColumns) != 0 || len(other.StoreColumns) != 10 {
+		t.Errorf("unmatched route = %+v, want the default rate and every column", other)
+	}
+
+	// UpdateConfig의 비율이 우선
+	rate := 0.1
+	tracer.UpdateConfig(trace.RuntimeConfig{SampleRate: &rate})
+	if e, _ := trace.Explain(middleware, "/api/payments/:id"); e.SampleRate != 0.1 || !e.RuntimeSampleRate {
+		t.Errorf("explain after UpdateConfig = %+v, want the runtime rate", e)
+	}
+
+	if _, err := trace.Explain(func(c *gin.Context) {}, "/"); err == nil {
+		t.Error("Explain accepted a handler that is not a trace middleware")
+	}
+}
@@ -0,0 +1,90 @@
+package trace
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RuntimeConfig 재시작 없이 바꿀 수 있는 수집 설정 (Tracer.UpdateConfig)
+// 설정한 값은 이 Tracer를 사용하는 모든 미들웨어의 옵션보다 우선한다
+type RuntimeConfig struct {
+	// 저장할 요청 비율 (0~1), nil이면 미들웨어 설정(WithSampleRate, WithRouteSampling) 사용
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+	// 설정 시 에러와 이 시간 이상 걸린 요청은 샘플링과 관계없이 저장, nil이면 미들웨어 설정(WithTailSampling) 사용
+	SlowThreshold *time.Duration `json:"slow_threshold,omitempty"`
+	// 수집하지 않을 경로 ("/health"처럼 정확히 비교하거나 "/internal/*"처럼 "/*"로 끝나면 접두사로 비교)
+	// 라우트 경로(c.FullPath)와 요청 경로를 모두 비교한다
+	SkipPaths []string `json:"skip_paths,omitempty"`
+}
+
+// runtimeSettings - 미들웨어가 요청마다 읽는 RuntimeConfig (교체만 하고 수정하지 않음)
+type runtimeSettings struct {
+	config RuntimeConfig
+	skips  []routePattern
+}
+
+// UpdateConfig - 수집 설정을 원자적으로 교체 (진행 중인 요청은 이전 설정으로 끝남)
+// 빈 RuntimeConfig를 넘기면 미들웨어 설정으로 되돌린다
+func (t *Tracer) UpdateConfig(config RuntimeConfig) {
+	if config.SampleRate != nil {
+		rate := min(max(*config.SampleRate, 0), 1)
+		config.SampleRate = &rate
+	}
+	if config.SlowThreshold != nil {
+		threshold := max(*config.SlowThreshold, 0)
+		config.SlowThreshold = &threshold
+	}
+	config.SkipPaths = slices.Clone(config.SkipPaths)
+
+	settings := &runtimeSettings{config: config}
+	for _, pattern := range config.SkipPaths {
+		settings.skips = append(settings.skips, parseRoutePattern(pattern))
+	}
+	t.runtime.Store(settings)
+}
+
+// RuntimeConfig - 현재 적용 중인 수집 설정
+func (t *Tracer) RuntimeConfig() RuntimeConfig {
+	if s := t.runtimeSettings(); s != nil {
+		config := s.config
+		config.SkipPaths = slices.Clone(config.SkipPaths)
+		return config
+	}
+	return RuntimeConfig{}
+}
+
+// UpdateConfig - 기본 Tracer의 수집 설정 교체 (Start 전이면 ErrNotRunning)
+func UpdateConfig(config RuntimeConfig) error {
+	t := defaultTracer.Load()
+	if t == nil {
+		return ErrNotRunning
+	}
+	t.UpdateConfig(config)
+	return nil
+}
+
+// runtimeSettings - 현재 RuntimeConfig (Tracer가 없거나 설정한 적이 없으면 nil)
+func (t *Tracer) runtimeSettings() *runtimeSettings {
+	if t == nil {
+		return nil
+	}
+	return t.runtime.Load()
+}
+
+// skip - 요청이 SkipPaths에 해당하는지
+func (s *runtimeSettings) skip(c *gin.Context) bool {
+	if s == nil || len(s.skips) == 0 {
+		return false
+	}
+	route, path := c.FullPath(), c.Request.URL.Path
+	for _, skip := range s.skips {
+		for _, p := range [...]string{route, path} {
+			if p != "" && skip.match(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
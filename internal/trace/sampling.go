@@ -0,0 +1,199 @@
+package trace
+
+import (
+	"cmp"
+	"hash/fnv"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 샘플링 결정을 하위 서비스에 전달하는 헤더 ("1" 저장, "0" 제외)
+const sampledHeader = "X-Trace-Sampled"
+
+// SamplingMode 샘플링 결정 방식
+type SamplingMode int
+
+const (
+	// SampleRandom 요청마다 독립적으로 결정 (기본값)
+	SampleRandom SamplingMode = iota
+	// SampleByTraceID Trace ID 해시로 결정하여 같은 trace의 요청은 모두 함께 저장되거나 함께 제외됨
+	SampleByTraceID
+)
+
+// WithSampleRate 저장할 요청 비율 설정 (0~1, 기본 1)
+// 제외된 요청도 Trace ID는 컨텍스트에 기록되며 접근 로그는 그대로 출력된다
+func WithSampleRate(rate float64) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.SampleRate = min(max(rate, 0), 1)
+	}
+}
+
+// WithSamplingMode 샘플링 결정 방식 설정
+func WithSamplingMode(mode SamplingMode) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.SamplingMode = mode
+	}
+}
+
+// WithTailSampling 에러와 느린 요청은 샘플링과 관계없이 항상 저장
+// 핸들러가 끝난 뒤 상태 코드가 500 이상이거나 응답 시간이 slowThreshold 이상이면 저장하고,
+// 나머지 요청에만 WithSampleRate 비율을 적용한다 (slowThreshold가 0이면 에러만 항상 저장)
+func WithTailSampling(slowThreshold time.Duration) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.TailSampling = true
+		config.SlowThreshold = max(slowThreshold, 0)
+	}
+}
+
+// WithRouteSampling 라우트 패턴별 저장 비율 설정 (표에 없는 라우트는 WithSampleRate 비율)
+// "/api/payments/*"처럼 "/*"로 끝나는 패턴은 접두사로, 나머지는 라우트 경로(c.FullPath)와 정확히 비교하며
+// 여러 패턴이 맞으면 가장 긴 패턴을 사용한다 (WithRouteRules에 SampleRate만 설정한 규칙과 같음)
+func WithRouteSampling(rates map[string]float64) MiddlewareOption {
+	rules := make([]RouteRule, 0, len(rates))
+	for _, pattern := range slices.Sorted(maps.Keys(rates)) {
+		rate := rates[pattern]
+		rules = append(rules, RouteRule{Pattern: pattern, SampleRate: &rate})
+	}
+	return WithRouteRules(rules...)
+}
+
+// routePattern 라우트 규칙의 패턴 ("/*"로 끝나면 접두사 비교)
+type routePattern struct {
+	pattern string
+	prefix  bool
+}
+
+func parseRoutePattern(pattern string) routePattern {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, "/") {
+		return routePattern{pattern: prefix, prefix: true}
+	}
+	return routePattern{pattern: pattern}
+}
+
+func (p routePattern) match(route string) bool {
+	return p.prefix && strings.HasPrefix(route, p.pattern) || route == p.pattern
+}
+
+// sortRouteRules - 긴 패턴부터 비교하도록 정렬
+func sortRouteRules[R any](rules []R, pattern func(R) routePattern) {
+	slices.SortFunc(rules, func(a, b R) int {
+		return cmp.Compare(len(pattern(b).pattern), len(pattern(a).pattern))
+	})
+}
+
+// sampleRate - 라우트에 적용할 저장 비율
+func sampleRate(config *MiddlewareConfig, route string) float64 {
+	if rule := matchRouteRule(config, route, hasSampleRate); rule != nil {
+		return rule.rate
+	}
+	return config.SampleRate
+}
+
+// WithRespectUpstreamSampling 상위 서비스의 샘플링 결정을 이 서비스의 샘플링 비율보다 우선
+// 요청의 X-Trace-Sampled 헤더("1" 또는 "0")를, 없으면 traceparent/B3 헤더의 sampled 값(각 연동이 켜진 경우)을 사용하며,
+// 결정이 없는 요청에만 WithSampleRate, WithRouteSampling, WithAdaptiveSampling을 적용한다
+// 상위 서비스가 제외한 요청도 WithTailSampling 조건(에러, 느린 요청)에 맞으면 저장한다
+func WithRespectUpstreamSampling() MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.RespectUpstreamSampling = true
+	}
+}
+
+// headSample - 핸들러 실행 전 샘플링 결정 (NewTransport가 X-Trace-Sampled, traceparent 헤더로 하위 서비스에 전달)
+// 샘플링 비율로는 저장 대상이지만 적응형 샘플링(과부하 보호)으로 제외된 경우 overload가 true
+// runtime의 값(UpdateConfig)이 있으면 미들웨어 설정보다 우선한다
+func headSample(c *gin.Context, config *MiddlewareConfig, runtime *runtimeSettings, route, traceID string) (keep, overload bool) {
+	if config.RespectUpstreamSampling {
+		if upstream, ok := upstreamDecision(c); ok {
+			return upstream, false
+		}
+	}
+	rate := sampleRate(config, route)
+	if runtime != nil && runtime.config.SampleRate != nil {
+		rate = *runtime.config.SampleRate
+	}
+	adjusted := rate * config.adaptive.probability(route, rate, time.Now())
+	if adjusted >= rate || rate <= 0 {
+		return sampled(config.SamplingMode, traceID, rate), false
+	}
+	// 같은 값으로 두 비율을 비교하여 적응형 샘플링 때문에 제외된 경우를 구분
+	value := sampleValue(config.SamplingMode, traceID)
+	return value < adjusted, value >= adjusted && value < rate
+}
+
+// upstreamDecision - 요청 헤더에 담긴 상위 서비스의 샘플링 결정 (결정이 없으면 ok가 false)
+// X-Trace-Sampled를 traceparent, B3 헤더보다 우선하며, B3 디버그 플래그("d")는 저장으로 본다
+func upstreamDecision(c *gin.Context) (keep, ok bool) {
+	switch c.GetHeader(sampledHeader) {
+	case "1":
+		return true, true
+	case "0":
+		return false, true
+	}
+	state, _ := c.Get(b3StateKey)
+	if b3, _ := state.(*b3State); b3 != nil {
+		switch b3.sampled {
+		case "1", "d":
+			return true, true
+		case "0":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// sampledValue - X-Trace-Sampled 헤더 값
+func sampledValue(keep bool) string {
+	if keep {
+		return "1"
+	}
+	return "0"
+}
+
+// keepStep - 핸들러 실행 후 Step을 저장할지 결정
+// 헤드 샘플링으로 제외된 요청도 WithTailSampling 조건(에러, 느린 요청)에 맞으면 저장한다
+func keepStep(config *MiddlewareConfig, runtime *runtimeSettings, step *Step, head, overload bool) (kept, dropOverload bool) {
+	tail, slowThreshold := config.TailSampling, config.SlowThreshold
+	if runtime != nil && runtime.config.SlowThreshold != nil {
+		tail, slowThreshold = true, *runtime.config.SlowThreshold
+	}
+	if tail && isTailKeep(step, slowThreshold) {
+		return true, false
+	}
+	return head, overload
+}
+
+// isTailKeep - 샘플링과 관계없이 저장할 요청인지 (에러 또는 느린 요청)
+func isTailKeep(step *Step, slowThreshold time.Duration) bool {
+	if step.StatusCode >= 500 {
+		return true
+	}
+	return slowThreshold > 0 && step.LatencyMs >= slowThreshold.Milliseconds()
+}
+
+// sampled - 비율에 따라 샘플 여부 결정
+func sampled(mode SamplingMode, traceID string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	return sampleValue(mode, traceID) < rate
+}
+
+// sampleValue - 샘플링 비율과 비교할 [0, 1) 값 (SampleByTraceID면 Trace ID마다 고정)
+func sampleValue(mode SamplingMode, traceID string) float64 {
+	if mode == SampleByTraceID {
+		h := fnv.New64a()
+		h.Write([]byte(traceID))
+		return float64(h.Sum64()) / math.MaxUint64
+	}
+	return rand.Float64()
+}
@@ -0,0 +1,146 @@
+package trace_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// chainedServices - checkout 서비스가 NewTransport로 payments 서비스를 호출하는 두 서버 (같은 DB에 ServiceName으로 구분해 저장)
+type chainedServices struct {
+	checkout, payments *trace.Tracer
+	router             *gin.Engine
+	received           http.Header // payments가 받은 요청 헤더
+}
+
+func newChainedServices(t *testing.T, checkoutOpts, paymentsOpts []trace.MiddlewareOption, paymentsStatus int) *chainedServices {
+	t.Helper()
+	db := tracetest.NewTempDB(t)
+	s := &chainedServices{}
+	var err error
+	if s.checkout, err = trace.New(trace.Config{DB: db, ServiceName: "checkout"}); err != nil {
+		t.Fatal(err)
+	}
+	if s.payments, err = trace.New(trace.Config{DB: db, ServiceName: "payments"}); err != nil {
+		t.Fatal(err)
+	}
+
+	payments := gin.New()
+	payments.Use(s.payments.Middleware(paymentsOpts...))
+	payments.POST("/charge", func(c *gin.Context) {
+		s.received = c.Request.Header.Clone()
+		c.Status(paymentsStatus)
+	})
+	server := httptest.NewServer(payments)
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: trace.NewTransport(nil)}
+	s.router = gin.New()
+	s.router.Use(s.checkout.Middleware(checkoutOpts...))
+	s.router.GET("/checkout", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c.Request.Context(), "POST", server.URL+"/charge?user_id=user-1&access_token=token", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+		c.Status(http.StatusOK)
+	})
+	return s
+}
+
+// run - checkout 요청 하나를 처리하고 서비스별로 저장된 요청 Step 수 반환
+func (s *chainedServices) run(t *testing.T) (checkout, payments int) {
+	t.Helper()
+	s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/checkout?user_id=user-1&access_token=token", nil))
+	stopTracer(t, s.checkout)
+	stopTracer(t, s.payments)
+	checkout = len(storedSteps(t, s.checkout, trace.QueryFilter{ServiceName: "checkout", RequestsOnly: true}))
+	payments = len(storedSteps(t, s.checkout, trace.QueryFilter{ServiceName: "payments", RequestsOnly: true}))
+	return checkout, payments
+}
+
+func TestUpstreamSamplingPrecedence(t *testing.T) {
+	respect := trace.WithRespectUpstreamSampling()
+	tests := []struct {
+		name           string
+		checkout       []trace.MiddlewareOption
+		payments       []trace.MiddlewareOption
+		paymentsStatus int
+		debugCapture   bool // payments에서 user-1의 디버그 캡처 (로컬 강제 저장)
+		wantHeader     string
+		wantCheckout   int
+		wantPayments   int
+	}{
+		{"upstream drop wins over local rate", []trace.MiddlewareOption{trace.WithSampleRate(0)}, []trace.MiddlewareOption{respect}, 200, false, "0", 0, 0},
+		{"upstream keep wins over local rate", nil, []trace.MiddlewareOption{respect, trace.WithSampleRate(0)}, 200, false, "1", 1, 1},
+		{"local rate without respect", []trace.MiddlewareOption{trace.WithSampleRate(0)}, nil, 200, false, "0", 0, 1},
+		{"tail upgrade over upstream drop", []trace.MiddlewareOption{trace.WithSampleRate(0)}, []trace.MiddlewareOption{respect, trace.WithTailSampling(0)}, 503, false, "0", 0, 1},
+		{"no tail upgrade for success", []trace.MiddlewareOption{trace.WithSampleRate(0)}, []trace.MiddlewareOption{respect, trace.WithTailSampling(0)}, 200, false, "0", 0, 0},
+		{"debug capture over upstream drop", []trace.MiddlewareOption{trace.WithSampleRate(0)}, []trace.MiddlewareOption{respect}, 200, true, "0", 0, 1},
+		{"upstream keep wins over route rate", nil, []trace.MiddlewareOption{respect, trace.WithRouteSampling(map[string]float64{"/charge": 0})}, 200, false, "1", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newChainedServices(t, tt.checkout, tt.payments, tt.paymentsStatus)
+			if tt.debugCapture {
+				if _, err := s.payments.StartDebugCapture(trace.DebugCaptureSpec{UserID: "user-1", Duration: time.Minute}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			checkout, payments := s.run(t)
+			if got := s.received.Get("X-Trace-Sampled"); got != tt.wantHeader {
+				t.Errorf("X-Trace-Sampled = %q, want %q", got, tt.wantHeader)
+			}
+			if checkout != tt.wantCheckout || payments != tt.wantPayments {
+				t.Fatalf("stored checkout=%d payments=%d, want %d and %d", checkout, payments, tt.wantCheckout, tt.wantPayments)
+			}
+		})
+	}
+}
+
+func TestUpstreamSamplingTraceparentFlag(t *testing.T) {
+	w3c := trace.WithTraceContextPropagation()
+	tests := []struct {
+		name         string
+		checkout     []trace.MiddlewareOption
+		wantFlags    string
+		wantPayments int
+	}{
+		{"sampled out", []trace.MiddlewareOption{w3c, trace.WithSampleRate(0)}, "00", 0},
+		{"sampled in", []trace.MiddlewareOption{w3c}, "01", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newChainedServices(t, tt.checkout, []trace.MiddlewareOption{w3c, trace.WithRespectUpstreamSampling()}, 200)
+			s.run(t)
+			traceparent := s.received.Get("traceparent")
+			if len(traceparent) != 55 || traceparent[53:] != tt.wantFlags {
+				t.Fatalf("outgoing traceparent = %q, want flags %s", traceparent, tt.wantFlags)
+			}
+
+			// X-Trace-Sampled 없이 traceparent만 보내는 서비스의 결정도 따름
+			tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := gin.New()
+			r.Use(tracer.Middleware(w3c, trace.WithRespectUpstreamSampling()))
+			r.POST("/charge", func(c *gin.Context) { c.Status(http.StatusOK) })
+			req := httptest.NewRequest("POST", "/charge?user_id=user-1&access_token=token", nil)
+			req.Header.Set("traceparent", traceparent)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			stopTracer(t, tracer)
+			if got := len(storedSteps(t, tracer, trace.QueryFilter{})); got != tt.wantPayments {
+				t.Fatalf("stored %d steps from traceparent flags %s, want %d", got, tt.wantFlags, tt.wantPayments)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package trace
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// flushSchedule - 플러시 시점 계산 (여러 인스턴스의 동시 플러시 분산용)
+type flushSchedule struct {
+	interval time.Duration
+	jitter   time.Duration
+	alignTo  time.Duration
+	offset   time.Duration // 인스턴스별 고정 오프셋 (AlignFlushTo 사용 시)
+}
+
+func newFlushSchedule(cfg Config) flushSchedule {
+	s := flushSchedule{
+		interval: cfg.FlushInterval,
+		jitter:   cfg.FlushJitter,
+		alignTo:  cfg.AlignFlushTo,
+	}
+	if s.alignTo > 0 {
+		s.offset = instanceOffset(instanceKey(), s.interval)
+	}
+	return s
+}
+
+// first - 첫 플러시까지의 대기 시간
+func (s flushSchedule) first(now time.Time) time.Duration {
+	if s.alignTo > 0 {
+		// 다음 경계 + 인스턴스 오프셋
+		boundary := now.Truncate(s.alignTo).Add(s.alignTo)
+		return boundary.Add(s.offset).Sub(now)
+	}
+	return s.randomJitter() + s.interval
+}
+
+// next - 플러시 이후 다음 플러시까지의 대기 시간
+func (s flushSchedule) next() time.Duration {
+	if s.alignTo > 0 {
+		// 정렬 모드에서는 위상이 흐트러지지 않도록 지터를 적용하지 않는다
+		return s.interval
+	}
+	return s.interval + s.randomJitter()
+}
+
+// resume - 유휴 상태로 멈춘 워커가 Step을 받았을 때 다음 플러시까지의 대기 시간
+// 정렬 모드에서는 인스턴스 위상(경계 + offset + k*interval) 중 가장 가까운 다음 시점이므로 interval을 넘지 않는다
+func (s flushSchedule) resume(now time.Time) time.Duration {
+	if s.alignTo <= 0 || s.interval <= 0 {
+		return s.next()
+	}
+	phase := now.Truncate(s.alignTo).Add(s.offset)
+	wait := phase.Sub(now) % s.interval
+	if wait <= 0 {
+		wait += s.interval
+	}
+	return wait
+}
+
+func (s flushSchedule) randomJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return rand.N(s.jitter)
+}
+
+// runID - 프로세스마다 무작위로 만든 실행 ID
+// 컨테이너에서는 PID가 모두 1이고 hostNetwork 등으로 호스트명이 같을 수 있어 호스트명 + PID만으로는 인스턴스가 구분되지 않는다
+var runID = sync.OnceValue(func() string {
+	b := make([]byte, 8)
+	if _, err := crand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+})
+
+// instanceKey - 인스턴스를 구분하는 값 (호스트명 + PID + 실행 ID)
+func instanceKey() string {
+	host, _ := os.Hostname()
+	return host + "/" + strconv.Itoa(os.Getpid()) + "/" + runID()
+}
+
+// instanceOffset - 인스턴스 키 해시로부터 [0, interval) 범위의 고정 오프셋 계산
+func instanceOffset(key string, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
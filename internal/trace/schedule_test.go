@@ -0,0 +1,27 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstanceKeyIncludesRunID(t *testing.T) {
+	key := instanceKey()
+	if !strings.HasSuffix(key, "/"+runID()) || len(runID()) != 16 {
+		t.Fatalf("instanceKey() = %q, want hostname/pid/<16 hex run id>", key)
+	}
+	if runID() != runID() {
+		t.Fatal("runID changed within one process")
+	}
+
+	// 호스트명과 PID가 같은 인스턴스(컨테이너의 PID 1)도 실행 ID가 다르면 오프셋이 흩어진다
+	interval := time.Second
+	offsets := make(map[time.Duration]bool)
+	for _, id := range []string{"0f1e2d3c4b5a6978", "8877665544332211", "a1b2c3d4e5f60718", "deadbeefcafef00d"} {
+		offsets[instanceOffset("web/1/"+id, interval)] = true
+	}
+	if len(offsets) < 2 {
+		t.Fatalf("instances with the same hostname and PID got the same offset: %v", offsets)
+	}
+}
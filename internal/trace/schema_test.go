@@ -0,0 +1,113 @@
+package trace_test
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/schema"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// 버전이 섞여 배포되는 동안 구버전 writer의 INSERT가 실패하지 않도록 모든 컬럼은 NULL 허용이거나 기본값이 있어야 한다
+func TestStepColumnsAreNullableOrDefaulted(t *testing.T) {
+	s, err := schema.Parse(&trace.Step{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		if field.NotNull && !field.HasDefaultValue {
+			t.Errorf("column %s is NOT NULL without a default", field.DBName)
+		}
+		if field.PrimaryKey {
+			t.Errorf("column %s is a primary key; old writers cannot fill it", field.DBName)
+		}
+	}
+}
+
+// newerStep - 이후 버전이 컬럼을 추가한 Step 모델
+type newerStep struct {
+	trace.Step
+	Plan string `gorm:"size:32"`
+}
+
+// olderStep - 일부 컬럼만 알던 이전 버전의 Step 모델
+type olderStep struct {
+	TraceID    string
+	UserID     string
+	Path       string
+	Method     string
+	StatusCode int
+	CreatedAt  int64
+	Seq        int64
+}
+
+func TestCrossVersionMigrateWriteRead(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("newer schema, this writer and reader", func(t *testing.T) {
+		db := tracetest.NewTempDB(t)
+		if err := db.Table("steps").AutoMigrate(&newerStep{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Table("steps").Create(&newerStep{Step: trace.Step{TraceID: "new", UserID: "alice", Path: "/v2", CreatedAt: time.Now().Unix()}, Plan: "pro"}).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		var logs strings.Builder
+		log.SetOutput(&logs)
+		defer log.SetOutput(os.Stderr)
+		tracer, err := trace.New(trace.Config{DB: db})
+		if err != nil {
+			t.Fatal(err)
+		}
+		serve(t, tracer, "/v1?user_id=alice&access_token=a")
+		stopTracer(t, tracer)
+		if !strings.Contains(logs.String(), "unknown_columns=[plan]") {
+			t.Errorf("no schema drift warning for the plan column in %q", logs.String())
+		}
+
+		result, err := tracer.Store().Query(ctx, trace.QueryFilter{UserID: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Steps) != 2 {
+			t.Fatalf("Query = %d steps, want the newer and this writer's step", len(result.Steps))
+		}
+	})
+
+	t.Run("older schema, this reader", func(t *testing.T) {
+		db := tracetest.NewTempDB(t)
+		if err := db.Table("steps").AutoMigrate(&olderStep{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Table("steps").Create(&olderStep{TraceID: "old", UserID: "bob", Path: "/v0", Method: "GET", StatusCode: 200, CreatedAt: time.Now().Unix(), Seq: 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		store, err := trace.NewStore(db, trace.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := store.Query(ctx, trace.QueryFilter{UserID: "bob"})
+		if err != nil {
+			t.Fatalf("Query on a table without newer columns: %v", err)
+		}
+		if len(result.Steps) != 1 || result.Steps[0].Path != "/v0" || result.Steps[0].SpanID != "" {
+			t.Fatalf("Query = %+v, want the old step with newer fields empty", result.Steps)
+		}
+		var csv strings.Builder
+		if err := store.Export(ctx, trace.QueryFilter{}, trace.ExportCSV, &csv); err != nil {
+			t.Fatalf("Export on a table without newer columns: %v", err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package trace
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Sink Step 저장소 인터페이스
+// Write는 재시도될 수 있으므로 같은 배치가 여러 번 전달될 수 있다
+type Sink interface {
+	Write(ctx context.Context, steps []Step) error
+	Close() error
+}
+
+// GORM 배치 INSERT 크기 (메모리 효율성을 위해 500으로 제한)
+const gormInsertBatchSize = 500
+
+// GormSink GORM을 사용하는 기본 Sink
+type GormSink struct {
+	DB *gorm.DB
+	// 저장할 테이블 (비어 있으면 "steps")
+	Table string
+
+	// true면 Close 시 커넥션 풀을 닫음 (SeparateConnection으로 연 풀)
+	ownsConn bool
+	// 설정 시 생성일별 테이블에 저장 (PartitionByDay)
+	partitions *partitionWriter
+}
+
+// NewGormSink - GORM Sink 생성 (Step 테이블 마이그레이션 포함)
+func NewGormSink(db *gorm.DB) (*GormSink, error) {
+	return NewGormSinkWithTable(db, defaultStepTable)
+}
+
+// NewGormSinkWithTable - 지정한 테이블("schema.table" 가능)에 저장하는 GORM Sink 생성
+func NewGormSinkWithTable(db *gorm.DB, table string) (*GormSink, error) {
+	if table == "" {
+		table = defaultStepTable
+	}
+	if err := db.Table(table).AutoMigrate(&Step{}); err != nil {
+		return nil, err
+	}
+	warnUnknownColumns(db, table)
+	return &GormSink{DB: db, Table: table}, nil
+}
+
+// Write - 배치 단위로 저장
+func (s *GormSink) Write(ctx context.Context, steps []Step) error {
+	if s.partitions != nil {
+		return s.partitions.write(ctx, s.DB, steps)
+	}
+	table := s.Table
+	if table == "" {
+		table = defaultStepTable
+	}
+	return s.DB.WithContext(ctx).Table(table).CreateInBatches(steps, gormInsertBatchSize).Error
+}
+
+// Close - 직접 연 커넥션 풀만 닫음 (애플리케이션이 넘긴 DB는 닫지 않음)
+func (s *GormSink) Close() error {
+	if !s.ownsConn {
+		return nil
+	}
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
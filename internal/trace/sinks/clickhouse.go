@@ -0,0 +1,112 @@
+package sinks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"trace/internal/trace"
+)
+
+// ClickHouseSink ClickHouse에 Step을 배치 INSERT 하는 Sink
+// clickhouse-go(v2)의 database/sql 드라이버로 연 *sql.DB를 사용하면
+// 트랜잭션 안의 prepared INSERT가 native 프로토콜의 배치 전송 하나로 처리된다
+type ClickHouseSink struct {
+	DB    *sql.DB
+	Table string
+}
+
+// clickHouseColumns 저장하는 컬럼 (ClickHouseSchema와 순서 일치)
+var clickHouseColumns = []string{
+	"trace_id", "user_id", "tenant_id", "path", "method", "status_code", "latency_ms",
+	"ip", "user_agent", "created_at", "version", "team", "pipeline_lag_ms",
+	"extra", "error", "span_id", "parent_span_id", "span_name",
+}
+
+// NewClickHouseSink - ClickHouse Sink 생성
+func NewClickHouseSink(db *sql.DB, table string) (*ClickHouseSink, error) {
+	if db == nil {
+		return nil, errors.New("sinks: clickhouse db is required")
+	}
+	if table == "" {
+		table = "trace_steps"
+	}
+	return &ClickHouseSink{DB: db, Table: table}, nil
+}
+
+// ClickHouseSchema - 권장 MergeTree 테이블 DDL 반환
+// 경로/시각 순으로 정렬하고 일 단위로 파티션을 나누며, ttl이 0보다 크면 오래된 데이터를 자동 삭제한다
+func ClickHouseSchema(table string, ttl time.Duration) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `CREATE TABLE IF NOT EXISTS %s
+(
+    trace_id        String,
+    user_id         String,
+    tenant_id       LowCardinality(String),
+    path            LowCardinality(String),
+    method          LowCardinality(String),
+    status_code     UInt16,
+    latency_ms      Int64,
+    ip              String,
+    user_agent      String,
+    created_at      DateTime,
+    version         LowCardinality(String),
+    team            LowCardinality(String),
+    pipeline_lag_ms Int64,
+    extra           String,
+    error           String,
+    span_id         String,
+    parent_span_id  String,
+    span_name       LowCardinality(String)
+)
+ENGINE = MergeTree
+PARTITION BY toYYYYMMDD(created_at)
+ORDER BY (path, created_at)`, table)
+	if days := int(ttl / (24 * time.Hour)); days > 0 {
+		fmt.Fprintf(&b, "\nTTL created_at + INTERVAL %d DAY", days)
+	}
+	return b.String()
+}
+
+// EnsureSchema - 권장 스키마로 테이블 생성 (이미 있으면 그대로 둠)
+func (s *ClickHouseSink) EnsureSchema(ctx context.Context, ttl time.Duration) error {
+	_, err := s.DB.ExecContext(ctx, ClickHouseSchema(s.Table, ttl))
+	return err
+}
+
+// Write - 트랜잭션 하나로 배치 INSERT
+func (s *ClickHouseSink) Write(ctx context.Context, steps []trace.Step) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(clickHouseColumns)), ", ")
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		s.Table, strings.Join(clickHouseColumns, ", "), placeholders))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, step := range steps {
+		_, err := stmt.ExecContext(ctx,
+			step.TraceID, step.UserID, step.TenantID, step.Path, step.Method, uint16(step.StatusCode), step.LatencyMs,
+			step.IP, step.UserAgent, time.Unix(step.CreatedAt, 0), step.Version, step.Team, step.PipelineLagMs,
+			step.Extra, step.Error, step.SpanID, step.ParentSpanID, step.SpanName,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close - DB는 호출자가 관리하므로 닫지 않음
+func (s *ClickHouseSink) Close() error {
+	return nil
+}
@@ -0,0 +1,162 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"trace/internal/trace"
+)
+
+// ElasticsearchSink _bulk API로 Step을 일별 인덱스(예: trace-2024.06.01)에 저장하는 Sink
+type ElasticsearchSink struct {
+	// Elasticsearch 주소 (예: http://localhost:9200)
+	URL string
+	// 인덱스 이름 접두사 (기본 "trace")
+	IndexPrefix string
+	// _bulk refresh 파라미터 ("", "true", "false", "wait_for")
+	Refresh string
+	// 요청마다 추가할 헤더 (Authorization 등)
+	Header http.Header
+	Client *http.Client
+}
+
+// NewElasticsearchSink - Elasticsearch Sink 생성
+func NewElasticsearchSink(url string) *ElasticsearchSink {
+	return &ElasticsearchSink{
+		URL:         strings.TrimRight(url, "/"),
+		IndexPrefix: "trace",
+		Client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// indexName - Step 생성 시각(UTC) 기준 일별 인덱스 이름
+func (s *ElasticsearchSink) indexName(step trace.Step) string {
+	return s.IndexPrefix + "-" + time.Unix(step.CreatedAt, 0).UTC().Format("2006.01.02")
+}
+
+// Write - 배치를 _bulk 요청 하나로 저장
+func (s *ElasticsearchSink) Write(ctx context.Context, steps []trace.Step) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, step := range steps {
+		action := map[string]map[string]string{"index": {"_index": s.indexName(step)}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(step); err != nil {
+			return err
+		}
+	}
+
+	url := s.URL + "/_bulk"
+	if s.Refresh != "" {
+		url += "?refresh=" + s.Refresh
+	}
+	resp, err := s.do(ctx, http.MethodPost, url, "application/x-ndjson", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("sinks: failed to decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	// 일부 문서가 실패한 경우 실패 수와 첫 번째 원인을 보고
+	failed := 0
+	var first error
+	for _, item := range result.Items {
+		for _, r := range item {
+			if r.Error == nil {
+				continue
+			}
+			failed++
+			if first == nil {
+				first = fmt.Errorf("%s: %s", r.Error.Type, r.Error.Reason)
+			}
+		}
+	}
+	return fmt.Errorf("sinks: bulk indexing failed for %d of %d steps: %w", failed, len(steps), first)
+}
+
+// EnsureIndexTemplate - 일별 인덱스에 적용될 인덱스 템플릿 등록
+// Kibana에서 바로 검색할 수 있도록 문자열 필드를 keyword로, 시각 필드를 date로 매핑한다
+func (s *ElasticsearchSink) EnsureIndexTemplate(ctx context.Context) error {
+	template := map[string]any{
+		"index_patterns": []string{s.IndexPrefix + "-*"},
+		"template": map[string]any{
+			"mappings": map[string]any{
+				"dynamic_templates": []any{
+					map[string]any{"strings": map[string]any{
+						"match_mapping_type": "string",
+						"mapping":            map[string]any{"type": "keyword"},
+					}},
+				},
+				"properties": map[string]any{
+					"CreatedAt": map[string]any{"type": "date", "format": "epoch_second"},
+					"LatencyMs": map[string]any{"type": "long"},
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, s.URL+"/_index_template/"+s.IndexPrefix, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *ElasticsearchSink) do(ctx context.Context, method, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("sinks: elasticsearch %s %s: %s: %s", method, url, resp.Status, msg)
+	}
+	return resp, nil
+}
+
+// Close - 보관 중인 연결 없음
+func (s *ElasticsearchSink) Close() error {
+	return nil
+}
@@ -0,0 +1,221 @@
+package sinks
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"trace/internal/trace"
+)
+
+// 백업 파일 이름에 붙는 시각 형식
+const backupTimeFormat = "20060102T150405.000"
+
+// FileSink Step을 JSON Lines 파일에 추가하고 크기 기준으로 회전하는 Sink
+// 회전된 파일은 <이름>-<시각>.jsonl(.gz)로 같은 디렉토리에 남는다
+type FileSink struct {
+	// 기록할 파일 경로 (예: /var/log/trace/steps.jsonl)
+	Path string
+	// 이 크기를 넘으면 회전 (0이면 회전하지 않음)
+	MaxSizeBytes int64
+	// 이보다 오래된 백업 삭제 (0이면 기간 제한 없음)
+	MaxAge time.Duration
+	// 보관할 최대 백업 수 (0이면 개수 제한 없음)
+	MaxBackups int
+	// 회전된 파일을 gzip으로 압축
+	Compress bool
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileSink - 파일 Sink 생성 (디렉토리가 없으면 생성)
+func NewFileSink(path string) (*FileSink, error) {
+	if path == "" {
+		return nil, errors.New("sinks: file path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSink{Path: path}, nil
+}
+
+// Write - 배치를 JSON Lines로 추가
+func (s *FileSink) Write(_ context.Context, steps []trace.Step) error {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	for _, step := range steps {
+		if err := enc.Encode(step); err != nil {
+			return err
+		}
+	}
+	data := buf.String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.MaxSizeBytes > 0 && s.size > 0 && s.size+int64(len(data)) > s.MaxSizeBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := io.WriteString(s.file, data)
+	s.size += int64(n)
+	return err
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate - 현재 파일을 백업 이름으로 옮기고 새 파일을 연다
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	backup := s.backupName(time.Now())
+	if err := os.Rename(s.Path, backup); err != nil {
+		return err
+	}
+	if s.Compress {
+		if err := compressFile(backup); err != nil {
+			return err
+		}
+	}
+	if err := s.cleanup(); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *FileSink) backupName(t time.Time) string {
+	ext := filepath.Ext(s.Path)
+	base := strings.TrimSuffix(s.Path, ext)
+	return base + "-" + t.Format(backupTimeFormat) + ext
+}
+
+// backups - 백업 파일 목록 (오래된 순)
+func (s *FileSink) backups() ([]string, error) {
+	ext := filepath.Ext(s.Path)
+	prefix := filepath.Base(strings.TrimSuffix(s.Path, ext)) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(s.Path))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		if _, err := time.Parse(backupTimeFormat, strings.TrimPrefix(stamp, prefix)); err != nil {
+			continue
+		}
+		names = append(names, filepath.Join(filepath.Dir(s.Path), name))
+	}
+	// 시각 형식이 사전순 정렬과 같으므로 이름순이 곧 시간순
+	sort.Strings(names)
+	return names, nil
+}
+
+// cleanup - MaxBackups, MaxAge를 넘는 백업 삭제
+func (s *FileSink) cleanup() error {
+	if s.MaxBackups <= 0 && s.MaxAge <= 0 {
+		return nil
+	}
+	names, err := s.backups()
+	if err != nil {
+		return err
+	}
+
+	var remove []string
+	if s.MaxBackups > 0 && len(names) > s.MaxBackups {
+		remove = append(remove, names[:len(names)-s.MaxBackups]...)
+		names = names[len(names)-s.MaxBackups:]
+	}
+	if s.MaxAge > 0 {
+		cutoff := time.Now().Add(-s.MaxAge)
+		for _, name := range names {
+			if info, err := os.Stat(name); err == nil && info.ModTime().Before(cutoff) {
+				remove = append(remove, name)
+			}
+		}
+	}
+
+	var errs []error
+	for _, name := range remove {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, bufio.NewReader(src)); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// Close - 열린 파일 닫기
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
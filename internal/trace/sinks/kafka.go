@@ -0,0 +1,103 @@
+// sinks 패키지 - trace.Sink 구현 모음
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"trace/internal/trace"
+)
+
+// KafkaMessage Kafka로 보낼 메시지
+type KafkaMessage struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// KafkaProducer Kafka 클라이언트 어댑터
+// segmentio/kafka-go, confluent-kafka-go, sarama 등의 producer를 감싸서 사용한다
+// Produce는 모든 메시지가 전송(ack)된 뒤 반환해야 한다
+type KafkaProducer interface {
+	Produce(ctx context.Context, messages []KafkaMessage) error
+	Close() error
+}
+
+// KafkaSink Step을 Kafka 토픽으로 보내는 Sink
+type KafkaSink struct {
+	Producer KafkaProducer
+	Topic    string
+	// Step 직렬화 함수 (기본 JSON)
+	Encoder func(trace.Step) ([]byte, error)
+	// 파티션 키 함수 (기본 TraceID, 같은 trace는 같은 파티션으로)
+	KeyFunc func(trace.Step) []byte
+}
+
+// KafkaOption 함수형 옵션 타입
+type KafkaOption func(*KafkaSink)
+
+// WithKafkaEncoder Step 직렬화 함수 설정
+func WithKafkaEncoder(encoder func(trace.Step) ([]byte, error)) KafkaOption {
+	return func(s *KafkaSink) {
+		s.Encoder = encoder
+	}
+}
+
+// WithKafkaKeyFunc 파티션 키 함수 설정
+func WithKafkaKeyFunc(keyFunc func(trace.Step) []byte) KafkaOption {
+	return func(s *KafkaSink) {
+		s.KeyFunc = keyFunc
+	}
+}
+
+// NewKafkaSink - Kafka Sink 생성
+func NewKafkaSink(producer KafkaProducer, topic string, opts ...KafkaOption) (*KafkaSink, error) {
+	if producer == nil {
+		return nil, errors.New("sinks: kafka producer is required")
+	}
+	if topic == "" {
+		return nil, errors.New("sinks: kafka topic is required")
+	}
+
+	s := &KafkaSink{
+		Producer: producer,
+		Topic:    topic,
+		Encoder:  encodeJSON,
+		KeyFunc:  traceIDKey,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+func encodeJSON(step trace.Step) ([]byte, error) {
+	return json.Marshal(step)
+}
+
+func traceIDKey(step trace.Step) []byte {
+	return []byte(step.TraceID)
+}
+
+// Write - 배치의 Step마다 메시지를 만들어 한 번에 전송
+func (s *KafkaSink) Write(ctx context.Context, steps []trace.Step) error {
+	messages := make([]KafkaMessage, 0, len(steps))
+	for _, step := range steps {
+		value, err := s.Encoder(step)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, KafkaMessage{
+			Topic: s.Topic,
+			Key:   s.KeyFunc(step),
+			Value: value,
+		})
+	}
+	return s.Producer.Produce(ctx, messages)
+}
+
+// Close - producer 종료
+func (s *KafkaSink) Close() error {
+	return s.Producer.Close()
+}
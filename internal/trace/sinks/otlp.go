@@ -0,0 +1,324 @@
+package sinks
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"trace/internal/trace"
+)
+
+// OTLP span 상수 (opentelemetry-proto trace/v1)
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+	otlpStatusCodeUnset  = 0
+	otlpStatusCodeError  = 2
+)
+
+// OTLPProtocol OTLP 전송 방식
+type OTLPProtocol int
+
+const (
+	// OTLPHTTP OTLP/HTTP(JSON), 수신 주소의 /v1/traces로 전송 (기본 포트 4318)
+	OTLPHTTP OTLPProtocol = iota
+	// OTLPGRPC OTLP/gRPC(protobuf), TraceService/Export 호출 (기본 포트 4317)
+	OTLPGRPC
+)
+
+// OTLPSink Step을 OpenTelemetry 서버 span으로 변환해 OTLP/HTTP(JSON) 또는 OTLP/gRPC로 보내는 Sink
+// Jaeger, Tempo, OpenTelemetry Collector 등 OTLP 수신기를 지원하는 백엔드에서 바로 조회할 수 있다
+type OTLPSink struct {
+	// OTLP 수신 주소 (예: http://otel-collector:4318), OTLPHTTP면 /v1/traces는 자동으로 붙는다
+	Endpoint string
+	Protocol OTLPProtocol
+	// resource의 service.name (기본 "trace", Step에 ServiceName이 있으면 그 값)
+	ServiceName string
+	// true면 Step.UserID를 enduser.id 속성으로 보냄 (기본 false, 사용자 ID가 외부 백엔드로 나가지 않도록)
+	IncludeUserID bool
+	// 요청마다 추가할 헤더 (인증 토큰 등)
+	Header http.Header
+	// OTLPGRPC면 HTTP/2 클라이언트여야 한다 (NewOTLPGRPCSink가 생성)
+	Client *http.Client
+}
+
+// OTLPOption 함수형 옵션 타입
+type OTLPOption func(*OTLPSink)
+
+// WithOTLPUserID Step.UserID를 enduser.id 속성으로 보냄 (기본 비활성화)
+func WithOTLPUserID() OTLPOption {
+	return func(s *OTLPSink) {
+		s.IncludeUserID = true
+	}
+}
+
+// WithOTLPHeader 요청마다 추가할 헤더 설정 (인증 토큰 등)
+func WithOTLPHeader(key, value string) OTLPOption {
+	return func(s *OTLPSink) {
+		if s.Header == nil {
+			s.Header = http.Header{}
+		}
+		s.Header.Add(key, value)
+	}
+}
+
+// NewOTLPSink - OTLP/HTTP Sink 생성
+func NewOTLPSink(endpoint string, opts ...OTLPOption) *OTLPSink {
+	s := &OTLPSink{
+		Endpoint:    strings.TrimRight(endpoint, "/"),
+		ServiceName: "trace",
+		Client:      &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewOTLPGRPCSink - OTLP/gRPC Sink 생성
+// http:// 주소는 평문 HTTP/2(h2c), https:// 주소는 TLS로 연결한다
+func NewOTLPGRPCSink(endpoint string, opts ...OTLPOption) *OTLPSink {
+	s := NewOTLPSink(endpoint, opts...)
+	s.Protocol = OTLPGRPC
+	s.Client = newGRPCClient(s.Endpoint)
+	return s
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"` // int64는 JSON에서 문자열
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+// otlpRequest ExportTraceServiceRequest의 JSON 표현
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func stringAttr(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+func intAttr(key string, value int64) otlpKeyValue {
+	s := strconv.FormatInt(value, 10)
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{IntValue: &s}}
+}
+
+func boolAttr(key string, value bool) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{BoolValue: &value}}
+}
+
+// otlpSpanID - 요청마다 다른 8바이트 span ID (같은 배치가 재시도되어도 같은 값)
+// Step에 span ID가 있으면 그대로 사용한다
+func otlpSpanID(step trace.Step) string {
+	if step.SpanID != "" {
+		return step.SpanID
+	}
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(step.Seq))
+	binary.BigEndian.PutUint64(buf[8:], uint64(step.EnqueuedAtNs))
+	h := sha256.New()
+	h.Write([]byte(step.TraceID))
+	h.Write(buf[:])
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// toOTLPSpan - Step 하나를 span으로 변환
+// 종료 시각은 버퍼 진입 시각, 시작 시각은 종료 시각에서 응답 시간을 뺀 값이다
+func toOTLPSpan(step trace.Step, includeUserID bool) otlpSpan {
+	end := step.EnqueuedAtNs
+	if end == 0 {
+		end = step.CreatedAt * int64(time.Second)
+	}
+	start := end - step.LatencyMs*int64(time.Millisecond)
+
+	attrs := []otlpKeyValue{
+		stringAttr("http.request.method", step.Method),
+		stringAttr("url.path", step.Path),
+		intAttr("http.response.status_code", int64(step.StatusCode)),
+		stringAttr("client.address", step.IP),
+		stringAttr("user_agent.original", step.UserAgent),
+	}
+	if includeUserID && step.UserID != "" {
+		attrs = append(attrs, stringAttr("enduser.id", step.UserID))
+	}
+	if step.TenantID != "" {
+		attrs = append(attrs, stringAttr("tenant.id", step.TenantID))
+	}
+	if step.SessionID != "" {
+		attrs = append(attrs, stringAttr("session.id", step.SessionID))
+	}
+	if step.PodName != "" {
+		attrs = append(attrs, stringAttr("k8s.pod.name", step.PodName))
+	}
+	if step.NodeName != "" {
+		attrs = append(attrs, stringAttr("k8s.node.name", step.NodeName))
+	}
+	if step.Namespace != "" {
+		attrs = append(attrs, stringAttr("k8s.namespace.name", step.Namespace))
+	}
+	if step.Country != "" {
+		attrs = append(attrs, stringAttr("geo.country.iso_code", step.Country))
+	}
+	if step.Region != "" {
+		attrs = append(attrs, stringAttr("geo.region.name", step.Region))
+	}
+	if step.City != "" {
+		attrs = append(attrs, stringAttr("geo.locality.name", step.City))
+	}
+	if step.Version != "" {
+		attrs = append(attrs, stringAttr("service.version", step.Version))
+	}
+	if step.Team != "" {
+		attrs = append(attrs, stringAttr("trace.team", step.Team))
+	}
+	if step.RequestBytes > 0 {
+		attrs = append(attrs, intAttr("http.request.body.size", step.RequestBytes))
+	}
+	if step.ResponseBytes > 0 {
+		attrs = append(attrs, intAttr("http.response.body.size", step.ResponseBytes))
+	}
+	if step.CacheHit {
+		attrs = append(attrs, boolAttr("trace.cache_hit", true), stringAttr("trace.cache_name", step.CacheName))
+	}
+
+	status := otlpStatusCodeUnset
+	if step.StatusCode >= 500 {
+		status = otlpStatusCodeError
+	}
+
+	// StartSpan으로 기록한 하위 span은 내부 span
+	name, kind := step.Method+" "+step.Path, otlpSpanKindServer
+	if step.SpanName != "" {
+		name, kind = step.SpanName, otlpSpanKindInternal
+		if step.Error != "" {
+			status = otlpStatusCodeError
+		}
+	}
+
+	return otlpSpan{
+		TraceID:           trace.HexTraceID(step.TraceID),
+		SpanID:            otlpSpanID(step),
+		ParentSpanID:      step.ParentSpanID,
+		Name:              name,
+		Kind:              kind,
+		StartTimeUnixNano: strconv.FormatInt(start, 10),
+		EndTimeUnixNano:   strconv.FormatInt(end, 10),
+		Attributes:        attrs,
+		Status:            otlpStatus{Message: step.Error, Code: status},
+	}
+}
+
+// Write - 배치를 ExportTraceServiceRequest 하나로 전송
+func (s *OTLPSink) Write(ctx context.Context, steps []trace.Step) error {
+	// Step의 서비스 이름과 배포 환경별로 resource를 나눈다 (Step에 없으면 OTLPSink.ServiceName)
+	type resourceKey struct{ service, environment string }
+	var req otlpRequest
+	index := make(map[resourceKey]int)
+	for _, step := range steps {
+		key := resourceKey{cmp.Or(step.ServiceName, s.ServiceName), step.Environment}
+		i, ok := index[key]
+		if !ok {
+			attrs := []otlpKeyValue{stringAttr("service.name", key.service)}
+			if key.environment != "" {
+				attrs = append(attrs, stringAttr("deployment.environment.name", key.environment))
+			}
+			i = len(req.ResourceSpans)
+			index[key] = i
+			req.ResourceSpans = append(req.ResourceSpans, otlpResourceSpans{
+				Resource:   otlpResource{Attributes: attrs},
+				ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "trace"}}},
+			})
+		}
+		scope := &req.ResourceSpans[i].ScopeSpans[0]
+		scope.Spans = append(scope.Spans, toOTLPSpan(step, s.IncludeUserID))
+	}
+
+	if s.Protocol == OTLPGRPC {
+		return s.exportGRPC(ctx, req)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Endpoint+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.Header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("sinks: otlp export: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+// Close - 보관 중인 연결 없음
+func (s *OTLPSink) Close() error {
+	return nil
+}
@@ -0,0 +1,183 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// OTLP/gRPC 호출 경로 (opentelemetry-proto collector/trace/v1)
+const otlpGRPCExportPath = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+
+// newGRPCClient - gRPC 호출용 HTTP/2 클라이언트 (http:// 주소는 TLS 없이 연결)
+func newGRPCClient(endpoint string) *http.Client {
+	transport := &http2.Transport{}
+	if !strings.HasPrefix(endpoint, "https://") {
+		transport.AllowHTTP = true
+		transport.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}
+}
+
+// exportGRPC - 요청을 protobuf로 인코딩하여 TraceService/Export 호출
+// gRPC 상태는 응답 trailer(또는 trailer만 있는 응답의 헤더)의 grpc-status로 판단한다
+func (s *OTLPSink) exportGRPC(ctx context.Context, req otlpRequest) error {
+	message := marshalOTLPRequest(req)
+	// gRPC 메시지 프레임: 압축 여부(1바이트) + 길이(4바이트, big endian) + 메시지
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+
+	target, err := url.JoinPath(s.Endpoint, otlpGRPCExportPath)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(frame))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.Header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	httpReq.Header.Set("TE", "trailers")
+
+	client := s.Client
+	if client == nil {
+		client = newGRPCClient(s.Endpoint)
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// trailer는 본문을 끝까지 읽은 뒤에 채워진다
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sinks: otlp grpc export: %s", resp.Status)
+	}
+
+	status, grpcMessage := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, grpcMessage = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		msg, _ := url.PathUnescape(grpcMessage)
+		return fmt.Errorf("sinks: otlp grpc export: grpc-status=%q: %s", status, msg)
+	}
+	return nil
+}
+
+// marshalOTLPRequest - ExportTraceServiceRequest의 protobuf 인코딩
+// 필드 번호는 opentelemetry-proto trace/v1, common/v1, resource/v1을 따른다
+func marshalOTLPRequest(req otlpRequest) []byte {
+	var b []byte
+	for _, rs := range req.ResourceSpans {
+		b = appendMessage(b, 1, marshalResourceSpans(rs))
+	}
+	return b
+}
+
+func marshalResourceSpans(rs otlpResourceSpans) []byte {
+	var resource []byte
+	for _, attr := range rs.Resource.Attributes {
+		resource = appendMessage(resource, 1, marshalKeyValue(attr))
+	}
+	b := appendMessage(nil, 1, resource)
+	for _, ss := range rs.ScopeSpans {
+		var scopeSpans []byte
+		scopeSpans = appendMessage(scopeSpans, 1, appendString(nil, 1, ss.Scope.Name))
+		for _, span := range ss.Spans {
+			scopeSpans = appendMessage(scopeSpans, 2, marshalSpan(span))
+		}
+		b = appendMessage(b, 2, scopeSpans)
+	}
+	return b
+}
+
+func marshalSpan(span otlpSpan) []byte {
+	b := appendHexBytes(nil, 1, span.TraceID)
+	b = appendHexBytes(b, 2, span.SpanID)
+	b = appendHexBytes(b, 4, span.ParentSpanID)
+	b = appendString(b, 5, span.Name)
+	b = protowire.AppendTag(b, 6, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(span.Kind))
+	b = appendFixedNanos(b, 7, span.StartTimeUnixNano)
+	b = appendFixedNanos(b, 8, span.EndTimeUnixNano)
+	for _, attr := range span.Attributes {
+		b = appendMessage(b, 9, marshalKeyValue(attr))
+	}
+
+	status := appendString(nil, 2, span.Status.Message)
+	if span.Status.Code != 0 {
+		status = protowire.AppendTag(status, 3, protowire.VarintType)
+		status = protowire.AppendVarint(status, uint64(span.Status.Code))
+	}
+	return appendMessage(b, 15, status)
+}
+
+func marshalKeyValue(kv otlpKeyValue) []byte {
+	var value []byte
+	switch {
+	case kv.Value.StringValue != nil:
+		value = protowire.AppendTag(value, 1, protowire.BytesType)
+		value = protowire.AppendString(value, *kv.Value.StringValue)
+	case kv.Value.BoolValue != nil:
+		value = protowire.AppendTag(value, 2, protowire.VarintType)
+		value = protowire.AppendVarint(value, protowire.EncodeBool(*kv.Value.BoolValue))
+	case kv.Value.IntValue != nil:
+		n, _ := strconv.ParseInt(*kv.Value.IntValue, 10, 64)
+		value = protowire.AppendTag(value, 3, protowire.VarintType)
+		value = protowire.AppendVarint(value, uint64(n))
+	}
+	b := appendString(nil, 1, kv.Key)
+	return appendMessage(b, 2, value)
+}
+
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+// appendString - 빈 문자열은 proto3 기본값이므로 생략
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// appendHexBytes - JSON 표현의 hex ID를 bytes 필드로 기록 (빈 값이나 hex가 아닌 값은 생략)
+func appendHexBytes(b []byte, num protowire.Number, id string) []byte {
+	raw, err := hex.DecodeString(id)
+	if err != nil || len(raw) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, raw)
+}
+
+func appendFixedNanos(b []byte, num protowire.Number, nanos string) []byte {
+	n, _ := strconv.ParseInt(nanos, 10, 64)
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, uint64(n))
+}
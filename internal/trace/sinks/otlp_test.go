@@ -0,0 +1,132 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"trace/internal/trace"
+)
+
+func otlpTestSteps() []trace.Step {
+	return []trace.Step{{
+		TraceID:      "0123456789abcdef0123456789abcdef",
+		SpanID:       "0123456789abcdef",
+		UserID:       "user-1",
+		Method:       "GET",
+		Path:         "/orders/:id",
+		StatusCode:   503,
+		LatencyMs:    12,
+		EnqueuedAtNs: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(),
+	}}
+}
+
+func TestOTLPHTTPSendsUserIDOnlyWhenEnabled(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	if err := NewOTLPSink(srv.URL).Write(context.Background(), otlpTestSteps()); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(body, []byte("enduser.id")) || bytes.Contains(body, []byte("user-1")) {
+		t.Fatalf("user ID exported by default: %s", body)
+	}
+
+	if err := NewOTLPSink(srv.URL, WithOTLPUserID()).Write(context.Background(), otlpTestSteps()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(body, []byte(`"enduser.id"`)) {
+		t.Fatalf("user ID not exported with WithOTLPUserID: %s", body)
+	}
+}
+
+// grpcServer - h2c로 TraceService/Export를 받는 테스트 서버 (받은 protobuf 메시지를 전달)
+func grpcServer(t *testing.T, status string, messages chan<- []byte) *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpGRPCExportPath || r.Header.Get("Content-Type") != "application/grpc" || r.ProtoMajor != 2 {
+			t.Errorf("unexpected request: %s %s %q", r.Proto, r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		frame, _ := io.ReadAll(r.Body)
+		if len(frame) < 5 || int(binary.BigEndian.Uint32(frame[1:5])) != len(frame)-5 {
+			t.Errorf("malformed grpc frame: %d bytes", len(frame))
+			return
+		}
+		messages <- frame[5:]
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Grpc-Status", status)
+		w.Header().Set("Grpc-Message", "unavailable%20now")
+	})
+	srv := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// protoStrings - 메시지 안의 모든 length-delimited 필드를 재귀적으로 문자열로 모음
+func protoStrings(b []byte) []string {
+	var out []string
+	for len(b) > 0 {
+		_, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return out
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(0, typ, b)
+		if n < 0 {
+			return out
+		}
+		if typ == protowire.BytesType {
+			v, _ := protowire.ConsumeBytes(b)
+			out = append(out, string(v))
+			out = append(out, protoStrings(v)...)
+		}
+		b = b[n:]
+	}
+	return out
+}
+
+func TestOTLPGRPCExport(t *testing.T) {
+	messages := make(chan []byte, 1)
+	srv := grpcServer(t, "0", messages)
+
+	sink := NewOTLPGRPCSink(srv.URL)
+	if err := sink.Write(context.Background(), otlpTestSteps()); err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Join(protoStrings(<-messages), "\n")
+	for _, want := range []string{"service.name", "GET /orders/:id", "http.response.status_code", "\x01\x23\x45\x67\x89\xab\xcd\xef"} {
+		if !strings.Contains(fields, want) {
+			t.Errorf("exported message has no %q", want)
+		}
+	}
+	if strings.Contains(fields, "enduser.id") {
+		t.Error("user ID exported by default")
+	}
+}
+
+func TestOTLPGRPCExportReportsStatus(t *testing.T) {
+	messages := make(chan []byte, 1)
+	srv := grpcServer(t, "14", messages)
+
+	err := NewOTLPGRPCSink(srv.URL).Write(context.Background(), otlpTestSteps())
+	if err == nil || !strings.Contains(err.Error(), `grpc-status="14"`) || !strings.Contains(err.Error(), "unavailable now") {
+		t.Fatalf("err = %v, want grpc-status 14", err)
+	}
+}
@@ -0,0 +1,43 @@
+package trace
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// countingBody - 핸들러가 읽은 요청 본문 크기 집계 (Content-Length가 없는 chunked 요청용)
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// startRequestSize - Content-Length를 알 수 없는 요청이면 본문을 감싸 읽은 크기를 센다
+func startRequestSize(c *gin.Context) *countingBody {
+	if c.Request.ContentLength >= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil
+	}
+	body := &countingBody{ReadCloser: c.Request.Body}
+	c.Request.Body = body
+	return body
+}
+
+// requestBytes - 요청 본문 크기 (Content-Length, 없으면 핸들러가 읽은 크기)
+func requestBytes(c *gin.Context, body *countingBody) int64 {
+	if body != nil {
+		return body.n
+	}
+	return max(c.Request.ContentLength, 0)
+}
+
+// responseBytes - 핸들러가 쓴 응답 본문 크기 (아무것도 쓰지 않았으면 0)
+func responseBytes(c *gin.Context) int64 {
+	return int64(max(c.Writer.Size(), 0))
+}
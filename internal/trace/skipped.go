@@ -0,0 +1,209 @@
+package trace
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 추적할 최대 (메서드, 경로, 상태 코드) 수 (경로에 ID가 들어가 무한히 늘어나는 경우 대비, 넘으면 새 키는 무시)
+const maxEndpointCounters = 1000
+
+// 요청 지연 시간 히스토그램 버킷 상한 (초, 롤업의 rollupBounds와 같은 구간)
+var requestLatencyBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// WithCountSkipped - 미들웨어가 저장하지 않기로 한 요청도 경로, 상태 코드별 요청 수와 지연 시간 집계
+// 필터(WithFilter, UpdateConfig의 SkipPaths), 사용자/세션 식별자 없음, 샘플링으로 제외한 요청은 Step을 만들지 않고
+// Stats()의 endpoints와 롤업 테이블(Rollups)에 uncollected로 더하므로, 저장한 요청만으로 계산한 지연 시간과 요청 수를 보정할 수 있다
+// 집계는 추출 함수를 호출하지 않으며 메모리를 할당하지 않는다 (경로, 상태 코드 조합이 처음 나올 때만 할당)
+func WithCountSkipped() MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.CountSkipped = true
+	}
+}
+
+// EndpointCounts 메서드, 경로, 상태 코드별로 저장한 요청과 저장하지 않은 요청의 지연 시간 분포 (WithCountSkipped)
+// 백분위는 히스토그램 버킷 상한이며, 10초를 넘는 값은 10000으로 표시한다
+type EndpointCounts struct {
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	StatusCode int    `json:"status_code"`
+	// Step으로 저장한 요청 (StepFilter로 버린 Step 포함)
+	Collected LagStats `json:"collected"`
+	// 필터, 식별자 없음, 샘플링으로 Step을 만들지 않은 요청
+	Uncollected LagStats `json:"uncollected"`
+}
+
+type endpointKey struct {
+	method, path string
+	status       int
+}
+
+type endpointCounter struct {
+	collected   *histogram
+	uncollected *histogram
+}
+
+// uncollectedRollup - 롤업 테이블에 아직 더하지 않은 저장하지 않은 요청 증가분
+type uncollectedRollup struct {
+	requests     int64
+	errors       int64
+	latencySumMs int64
+}
+
+// endpointCounters - Tracer 시작 후 누적한 메서드, 경로, 상태 코드별 요청 지연 시간
+type endpointCounters struct {
+	mu       sync.RWMutex
+	counters map[endpointKey]*endpointCounter
+
+	// Rollups가 true일 때만 사용하며, 롤업 갱신(배치 저장 후와 Stop)에서 꺼냄
+	rollupMu sync.Mutex
+	rollups  map[rollupKey]uncollectedRollup
+}
+
+// counter - key의 집계 (처음 나온 키면 생성, 키 수가 상한에 도달했으면 nil)
+func (e *endpointCounters) counter(key endpointKey) *endpointCounter {
+	e.mu.RLock()
+	c := e.counters[key]
+	e.mu.RUnlock()
+	if c != nil {
+		return c
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c = e.counters[key]; c != nil {
+		return c
+	}
+	if e.counters == nil {
+		e.counters = make(map[endpointKey]*endpointCounter)
+	}
+	if len(e.counters) >= maxEndpointCounters {
+		return nil
+	}
+	c = &endpointCounter{
+		collected:   newHistogram(requestLatencyBounds...),
+		uncollected: newHistogram(requestLatencyBounds...),
+	}
+	e.counters[key] = c
+	return c
+}
+
+// addUncollected - 저장하지 않은 요청을 롤업 증가분에 더함
+func (e *endpointCounters) addUncollected(key rollupKey, failed bool, latency time.Duration) {
+	e.rollupMu.Lock()
+	defer e.rollupMu.Unlock()
+	if e.rollups == nil {
+		e.rollups = make(map[rollupKey]uncollectedRollup)
+	}
+	r := e.rollups[key]
+	r.requests++
+	if failed {
+		r.errors++
+	}
+	r.latencySumMs += latency.Milliseconds()
+	e.rollups[key] = r
+}
+
+// takeUncollected - 모아 둔 롤업 증가분을 꺼냄
+func (e *endpointCounters) takeUncollected() map[rollupKey]uncollectedRollup {
+	e.rollupMu.Lock()
+	defer e.rollupMu.Unlock()
+	rollups := e.rollups
+	e.rollups = nil
+	return rollups
+}
+
+// restoreUncollected - 롤업 테이블에 더하지 못한 증가분을 다음 갱신에서 다시 시도하도록 되돌림
+func (e *endpointCounters) restoreUncollected(rollups map[rollupKey]uncollectedRollup) {
+	e.rollupMu.Lock()
+	defer e.rollupMu.Unlock()
+	if e.rollups == nil {
+		e.rollups = make(map[rollupKey]uncollectedRollup, len(rollups))
+	}
+	for k, r := range rollups {
+		cur := e.rollups[k]
+		cur.requests += r.requests
+		cur.errors += r.errors
+		cur.latencySumMs += r.latencySumMs
+		e.rollups[k] = cur
+	}
+}
+
+// snapshot - 요청 수가 많은 순 (같으면 메서드, 경로, 상태 코드 순)
+func (e *endpointCounters) snapshot() []EndpointCounts {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if len(e.counters) == 0 {
+		return nil
+	}
+	counts := make([]EndpointCounts, 0, len(e.counters))
+	for k, c := range e.counters {
+		counts = append(counts, EndpointCounts{
+			Method:      k.method,
+			Path:        k.path,
+			StatusCode:  k.status,
+			Collected:   c.collected.summary(),
+			Uncollected: c.uncollected.summary(),
+		})
+	}
+	slices.SortFunc(counts, func(a, b EndpointCounts) int {
+		if n := cmp.Compare(b.Collected.Count+b.Uncollected.Count, a.Collected.Count+a.Uncollected.Count); n != 0 {
+			return n
+		}
+		if n := strings.Compare(a.Method, b.Method); n != 0 {
+			return n
+		}
+		if n := strings.Compare(a.Path, b.Path); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.StatusCode, b.StatusCode)
+	})
+	return counts
+}
+
+// countRequest - 요청 하나를 메서드, 경로, 상태 코드별 집계에 더함 (collected가 false면 롤업 증가분에도 더함)
+func (t *Tracer) countRequest(method, path string, status int, latency time.Duration, collected bool) {
+	if t == nil {
+		return
+	}
+	c := t.endpoints.counter(endpointKey{method, path, status})
+	if c == nil {
+		return
+	}
+	if collected {
+		c.collected.observe(latency)
+		return
+	}
+	c.uncollected.observe(latency)
+	if t.rollupDB != nil {
+		now := time.Now().Unix()
+		t.endpoints.addUncollected(rollupKey{path, method, now - now%60}, status >= 500, latency)
+	}
+}
+
+// skipRequest - 저장하지 않는 요청의 핸들러 실행 (CountSkipped이면 지연 시간과 상태 코드만 집계)
+func skipRequest(c *gin.Context, config *MiddlewareConfig, tracer *Tracer) {
+	if !config.CountSkipped {
+		finishPanic(c, config.PanicMode, next(c, config.PanicMode))
+		return
+	}
+	start := time.Now()
+	recovered := next(c, config.PanicMode)
+	elapsed := time.Since(start)
+	defer finishPanic(c, config.PanicMode, recovered)
+	tracer.countRequest(c.Request.Method, routePath(c), responseStatus(c, recovered), elapsed, false)
+}
+
+// responseStatus - 응답 상태 코드 (panic을 복구했으면 500)
+func responseStatus(c *gin.Context, recovered *recoveredPanic) int {
+	if recovered != nil {
+		return http.StatusInternalServerError
+	}
+	return c.Writer.Status()
+}
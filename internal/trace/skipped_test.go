@@ -0,0 +1,149 @@
+package trace_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// endpointCounts - Stats의 endpoints에서 method, path, status에 해당하는 항목
+func endpointCounts(stats trace.PipelineStats, method, path string, status int) trace.EndpointCounts {
+	for _, e := range stats.Endpoints {
+		if e.Method == method && e.Path == path && e.StatusCode == status {
+			return e
+		}
+	}
+	return trace.EndpointCounts{}
+}
+
+func TestCountSkippedMatchesSampleRate(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	tracer, err := trace.New(trace.Config{DB: db, Rollups: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	extracted := 0
+	r := gin.New()
+	r.Use(tracer.Middleware(
+		trace.WithCountSkipped(),
+		trace.WithSampleRate(0.25),
+		trace.WithFilter(func(c *gin.Context) bool { return c.Request.URL.Path != "/healthz" }),
+		trace.WithUserIDExtractor(func(c *gin.Context) string {
+			extracted++
+			return c.Query("user_id")
+		}),
+	))
+	r.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusServiceUnavailable) })
+
+	const requests = 2000
+	for i := range requests {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", fmt.Sprintf("/orders?user_id=u%d&access_token=a", i), nil))
+	}
+	for range 10 {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	}
+	for range 5 {
+		// 식별자가 없어 저장하지 않는 요청
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	}
+	stopTracer(t, tracer)
+
+	// 필터로 제외한 요청은 추출 함수를 호출하지 않음
+	if extracted != requests+5 {
+		t.Fatalf("user ID extractor ran %d times, want %d", extracted, requests+5)
+	}
+
+	stats := tracer.Stats()
+	orders := endpointCounts(stats, "GET", "/orders", http.StatusOK)
+	var stored int64
+	if err := db.Model(&trace.Step{}).Where("path = ?", "/orders").Count(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if orders.Collected.Count != stored {
+		t.Fatalf("collected %d /orders requests, but %d steps were stored", orders.Collected.Count, stored)
+	}
+	if total := orders.Collected.Count + orders.Uncollected.Count; total != requests {
+		t.Fatalf("counted %d /orders requests, want %d", total, requests)
+	}
+	// 표준편차(약 19)의 5배 안에서 샘플링 비율과 일치
+	if ratio := float64(orders.Collected.Count) / requests; math.Abs(ratio-0.25) > 0.05 {
+		t.Fatalf("collected ratio = %.3f, want about 0.25", ratio)
+	}
+	if e := endpointCounts(stats, "GET", "/healthz", http.StatusNoContent); e.Collected.Count != 0 || e.Uncollected.Count != 10 {
+		t.Fatalf("/healthz counts = %+v, want 10 uncollected", e)
+	}
+	if e := endpointCounts(stats, "GET", "/fail", http.StatusServiceUnavailable); e.Collected.Count != 0 || e.Uncollected.Count != 5 {
+		t.Fatalf("/fail counts = %+v, want 5 uncollected", e)
+	}
+
+	// 롤업 테이블에 두 계열이 함께 저장되어 TopEndpoints로 조회
+	top, err := tracer.Store().TopEndpoints(context.Background(), time.Hour, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]trace.EndpointSummary{}
+	for _, e := range top {
+		byPath[e.Path] = e
+	}
+	if e := byPath["/orders"]; e.Count != orders.Collected.Count || e.Uncollected != orders.Uncollected.Count {
+		t.Fatalf("TopEndpoints /orders = %+v, want %d collected and %d uncollected", e, orders.Collected.Count, orders.Uncollected.Count)
+	}
+	if e := byPath["/healthz"]; e.Count != 0 || e.Uncollected != 10 {
+		t.Fatalf("TopEndpoints /healthz = %+v, want 10 uncollected", e)
+	}
+	if e := byPath["/fail"]; e.Uncollected != 5 || e.UncollectedErrorRate != 1 {
+		t.Fatalf("TopEndpoints /fail = %+v, want 5 uncollected errors", e)
+	}
+}
+
+func TestCountSkippedDisabled(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, tracer, "/orders?user_id=u&access_token=a")
+	serve(t, tracer, "/anonymous")
+	stopTracer(t, tracer)
+	if endpoints := tracer.Stats().Endpoints; len(endpoints) != 0 {
+		t.Fatalf("Stats().Endpoints = %+v without WithCountSkipped, want none", endpoints)
+	}
+}
+
+func TestCountSkippedDoesNotAllocate(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t), Rollups: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+
+	// 필터로 제외한 요청과 식별자가 없는 요청의 할당 수가 집계 여부와 관계없이 같음
+	allocs := func(opts ...trace.MiddlewareOption) float64 {
+		r := gin.New()
+		r.Use(tracer.Middleware(append(opts, trace.WithFilter(func(c *gin.Context) bool { return c.Request.URL.Path != "/healthz" }))...))
+		r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+		r.GET("/anonymous", func(c *gin.Context) { c.Status(http.StatusOK) })
+		healthz, anonymous := httptest.NewRequest("GET", "/healthz", nil), httptest.NewRequest("GET", "/anonymous", nil)
+		w := httptest.NewRecorder()
+		// 처음 나온 경로의 집계는 미리 생성
+		r.ServeHTTP(w, healthz)
+		r.ServeHTTP(w, anonymous)
+		return testing.AllocsPerRun(200, func() {
+			r.ServeHTTP(w, healthz)
+			r.ServeHTTP(w, anonymous)
+		})
+	}
+	if without, with := allocs(), allocs(trace.WithCountSkipped()); with > without {
+		t.Fatalf("skipped requests allocate %.1f times with WithCountSkipped, %.1f without", with, without)
+	}
+}
@@ -0,0 +1,48 @@
+package trace
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogHandler - 로그 레코드에 요청 컨텍스트의 trace_id, user_id를 추가하는 slog.Handler
+type slogHandler struct {
+	next slog.Handler
+}
+
+// NewSlogHandler - next에 전달하는 모든 로그에 컨텍스트의 trace_id와 user_id 필드를 추가하는 slog.Handler 반환
+// slog.InfoContext(c.Request.Context(), ...)처럼 컨텍스트를 넘긴 로그에만 적용되며, WithGroup 이후에는 그룹 안에 기록된다
+func NewSlogHandler(next slog.Handler) slog.Handler {
+	return &slogHandler{next: next}
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx == nil {
+		return h.next.Handle(ctx, record)
+	}
+	traceID, hasTrace := FromContext(ctx)
+	userID, hasUser := UserIDFromContext(ctx)
+	if hasTrace || hasUser {
+		// 다른 핸들러와 공유될 수 있으므로 복사본에 추가
+		record = record.Clone()
+		if hasTrace {
+			record.AddAttrs(slog.String(TraceIDKey, traceID))
+		}
+		if hasUser {
+			record.AddAttrs(slog.String(UserIDKey, userID))
+		}
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &slogHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	return &slogHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,121 @@
+package trace
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 느린 엔드포인트마다 반환할 예시 trace 수
+const slowestExamples = 3
+
+// SlowestBy SlowestEndpoints 정렬 기준
+type SlowestBy int
+
+const (
+	// SlowestByP95 p95 지연 시간 순 (기본값)
+	SlowestByP95 SlowestBy = iota
+	// SlowestByMax 최대 지연 시간 순
+	SlowestByMax
+)
+
+func (b SlowestBy) String() string {
+	switch b {
+	case SlowestByP95:
+		return "p95"
+	case SlowestByMax:
+		return "max"
+	default:
+		return fmt.Sprintf("SlowestBy(%d)", int(b))
+	}
+}
+
+// SlowEndpoint 느린 엔드포인트와 가장 느렸던 요청의 Trace ID
+type SlowEndpoint struct {
+	EndpointSummary
+	MaxMs int64 `json:"max_ms"`
+	// 가장 느린 요청부터 최대 3개 (waterfall에서 바로 열어 볼 수 있음)
+	ExampleTraceIDs []string `json:"example_trace_ids"`
+}
+
+// SlowestEndpoints - 최근 window 동안 가장 느린 엔드포인트 n개 반환 (최적화 대상 찾기용, 하위 span 제외)
+// 백분위는 Aggregate와 같은 방식으로 계산하며, 엔드포인트마다 가장 느린 요청의 Trace ID를 함께 반환한다
+func SlowestEndpoints(ctx context.Context, db *gorm.DB, window time.Duration, n int, by SlowestBy) ([]SlowEndpoint, error) {
+	return storeFor(db).SlowestEndpoints(ctx, window, n, by)
+}
+
+// SlowestEndpoints - 최근 window 동안 가장 느린 엔드포인트 n개 반환
+func (s *Store) SlowestEndpoints(ctx context.Context, window time.Duration, n int, by SlowestBy) ([]SlowEndpoint, error) {
+	to := time.Now()
+	from := to.Add(-window)
+	rows, err := s.Aggregate(ctx, AggQuery{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := s.stepsFrom(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	scope := func() *gorm.DB {
+		return steps().
+			Where(requestStepsOnly).
+			Where("created_at >= ? AND created_at < ?", from.Unix(), to.Unix())
+	}
+
+	var maxRows []struct {
+		Path   string
+		Method string
+		MaxMs  int64
+	}
+	err = scope().
+		Select("path, method, MAX(latency_ms) AS max_ms").
+		Group("path, method").
+		Scan(&maxRows).Error
+	if err != nil {
+		return nil, err
+	}
+	maxMs := make(map[[2]string]int64, len(maxRows))
+	for _, r := range maxRows {
+		maxMs[[2]string{r.Path, r.Method}] = r.MaxMs
+	}
+
+	slowest := make([]SlowEndpoint, 0, len(rows))
+	for _, r := range rows {
+		slowest = append(slowest, SlowEndpoint{
+			EndpointSummary: EndpointSummary{
+				Path:      r.Path,
+				Method:    r.Method,
+				Count:     r.Count,
+				P50Ms:     r.P50Ms,
+				P95Ms:     r.P95Ms,
+				P99Ms:     r.P99Ms,
+				ErrorRate: r.ErrorRate,
+			},
+			MaxMs: maxMs[[2]string{r.Path, r.Method}],
+		})
+	}
+	slices.SortFunc(slowest, func(a, b SlowEndpoint) int {
+		if by == SlowestByMax {
+			return cmp.Or(cmp.Compare(b.MaxMs, a.MaxMs), cmp.Compare(b.P95Ms, a.P95Ms))
+		}
+		return cmp.Or(cmp.Compare(b.P95Ms, a.P95Ms), cmp.Compare(b.MaxMs, a.MaxMs))
+	})
+	slowest = slowest[:min(max(n, 0), len(slowest))]
+
+	for i := range slowest {
+		err := scope().
+			Where("path = ? AND method = ?", slowest[i].Path, slowest[i].Method).
+			Order("latency_ms DESC").
+			Limit(slowestExamples).
+			Pluck("trace_id", &slowest[i].ExampleTraceIDs).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return slowest, nil
+}
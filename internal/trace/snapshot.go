@@ -0,0 +1,91 @@
+package trace
+
+import (
+	"fmt"
+	"sync"
+)
+
+// pendingBatches - 저장을 시작했지만 끝나지 않은 배치 (Stop 시간 초과 시 종료 스냅샷에 기록)
+// flush는 별도 고루틴에서 실행되므로 Stop이 시간 안에 끝나지 않으면 남은 Step은 대부분 버퍼가 아니라 여기에 있다
+type pendingBatches struct {
+	mu      sync.Mutex
+	nextID  uint64
+	batches map[uint64][]Step
+}
+
+// trackPending - 저장 전 배치의 복사본 등록 (ShutdownSnapshotPath가 없으면 0)
+// 저장 과정에서 logs를 고치므로 스냅샷이 함께 읽지 않도록 복사본을 보관한다
+func (t *Tracer) trackPending(logs []Step) uint64 {
+	if t.cfg.ShutdownSnapshotPath == "" {
+		return 0
+	}
+	p := &t.pending
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.batches == nil {
+		p.batches = make(map[uint64][]Step)
+	}
+	p.nextID++
+	p.batches[p.nextID] = append([]Step(nil), logs...)
+	return p.nextID
+}
+
+// done - 배치 등록 해제, 이미 스냅샷에 기록된 배치면 true
+func (p *pendingBatches) done(id uint64) (snapshotted bool) {
+	if id == 0 {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.batches[id]; !ok {
+		return true
+	}
+	delete(p.batches, id)
+	return false
+}
+
+// take - 남은 배치를 모두 꺼냄 (이후 done은 true를 반환)
+func (p *pendingBatches) take() []Step {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var steps []Step
+	for id, batch := range p.batches {
+		steps = append(steps, batch...)
+		delete(p.batches, id)
+	}
+	return steps
+}
+
+// writeShutdownSnapshot - Stop이 시간 안에 끝나지 않았을 때 저장하지 못한 Step을 ShutdownSnapshotPath에 기록
+// 저장 중인 배치와 버퍼에 남은 Step을 기록하며, 버퍼는 이미 닫혀 있으므로 워커와 나눠 받아도 Step은 한쪽에만 전달된다
+// 스냅샷에 기록한 배치는 이후 저장에 실패해도 spill/dead-letter로 보내지 않는다
+func (t *Tracer) writeShutdownSnapshot() error {
+	path := t.cfg.ShutdownSnapshotPath
+	if path == "" {
+		return nil
+	}
+	steps := t.pending.take()
+	drain := func(ch <-chan Step) {
+		for step := range ch {
+			steps = append(steps, step)
+		}
+	}
+	if t.shards != nil {
+		for _, q := range t.shards.queues {
+			drain(q)
+		}
+	} else {
+		drain(t.buffer)
+	}
+	if len(steps) == 0 {
+		return nil
+	}
+	// spill 파일과 마찬가지로 평문이 디스크에 남지 않도록 암호화 (이미 암호화된 값은 그대로)
+	for i := range steps {
+		t.cipher.encryptStep(&steps[i])
+	}
+	if err := appendDLQFile(path, steps); err != nil {
+		return fmt.Errorf("trace: failed to write %d steps to shutdown snapshot %s: %w", len(steps), path, err)
+	}
+	return fmt.Errorf("trace: wrote %d unsaved steps to shutdown snapshot %s", len(steps), path)
+}
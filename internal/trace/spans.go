@@ -0,0 +1,139 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 조회 API가 하위 span을 제외하고 요청 Step만 집계하기 위한 조건
+// 이전 버전에서 저장된 행은 span_name이 NULL이다
+const requestStepsOnly = "COALESCE(span_name, '') = ''"
+
+type spanRecorderKey struct{}
+
+// spanRecorder - 요청 하나의 하위 span을 모아 두었다가 요청 Step과 함께 저장
+// 요청 Step이 샘플링에서 제외되면 하위 span도 저장하지 않는다
+type spanRecorder struct {
+	emit   func(Step) // 저장 함수 (미들웨어의 Tracer로 전달)
+	parent Step       // 요청 Step의 공통 값 (Trace ID, 요청 span ID 등)
+	b3     *b3State   // B3 연동 설정 (비활성화면 nil)
+	// 헤드 샘플링 결정 (하위 서비스 호출의 X-Trace-Sampled, traceparent sampled 플래그)
+	sampled bool
+
+	mu          sync.Mutex
+	state       spanRecorderState
+	spans       []Step
+	slowQueries []SlowQuery // GORM 플러그인 WithSlowQueries 모드에서 기록한 느린 쿼리
+}
+
+type spanRecorderState int
+
+const (
+	spansPending spanRecorderState = iota // 요청 처리 중
+	spansKept                             // 요청 Step 저장됨, 이후 종료되는 span은 바로 저장
+	spansDropped                          // 요청 Step 제외됨
+)
+
+// installSpanRecorder - 요청 컨텍스트에 하위 span 저장소 등록 (c.Copy로 복사한 컨텍스트에서도 공유)
+// 요청 컨텍스트만 전달받는 코드(NewTransport, GORM 플러그인 등)도 parent 값으로 span을 만들 수 있다
+func installSpanRecorder(c *gin.Context, emit func(Step), parent Step) *spanRecorder {
+	// 샘플링 결정은 미들웨어가 기록 (PopulateContext로 만든 컨텍스트는 저장 대상으로 전달)
+	rec := &spanRecorder{emit: emit, parent: parent, sampled: true}
+	if state, ok := c.Get(b3StateKey); ok {
+		rec.b3, _ = state.(*b3State)
+	}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), spanRecorderKey{}, rec))
+	return rec
+}
+
+func (r *spanRecorder) record(step Step) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch r.state {
+	case spansPending:
+		r.spans = append(r.spans, step)
+	case spansKept:
+		r.emit(step)
+	}
+}
+
+// finish - 요청 Step 저장 여부에 따라 모아 둔 span을 저장하거나 버림
+func (r *spanRecorder) finish(kept bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !kept {
+		r.state = spansDropped
+		r.spans = nil
+		return
+	}
+	r.state = spansKept
+	for _, step := range r.spans {
+		r.emit(step)
+	}
+	r.spans = nil
+}
+
+// Span 요청 안의 하위 작업 (DB 조회, 외부 호출 등)
+type Span struct {
+	rec   *spanRecorder
+	step  Step
+	start time.Time
+	once  sync.Once
+}
+
+// StartSpan - 현재 요청의 하위 span 시작
+// End를 호출하면 요청과 같은 Trace ID, 요청 span을 부모로 하는 Step(SpanName 설정)이 기록된다
+// trace 미들웨어가 수집하지 않는 요청에서는 아무것도 기록하지 않는 span을 반환한다
+func StartSpan(c *gin.Context, name string) *Span {
+	return StartSpanFromContext(c.Request.Context(), name)
+}
+
+// StartSpanFromContext - 요청 컨텍스트(c.Request.Context()와 그 하위 컨텍스트)로 하위 span 시작
+// gin.Context를 직접 전달할 수 없는 라이브러리 코드에서 사용한다
+func StartSpanFromContext(ctx context.Context, name string) *Span {
+	rec := requestSpans(ctx)
+	if rec == nil {
+		return &Span{}
+	}
+
+	step := rec.parent
+	step.SpanID = newSpanID()
+	step.ParentSpanID = rec.parent.SpanID
+	step.SpanName = name
+	return &Span{rec: rec, start: time.Now(), step: step}
+}
+
+// requestSpans - 요청 컨텍스트의 하위 span 저장소 (trace 미들웨어를 거치지 않았으면 nil)
+func requestSpans(ctx context.Context) *spanRecorder {
+	rec, _ := requestContext(ctx).Value(spanRecorderKey{}).(*spanRecorder)
+	return rec
+}
+
+// SetError - span에 에러 기록 (End 전에 호출)
+func (s *Span) SetError(err error) {
+	if s.rec == nil || err == nil {
+		return
+	}
+	s.step.Error = truncateUTF8(err.Error(), maxErrorLen)
+}
+
+// End - span 종료 및 기록 (여러 번 호출해도 한 번만 기록)
+func (s *Span) End() {
+	if s.rec == nil {
+		return
+	}
+	s.once.Do(func() {
+		now := time.Now()
+		s.step.LatencyMs = now.Sub(s.start).Milliseconds()
+		s.step.CreatedAt = now.Unix()
+		s.step.EnqueuedAtNs = now.UnixNano()
+		s.step.enqueuedAt = now
+		s.rec.record(s.step)
+	})
+}
@@ -0,0 +1,309 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// 디스크 spill 기본값
+const (
+	defaultSpillMaxBytes       = 64 << 20
+	defaultSpillReplayInterval = 30 * time.Second
+)
+
+// spill 파일이 SpillMaxBytes에 도달한 경우
+var errSpillFull = errors.New("trace: spill file is full")
+
+// spillFile - 버퍼가 가득 찼거나 저장에 실패한 Step을 보관하는 추가 전용 파일 (dead-letter 파일 형식)
+// 재전송 중인 Step은 path+".replay"로 옮겨 두므로 재전송 도중 종료되어도 다음 시작 시 다시 재전송한다
+type spillFile struct {
+	path     string
+	maxBytes int64
+
+	mu   sync.Mutex
+	f    *os.File
+	w    *DLQWriter
+	size int64
+}
+
+func openSpill(path string, maxBytes int64) (*spillFile, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultSpillMaxBytes
+	}
+	s := &spillFile{path: path, maxBytes: maxBytes}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open - spill 파일을 이어 쓰기로 열기 (비어 있으면 헤더부터 기록)
+func (s *spillFile) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("trace: failed to open spill file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("trace: failed to open spill file: %w", err)
+	}
+	s.f, s.size = f, info.Size()
+	if s.size == 0 {
+		s.w = NewDLQWriter(f)
+	} else {
+		s.w = NewDLQAppender(f)
+	}
+	return nil
+}
+
+func (s *spillFile) replayPath() string {
+	return s.path + ".replay"
+}
+
+// write - Step을 파일 끝에 기록하고 기록한 수 반환 (파일이 가득 차면 errSpillFull)
+func (s *spillFile) write(steps []Step) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return 0, os.ErrClosed
+	}
+	counter := &countingWriter{w: s.f}
+	s.w.w = counter
+	defer func() { s.w.w = s.f }()
+
+	for i, step := range steps {
+		if s.size+counter.n >= s.maxBytes {
+			s.size += counter.n
+			return i, errSpillFull
+		}
+		if err := s.w.Write(step); err != nil {
+			s.size += counter.n
+			return i, err
+		}
+	}
+	s.size += counter.n
+	return len(steps), nil
+}
+
+// take - 재전송할 Step 반환 (이전 재전송 파일이 남아 있으면 그것부터, 없으면 현재 spill 파일을 옮겨서 읽음)
+// 재전송이 끝나면 done(남은 Step)을 호출해야 하며, 남은 Step이 없으면 재전송 파일을 삭제한다
+func (s *spillFile) take() ([]Step, error) {
+	replay := s.replayPath()
+	if _, err := os.Stat(replay); errors.Is(err, os.ErrNotExist) {
+		s.mu.Lock()
+		if s.f == nil || s.size <= int64(dlqHeaderSize) {
+			s.mu.Unlock()
+			return nil, nil
+		}
+		s.f.Close()
+		err := os.Rename(s.path, replay)
+		if openErr := s.open(); err == nil {
+			err = openErr
+		}
+		s.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := os.ReadFile(replay)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < dlqHeaderSize {
+		// 헤더를 쓰기 전에 종료된 경우
+		return nil, os.Remove(replay)
+	}
+	var steps []Step
+	report, err := ReadDLQFile(bytes.NewReader(data), func(step Step) error {
+		steps = append(steps, step)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !report.Valid() {
+		log.Printf("trace spill file %s was damaged: recovered %d steps, skipped %d corrupt records", replay, report.Records, report.Corrupt)
+	}
+	return steps, nil
+}
+
+// done - 재전송하지 못한 Step만 재전송 파일에 남김 (모두 보냈으면 파일 삭제)
+func (s *spillFile) done(remaining []Step) error {
+	replay := s.replayPath()
+	if len(remaining) == 0 {
+		return os.Remove(replay)
+	}
+
+	var buf bytes.Buffer
+	w := NewDLQWriter(&buf)
+	for _, step := range remaining {
+		if err := w.Write(step); err != nil {
+			return err
+		}
+	}
+	tmp := replay + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, replay)
+}
+
+func (s *spillFile) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// countingWriter - 기록한 바이트 수 집계
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// spillSteps - Step을 spill 파일에 기록하고 기록하지 못한 Step 수 반환
+func (t *Tracer) spillSteps(steps []Step) (lost int) {
+	if t.spill == nil {
+		return len(steps)
+	}
+	n, err := t.spill.write(steps)
+	t.spilledSteps.Add(int64(n))
+	if n > 0 {
+		signal(t.spillWake)
+	}
+	if err != nil && !errors.Is(err, errSpillFull) {
+		log.Printf("trace spill write failed: %v", err)
+	}
+	return len(steps) - n
+}
+
+// startReplayer - SpillPath가 설정된 경우 주기적으로 spill 파일의 Step을 Sink에 다시 저장
+func (t *Tracer) startReplayer() {
+	if t.spill == nil {
+		return
+	}
+	interval := t.cfg.SpillReplayInterval
+	if interval <= 0 {
+		interval = defaultSpillReplayInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.replayCancel = cancel
+
+	t.workersWG.Add(1)
+	go func() {
+		defer t.workersWG.Done()
+		timer := t.clock.NewTimer(interval)
+		defer timer.Stop()
+		idle := 0
+		for {
+			if t.replaySpill(ctx) {
+				idle = 0
+			} else {
+				idle++
+			}
+
+			timerC, wake := timer.C(), (chan struct{})(nil)
+			if idle >= idleIntervalsBeforePark {
+				// spill 파일이 계속 비어 있으면 다음 spill 기록까지 타이머 없이 대기
+				timerC, wake = nil, t.spillWake
+			} else {
+				timer.Reset(interval)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-timerC:
+			case <-wake:
+				// 저장소가 막혀 spill했으므로 바로 재전송하지 않고 한 주기 뒤에 시도
+				idle = 0
+				timer.Reset(interval)
+				select {
+				case <-ctx.Done():
+					return
+				case <-timer.C():
+				}
+			}
+		}
+	}()
+}
+
+// replaySpill - spill 파일의 Step을 BatchSize씩 저장 (실패하면 남은 Step을 다음 주기에 다시 시도)
+// 재전송할 Step이 있었으면 true
+func (t *Tracer) replaySpill(ctx context.Context) bool {
+	steps, err := t.spill.take()
+	if err != nil {
+		log.Printf("trace spill replay failed: %v", err)
+		return true
+	}
+	if len(steps) == 0 {
+		return false
+	}
+
+	sent := 0
+	for sent < len(steps) && ctx.Err() == nil {
+		batch := steps[sent:min(sent+t.cfg.BatchSize, len(steps))]
+		stampProvenance(batch, "spill")
+		t.prepareReplayed(batch)
+		if err := t.flushWithRetry(batch, 1); err != nil {
+			log.Printf("trace spill replay paused, %d steps left: %v", len(steps)-sent, err)
+			break
+		}
+		sent += len(batch)
+		t.replayedSteps.Add(int64(len(batch)))
+	}
+	if err := t.spill.done(steps[sent:]); err != nil {
+		log.Printf("trace spill replay failed to update %s: %v", t.spill.replayPath(), err)
+	}
+	return true
+}
+
+// prepareReplayed - 워커를 거치지 않고 spill된 Step(버퍼가 가득 차 기록한 Step)에 저장 전 단계 적용
+// 저장 시도가 있었던 Step(FlushedAtNs 기록됨)은 이미 prepare를 거쳤으므로 그대로 둔다
+// spill 전에 암호화했으므로 Enricher가 평문을 보도록 복호화한 뒤 prepare에서 다시 암호화한다
+// 익명화 전 IP는 spill 파일에 남기지 않으므로 지역 정보는 채우지 않는다
+func (t *Tracer) prepareReplayed(batch []Step) {
+	pending := make([]Step, 0, len(batch))
+	index := make([]int, 0, len(batch))
+	for i := range batch {
+		if batch[i].FlushedAtNs == 0 {
+			pending = append(pending, batch[i])
+			index = append(index, i)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	t.cipher.decryptSteps(pending)
+	t.prepare(pending)
+	for j, i := range index {
+		batch[i] = pending[j]
+	}
+}
+
+// stopReplayer - 재전송 종료 요청 (t.mu 보유 상태에서 호출)
+func (t *Tracer) stopReplayer() {
+	if t.replayCancel != nil {
+		t.replayCancel()
+		t.replayCancel = nil
+	}
+}
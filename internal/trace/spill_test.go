@@ -0,0 +1,76 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type nopSink struct{}
+
+func (nopSink) Write(context.Context, []Step) error { return nil }
+func (nopSink) Close() error                        { return nil }
+
+func TestReplayedStepsAreEnrichedAndEncryptedOnce(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	tracer, err := New(Config{
+		Sink:          nopSink{},
+		EncryptionKey: bytes.Repeat([]byte{3}, 32),
+		Enrichers: []Enricher{EnricherFunc(func(ctx context.Context, step *Step) error {
+			mu.Lock()
+			seen = append(seen, step.UserID)
+			mu.Unlock()
+			step.Extra = "tier=gold"
+			return nil
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 버퍼가 가득 차 spill된 Step은 암호화만 되어 있고, 저장에 실패한 Step은 prepare를 거쳤다
+	overflowed := Step{TraceID: "a", UserID: "alice"}
+	tracer.cipher.encryptStep(&overflowed)
+	failed := []Step{{TraceID: "b", UserID: "bob", FlushedAtNs: 1}}
+	tracer.prepare(failed)
+	seen = nil
+
+	batch := []Step{overflowed, failed[0]}
+	tracer.prepareReplayed(batch)
+
+	if len(seen) != 1 || seen[0] != "alice" {
+		t.Fatalf("enricher saw %q, want only the plaintext of the overflowed step", seen)
+	}
+	tracer.cipher.decryptSteps(batch)
+	for i, want := range []string{"alice", "bob"} {
+		if batch[i].UserID != want || batch[i].Extra != "tier=gold" {
+			t.Fatalf("step %d decrypts to %q/%q, want %q/tier=gold", i, batch[i].UserID, batch[i].Extra, want)
+		}
+	}
+}
+
+func TestReplayedLagIsCountedSeparately(t *testing.T) {
+	tracer, err := New(Config{Sink: nopSink{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	tracer.observeLag([]Step{
+		{Provenance: "retry:1", EnqueuedAtNs: now.Add(-time.Second).UnixNano()},
+		// spill 파일에서 읽은 Step은 단조 시계 없이 벽시계 차이로 계산한다
+		{Provenance: "retry:2,spill", EnqueuedAtNs: now.Add(-10 * time.Minute).UnixNano()},
+		// 벽시계가 뒤로 조정되어 진입 시각이 미래인 경우
+		{Provenance: "spill", EnqueuedAtNs: now.Add(time.Hour).UnixNano()},
+	}, now)
+
+	live, replayed := tracer.pipelineLag.summary(), tracer.replayedLag.summary()
+	if live.Count != 1 || live.P50Ms != 1000 {
+		t.Fatalf("live lag = %+v, want one retried step in the 1s bucket", live)
+	}
+	if replayed.Count != 2 || replayed.P50Ms != 100 || replayed.AvgMs != float64(5*time.Minute/time.Millisecond) {
+		t.Fatalf("replayed lag = %+v, want the spill steps at 10m and clamped 0", replayed)
+	}
+}
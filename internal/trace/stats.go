@@ -0,0 +1,104 @@
+package trace
+
+import (
+	"sync"
+	"time"
+)
+
+// PipelineStats 파이프라인 실행 통계 (함수 Stats와 이름이 겹치지 않도록 PipelineStats로 정의)
+type PipelineStats struct {
+	Received          int64     `json:"received"`           // 파이프라인에 들어온 Step 수
+	Stored            int64     `json:"stored"`             // Sink에 저장한 Step 수
+	Dropped           int64     `json:"dropped"`            // 버퍼가 가득 차 버린 Step 수
+	Failed            int64     `json:"failed"`             // 재시도 후에도 저장하지 못한 Step 수
+	Spilled           int64     `json:"spilled"`            // spill 파일에 기록한 Step 수
+	BufferDepth       int       `json:"buffer_depth"`       // 저장을 기다리는 Step 수
+	BufferCapacity    int       `json:"buffer_capacity"`    // 버퍼 크기 (샤드 포함)
+	BufferUtilization float64   `json:"buffer_utilization"` // BufferDepth / BufferCapacity (0~1)
+	LastFlushAt       time.Time `json:"last_flush_at"`      // 마지막 저장 시도 시각 (없으면 zero)
+	LastFlushError    string    `json:"last_flush_error,omitempty"`
+	InvalidClientIPs  uint64    `json:"invalid_client_ips"` // IP로 파싱되지 않아 빈 값으로 저장한 요청 수
+	AuthFailures      uint64    `json:"auth_failures"`      // 조회 API, 대시보드에서 인증에 실패한 요청 수 (AdminAuth)
+
+	// 저장한 Step의 파이프라인 지연 시간 (버퍼 진입부터 Sink 저장 완료까지, spill 파일에서 다시 저장한 Step은 Replayed)
+	PipelineLag         LagStats `json:"pipeline_lag"`
+	ReplayedPipelineLag LagStats `json:"replayed_pipeline_lag"`
+
+	// 최근 ErrorRateWindow 동안 요청이 있었던 경로의 에러율 (에러율이 높은 순)
+	Routes []RouteErrorRate `json:"routes,omitempty"`
+
+	// 메서드, 경로, 상태 코드별 저장한 요청과 저장하지 않은 요청의 지연 시간 (WithCountSkipped, 요청 수가 많은 순)
+	Endpoints []EndpointCounts `json:"endpoints,omitempty"`
+
+	// 저장 컬럼 규칙(RouteRule.StoreColumns)별로 비운 요청 수와 바이트 수 (줄인 바이트가 많은 순)
+	Storage []StorageStats `json:"storage,omitempty"`
+}
+
+// LagStats 지연 시간 분포 (백분위는 히스토그램 버킷 상한)
+type LagStats struct {
+	Count int64   `json:"count"`
+	AvgMs float64 `json:"avg_ms"`
+	P50Ms int64   `json:"p50_ms"`
+	P95Ms int64   `json:"p95_ms"`
+	P99Ms int64   `json:"p99_ms"`
+}
+
+// flushStatus - 마지막 저장 시도 결과
+type flushStatus struct {
+	mu  sync.Mutex
+	at  time.Time
+	err error
+}
+
+func (s *flushStatus) record(at time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.at, s.err = at, err
+}
+
+func (s *flushStatus) load() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.at, s.err
+}
+
+// Stats - 이 Tracer의 현재 파이프라인 통계
+func (t *Tracer) Stats() PipelineStats {
+	if t == nil {
+		return PipelineStats{}
+	}
+	depth, capacity := t.bufferUsage()
+	stats := PipelineStats{
+		Received:       t.stepsReceived.Load(),
+		Stored:         t.storedSteps.Load(),
+		Dropped:        t.droppedSteps.Load(),
+		Failed:         t.failedSteps.Load(),
+		Spilled:        t.spilledSteps.Load(),
+		BufferDepth:    depth,
+		BufferCapacity: capacity,
+
+		PipelineLag:         t.pipelineLag.summary(),
+		ReplayedPipelineLag: t.replayedLag.summary(),
+	}
+	stats.InvalidClientIPs = uint64(t.invalidClientIPs.Load())
+	stats.AuthFailures = uint64(t.authFailures.Load())
+	if capacity > 0 {
+		stats.BufferUtilization = float64(depth) / float64(capacity)
+	}
+	at, err := t.lastFlush.load()
+	stats.LastFlushAt = at
+	if err != nil {
+		stats.LastFlushError = err.Error()
+	}
+	if t.errorRates != nil {
+		stats.Routes = t.errorRates.snapshot(time.Now())
+	}
+	stats.Endpoints = t.endpoints.snapshot()
+	stats.Storage = t.StorageStats()
+	return stats
+}
+
+// Stats - 기본 Tracer의 현재 파이프라인 통계 (시작 전이면 zero 값)
+func Stats() PipelineStats {
+	return defaultTracer.Load().Stats()
+}
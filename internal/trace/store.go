@@ -0,0 +1,56 @@
+package trace
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Store 저장된 Step 조회 핸들 (DB 연결, 테이블 구성, 컬럼 암호화 키)
+// Tracer.Store로 Tracer와 같은 설정의 핸들을 얻고, Tracer를 시작하지 않는 프로세스(tracectl, 조회 전용 서비스 등)에서는 NewStore로 만든다
+// Tracer마다 따로 가지므로 테이블이나 암호화 키가 다른 Tracer를 여러 개 시작해도 서로 덮어쓰지 않는다
+type Store struct {
+	db      *gorm.DB
+	tables  stepTables
+	cipher  *columnCipher // 암호화 키가 없으면 nil
+	rollups bool          // 롤업 테이블을 갱신하면 true (TopEndpoints가 원본 Step 대신 사용)
+	top     *topEndpointsCache
+}
+
+// NewStore - cfg의 TableName, TableSchema, PartitionByDay, Rollups와 암호화 키로 db를 조회하는 핸들 생성
+func NewStore(db *gorm.DB, cfg Config) (*Store, error) {
+	if db == nil {
+		return nil, errors.New("trace: NewStore requires a DB")
+	}
+	if err := validateTableNames(cfg); err != nil {
+		return nil, err
+	}
+	cipher, err := encryptionCipher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		db:      db,
+		tables:  stepTables{base: qualifiedTableName(cfg), daily: cfg.PartitionByDay},
+		cipher:  cipher,
+		rollups: cfg.Rollups,
+		top:     newTopEndpointsCache(topEndpointsCacheTTL(cfg)),
+	}, nil
+}
+
+// Store - 이 Tracer가 저장한 테이블을 조회하는 핸들 (사용자 정의 Sink에 저장하면 nil)
+func (t *Tracer) Store() *Store {
+	if t == nil {
+		return nil
+	}
+	return t.store
+}
+
+// storeFor - 패키지 조회 함수가 db를 조회할 핸들
+// 기본 Tracer가 DB에 저장하면 그 테이블 구성과 암호화 키를 사용하고, 없으면 기본 테이블(steps)을 평문으로 조회한다
+func storeFor(db *gorm.DB) *Store {
+	if t := defaultTracer.Load(); t != nil && t.store != nil {
+		return &Store{db: db, tables: t.store.tables, cipher: t.store.cipher, rollups: t.store.rollups, top: t.store.top}
+	}
+	return &Store{db: db, tables: stepTables{base: defaultStepTable}}
+}
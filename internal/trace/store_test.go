@@ -0,0 +1,136 @@
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestStoresDoNotShareTablesOrKeys(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+
+	plain, err := trace.New(trace.Config{DB: db, TableName: "plain_steps"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := trace.New(trace.Config{DB: db, TableName: "secret_steps", EncryptionKey: bytes.Repeat([]byte{7}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, plain, "/plain?user_id=alice&access_token=a")
+	serve(t, encrypted, "/secret?user_id=bob&access_token=b")
+	stopTracer(t, plain)
+	stopTracer(t, encrypted)
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		tracer     *trace.Tracer
+		path, user string
+	}{
+		{plain, "/plain", "alice"},
+		{encrypted, "/secret", "bob"},
+	} {
+		result, err := tc.tracer.Store().Query(ctx, trace.QueryFilter{UserID: tc.user})
+		if err != nil {
+			t.Fatalf("Query(%s): %v", tc.user, err)
+		}
+		if len(result.Steps) != 1 || result.Steps[0].Path != tc.path || result.Steps[0].UserID != tc.user {
+			t.Fatalf("Query(%s) = %+v, want one decrypted step for %s", tc.user, result.Steps, tc.path)
+		}
+		owner, err := tc.tracer.Store().ResolveTraceOwner(ctx, result.Steps[0].TraceID)
+		if err != nil || owner != tc.user {
+			t.Fatalf("ResolveTraceOwner = %q, %v, want %q", owner, err, tc.user)
+		}
+	}
+
+	// 암호화한 Tracer를 나중에 시작해도 평문 테이블의 조회 핸들은 바뀌지 않는다
+	var stored []string
+	if err := db.Table("secret_steps").Pluck("user_id", &stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0] == "bob" {
+		t.Fatalf("secret_steps.user_id = %v, want ciphertext", stored)
+	}
+}
+
+func TestNewStoreMatchesServerConfig(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	key := bytes.Repeat([]byte{1}, 32)
+	cfg := trace.Config{DB: db, TableName: "orders_steps", EncryptionKey: key}
+
+	tracer, err := trace.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, tracer, "/orders?user_id=carol&access_token=c")
+	stopTracer(t, tracer)
+
+	store, err := trace.NewStore(db, trace.Config{TableName: "orders_steps", EncryptionKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.Query(context.Background(), trace.QueryFilter{UserID: "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Steps) != 1 || result.Steps[0].UserID != "carol" {
+		t.Fatalf("Query = %+v, want one step for carol", result.Steps)
+	}
+
+	if _, err := trace.NewStore(db, trace.Config{TableName: "orders; DROP"}); err == nil {
+		t.Fatal("NewStore accepted an invalid table name")
+	}
+}
+
+func TestTopEndpointsAreCachedPerStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	var stores []*trace.Store
+	for _, path := range []string{"/first", "/second"} {
+		db := tracetest.NewTempDB(t)
+		if err := db.Create(&trace.Step{TraceID: "t", Path: path, Method: "GET", StatusCode: 200, CreatedAt: now}).Error; err != nil {
+			t.Fatal(err)
+		}
+		store, err := trace.NewStore(db, trace.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores = append(stores, store)
+	}
+
+	for i, want := range []string{"/first", "/second"} {
+		top, err := stores[i].TopEndpoints(ctx, time.Hour, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(top) != 1 || top[0].Path != want {
+			t.Fatalf("store %d TopEndpoints = %+v, want only %s", i, top, want)
+		}
+	}
+}
+
+func TestTopEndpointsUsesRollups(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	tracer, err := trace.New(trace.Config{DB: db, Rollups: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, tracer, "/rolled?user_id=u&access_token=a")
+	stopTracer(t, tracer)
+
+	// 원본 Step을 지워도 롤업 테이블로 계산한다
+	if err := db.Exec("DELETE FROM steps").Error; err != nil {
+		t.Fatal(err)
+	}
+	top, err := tracer.Store().TopEndpoints(context.Background(), time.Hour, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].Path != "/rolled" || top[0].Count != 1 {
+		t.Fatalf("TopEndpoints = %+v, want one request to /rolled from the rollup table", top)
+	}
+}
@@ -0,0 +1,167 @@
+package trace
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 실시간 tail 제한
+const (
+	maxTailSubscribers = 16
+	tailBufferSize     = 256
+	tailHeartbeat      = 15 * time.Second
+)
+
+var errTooManyTails = errors.New("trace: too many live tail subscribers")
+
+// tailFilter 실시간 tail 조건 (빈 값인 조건은 적용하지 않음)
+type tailFilter struct {
+	pathPrefix   string
+	userID       string
+	minStatus    int
+	requestsOnly bool
+}
+
+func (f tailFilter) match(step *Step) bool {
+	return strings.HasPrefix(step.Path, f.pathPrefix) &&
+		(f.userID == "" || step.UserID == f.userID) &&
+		step.StatusCode >= f.minStatus &&
+		(!f.requestsOnly || step.SpanName == "")
+}
+
+type tailSubscriber struct {
+	filter  tailFilter
+	ch      chan Step
+	dropped atomic.Int64
+}
+
+// tailHub - 버퍼에 들어가는 Step을 tail 구독자에게 전달 (느린 구독자의 Step은 버림)
+type tailHub struct {
+	mu   sync.RWMutex
+	subs map[*tailSubscriber]struct{}
+	n    atomic.Int32 // 구독자가 없을 때 잠금 없이 건너뛰기 위한 수
+}
+
+func (h *tailHub) subscribe(filter tailFilter) (*tailSubscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subs) >= maxTailSubscribers {
+		return nil, errTooManyTails
+	}
+	if h.subs == nil {
+		h.subs = make(map[*tailSubscriber]struct{})
+	}
+	sub := &tailSubscriber{filter: filter, ch: make(chan Step, tailBufferSize)}
+	h.subs[sub] = struct{}{}
+	h.n.Add(1)
+	return sub, nil
+}
+
+func (h *tailHub) unsubscribe(sub *tailSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		h.n.Add(-1)
+	}
+}
+
+// publish - 조건에 맞는 구독자에게 Step 전달 (요청 처리 고루틴을 막지 않도록 가득 찬 구독자는 건너뜀)
+func (h *tailHub) publish(step Step) {
+	if h.n.Load() == 0 {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if !sub.filter.match(&step) {
+			continue
+		}
+		select {
+		case sub.ch <- step:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// TailHandler - 기본 Tracer에 새로 수집되는 Step을 Server-Sent Events로 보내는 Gin 핸들러
+//
+//	curl -N 'http://localhost:8080/debug/tail?path_prefix=/api/orders&min_status=500'
+//
+// path_prefix, user_id, min_status, requests_only로 서버에서 거른다
+// 저장 전(암호화 전) 값이 그대로 노출되므로 반드시 인증 미들웨어 뒤에 등록해야 한다
+func TailHandler() gin.HandlerFunc {
+	return tailHandler(defaultTracer.Load)
+}
+
+// TailHandler - 이 Tracer에 새로 수집되는 Step을 Server-Sent Events로 보내는 Gin 핸들러
+func (t *Tracer) TailHandler() gin.HandlerFunc {
+	return tailHandler(func() *Tracer { return t })
+}
+
+func tailHandler(tracer func() *Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t := tracer()
+		if t == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": ErrNotRunning.Error()})
+			return
+		}
+		filter := tailFilter{
+			pathPrefix:   c.Query("path_prefix"),
+			userID:       c.Query("user_id"),
+			requestsOnly: c.Query("requests_only") == "true",
+		}
+		if v := c.Query("min_status"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min_status: " + strconv.Quote(v)})
+				return
+			}
+			filter.minStatus = n
+		}
+
+		sub, err := t.tail.subscribe(filter)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		defer t.tail.unsubscribe(sub)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no") // nginx 프록시 버퍼링 끄기
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(tailHeartbeat)
+		defer heartbeat.Stop()
+		var reported int64
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case step := <-sub.ch:
+				// 느려서 버린 Step이 있으면 먼저 알림
+				if dropped := sub.dropped.Load(); dropped > reported {
+					c.SSEvent("dropped", gin.H{"count": dropped - reported})
+					reported = dropped
+				}
+				c.SSEvent("step", step)
+			case <-heartbeat.C:
+				// 프록시가 유휴 연결을 끊지 않도록 주석 줄 전송
+				if _, err := c.Writer.WriteString(": keepalive\n\n"); err != nil {
+					return
+				}
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -2,10 +2,16 @@
 package trace
 
 import (
+	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +22,8 @@ import (
 type Step struct {
 	TraceID    string `gorm:"index"` // 인덱스 추가로 검색 성능 향상
 	UserID     string `gorm:"index"` // 유저별 검색을 위한 인덱스
+	TenantID   string `gorm:"index"` // 고객사(테넌트) ID (WithTenantIDExtractor)
+	SessionID  string `gorm:"index"` // 브라우저 세션 ID (WithSessionIDExtractor, 비로그인 요청 포함)
 	Path       string // API 경로
 	Method     string // HTTP 메서드 (GET, POST, PUT, DELETE 등)
 	StatusCode int    // HTTP 상태 코드
@@ -23,10 +31,69 @@ type Step struct {
 	IP         string // 클라이언트 IP
 	UserAgent  string // 사용자 에이전트
 	CreatedAt  int64  `gorm:"index"` // 타임스탬프 (Unix timestamp)
+
+	EnqueuedAtNs  int64 // 버퍼 진입 시각 (Unix nano)
+	FlushedAtNs   int64 // 저장 직전 시각 (Unix nano)
+	PipelineLagMs int64 // 버퍼 진입부터 저장까지 걸린 시간 (밀리초)
+
+	Provenance string // 저장까지 거친 경로 (예: "retry:2", "spill", "dlq", "fallback", "import"), 정상 경로면 빈 값
+
+	CacheHit  bool   // 애플리케이션 캐시에서 응답했는지 여부 (MarkCacheHit)
+	CacheName string // 응답한 캐시 이름
+
+	Team   string `gorm:"index"` // 담당 팀 (team 라벨)
+	Labels string // 고정 라벨 (JSON)
+
+	ServiceName string `gorm:"index"` // 서비스 이름 (Config.ServiceName)
+	Environment string `gorm:"index"` // 배포 환경 (Config.Environment, 예: production)
+	Version     string `gorm:"index"` // 배포 버전
+
+	PodName   string `gorm:"index"` // Kubernetes Pod 이름 (Config.KubernetesMetadata)
+	NodeName  string `gorm:"index"` // Kubernetes 노드 이름
+	Namespace string // Kubernetes 네임스페이스
+
+	Country string `gorm:"index"` // 클라이언트 국가 ISO 코드 (Config.GeoResolver)
+	Region  string // 클라이언트 지역 (시/도, 주 등)
+	City    string // 클라이언트 도시
+
+	Params string // 라우트 파라미터 (JSON, 예: {"id":"123"}, WithRouteParams)
+	Query  string // 요청 쿼리 문자열 (WithQueryCapture, 민감한 키는 마스킹)
+
+	Extra string // 사용자 정의 필드 (JSON, WithFieldsExtractor)
+
+	RequestBytes  int64 // 요청 본문 크기 (Content-Length, 없으면 핸들러가 읽은 바이트 수)
+	ResponseBytes int64 // 응답 본문 크기 (핸들러가 쓴 바이트 수)
+
+	RequestBody  string // 요청 본문 앞부분 (WithBodyCapture)
+	ResponseBody string // 응답 본문 앞부분 (WithBodyCapture)
+
+	Headers string // 저장한 요청/응답 헤더 (JSON, WithHeaderCapture)
+
+	CaptureID string `gorm:"index"` // 디버그 캡처 ID (StartDebugCapture), 캡처한 요청이 아니면 빈 값
+
+	Error     string // 핸들러가 c.Error로 남긴 에러 메시지
+	ErrorType string // 에러 종류 (bind, render, private, public)
+
+	Panic      string // 복구한 panic 값 (WithPanicRecovery)
+	PanicStack string // panic 스택 트레이스 앞부분
+
+	SpanID       string // 요청의 span ID (16자리 hex)
+	ParentSpanID string // 부모 span ID (상위 서비스의 B3 span 또는 하위 span의 요청 span)
+	SpanName     string // 하위 span 이름 (StartSpan), 요청 Step이면 빈 값
+
+	Phase string `gorm:"index"` // 작업 단계 (StartOperation: checkpoint, finished, abandoned), 요청 Step이면 빈 값
+
+	Seq int64 // 프로세스 내 버퍼 진입 순서
+
+	geoIP      string    // 지역 조회에 사용할 익명화 전 IP (저장하지 않음)
+	enqueuedAt time.Time // 버퍼 진입 시각 (단조 시계 포함, 저장하지 않음)
 }
 
 // Config 설정 구조체
+// 0으로 둔 FlushInterval, BatchSize, BufferSize와 풀 설정은 New/Start에서 DefaultConfig의 값으로 채운다
 type Config struct {
+	// Step 저장소 (비어 있으면 DB를 사용하는 GormSink)
+	Sink            Sink
 	DB              *gorm.DB
 	FlushInterval   time.Duration
 	BatchSize       int
@@ -34,6 +101,114 @@ type Config struct {
 	MaxOpenConn     int
 	MaxIdleConn     int
 	ConnMaxLifetime time.Duration
+	// true면 DB 커넥션 풀에 MaxOpenConn, MaxIdleConn, ConnMaxLifetime 적용
+	// 애플리케이션과 풀을 공유하는 경우 애플리케이션 설정을 덮어쓰므로 기본값은 false
+	ManagePool bool
+	// true면 같은 Dialector로 trace 전용 커넥션 풀을 열어 저장에 사용 (풀 설정은 항상 적용)
+	SeparateConnection bool
+	// DB와 Sink가 모두 비어 있으면 이 DSN으로 trace 전용 연결을 열어 사용 (RegisterDialector로 등록한 스킴, 풀 설정 항상 적용)
+	DSN string
+	// 모든 Step에 기록할 서비스 이름과 배포 환경 (여러 서비스나 환경이 저장소 하나를 공유할 때 구분용)
+	ServiceName string
+	Environment string
+	// 배포 버전 (비어 있으면 빌드 정보의 VCS revision 사용)
+	Version string
+	// true면 시작 시 downward API 환경 변수(POD_NAME, NODE_NAME, POD_NAMESPACE)를 한 번 읽어 모든 Step에 기록
+	KubernetesMetadata bool
+	// true면 사용자 ID 해시로 나눈 샤드마다 순차 저장하여 사용자별 저장 순서 보장
+	PerUserOrdering bool
+	// PerUserOrdering 샤드 수 (기본 8)
+	OrderingShards int
+	// 샤드별 버퍼 크기 (기본 BufferSize / OrderingShards)
+	ShardBufferSize int
+	// 버퍼(PerUserOrdering이면 샤드 버퍼)가 가득 찼을 때의 처리 방식 (기본 DropNewest)
+	Overflow OverflowPolicy
+	// 예상 초당 요청 수 (설정 시 BufferSize가 충분한지 검사)
+	ExpectedRPS int
+	// 플러시 시점에 더할 랜덤 지터 (첫 플러시 오프셋 및 매 플러시마다 적용)
+	FlushJitter time.Duration
+	// 설정 시 첫 플러시를 이 단위의 벽시계 경계 + 인스턴스별 고정 오프셋에 맞춤
+	AlignFlushTo time.Duration
+	// 이 Tracer의 Store가 TopEndpoints 결과를 캐시할 시간 (기본 30초, 다른 Tracer의 캐시에는 영향 없음)
+	TopEndpointsCacheTTL time.Duration
+	// Trace ID 생성에 사용할 HMAC 키 (배포 단위로 고정 권장, 비어 있으면 프로세스마다 랜덤 생성)
+	TraceIDSecret []byte
+	// true면 기존 "userID:sha256(token)" 형식의 Trace ID 사용 (사용자 ID 노출 주의)
+	LegacyTraceIDs bool
+	// 설정 시 DB에서 이 기간보다 오래된 Step을 주기적으로 삭제 (0이면 삭제하지 않음)
+	Retention time.Duration
+	// 보관 기간 정리 주기 (기본 1시간)와 한 번에 삭제할 Step 수 (기본 1000)
+	RetentionInterval  time.Duration
+	RetentionBatchSize int
+	// 설정 시 삭제 전에 Step을 이 Sink에 먼저 저장 (Close는 호출자가 관리)
+	RetentionArchive Sink
+	// true면 Step을 생성일(UTC)별 테이블(steps_20240601)에 저장하고 조회 API가 여러 테이블을 함께 조회
+	// 보관 기간 정리는 기간이 지난 테이블을 통째로 삭제한다 (기본 DB Sink에서만 사용 가능)
+	PartitionByDay bool
+	// Step 테이블 이름 (기본 "steps", PartitionByDay면 일별 테이블 이름의 접두사)
+	// 여러 서비스가 DB 하나를 공유하는 경우 서비스별로 다르게 설정한다
+	TableName string
+	// Step 테이블의 스키마 (PostgreSQL 스키마, MySQL 데이터베이스 등, 비어 있으면 연결 기본값)
+	TableSchema string
+	// 설정 시 버퍼가 가득 찼거나 재시도 후에도 저장하지 못한 Step을 이 파일에 기록하고 저장소가 복구되면 다시 저장
+	// 재시작 후에도 남은 Step을 이어서 저장하므로 Tracer마다 다른 경로를 사용한다
+	SpillPath string
+	// spill 파일 최대 크기 (기본 64MB, 초과하면 드롭)와 재저장 시도 주기 (기본 30초)
+	SpillMaxBytes       int64
+	SpillReplayInterval time.Duration
+	// 재시도 후에도 저장하지 못한 배치를 받을 함수 (SpillPath가 있으면 spill 파일에 기록하지 못한 Step만 전달)
+	// 비어 있으면 DeadLetterPath에 dead-letter 파일 형식으로 기록하고, DeadLetterPath도 비어 있으면 버린 Step 수와 에러만 로그에 남긴다
+	DeadLetterHandler DeadLetterHandler
+	DeadLetterPath    string
+	// 설정 시 재시도 후에도 저장하지 못한 배치를 spill 파일과 dead-letter보다 먼저 이 Sink에 저장 (Provenance "fallback", Close는 호출자가 관리)
+	FallbackSink Sink
+	// 설정 시 Stop의 ctx가 끝날 때까지 저장하지 못하고 버퍼에 남은 Step을 이 파일에 dead-letter 파일 형식으로 기록 (ReadDLQFile로 읽음)
+	ShutdownSnapshotPath string
+	// Sink 저장 시도마다 호출되는 콜백 (재시도와 spill 재저장 포함, 저장 고루틴에서 동기적으로 호출되므로 오래 걸리면 안 됨)
+	// OnFlushError의 batch는 재시도에 다시 사용되므로 보관하려면 복사해야 한다
+	OnFlushSuccess func(count int, took time.Duration)
+	OnFlushError   func(err error, batch []Step)
+	// Step을 저장하지 않고 버릴 때 호출되는 콜백 (요청 처리 고루틴에서 동기적으로 호출되므로 오래 걸리면 안 됨)
+	OnDrop func(step Step, reason DropReason)
+	// 경로별 에러율(5xx)을 계산할 슬라이딩 윈도 (기본 1분, Stats의 Routes로 확인)
+	ErrorRateWindow time.Duration
+	// 설정 시 경로의 에러율이 이 값(0~1) 이상이 되면 OnErrorRateExceeded 호출 (윈도 안의 요청이 ErrorRateMinRequests(기본 20) 이상일 때만)
+	ErrorRateThreshold   float64
+	ErrorRateMinRequests int
+	// 경로의 에러율이 ErrorRateThreshold를 넘을 때 한 번 호출 (기준 아래로 내려간 뒤 다시 넘으면 다시 호출)
+	// 요청 처리 고루틴에서 동기적으로 호출되므로 오래 걸리면 안 됨
+	OnErrorRateExceeded func(rate RouteErrorRate)
+	// Healthy가 실패로 판단하는 버퍼 사용률 (0~1, 기본 0.9)
+	HealthMaxBufferUtilization float64
+	// 저장할 클라이언트 IP 처리 방식 (기본 IPKeep)
+	IPAnonymization IPAnonymization
+	// IPHash의 salt 교체 주기 (기본 24시간)
+	IPHashRotation time.Duration
+	// true면 저장에 성공한 Step을 경로와 분 단위로 모아 롤업 테이블(step_rollups)에 더함 (DB 필요, SummarizeRollups로 조회)
+	Rollups bool
+	// true면 요청 Step마다 분, 서비스별 기대 수와 버린 이유를 장부 테이블(trace_ledger)에 기록 (DB 필요, Reconcile로 유실 여부 검증)
+	Ledger bool
+	// 설정 시 저장 고루틴에서 클라이언트 IP를 국가, 지역, 도시로 변환하여 기록 (예: MaxMindResolver)
+	GeoResolver GeoResolver
+	// 저장 전에 워커에서 순서대로 실행할 Enricher (UA 분석, 사용자 등급 조회 등, 실패해도 Step은 저장)
+	Enrichers []Enricher
+	// 배치 안에서 Enricher를 동시에 실행할 Step 수 (기본 4)와 배치 하나의 Enricher 실행 제한 시간 (기본 5초)
+	EnrichConcurrency int
+	EnrichTimeout     time.Duration
+	// 설정 시 UserID, IP, Extra를 AES-GCM으로 암호화하여 저장 (16, 24, 32바이트 키, 조회 API는 자동 복호화)
+	EncryptionKey []byte
+	// KMS 등에서 암호화 키를 받아오는 함수 (New에서 한 번 호출, EncryptionKey 대신 사용)
+	EncryptionKeyFunc func() ([]byte, error)
+	// 시작 시 적용할 수집 설정 (샘플링 비율 등, 실행 중에는 UpdateConfig로 변경)
+	Runtime RuntimeConfig
+	// Tracer 이름 (설정 시 지표에 tracer 라벨로 붙음, 한 프로세스에서 여러 Tracer를 구분할 때 사용)
+	Name string
+	// 설정 시 true를 반환한 Step만 저장 (하위 span도 각각 판단, 예: ErrorsOnly)
+	StepFilter func(Step) bool
+	// StartOperation으로 시작한 작업이 이 시간까지 Finish하지 않으면 abandoned Step 기록 (기본 1시간)
+	OperationDeadline time.Duration
+	// InstallSignalHandler, FlushOnPanic이 프로세스 종료 전에 남은 Step을 저장하며 기다리는 최대 시간 (기본 5초)
+	ExitFlushTimeout time.Duration
 }
 
 // MiddlewareConfig 미들웨어 설정 구조체
@@ -42,10 +217,74 @@ type MiddlewareConfig struct {
 	UserIDExtractor func(c *gin.Context) string
 	// 토큰 추출 함수
 	TokenExtractor func(c *gin.Context) string
-	// Trace ID 생성 함수
+	// 테넌트 ID 추출 함수 (없으면 기록하지 않음)
+	TenantIDExtractor func(c *gin.Context) string
+	// 세션 ID 추출 함수 (설정 시 사용자 ID가 없는 요청도 세션 ID가 있으면 수집)
+	SessionIDExtractor func(c *gin.Context) string
+	// Trace ID 생성 함수 (비어 있으면 Tracer의 HMAC 생성 함수)
 	TraceIDGenerator func(userID, token string) string
 	// 필터링 함수 (true면 로그 수집, false면 스킵)
 	Filter func(c *gin.Context) bool
+	// 신뢰할 프록시 대역 (설정 시 gin 엔진 설정 대신 사용)
+	TrustedProxies []netip.Prefix
+	// 클라이언트 IP를 읽을 헤더와 오른쪽 기준 위치 (1부터 시작)
+	ClientIPHeader         string
+	ClientIPHeaderPosition int
+	// 접근 로그 형식 (AccessLogNone이면 출력하지 않음)
+	AccessLog AccessLogFormat
+	// Step에 기록할 고정 라벨
+	Labels map[string]string
+	// Step.Extra에 기록할 사용자 정의 필드 추출 함수
+	FieldsExtractor func(c *gin.Context) map[string]any
+	// 저장할 요청/응답 본문 최대 크기 (0이면 저장하지 않음)
+	MaxRequestBodyBytes  int
+	MaxResponseBodyBytes int
+	// 값 그대로 저장할 헤더와 해시로 저장할 헤더 (http.CanonicalHeaderKey 형식)
+	CaptureHeaders []string
+	RedactHeaders  []string
+	// 해시로 저장할 라우트 파라미터와 저장하지 않을 라우트 파라미터
+	HashParams    []string
+	ExcludeParams []string
+	// true면 쿼리 문자열 저장 (RedactQueryKeys의 키는 마스킹, 소문자)
+	CaptureQuery    bool
+	RedactQueryKeys []string
+	// 버퍼에 들어가기 전 Step에 적용할 함수 (nil을 반환하면 저장하지 않음)
+	Redactors []func(*Step) *Step
+	// Trace ID를 기록할 응답 헤더 (기본 X-Trace-ID, 빈 값이면 기록하지 않음)
+	TraceIDHeader string
+	// 값이 있으면 Trace ID로 사용할 요청 헤더 (예: 게이트웨이의 X-Request-ID)
+	TraceIDFromHeader string
+	// 핸들러 panic 처리 방식 (기본 PanicPropagate)
+	PanicMode PanicMode
+	// 저장할 요청 비율 (0~1, 기본 1)과 결정 방식
+	SampleRate   float64
+	SamplingMode SamplingMode
+	// true면 에러(5xx)와 SlowThreshold 이상 걸린 요청은 샘플링과 관계없이 저장
+	TailSampling  bool
+	SlowThreshold time.Duration
+	// 라우트 패턴별 저장 비율과 저장 컬럼 (WithRouteRules, WithRouteSampling)
+	RouteRules []RouteRule
+	// 라우트별 초당 최대 저장 Step 수 (0이면 적응형 샘플링 사용 안 함)
+	AdaptiveMaxPerSecond float64
+	// true면 상위 서비스의 샘플링 결정(X-Trace-Sampled, traceparent, B3)을 샘플링 비율보다 우선
+	RespectUpstreamSampling bool
+	// true면 필터, 식별자 없음, 샘플링으로 저장하지 않은 요청도 경로, 상태 코드별로 집계 (Stats의 endpoints, 롤업의 uncollected)
+	CountSkipped bool
+	// true면 요청의 B3 헤더로 상위 trace에 합류 (B3Format은 InjectB3의 헤더 형식)
+	B3Propagation bool
+	B3Format      B3Format
+	// true면 요청의 W3C traceparent 헤더로 상위 trace에 합류 (B3 헤더보다 우선)
+	TraceContextPropagation bool
+
+	tracer        *Tracer   // Step을 저장할 Tracer (nil이면 기본 Tracer)
+	mirrors       []*Tracer // 같은 Step을 함께 받을 Tracer
+	encodedLabels string
+	labelsErr     error // WithStaticLabels, Labeled의 잘못된 라벨 (라벨은 적용하지 않음)
+	routeRules    []routeRule
+	adaptive      *adaptiveSampler
+
+	// 디버그 캡처 요청이면 true (모든 요청/응답 헤더 저장)
+	captureAllHeaders bool
 }
 
 // 기본 추출 함수들
@@ -57,11 +296,28 @@ func defaultTokenExtractor(c *gin.Context) string {
 	return c.Query("access_token")
 }
 
-func defaultTraceIDGenerator(userID, token string) string {
+// 기존 형식의 Trace ID 생성 함수 (Config.LegacyTraceIDs)
+func legacyTraceIDGenerator(userID, token string) string {
 	h := sha256.Sum256([]byte(token))
 	return fmt.Sprintf("%s:%s", userID, hex.EncodeToString(h[:]))
 }
 
+var (
+	traceIDSecret     []byte
+	traceIDSecretOnce sync.Once
+)
+
+// traceSecret - TraceIDSecret을 설정하지 않은 Tracer가 공유하는 HMAC 키 (최초 사용 시 랜덤 생성)
+func traceSecret() []byte {
+	traceIDSecretOnce.Do(func() {
+		traceIDSecret = make([]byte, 32)
+		if _, err := rand.Read(traceIDSecret); err != nil {
+			log.Printf("failed to generate trace id secret: %v", err)
+		}
+	})
+	return traceIDSecret
+}
+
 func defaultFilter(c *gin.Context) bool {
 	return true
 }
@@ -83,6 +339,22 @@ func WithTokenExtractor(extractor func(c *gin.Context) string) MiddlewareOption
 	}
 }
 
+// WithTenantIDExtractor 테넌트 ID 추출 함수 설정
+// 핸들러 실행 전에 호출되며, 결과는 Step.TenantID와 gin.Context의 "tenant_id"에 기록된다
+func WithTenantIDExtractor(extractor func(c *gin.Context) string) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.TenantIDExtractor = extractor
+	}
+}
+
+// WithSessionIDExtractor 세션 ID 추출 함수 설정 (예: 세션 쿠키)
+// 설정하면 사용자 ID나 토큰이 없는 비로그인 요청도 세션 ID가 있으면 수집하여 Step.SessionID로 묶을 수 있다
+func WithSessionIDExtractor(extractor func(c *gin.Context) string) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.SessionIDExtractor = extractor
+	}
+}
+
 // WithTraceIDGenerator Trace ID 생성 함수 설정
 func WithTraceIDGenerator(generator func(userID, token string) string) MiddlewareOption {
 	return func(config *MiddlewareConfig) {
@@ -90,6 +362,42 @@ func WithTraceIDGenerator(generator func(userID, token string) string) Middlewar
 	}
 }
 
+// 기본으로 Trace ID를 기록하는 응답 헤더
+const defaultTraceIDHeader = "X-Trace-ID"
+
+// WithTraceIDHeader Trace ID를 기록할 응답 헤더 이름 설정 (빈 값이면 응답에 기록하지 않음)
+// 사용자나 지원 담당자가 문의할 때 응답 헤더의 Trace ID로 저장된 Step을 바로 찾을 수 있다
+func WithTraceIDHeader(name string) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.TraceIDHeader = name
+	}
+}
+
+// 요청 헤더에서 받아들이는 Trace ID 최대 길이
+const maxRequestIDLen = 128
+
+// WithTraceIDFromHeader 요청 헤더(예: X-Request-ID)에 값이 있으면 새로 만들지 않고 Trace ID로 사용
+// 로드밸런서/게이트웨이 로그와 같은 ID로 Step을 찾을 수 있으며, traceparent, B3 헤더가 있으면 그 값을 우선한다
+// 128자를 넘거나 영문자, 숫자, "-", "_", ".", ":" 외의 문자가 있는 값은 무시하고 새로 만든다
+func WithTraceIDFromHeader(name string) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.TraceIDFromHeader = name
+	}
+}
+
+// validRequestID - 요청 헤더의 값을 Trace ID로 써도 되는지 확인 (응답 헤더와 로그에 그대로 쓰이므로 제한)
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		if !(r == '-' || r == '_' || r == '.' || r == ':' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
 // WithFilter 필터링 함수 설정
 func WithFilter(filter func(c *gin.Context) bool) MiddlewareOption {
 	return func(config *MiddlewareConfig) {
@@ -97,26 +405,144 @@ func WithFilter(filter func(c *gin.Context) bool) MiddlewareOption {
 	}
 }
 
-var buffer chan Step
+// 버퍼 진입 순서 (Step.Seq)
+var stepSeq atomic.Int64
 
-// Start 워커 초기화
-func Start(cfg Config) error {
-	buffer = make(chan Step, cfg.BufferSize)
+// warnUnknownColumns - 새 버전이 추가한 컬럼 등 이 바이너리가 모르는 컬럼이 있으면 경고
+// 신규 컬럼은 항상 NULL 허용 또는 기본값을 가져야 구버전 writer의 INSERT가 실패하지 않는다
+func warnUnknownColumns(db *gorm.DB, table string) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&Step{}); err != nil {
+		log.Printf("failed to parse trace schema: %v", err)
+		return
+	}
 
-	sqlDB, err := cfg.DB.DB()
+	columnTypes, err := db.Table(table).Migrator().ColumnTypes(&Step{})
 	if err != nil {
-		return err
+		log.Printf("failed to inspect trace table columns: %v", err)
+		return
 	}
-	sqlDB.SetMaxOpenConns(cfg.MaxOpenConn)
-	sqlDB.SetMaxIdleConns(cfg.MaxIdleConn)
-	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	if err := cfg.DB.AutoMigrate(&Step{}); err != nil {
-		return err
+	known := make(map[string]bool, len(stmt.Schema.DBNames))
+	for _, name := range stmt.Schema.DBNames {
+		known[name] = true
 	}
 
-	go startWorker(cfg.DB, cfg.FlushInterval, cfg.BatchSize)
-	return nil
+	var unknown []string
+	for _, ct := range columnTypes {
+		if !known[ct.Name()] {
+			unknown = append(unknown, ct.Name())
+		}
+	}
+	if len(unknown) > 0 {
+		log.Printf("trace schema drift: table=%s unknown_columns=%v", table, unknown)
+	}
+}
+
+// gin.Context에 저장되는 키
+const (
+	TraceIDKey   = "trace_id"
+	UserIDKey    = "user_id"
+	TenantIDKey  = "tenant_id"
+	SessionIDKey = "session_id"
+	SampledKey   = "trace_sampled"  // bool, 상위 서비스가 샘플링에서 제외하지 않았는지
+	MetadataKey  = "trace_metadata" // map[string]any, SetMetadata로 추가한 값 (Step.Extra에 함께 기록)
+)
+
+// RequestContext 미들웨어가 요청마다 컨텍스트에 기록하는 trace 정보
+type RequestContext struct {
+	TraceID   string
+	SpanID    string
+	UserID    string
+	TenantID  string
+	SessionID string
+	Sampled   bool
+	// Trace ID를 기록할 응답 헤더 이름 (빈 값이면 기록하지 않음)
+	Header string
+	// 핸들러가 SetMetadata로 값을 추가할 맵 (nil이면 빈 맵 생성)
+	Metadata map[string]any
+}
+
+// PopulateContext - 핸들러가 사용할 trace 정보를 gin.Context와 요청 컨텍스트에 기록
+// 미들웨어와 tracetest.SetupContext가 같은 함수를 사용하여 두 경로가 항상 같은 상태를 만든다
+// 하위 span은 기록만 되고 저장되지 않는다
+func PopulateContext(c *gin.Context, rc RequestContext) {
+	populateContext(c, rc, func(Step) {}, "")
+}
+
+// populateContext - 키, 응답 헤더, 요청 컨텍스트 값, 캐시 표시와 span 저장소 등록
+// 미들웨어는 emit에 Step 저장 함수를, version에 배포 버전을 전달한다
+func populateContext(c *gin.Context, rc RequestContext, emit func(Step), version string) (*cacheMarker, *spanRecorder) {
+	if rc.Metadata == nil {
+		rc.Metadata = map[string]any{}
+	}
+	c.Set(TraceIDKey, rc.TraceID)
+	c.Set(UserIDKey, rc.UserID)
+	c.Set(SpanIDKey, rc.SpanID)
+	c.Set(SampledKey, rc.Sampled)
+	c.Set(MetadataKey, rc.Metadata)
+	if rc.TenantID != "" {
+		c.Set(TenantIDKey, rc.TenantID)
+	}
+	if rc.SessionID != "" {
+		c.Set(SessionIDKey, rc.SessionID)
+	}
+	if rc.Header != "" {
+		c.Header(rc.Header, rc.TraceID)
+	}
+	// gin.Context 없이 요청 컨텍스트만 전달받는 코드에서도 FromContext로 조회할 수 있도록 기록
+	ctx := ContextWithUserID(ContextWithTraceID(c.Request.Context(), rc.TraceID), rc.UserID)
+	c.Request = c.Request.WithContext(ctx)
+	marker := installCacheMarker(c)
+	spans := installSpanRecorder(c, emit, Step{
+		TraceID:   rc.TraceID,
+		UserID:    rc.UserID,
+		TenantID:  rc.TenantID,
+		SessionID: rc.SessionID,
+		Path:      routePath(c),
+		Method:    c.Request.Method,
+		UserAgent: c.Request.UserAgent(),
+		Version:   version,
+		SpanID:    rc.SpanID,
+	})
+	return marker, spans
+}
+
+// SetMetadata - 요청 Step의 Extra에 함께 기록할 값 추가
+// trace 미들웨어가 Step을 수집하지 않는 요청이면 아무것도 하지 않는다
+func SetMetadata(c *gin.Context, key string, value any) {
+	if metadata, ok := c.Get(MetadataKey); ok {
+		if m, ok := metadata.(map[string]any); ok {
+			m[key] = value
+		}
+	}
+}
+
+// ResolveTraceOwner - db에 저장된 Step을 조회하여 Trace ID의 사용자 ID 반환 (기본 Tracer의 테이블 구성과 암호화 키 사용)
+// Trace ID 자체에는 사용자 정보가 없으므로 지원 업무 시 DB를 통해 매핑한다
+func ResolveTraceOwner(ctx context.Context, db *gorm.DB, traceID string) (string, error) {
+	return storeFor(db).ResolveTraceOwner(ctx, traceID)
+}
+
+// ResolveTraceOwner - 저장된 Step을 조회하여 Trace ID의 사용자 ID 반환
+func (s *Store) ResolveTraceOwner(ctx context.Context, traceID string) (string, error) {
+	steps, err := s.stepsFrom(ctx, time.Time{}, time.Time{})
+	if err != nil {
+		return "", err
+	}
+
+	var userIDs []string
+	err = steps().
+		Where("trace_id = ?", traceID).
+		Limit(1).
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return "", err
+	}
+	if len(userIDs) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return s.cipher.decrypt("user_id", userIDs[0])
 }
 
 // Middleware - 기본 Gin 미들웨어 (기존 호환성 유지)
@@ -126,136 +552,455 @@ func Middleware() gin.HandlerFunc {
 
 // MiddlewareWithConfig - 설정 가능한 Gin 미들웨어
 func MiddlewareWithConfig(options ...MiddlewareOption) gin.HandlerFunc {
+	return newMiddleware(newMiddlewareConfig(options...))
+}
+
+// newMiddlewareConfig - 기본 설정에 옵션을 적용한 미들웨어 설정
+func newMiddlewareConfig(options ...MiddlewareOption) *MiddlewareConfig {
 	config := &MiddlewareConfig{
-		UserIDExtractor:  defaultUserIDExtractor,
-		TokenExtractor:   defaultTokenExtractor,
-		TraceIDGenerator: defaultTraceIDGenerator,
-		Filter:           defaultFilter,
+		UserIDExtractor: defaultUserIDExtractor,
+		TokenExtractor:  defaultTokenExtractor,
+		Filter:          defaultFilter,
+		SampleRate:      1,
+		TraceIDHeader:   defaultTraceIDHeader,
 	}
 
 	// 옵션 적용
 	for _, option := range options {
 		option(config)
 	}
+	return config
+}
 
-	return func(c *gin.Context) {
-		// 필터링 체크
-		if !config.Filter(c) {
-			c.Next()
+// newMiddleware - 설정으로 미들웨어 핸들러 생성
+func newMiddleware(config *MiddlewareConfig) gin.HandlerFunc {
+	warnUntrustedClientIPHeader(config)
+	return registerMiddleware(func(c *gin.Context) {
+		// 필터링 체크 (UpdateConfig의 SkipPaths 포함)
+		tracer := config.pipeline()
+		runtime := tracer.runtimeSettings()
+		if !config.Filter(c) || runtime.skip(c) {
+			skipRequest(c, config, tracer)
 			return
 		}
 
 		userID := config.UserIDExtractor(c)
 		token := config.TokenExtractor(c)
+		var sessionID string
+		if config.SessionIDExtractor != nil {
+			sessionID = config.SessionIDExtractor(c)
+		}
+		authenticated := userID != "" && token != ""
+		collect := authenticated || sessionID != ""
+		if !authenticated {
+			// 비로그인 요청은 세션 단위로 Trace ID 생성
+			userID, token = "", sessionID
+		}
 
-		if userID == "" || token == "" {
-			c.Next()
+		if !collect && config.AccessLog == AccessLogNone {
+			skipRequest(c, config, tracer)
 			return
 		}
 
-		traceID := config.TraceIDGenerator(userID, token)
+		var traceID, spanID, parentSpanID, tenantID, captureID string
+		// 디버그 캡처 요청이면 쿼리, 헤더, 본문 저장 설정을 높인 복사본 (나머지 설정은 config 그대로)
+		capture := config
+		var marker *cacheMarker
+		var spans *spanRecorder
+		var bodies *bodyCapture
+		var requestSize *countingBody
+		var columns *routeRule
+		// 헤드 샘플링 결정 (디버그 캡처 요청은 항상 저장)
+		head, overload := true, false
+		if collect {
+			traceID, parentSpanID = resolveTraceID(c, config, tracer, userID, token)
+			spanID = newSpanID()
+			if config.TenantIDExtractor != nil {
+				tenantID = config.TenantIDExtractor(c)
+			}
+			if id, captureBodies := tracer.claimDebugCapture(userID); id != "" {
+				captureID, capture = id, debugCaptureConfig(config, captureBodies)
+			}
+			if captureID == "" {
+				head, overload = headSample(c, config, runtime, routePath(c), traceID)
+			}
+			marker, spans = populateContext(c, RequestContext{
+				TraceID:   traceID,
+				SpanID:    spanID,
+				UserID:    userID,
+				TenantID:  tenantID,
+				SessionID: sessionID,
+				Sampled:   upstreamSampled(c),
+				Header:    config.TraceIDHeader,
+			}, config.emitter(tracer), tracer.deployVersion())
+			spans.parent.CaptureID = captureID
+			spans.sampled = head
+			if captureID == "" {
+				// 디버그 캡처 요청은 모든 컬럼 저장
+				columns = routeColumns(config, routePath(c))
+				columns.apply(&spans.parent)
+			}
+			requestSize = startRequestSize(c)
+			bodies = startBodyCapture(c, capture)
+		}
 
 		start := time.Now()
-		c.Next()
-		latency := time.Since(start).Milliseconds()
+		recovered := next(c, config.PanicMode)
+		elapsed := time.Since(start)
+		requestBody, responseBody := bodies.finish(c)
+		ip := clientIP(c, config, tracer)
+
+		// Step과 접근 로그를 남긴 뒤 응답하거나 다시 panic
+		defer finishPanic(c, config.PanicMode, recovered)
+		status := responseStatus(c, recovered)
+
+		if config.AccessLog != AccessLogNone {
+			writeAccessLog(gin.DefaultWriter, config.AccessLog, accessLogEntry{
+				Time:       start.Add(elapsed),
+				StatusCode: status,
+				Latency:    elapsed,
+				ClientIP:   ip,
+				Method:     c.Request.Method,
+				Path:       c.Request.URL.Path,
+				TraceID:    traceID,
+				Errors:     c.Errors.ByType(gin.ErrorTypePrivate).String(),
+			})
+		}
+
+		if !collect {
+			if config.CountSkipped {
+				tracer.countRequest(c.Request.Method, routePath(c), status, elapsed, false)
+			}
+			return
+		}
 
+		now := time.Now()
 		step := Step{
 			TraceID:    traceID,
 			UserID:     userID,
-			Path:       c.FullPath(),
+			TenantID:   tenantID,
+			SessionID:  sessionID,
+			Path:       routePath(c),
 			Method:     c.Request.Method,
-			StatusCode: c.Writer.Status(),
-			LatencyMs:  latency,
-			IP:         c.ClientIP(),
+			StatusCode: status,
+			LatencyMs:  elapsed.Milliseconds(),
+			IP:         ip,
 			UserAgent:  c.Request.UserAgent(),
-			CreatedAt:  time.Now().Unix(),
+			CreatedAt:  now.Unix(),
+
+			EnqueuedAtNs: now.UnixNano(),
+			enqueuedAt:   now,
+			Version:      tracer.deployVersion(),
+			SpanID:       spanID,
+			ParentSpanID: parentSpanID,
+			CaptureID:    captureID,
 		}
-
-		select {
-		case buffer <- step:
-			// 정상 저장
-		default:
-			// 버퍼가 가득 찬 경우 드롭
+		kept, overload := keepStep(config, runtime, &step, head, overload)
+		// 요청 Step보다 먼저 버퍼에 들어가지 않도록 요청 Step 저장 후 하위 span 처리
+		defer spans.finish(kept)
+		if config.CountSkipped {
+			tracer.countRequest(step.Method, step.Path, status, elapsed, kept)
+		}
+		if !kept {
+			if overload {
+				config.dropped(tracer, step, DropOverload)
+			}
+			return
 		}
+		step.CacheHit, step.CacheName = cacheHit(c, marker)
+		if config.Labels != nil {
+			step.Team = config.Labels[teamLabel]
+			step.Labels = config.encodedLabels
+		}
+		step.Params = captureParams(c, config, tracer)
+		step.Query = captureQuery(c.Request.URL.RawQuery, capture)
+		if queries := spans.takeSlowQueries(); len(queries) > 0 {
+			SetMetadata(c, "slow_queries", queries)
+		}
+		step.Extra = extraFields(c, config.FieldsExtractor)
+		step.RequestBody, step.ResponseBody = requestBody, responseBody
+		step.RequestBytes, step.ResponseBytes = requestBytes(c, requestSize), responseBytes(c)
+		step.Headers = captureHeaders(c, capture, tracer)
+		step.Error, step.ErrorType = handlerErrors(c)
+		if recovered != nil {
+			step.Panic, step.PanicStack = recovered.message(), recovered.stack
+		}
+		if columns != nil {
+			tracer.countStorage(columns.rule.Pattern, columns.apply(&step))
+		}
+
+		config.emitter(tracer)(step)
+	}, config)
+}
+
+// enqueue - Step을 버퍼에 넣음 (PerUserOrdering이면 사용자별 샤드로)
+// Tracer가 없거나 중지된 경우 버린다
+func (t *Tracer) enqueue(step Step) {
+	if t == nil {
+		return
+	}
+	// Stop이 채널을 닫는 동안에는 보내지 않도록 읽기 잠금
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.running {
+		return
+	}
+
+	t.stamp(&step)
+	if t.cfg.GeoResolver != nil {
+		step.geoIP = step.IP
+	}
+	step.IP = t.anonymizeIP(step.IP)
+	t.recordErrorRate(step)
+	if t.cfg.StepFilter != nil && !t.cfg.StepFilter(step) {
+		return
+	}
+	t.ledger.expect(step)
+
+	step.Seq = stepSeq.Add(1)
+	t.tail.publish(step)
+	t.stepsReceived.Add(1)
+	if t.shards != nil {
+		t.shards.enqueue(step)
+		return
+	}
+
+	t.cfg.Overflow.push(t.buffer, step, t.overflowed)
+}
+
+// overflowed - 버퍼에 넣지 못한 Step 처리 (SpillPath가 있으면 디스크에 기록, 아니면 드롭)
+func (t *Tracer) overflowed(step Step) {
+	// 워커를 거치지 않으므로 spill 파일에 평문이 남지 않도록 여기서 암호화
+	t.cipher.encryptStep(&step)
+	if t.spillSteps([]Step{step}) > 0 {
+		t.droppedSteps.Add(1)
+		t.drop(step, DropBufferFull)
 	}
 }
 
-func startWorker(db *gorm.DB, interval time.Duration, batchSize int) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// 버퍼가 이 횟수만큼 연속으로 비어 있으면 타이머를 멈추고 채널 수신만 대기
+const idleIntervalsBeforePark = 3
+
+// startWorker - 채널에서 Step을 모아 배치 크기 또는 플러시 주기마다 flushFn 호출
+// flushFn이 반환된 뒤에는 슬라이스를 재사용하므로 보관하려면 복사해야 한다
+func startWorker(clk clock, ch <-chan Step, schedule flushSchedule, batchSize int, flushFn func([]Step)) {
+	timer := clk.NewTimer(schedule.first(clk.Now()))
+	defer timer.Stop()
+	timerC := timer.C()
 
 	buf := make([]Step, 0, batchSize*2) // 초기 용량 설정
+	idle := 0
 
 	for {
 		select {
-		case log, ok := <-buffer:
+		case log, ok := <-ch:
 			if !ok {
 				// 채널이 닫힌 경우
 				if len(buf) > 0 {
-					flush(db, buf)
+					flushFn(buf)
 				}
 				return
 			}
 
 			buf = append(buf, log)
 			if len(buf) >= batchSize {
-				flush(db, buf)
+				flushFn(buf)
 				buf = buf[:0] // 슬라이스 재사용
 			}
-		case <-ticker.C:
+			if timerC == nil {
+				// 유휴 상태에서 깨어난 경우 AlignFlushTo 위상과 지터를 유지한 다음 플러시 시점으로 타이머 재설정
+				timer.Reset(schedule.resume(clk.Now()))
+				timerC = timer.C()
+				idle = 0
+			}
+		case <-timerC:
 			if len(buf) > 0 {
-				flush(db, buf)
+				flushFn(buf)
 				buf = buf[:0] // 슬라이스 재사용
+				idle = 0
+			} else {
+				idle++
 			}
+			if idle >= idleIntervalsBeforePark {
+				// 트래픽이 없으면 불필요한 wakeup을 줄이기 위해 타이머 정지
+				timerC = nil
+				continue
+			}
+			timer.Reset(schedule.next())
 		}
 	}
 }
 
-func flush(db *gorm.DB, logs []Step) {
+// flush - 복사본을 만들어 별도 고루틴에서 저장 (워커를 막지 않음)
+func (t *Tracer) flush(logs []Step) {
 	if len(logs) == 0 {
 		return
 	}
 
-	go func(logs []Step) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("panic during trace flush: %v", r)
-			}
-		}()
-
-		// 재시도 로직 (최대 3회)
-		maxRetries := 3
-		for attempt := 1; attempt <= maxRetries; attempt++ {
-			if err := flushWithRetry(db, logs, attempt); err != nil {
-				if attempt == maxRetries {
-					log.Printf("failed to flush after %d attempts: %v", maxRetries, err)
+	// 워커가 버퍼 슬라이스를 재사용하므로 복사본을 넘긴다
+	logs = append([]Step(nil), logs...)
+
+	t.flushWG.Add(1)
+	go func() {
+		defer t.flushWG.Done()
+		t.flushBatch(logs)
+	}()
+}
+
+// flushBatch - 재시도를 포함하여 배치를 동기적으로 저장
+func (t *Tracer) flushBatch(logs []Step) {
+	// dead-letter로 보낸 Step까지 기록한 뒤 장부 저장
+	defer t.flushLedger()
+	pending := t.trackPending(logs)
+	defer t.pending.done(pending)
+	t.prepare(logs)
+
+	failed := len(logs)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic during trace flush: %v", r)
+			t.deadLetter(logs, fmt.Errorf("panic during flush: %v", r))
+		}
+		t.failedSteps.Add(int64(failed))
+	}()
+
+	// 재시도 로직 (최대 3회)
+	maxRetries := 3
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if err := t.flushWithRetry(logs, attempt); err != nil {
+			if attempt == maxRetries {
+				log.Printf("failed to flush after %d attempts: %v", maxRetries, err)
+				// SpillPath가 있으면 디스크에 남겨 두었다가 저장소가 복구되면 다시 저장
+				// spill 파일에 기록하지 못한 나머지는 dead-letter 핸들러로 전달
+				if t.pending.done(pending) {
+					// Stop이 이미 종료 스냅샷에 기록한 배치
+					failed = 0
 					return
 				}
-				// 재시도 전 잠시 대기
-				time.Sleep(time.Duration(attempt) * time.Second)
-				continue
+				if t.writeFallback(logs) {
+					failed = 0
+					return
+				}
+				failed = t.spillSteps(logs)
+				t.deadLetter(logs[len(logs)-failed:], err)
+				return
 			}
-
-			log.Printf("successfully flushed %d trace logs", len(logs))
-			return
+			// 재시도 전 잠시 대기
+			time.Sleep(time.Duration(attempt) * time.Second)
+			continue
 		}
-	}(logs)
+
+		failed = 0
+		log.Printf("successfully flushed %d trace logs", len(logs))
+		return
+	}
 }
 
 // flushWithRetry - 재시도 로직이 포함된 flush 함수
-func flushWithRetry(db *gorm.DB, logs []Step, attempt int) error {
-	// 배치 크기 설정 (메모리 효율성을 위해 500으로 제한)
-	batchSize := min(500, len(logs))
+func (t *Tracer) flushWithRetry(logs []Step, attempt int) error {
+	stampFlushed(logs, time.Now())
+	if attempt > 1 {
+		stampProvenance(logs, fmt.Sprintf("retry:%d", attempt-1))
+	}
+	t.flushAttempts.Add(1)
+	start := time.Now()
+	err := t.cfg.Sink.Write(context.Background(), logs)
+	took := time.Since(start)
+	t.flushDuration.observe(took)
+	if err != nil {
+		t.flushFailures.Add(1)
+		err = fmt.Errorf("failed to write %d steps (attempt %d): %w", len(logs), attempt, err)
+		t.lastFlush.record(start, err)
+		if t.cfg.OnFlushError != nil {
+			callHook("OnFlushError", func() { t.cfg.OnFlushError(err, logs) })
+		}
+		return err
+	}
+	t.storedSteps.Add(int64(len(logs)))
+	t.observeLag(logs, start.Add(took))
+	signal(t.storedWake)
+	t.lastFlush.record(start, nil)
+	t.rollup(logs)
+	if t.cfg.OnFlushSuccess != nil {
+		callHook("OnFlushSuccess", func() { t.cfg.OnFlushSuccess(len(logs), took) })
+	}
+	return nil
+}
 
-	// 배치 단위로 저장
-	for i := 0; i < len(logs); i += batchSize {
-		end := min(i+batchSize, len(logs))
+// callHook - 사용자 콜백 호출 (panic이 저장 경로를 멈추지 않도록 기록만 함)
+func callHook(name string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in trace %s hook: %v", name, r)
+		}
+	}()
+	fn()
+}
+
+// stampFlushed - 저장 직전 시각과 파이프라인 지연 시간 기록
+func stampFlushed(batch []Step, now time.Time) {
+	flushedAt := now.UnixNano()
+	for i := range batch {
+		batch[i].FlushedAtNs = flushedAt
+		batch[i].PipelineLagMs = pipelineLag(batch[i], now).Milliseconds()
+	}
+}
 
-		batch := logs[i:end]
-		if err := db.CreateInBatches(batch, batchSize).Error; err != nil {
-			return fmt.Errorf("failed to create batch %d-%d (attempt %d): %v", i, end-1, attempt, err)
+// pipelineLag - 버퍼 진입부터 now까지 걸린 시간
+// 같은 프로세스에서 넣은 Step은 단조 시계로 계산하고, spill 파일에서 읽은 Step처럼 단조 시계가 없으면 벽시계 차이를 사용한다
+// 벽시계가 뒤로 조정된 경우 음수 지연이 나오지 않도록 보정
+func pipelineLag(step Step, now time.Time) time.Duration {
+	if !step.enqueuedAt.IsZero() {
+		return max(now.Sub(step.enqueuedAt), 0)
+	}
+	return time.Duration(max(now.UnixNano()-step.EnqueuedAtNs, 0))
+}
+
+// observeLag - 저장에 성공한 Step의 버퍼 진입부터 저장 완료(storedAt)까지 걸린 시간 기록
+// Step의 PipelineLagMs는 저장 직전 시각 기준이므로 Sink가 멈춘 시간은 히스토그램에만 반영된다
+// spill 파일에서 다시 저장한 Step은 장애 기간만큼 지연되므로 따로 집계한다
+func (t *Tracer) observeLag(logs []Step, storedAt time.Time) {
+	for _, step := range logs {
+		lag := pipelineLag(step, storedAt)
+		if hasProvenance(step.Provenance, "spill") {
+			t.replayedLag.observe(lag)
+		} else {
+			t.pipelineLag.observe(lag)
 		}
 	}
+}
 
-	return nil
+// hasProvenance - 경로 문자열에 name 태그가 있는지 여부
+func hasProvenance(provenance, name string) bool {
+	for _, part := range strings.Split(provenance, ",") {
+		if partName, _, _ := strings.Cut(part, ":"); partName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stampProvenance - 경로 태그 기록 (같은 종류의 태그는 최신 값으로 교체)
+func stampProvenance(batch []Step, tag string) {
+	for i := range batch {
+		batch[i].Provenance = addProvenance(batch[i].Provenance, tag)
+	}
+}
+
+// addProvenance - 쉼표로 구분된 경로 문자열에 태그 추가
+// "retry:1"과 "retry:2"처럼 ':' 앞 이름이 같은 태그는 교체한다
+func addProvenance(provenance, tag string) string {
+	if provenance == "" {
+		return tag
+	}
+
+	name, _, _ := strings.Cut(tag, ":")
+	parts := strings.Split(provenance, ",")
+	for i, part := range parts {
+		if partName, _, _ := strings.Cut(part, ":"); partName == name {
+			parts[i] = tag
+			return strings.Join(parts, ",")
+		}
+	}
+	return provenance + "," + tag
 }
@@ -0,0 +1,39 @@
+package trace_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestDefaultTraceIDsDoNotContainUserIDs(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+
+	r := gin.New()
+	r.Use(tracer.Middleware())
+	r.GET("/orders", func(c *gin.Context) { c.Status(200) })
+
+	for _, userID := range []string{"alice", "kim.minsu", "user_42@example.com", "홍길동", "token"} {
+		w := httptest.NewRecorder()
+		query := url.Values{"user_id": {userID}, "access_token": {"secret-token"}}
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/orders?"+query.Encode(), nil))
+
+		id := w.Header().Get("X-Trace-ID")
+		if id == "" {
+			t.Fatalf("no trace ID for user %q", userID)
+		}
+		if strings.Contains(strings.ToLower(id), strings.ToLower(userID)) || strings.Contains(id, "secret-token") {
+			t.Fatalf("trace ID %q leaks user ID %q or the token", id, userID)
+		}
+	}
+}
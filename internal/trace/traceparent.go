@@ -0,0 +1,87 @@
+package trace
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// W3C Trace Context 헤더 이름
+const (
+	traceparentHeader = "traceparent"
+	tracestateHeader  = "tracestate"
+)
+
+// WithTraceContextPropagation W3C Trace Context(traceparent) 헤더 연동 설정
+// 요청에 올바른 traceparent가 있으면 그 trace ID를 Step.TraceID로, parent-id를 Step.ParentSpanID로 사용한다
+// WithB3Propagation과 함께 쓰면 traceparent를 우선하고, 없거나 형식이 틀리면 B3 헤더를 사용한다
+func WithTraceContextPropagation() MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.TraceContextPropagation = true
+	}
+}
+
+// traceparent 요청 헤더에서 읽은 W3C Trace Context 값
+type traceparent struct {
+	traceID string
+	spanID  string
+	sampled string // "1" 또는 "0"
+}
+
+// parseTraceparent - "{version}-{trace-id}-{parent-id}-{flags}" 형식 읽기
+// 알 수 없는 이후 버전은 앞의 네 필드만 사용하고, 모두 0인 ID나 ff 버전은 받지 않는다
+func parseTraceparent(value string) (traceparent, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return traceparent{}, false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || !isHex(version) || version == "ff" || version == "00" && len(parts) != 4 {
+		return traceparent{}, false
+	}
+	if len(traceID) != 32 || !isHex(traceID) || traceID == strings.Repeat("0", 32) {
+		return traceparent{}, false
+	}
+	if len(spanID) != 16 || !isHex(spanID) || spanID == strings.Repeat("0", 16) {
+		return traceparent{}, false
+	}
+	if len(flags) != 2 || !isHex(flags) {
+		return traceparent{}, false
+	}
+
+	tp := traceparent{traceID: traceID, spanID: spanID, sampled: "0"}
+	// sampled 플래그는 최하위 비트
+	if strings.IndexByte("13579bdf", flags[1]) >= 0 {
+		tp.sampled = "1"
+	}
+	return tp, true
+}
+
+// InjectTraceparent - 외부 서비스 호출 헤더에 현재 요청의 traceparent(와 받은 tracestate) 기록
+// 호출마다 새 span ID를 parent-id로 사용하므로 다음 서비스는 같은 trace에 합류한다
+// trace 미들웨어가 Step을 수집하지 않은 요청이면 아무것도 하지 않는다
+func InjectTraceparent(c *gin.Context, header http.Header) {
+	state, _ := c.Get(b3StateKey)
+	b3, _ := state.(*b3State)
+	rec := requestSpans(c.Request.Context())
+	if c.GetString(SpanIDKey) == "" || rec == nil {
+		return
+	}
+	injectTraceparent(header, b3, c.GetString(TraceIDKey), newSpanID(), rec.sampled)
+}
+
+// injectTraceparent - spanID를 parent-id로 하는 traceparent 기록 (sampled 플래그는 이 서비스의 헤드 샘플링 결정)
+func injectTraceparent(header http.Header, state *b3State, traceID, spanID string, sampled bool) {
+	if traceID == "" || spanID == "" {
+		return
+	}
+	flags := "00"
+	if sampled {
+		flags = "01"
+	}
+	header.Set(traceparentHeader, "00-"+HexTraceID(traceID)+"-"+spanID+"-"+flags)
+	if state != nil && state.tracestate != "" {
+		header.Set(tracestateHeader, state.tracestate)
+	}
+}
@@ -0,0 +1,94 @@
+package trace_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+const (
+	w3cTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	w3cParent  = "00f067aa0ba902b7"
+	b3TraceID  = "463ac35c9f6413ad48485a3953bb6124"
+	b3SpanID   = "a2fb4a1d1a96d312"
+)
+
+func TestTraceparentTakesPrecedenceOverB3(t *testing.T) {
+	tracer, err := trace.New(trace.Config{DB: tracetest.NewTempDB(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopTracer(t, tracer)
+
+	var outgoing http.Header
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outgoing = r.Header.Clone()
+	}))
+	defer downstream.Close()
+	client := &http.Client{Transport: trace.NewTransport(nil)}
+
+	var traceID string
+	var sampled bool
+	r := gin.New()
+	r.Use(tracer.Middleware(trace.WithB3Propagation(trace.B3Multi), trace.WithTraceContextPropagation()))
+	r.GET("/orders", func(c *gin.Context) {
+		traceID, sampled = c.GetString(trace.TraceIDKey), c.GetBool(trace.SampledKey)
+		req, _ := http.NewRequestWithContext(c.Request.Context(), "GET", downstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	})
+
+	tests := []struct {
+		name        string
+		traceparent string
+		b3          bool
+		wantTraceID string
+		wantSampled bool
+		wantFlags   string // 이 서비스의 샘플링 결정 (WithRespectUpstreamSampling이 없으므로 항상 저장)
+	}{
+		{"traceparent wins", "00-" + w3cTraceID + "-" + w3cParent + "-01", true, w3cTraceID, true, "01"},
+		{"not sampled", "00-" + w3cTraceID + "-" + w3cParent + "-00", false, w3cTraceID, false, "01"},
+		{"future version", "01-" + w3cTraceID + "-" + w3cParent + "-01-extra", false, w3cTraceID, true, "01"},
+		{"invalid falls back to B3", "00-" + strings.Repeat("0", 32) + "-" + w3cParent + "-01", true, b3TraceID, true, "01"},
+		{"uppercase is invalid", "00-" + strings.ToUpper(w3cTraceID) + "-" + w3cParent + "-01", true, b3TraceID, true, "01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/orders?user_id=user-1&access_token=token", nil)
+			req.Header.Set("traceparent", tt.traceparent)
+			req.Header.Set("tracestate", "vendor=opaque")
+			if tt.b3 {
+				req.Header.Set("X-B3-TraceId", b3TraceID)
+				req.Header.Set("X-B3-SpanId", b3SpanID)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if traceID != tt.wantTraceID || sampled != tt.wantSampled {
+				t.Fatalf("trace ID = %q sampled = %v, want %q %v", traceID, sampled, tt.wantTraceID, tt.wantSampled)
+			}
+			parts := strings.Split(outgoing.Get("traceparent"), "-")
+			if len(parts) != 4 || parts[1] != tt.wantTraceID || parts[2] == w3cParent || parts[2] == b3SpanID {
+				t.Fatalf("outgoing traceparent = %q, want trace %s with a new parent-id", outgoing.Get("traceparent"), tt.wantTraceID)
+			}
+			if parts[3] != tt.wantFlags {
+				t.Fatalf("outgoing flags = %q, want %q", parts[3], tt.wantFlags)
+			}
+			if joined := tt.wantTraceID == w3cTraceID; (outgoing.Get("tracestate") == "vendor=opaque") != joined {
+				t.Fatalf("outgoing tracestate = %q, want it forwarded only when traceparent was used", outgoing.Get("tracestate"))
+			}
+			if outgoing.Get("X-B3-TraceId") != tt.wantTraceID || outgoing.Get("X-B3-SpanId") != parts[2] {
+				t.Fatalf("outgoing B3 = %q/%q, want the same trace and span as traceparent", outgoing.Get("X-B3-TraceId"), outgoing.Get("X-B3-SpanId"))
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package trace
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Tracer Step 수집 파이프라인 (버퍼, 워커, Sink와 지표를 소유)
+// New로 여러 개를 만들어 독립적으로 사용할 수 있으며, 패키지 함수 Start/Stop은 기본 Tracer를 사용한다
+type Tracer struct {
+	cfg      Config
+	tables   stepTables
+	version  string             // 모든 Step에 기록할 배포 버전
+	k8s      KubernetesMetadata // 모든 Step에 기록할 Pod 정보 (KubernetesMetadata가 false면 빈 값)
+	settings EffectiveSettings
+
+	// running, buffer, shards 변경 보호 (enqueue는 읽기 잠금)
+	mu            sync.RWMutex
+	running       bool
+	stopped       chan struct{} // 첫 Stop이 끝나면 닫힘 (Stop 전에는 nil)
+	stopErr       error         // 첫 Stop의 결과
+	buffer        chan Step
+	shards        *orderedShards
+	janitorCancel context.CancelFunc
+	replayCancel  context.CancelFunc
+	spill         *spillFile // SpillPath가 없으면 nil
+	deadLetters   DeadLetterHandler
+	ipHasher      *ipHasher     // IPAnonymization이 IPHash일 때 사용
+	cipher        *columnCipher // 암호화 키가 없으면 nil
+	rollupDB      *gorm.DB      // Rollups가 false면 nil
+	ledger        *ledger       // Ledger가 false면 nil
+	store         *Store        // 기본 DB Sink의 조회 핸들 (사용자 정의 Sink면 nil)
+	errorRates    *errorRates   // 경로별 에러율
+	tail          tailHub       // 실시간 tail 구독자
+	captures      debugCaptures // 활성 디버그 캡처 (StartDebugCapture)
+	clock         clock         // 워커와 주기 작업의 시계 (테스트에서 교체)
+	storedWake    chan struct{} // 유휴 상태로 멈춘 보관 기간 정리를 깨우는 신호 (Step 저장 시)
+	spillWake     chan struct{} // 유휴 상태로 멈춘 spill 재전송을 깨우는 신호 (spill 기록 시)
+
+	workersWG      sync.WaitGroup // 워커 고루틴
+	startedWorkers atomic.Int32   // 시작한 워커 수 (Healthy에서 종료된 워커 확인)
+	liveWorkers    atomic.Int32   // 실행 중인 워커 수
+	flushWG        sync.WaitGroup // 진행 중인 비동기 flush 고루틴
+	pending        pendingBatches // 저장 중인 배치 (ShutdownSnapshotPath가 있을 때만 기록)
+
+	stepsReceived    atomic.Int64 // 파이프라인에 들어온 Step 수
+	storedSteps      atomic.Int64 // Sink에 저장한 Step 수
+	droppedSteps     atomic.Int64 // 버퍼가 가득 차 버린 Step 수
+	failedSteps      atomic.Int64 // 재시도 후에도 저장하지 못한 Step 수
+	flushAttempts    atomic.Int64 // sink.Write 호출 수 (재시도 포함)
+	flushFailures    atomic.Int64 // 실패한 sink.Write 호출 수
+	retentionDeleted atomic.Int64 // 보관 기간 정리로 삭제한 Step 수
+	spilledSteps     atomic.Int64 // 디스크에 기록한 Step 수
+	replayedSteps    atomic.Int64 // 디스크에서 다시 저장한 Step 수
+	deadLettered     atomic.Int64 // dead-letter 핸들러로 전달한 Step 수
+	invalidClientIPs atomic.Int64 // IP로 파싱되지 않아 빈 값으로 저장한 클라이언트 IP 수
+	authFailures     atomic.Int64 // 조회 API, 대시보드에서 인증에 실패한 요청 수
+	flushDuration    *histogram
+	pipelineLag      *histogram                      // 저장한 Step의 파이프라인 지연 시간 (재시도 포함)
+	replayedLag      *histogram                      // spill 파일에서 다시 저장한 Step의 파이프라인 지연 시간
+	runtime          atomic.Pointer[runtimeSettings] // UpdateConfig로 바꾼 수집 설정
+	endpoints        endpointCounters                // 경로, 상태 코드별 요청 지연 시간 (WithCountSkipped)
+	storage          routeStorage                    // 저장 컬럼 규칙별로 비운 바이트 수 (RouteRule.StoreColumns)
+	lastFlush        flushStatus
+}
+
+var (
+	// Start로 시작한 기본 Tracer (WithTracer 없이 만든 미들웨어와 패키지 함수가 사용)
+	defaultTracer atomic.Pointer[Tracer]
+	// Start 동시 호출 방지
+	startMu sync.Mutex
+)
+
+// New - 생략한 값에 기본값을 채우고 설정을 검사한 뒤 버퍼와 워커를 시작한 Tracer 반환
+// 다른 Tracer나 기본 Tracer와 버퍼, 워커, 지표를 공유하지 않으며, 사용이 끝나면 Stop을 호출해야 한다
+func New(cfg Config) (*Tracer, error) {
+	cfg = applyDefaults(cfg)
+	warnings, err := validateConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	logConfigWarnings(warnings)
+
+	t := &Tracer{
+		tables:        stepTables{base: qualifiedTableName(cfg), daily: cfg.PartitionByDay},
+		version:       resolveVersion(cfg.Version),
+		flushDuration: newFlushDurationHistogram(),
+		pipelineLag:   newPipelineLagHistogram(),
+		replayedLag:   newPipelineLagHistogram(),
+		deadLetters:   deadLetterHandler(cfg),
+		clock:         systemClock{},
+		storedWake:    make(chan struct{}, 1),
+		spillWake:     make(chan struct{}, 1),
+	}
+	if cfg.KubernetesMetadata {
+		t.k8s = kubernetesMetadata()
+	}
+	if t.cipher, err = encryptionCipher(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Sink == nil {
+		ownsDB := false
+		if cfg.DB == nil {
+			db, err := OpenDSN(cfg.DSN)
+			if err != nil {
+				return nil, err
+			}
+			cfg.DB, cfg.ManagePool, ownsDB = db, true, true
+		}
+		db, err := writerDB(cfg)
+		if err != nil {
+			closeOwned(cfg.DB, ownsDB)
+			return nil, err
+		}
+		gormSink, err := NewGormSinkWithTable(db, t.tables.base)
+		if err != nil {
+			closeOwned(cfg.DB, ownsDB)
+			return nil, err
+		}
+		gormSink.ownsConn = db != cfg.DB || ownsDB
+		if cfg.PartitionByDay {
+			gormSink.partitions = &partitionWriter{tables: t.tables}
+			if err := gormSink.partitions.migrateExisting(db); err != nil {
+				closeOwned(cfg.DB, ownsDB)
+				return nil, err
+			}
+		}
+		cfg.Sink = gormSink
+		t.store = &Store{db: cfg.DB, tables: t.tables, cipher: t.cipher, top: newTopEndpointsCache(topEndpointsCacheTTL(cfg))}
+	}
+	if cfg.Rollups {
+		// 기본 DB Sink면 Step과 같은 연결(SeparateConnection이면 trace 전용 풀)에 기록
+		db := cfg.DB
+		if gormSink, ok := cfg.Sink.(*GormSink); ok {
+			db = gormSink.DB
+		}
+		if err := migrateRollups(db, t.tables.rollupTable()); err != nil {
+			return nil, err
+		}
+		t.rollupDB = db
+		if t.store != nil {
+			t.store.rollups = true
+		}
+	}
+	if cfg.Ledger {
+		// 롤업과 같은 연결에 기록
+		db := cfg.DB
+		if gormSink, ok := cfg.Sink.(*GormSink); ok {
+			db = gormSink.DB
+		}
+		if t.ledger, err = newLedger(db, t.tables.ledgerTable()); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.SpillPath != "" {
+		spill, err := openSpill(cfg.SpillPath, cfg.SpillMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		t.spill = spill
+	}
+	if cfg.IPAnonymization == IPHash {
+		t.ipHasher = &ipHasher{rotation: cfg.IPHashRotation}
+	}
+	t.errorRates = newErrorRates(cfg)
+	t.cfg = cfg
+	t.settings = newEffectiveSettings(cfg, t.version, warnings)
+	if cfg.KubernetesMetadata {
+		k8s := t.k8s
+		t.settings.Kubernetes = &k8s
+	}
+
+	t.running = true
+	schedule := newFlushSchedule(cfg)
+	if rc := cfg.Runtime; rc.SampleRate != nil || rc.SlowThreshold != nil || len(rc.SkipPaths) > 0 {
+		t.UpdateConfig(cfg.Runtime)
+	}
+	if cfg.PerUserOrdering {
+		t.startShards(schedule)
+	} else {
+		t.buffer = make(chan Step, cfg.BufferSize)
+		t.runWorker(t.buffer, schedule, cfg.BatchSize, t.flush)
+	}
+	t.startJanitor()
+	t.startReplayer()
+	return t, nil
+}
+
+// closeOwned - New가 실패했을 때 DSN으로 연 연결 닫기
+func closeOwned(db *gorm.DB, owned bool) {
+	if !owned {
+		return
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// Start 기본 Tracer 시작
+func Start(cfg Config) error {
+	startMu.Lock()
+	defer startMu.Unlock()
+	if t := defaultTracer.Load(); t != nil && t.isRunning() {
+		return ErrAlreadyStarted
+	}
+
+	t, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	defaultTracer.Store(t)
+	return nil
+}
+
+func (t *Tracer) isRunning() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.running
+}
+
+// Middleware - 이 Tracer에 Step을 저장하는 Gin 미들웨어 (옵션은 MiddlewareWithConfig와 같음)
+func (t *Tracer) Middleware(options ...MiddlewareOption) gin.HandlerFunc {
+	return MiddlewareWithConfig(append([]MiddlewareOption{WithTracer(t)}, options...)...)
+}
+
+// WithTracer Step을 저장할 Tracer 설정 (기본값은 Start로 시작한 Tracer)
+// HTTPMiddlewareWithConfig 등 다른 미들웨어 생성 함수에서도 사용할 수 있다
+func WithTracer(t *Tracer) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.tracer = t
+	}
+}
+
+// WithTracers Step을 primary와 mirrors에 모두 저장 (Trace ID, 배포 버전 등은 primary 설정을 따름)
+// 예를 들어 전체 요청은 ClickHouse Tracer에, 에러만 StepFilter를 설정한 PostgreSQL Tracer에 함께 저장할 수 있다
+func WithTracers(primary *Tracer, mirrors ...*Tracer) MiddlewareOption {
+	return func(config *MiddlewareConfig) {
+		config.tracer = primary
+		config.mirrors = mirrors
+	}
+}
+
+// emitter - 요청 Step과 하위 span을 redactor 적용 후 tracer와 mirrors에 보내는 함수
+func (config *MiddlewareConfig) emitter(tracer *Tracer) func(Step) {
+	emit := tracer.enqueue
+	if mirrors := config.mirrors; len(mirrors) > 0 {
+		emit = func(step Step) {
+			tracer.enqueue(step)
+			for _, m := range mirrors {
+				m.enqueue(step)
+			}
+		}
+	}
+	if len(config.Redactors) == 0 {
+		return emit
+	}
+	return func(step Step) {
+		if redacted := config.redact(&step); redacted != nil {
+			emit(*redacted)
+		}
+	}
+}
+
+// dropped - 미들웨어가 버린 Step을 tracer와 mirrors의 OnDrop으로 전달
+func (config *MiddlewareConfig) dropped(tracer *Tracer, step Step, reason DropReason) {
+	tracer.drop(step, reason)
+	for _, m := range config.mirrors {
+		m.drop(step, reason)
+	}
+}
+
+// ErrorsOnly - 에러 Step만 저장하는 StepFilter (5xx 응답, 핸들러 에러, panic)
+func ErrorsOnly(step Step) bool {
+	return step.StatusCode >= 500 || step.Error != "" || step.Panic != ""
+}
+
+// pipeline - 미들웨어가 Step을 보낼 Tracer (시작된 기본 Tracer가 없으면 nil)
+func (config *MiddlewareConfig) pipeline() *Tracer {
+	if config.tracer != nil {
+		return config.tracer
+	}
+	return defaultTracer.Load()
+}
+
+// stamp - Tracer의 서비스 이름, 배포 환경, 버전, Pod 정보를 Step에 기록 (이미 값이 있는 필드는 유지)
+func (t *Tracer) stamp(step *Step) {
+	if step.ServiceName == "" {
+		step.ServiceName = t.cfg.ServiceName
+	}
+	if step.Environment == "" {
+		step.Environment = t.cfg.Environment
+	}
+	if step.Version == "" {
+		step.Version = t.version
+	}
+	if step.PodName == "" && step.NodeName == "" && step.Namespace == "" {
+		step.PodName, step.NodeName, step.Namespace = t.k8s.PodName, t.k8s.NodeName, t.k8s.Namespace
+	}
+}
+
+// deployVersion - Step에 기록할 배포 버전 (Tracer가 없으면 빈 값)
+func (t *Tracer) deployVersion() string {
+	if t == nil {
+		return ""
+	}
+	return t.version
+}
+
+// secret - Trace ID와 헤더 해시에 사용할 HMAC 키 (설정하지 않았으면 프로세스 공용 랜덤 키)
+func (t *Tracer) secret() []byte {
+	if t != nil && len(t.cfg.TraceIDSecret) > 0 {
+		return t.cfg.TraceIDSecret
+	}
+	return traceSecret()
+}
+
+// generateTraceID - 기본 Trace ID 생성 (사용자 ID가 노출되지 않는 불투명한 HMAC 값)
+func (t *Tracer) generateTraceID(userID, token string) string {
+	if t != nil && t.cfg.LegacyTraceIDs {
+		return legacyTraceIDGenerator(userID, token)
+	}
+
+	mac := hmac.New(sha256.New, t.secret())
+	mac.Write([]byte(userID))
+	mac.Write([]byte{0}) // userID와 token 경계 구분
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DroppedSteps - 버퍼가 가득 차 버린 Step 수 (Overflow 정책으로 버린 Step 포함)
+func (t *Tracer) DroppedSteps() int64 {
+	if t == nil {
+		return 0
+	}
+	return t.droppedSteps.Load()
+}
+
+// DroppedSteps - 기본 Tracer가 버퍼가 가득 차 버린 Step 수
+func DroppedSteps() int64 {
+	return defaultTracer.Load().DroppedSteps()
+}
+
+// EffectiveConfig - 이 Tracer에 적용된 설정 반환 (비밀 값은 포함하지 않음)
+func (t *Tracer) EffectiveConfig() EffectiveSettings {
+	settings := t.settings
+	settings.ShardDepths = t.ShardDepths()
+	return settings
+}
+
+// EffectiveConfig - 마지막 Start에 적용된 설정 반환 (비밀 값은 포함하지 않음)
+func EffectiveConfig() EffectiveSettings {
+	t := defaultTracer.Load()
+	if t == nil {
+		return EffectiveSettings{}
+	}
+	return t.EffectiveConfig()
+}
+
+// MetricsHandler - 이 Tracer의 파이프라인 지표를 Prometheus 텍스트 형식으로 노출하는 핸들러
+func (t *Tracer) MetricsHandler() http.Handler {
+	return metricsHandler(func() *Tracer { return t })
+}
+
+// MetricsHandler - 기본 Tracer의 파이프라인 지표를 Prometheus 텍스트 형식으로 노출하는 핸들러
+// gin에서는 r.GET("/metrics", gin.WrapH(trace.MetricsHandler()))로 등록한다
+func MetricsHandler() http.Handler {
+	return metricsHandler(defaultTracer.Load)
+}
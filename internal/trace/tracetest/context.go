@@ -0,0 +1,158 @@
+package tracetest
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"trace/internal/trace"
+)
+
+// SetupConfig SetupContext 설정
+type SetupConfig struct {
+	TraceID   string
+	SpanID    string
+	UserID    string
+	TenantID  string
+	SessionID string
+	Sampled   bool
+	// Trace ID를 기록할 응답 헤더 이름 (빈 값이면 기록하지 않음)
+	Header   string
+	Metadata map[string]any
+}
+
+// SetupOption 함수형 옵션 타입
+type SetupOption func(*SetupConfig)
+
+// WithTraceID Trace ID 설정
+func WithTraceID(traceID string) SetupOption {
+	return func(config *SetupConfig) {
+		config.TraceID = traceID
+	}
+}
+
+// WithSpanID 요청 span ID 설정
+func WithSpanID(spanID string) SetupOption {
+	return func(config *SetupConfig) {
+		config.SpanID = spanID
+	}
+}
+
+// WithUserID 사용자 ID 설정
+func WithUserID(userID string) SetupOption {
+	return func(config *SetupConfig) {
+		config.UserID = userID
+	}
+}
+
+// WithTenantID 테넌트 ID 설정
+func WithTenantID(tenantID string) SetupOption {
+	return func(config *SetupConfig) {
+		config.TenantID = tenantID
+	}
+}
+
+// WithSessionID 세션 ID 설정
+func WithSessionID(sessionID string) SetupOption {
+	return func(config *SetupConfig) {
+		config.SessionID = sessionID
+	}
+}
+
+// WithSampled 상위 서비스의 샘플링 여부 설정 (기본 true)
+func WithSampled(sampled bool) SetupOption {
+	return func(config *SetupConfig) {
+		config.Sampled = sampled
+	}
+}
+
+// WithHeader Trace ID를 기록할 응답 헤더 이름 설정 (빈 값이면 기록하지 않음)
+func WithHeader(name string) SetupOption {
+	return func(config *SetupConfig) {
+		config.Header = name
+	}
+}
+
+// WithMetadata 핸들러 실행 전 메타데이터 맵에 넣어 둘 값 설정
+func WithMetadata(metadata map[string]any) SetupOption {
+	return func(config *SetupConfig) {
+		config.Metadata = metadata
+	}
+}
+
+// SetupContext - 미들웨어를 실행하지 않고 미들웨어와 동일하게 컨텍스트를 채움
+// gin.CreateTestContext로 만든 컨텍스트로 핸들러를 단위 테스트할 때 사용한다
+func SetupContext(c *gin.Context, opts ...SetupOption) {
+	config := &SetupConfig{
+		TraceID: "test-trace-id",
+		SpanID:  "0000000000000001",
+		UserID:  "test-user",
+		Sampled: true,
+		Header:  "X-Trace-ID",
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if c.Request == nil {
+		c.Request = httptest.NewRequest("GET", "/", nil)
+	}
+	metadata := make(map[string]any, len(config.Metadata))
+	for key, value := range config.Metadata {
+		metadata[key] = value
+	}
+	trace.PopulateContext(c, trace.RequestContext{
+		TraceID:   config.TraceID,
+		SpanID:    config.SpanID,
+		UserID:    config.UserID,
+		TenantID:  config.TenantID,
+		SessionID: config.SessionID,
+		Sampled:   config.Sampled,
+		Header:    config.Header,
+		Metadata:  metadata,
+	})
+}
+
+// AssertContext - 미들웨어가 보장하는 컨텍스트 상태인지 검사
+// 세션 ID로 수집한 비로그인 요청은 사용자 ID가 빈 문자열이므로, 세션 ID가 있으면 빈 사용자 ID를 허용한다
+func AssertContext(t testing.TB, c *gin.Context) {
+	t.Helper()
+
+	sessionID, _ := c.Get(trace.SessionIDKey)
+	if value, ok := sessionID.(string); sessionID != nil && (!ok || value == "") {
+		t.Errorf("trace context: %q must be a non-empty string, got %#v", trace.SessionIDKey, sessionID)
+	}
+	for _, key := range []string{trace.TraceIDKey, trace.UserIDKey, trace.SpanIDKey} {
+		value, ok := c.Get(key)
+		if !ok {
+			t.Errorf("trace context: missing %q", key)
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			t.Errorf("trace context: %q must be a string, got %#v", key, value)
+			continue
+		}
+		if s == "" && !(key == trace.UserIDKey && sessionID != nil) {
+			t.Errorf("trace context: %q must be a non-empty string, got %#v", key, value)
+		}
+	}
+	if value, ok := c.Get(trace.SampledKey); !ok {
+		t.Errorf("trace context: missing %q", trace.SampledKey)
+	} else if _, ok := value.(bool); !ok {
+		t.Errorf("trace context: %q must be a bool, got %#v", trace.SampledKey, value)
+	}
+	if value, ok := c.Get(trace.MetadataKey); !ok {
+		t.Errorf("trace context: missing %q", trace.MetadataKey)
+	} else if _, ok := value.(map[string]any); !ok {
+		t.Errorf("trace context: %q must be a map[string]any, got %#v", trace.MetadataKey, value)
+	}
+	if c.Request == nil {
+		t.Errorf("trace context: request is nil")
+		return
+	}
+	if _, ok := trace.FromContext(c.Request.Context()); !ok {
+		t.Errorf("trace context: request context has no trace ID")
+	}
+}
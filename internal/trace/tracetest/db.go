@@ -0,0 +1,157 @@
+// tracetest 패키지 - trace 모듈을 사용하는 테스트를 위한 도우미
+package tracetest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"trace/internal/trace"
+)
+
+var dbSeq atomic.Uint64
+
+// NewTempDB - 테스트마다 격리된 인메모리 SQLite DB 생성 (스키마 마이그레이션 포함)
+// 테스트 종료 시 자동으로 닫히므로 -shuffle, -parallel 환경에서도 테스트 간 간섭이 없다
+func NewTempDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:tracetest_%d?mode=memory&cache=shared", dbSeq.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open temp db: %v", err)
+	}
+	if err := db.AutoMigrate(&trace.Step{}); err != nil {
+		t.Fatalf("failed to migrate temp db: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql.DB: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+	return db
+}
+
+// SeedConfig Step 생성 설정
+type SeedConfig struct {
+	Seed    uint64
+	Paths   []string
+	UserIDs []string
+	From    time.Time
+	To      time.Time
+	// 5xx 응답 비율 (0~1)
+	ErrorRate float64
+}
+
+// SeedOption 함수형 옵션 타입
+type SeedOption func(*SeedConfig)
+
+// WithSeed 난수 시드 설정
+func WithSeed(seed uint64) SeedOption {
+	return func(config *SeedConfig) {
+		config.Seed = seed
+	}
+}
+
+// WithPaths 생성할 경로 목록 설정
+func WithPaths(paths ...string) SeedOption {
+	return func(config *SeedConfig) {
+		config.Paths = paths
+	}
+}
+
+// WithUserIDs 생성할 사용자 ID 목록 설정
+func WithUserIDs(userIDs ...string) SeedOption {
+	return func(config *SeedConfig) {
+		config.UserIDs = userIDs
+	}
+}
+
+// WithTimeRange 생성 시각 범위 설정
+func WithTimeRange(from, to time.Time) SeedOption {
+	return func(config *SeedConfig) {
+		config.From = from
+		config.To = to
+	}
+}
+
+// WithErrorRate 5xx 응답 비율 설정
+func WithErrorRate(rate float64) SeedOption {
+	return func(config *SeedConfig) {
+		config.ErrorRate = rate
+	}
+}
+
+// SeedSteps - 고정 시드로 현실적인 분포의 Step n개를 생성하여 저장
+// 경로는 앞쪽일수록 많이 호출되고, 지연 시간은 긴 꼬리를 갖는 분포를 따른다
+// 같은 시드와 WithTimeRange로 고정한 범위면 실행마다 같은 Step을 만든다 (기본 범위는 현재 시각 기준 최근 1시간)
+func SeedSteps(t testing.TB, db *gorm.DB, n int, opts ...SeedOption) []trace.Step {
+	t.Helper()
+
+	now := time.Now()
+	config := &SeedConfig{
+		Seed:      1,
+		Paths:     []string{"/api/users", "/api/orders", "/api/payments", "/healthz"},
+		UserIDs:   []string{"user-1", "user-2", "user-3"},
+		From:      now.Add(-time.Hour),
+		To:        now,
+		ErrorRate: 0.05,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	rng := rand.New(rand.NewPCG(config.Seed, config.Seed))
+	span := config.To.Sub(config.From)
+	methods := []string{"GET", "GET", "GET", "POST"}
+
+	steps := make([]trace.Step, n)
+	for i := range steps {
+		status := 200
+		if rng.Float64() < config.ErrorRate {
+			status = 500
+		}
+		created := config.From
+		if span > 0 {
+			created = created.Add(time.Duration(rng.Int64N(int64(span))))
+		}
+
+		steps[i] = trace.Step{
+			TraceID:    fmt.Sprintf("seed-%d", i),
+			UserID:     config.UserIDs[rng.IntN(len(config.UserIDs))],
+			Path:       config.Paths[zipfIndex(rng, len(config.Paths))],
+			Method:     methods[rng.IntN(len(methods))],
+			StatusCode: status,
+			LatencyMs:  int64(rng.ExpFloat64()*50) + 1,
+			IP:         fmt.Sprintf("10.0.%d.%d", rng.IntN(256), rng.IntN(256)),
+			UserAgent:  "tracetest",
+			CreatedAt:  created.Unix(),
+		}
+	}
+
+	if err := db.CreateInBatches(steps, 500).Error; err != nil {
+		t.Fatalf("failed to seed steps: %v", err)
+	}
+	return steps
+}
+
+// zipfIndex - 앞쪽 인덱스가 더 자주 선택되도록 치우친 인덱스 반환
+func zipfIndex(rng *rand.Rand, n int) int {
+	for i := 0; i < n-1; i++ {
+		if rng.IntN(2) == 0 {
+			return i
+		}
+	}
+	return n - 1
+}
@@ -0,0 +1,70 @@
+package tracetest_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+func TestNewTempDBIsIsolated(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		n := i + 1
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			t.Parallel()
+			db := tracetest.NewTempDB(t)
+			tracetest.SeedSteps(t, db, n)
+
+			var count int64
+			if err := db.Model(&trace.Step{}).Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if count != int64(n) {
+				t.Fatalf("count = %d, want %d (another test's rows are visible)", count, n)
+			}
+		})
+	}
+}
+
+func TestNewTempDBIsClosedOnCleanup(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	t.Run("inner", func(t *testing.T) {
+		db = tracetest.NewTempDB(t)
+		tracetest.SeedSteps(t, db, 1)
+	})
+	var count int64
+	if err := db.Model(&trace.Step{}).Count(&count).Error; err == nil {
+		t.Fatal("temp db is still open after its test finished")
+	}
+}
+
+func TestSeedStepsIsDeterministic(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	seed := func(seed uint64) []trace.Step {
+		return tracetest.SeedSteps(t, tracetest.NewTempDB(t), 200,
+			tracetest.WithSeed(seed), tracetest.WithTimeRange(from, to))
+	}
+
+	first, second := seed(7), seed(7)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("same seed produced different steps")
+	}
+	if reflect.DeepEqual(first, seed(8)) {
+		t.Fatal("different seeds produced the same steps")
+	}
+
+	counts := map[string]int{}
+	for _, step := range first {
+		if step.CreatedAt < from.Unix() || step.CreatedAt >= to.Unix() {
+			t.Fatalf("CreatedAt %d outside [%d, %d)", step.CreatedAt, from.Unix(), to.Unix())
+		}
+		counts[step.Path]++
+	}
+	if counts["/api/users"] <= counts["/healthz"] {
+		t.Fatalf("path distribution is not skewed: %v", counts)
+	}
+}
@@ -0,0 +1,59 @@
+package trace
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Transport 외부 HTTP 호출을 현재 요청의 하위 span으로 기록하는 http.RoundTripper
+// 요청 컨텍스트는 trace 미들웨어를 거친 c.Request.Context()(또는 그 하위 컨텍스트)여야 한다
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport - 외부 호출 추적 Transport 생성 (base가 nil이면 http.DefaultTransport)
+//
+//	client := &http.Client{Transport: trace.NewTransport(nil)}
+//	req, _ := http.NewRequestWithContext(c.Request.Context(), "GET", url, nil)
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip - 호출 대상 호스트, 경로, 상태 코드, 응답 시간을 하위 span으로 기록
+// 현재 요청의 샘플링 결정을 X-Trace-Sampled 헤더로 전달하며(WithRespectUpstreamSampling),
+// WithB3Propagation, WithTraceContextPropagation이 켜진 요청이면 B3, traceparent 헤더도 함께 전달한다
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	span := StartSpanFromContext(req.Context(), "HTTP "+req.Method+" "+req.URL.Host)
+	if span.rec == nil {
+		return base.RoundTrip(req)
+	}
+
+	// RoundTripper는 요청을 수정하면 안 되므로 복사본에 헤더 기록
+	req = req.Clone(req.Context())
+	req.Header.Set(sampledHeader, sampledValue(span.rec.sampled))
+	if state := span.rec.b3; state != nil {
+		if state.b3 {
+			injectB3(req.Header, state, span.step.TraceID, span.step.ParentSpanID, span.step.SpanID)
+		}
+		if state.traceparent {
+			injectTraceparent(req.Header, state, span.step.TraceID, span.step.SpanID, span.rec.sampled)
+		}
+	}
+
+	target, _ := json.Marshal(map[string]string{"host": req.URL.Host, "path": req.URL.Path})
+	span.step.Extra = string(target)
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+	} else {
+		span.step.StatusCode = resp.StatusCode
+	}
+	span.End()
+	return resp, err
+}
@@ -0,0 +1,124 @@
+package trace
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 회귀 여부를 판단하기 위해 각 버전에 필요한 최소 요청 수
+const minComparisonSamples = 30
+
+// 회귀로 판단하는 p95 증가율과 에러율 증가폭
+const (
+	regressionP95Ratio      = 1.2
+	regressionErrorRateDiff = 0.01
+)
+
+// resolveVersion - 설정된 버전이 없으면 빌드 정보의 VCS revision 사용
+func resolveVersion(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value[:min(12, len(setting.Value))]
+		}
+	}
+	return ""
+}
+
+// VersionStats 버전별 지연 시간과 에러율
+type VersionStats struct {
+	Version   string  `json:"version"`
+	Count     int64   `json:"count"`
+	P50Ms     int64   `json:"p50_ms"`
+	P95Ms     int64   `json:"p95_ms"`
+	P99Ms     int64   `json:"p99_ms"`
+	ErrorRate float64 `json:"error_rate"` // 5xx 비율 (0~1)
+}
+
+// Comparison 두 버전의 비교 결과 (Delta는 B - A)
+type Comparison struct {
+	Path           string       `json:"path"`
+	A              VersionStats `json:"a"`
+	B              VersionStats `json:"b"`
+	P50DeltaMs     int64        `json:"p50_delta_ms"`
+	P95DeltaMs     int64        `json:"p95_delta_ms"`
+	P99DeltaMs     int64        `json:"p99_delta_ms"`
+	ErrorRateDelta float64      `json:"error_rate_delta"`
+	// 두 버전 모두 최소 요청 수를 넘었는지 여부 (false면 Regression 판단을 하지 않음)
+	Sufficient bool `json:"sufficient"`
+	// B의 p95가 A보다 20% 이상 높거나 에러율이 1%p 이상 높으면 true
+	Regression bool `json:"regression"`
+}
+
+// CompareVersions - 기간 내 경로의 두 버전 지연 시간과 에러율 비교
+func CompareVersions(ctx context.Context, db *gorm.DB, path string, versionA, versionB string, from, to time.Time) (Comparison, error) {
+	a, err := versionStats(ctx, db, path, versionA, from, to)
+	if err != nil {
+		return Comparison{}, err
+	}
+	b, err := versionStats(ctx, db, path, versionB, from, to)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	cmp := Comparison{
+		Path:           path,
+		A:              a,
+		B:              b,
+		P50DeltaMs:     b.P50Ms - a.P50Ms,
+		P95DeltaMs:     b.P95Ms - a.P95Ms,
+		P99DeltaMs:     b.P99Ms - a.P99Ms,
+		ErrorRateDelta: b.ErrorRate - a.ErrorRate,
+		Sufficient:     a.Count >= minComparisonSamples && b.Count >= minComparisonSamples,
+	}
+	if cmp.Sufficient {
+		cmp.Regression = float64(b.P95Ms) > float64(a.P95Ms)*regressionP95Ratio ||
+			cmp.ErrorRateDelta >= regressionErrorRateDiff
+	}
+	return cmp, nil
+}
+
+func versionStats(ctx context.Context, db *gorm.DB, path, version string, from, to time.Time) (VersionStats, error) {
+	stats := VersionStats{Version: version}
+	steps, err := storeFor(db).stepsFrom(ctx, from, to)
+	if err != nil {
+		return stats, err
+	}
+	scope := func() *gorm.DB {
+		return steps().
+			Where(requestStepsOnly).
+			Where("path = ? AND version = ? AND created_at >= ? AND created_at < ?", path, version, from.Unix(), to.Unix())
+	}
+
+	var totals struct {
+		Count  int64
+		Errors int64
+	}
+	err = scope().
+		Select("COUNT(*) AS count, SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) AS errors").
+		Scan(&totals).Error
+	if err != nil || totals.Count == 0 {
+		return stats, err
+	}
+	stats.Count = totals.Count
+	stats.ErrorRate = float64(totals.Errors) / float64(totals.Count)
+
+	for _, pct := range []struct {
+		p   float64
+		dst *int64
+	}{{0.50, &stats.P50Ms}, {0.95, &stats.P95Ms}, {0.99, &stats.P99Ms}} {
+		if *pct.dst, err = latencyPercentile(scope, stats.Count, pct.p); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,102 @@
+package trace_test
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+)
+
+// seedVersion - path에 version의 요청 Step n개 저장 (지연 시간은 평균 meanMs, 표준편차 meanMs/6인 정규 분포, errorEvery번째마다 5xx)
+func seedVersion(t *testing.T, db *gorm.DB, rng *rand.Rand, path, version string, n int, meanMs float64, errorEvery int, at time.Time) {
+	t.Helper()
+	steps := make([]trace.Step, 0, n)
+	for i := range n {
+		status := http.StatusOK
+		if errorEvery > 0 && i%errorEvery == 0 {
+			status = http.StatusInternalServerError
+		}
+		steps = append(steps, trace.Step{
+			TraceID:    version + path,
+			UserID:     "u",
+			Path:       path,
+			Method:     "GET",
+			StatusCode: status,
+			LatencyMs:  max(int64(meanMs+rng.NormFloat64()*meanMs/6), 1),
+			Version:    version,
+			CreatedAt:  at.Unix(),
+		})
+	}
+	if err := db.CreateInBatches(steps, 100).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	rng := rand.New(rand.NewSource(42))
+	at := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name           string
+		countA, countB int
+		meanA, meanB   float64
+		errorEveryB    int
+		sufficient     bool
+		regression     bool
+	}{
+		// B의 지연 시간 분포를 50% 늦춤
+		{"latency shift", 300, 300, 100, 150, 0, true, true},
+		// 같은 분포에서 뽑은 표본은 무작위 차이만 있음
+		{"same distribution", 300, 300, 100, 100, 0, true, false},
+		// 지연 시간은 같고 B의 에러율이 5%
+		{"error rate increase", 300, 300, 100, 100, 20, true, true},
+		// 차이가 커도 표본이 작으면 판단하지 않음
+		{"tiny sample", 5, 5, 100, 1000, 1, false, false},
+		{"one side below minimum", 300, 29, 100, 1000, 0, false, false},
+		{"both at minimum", 30, 30, 100, 1000, 0, true, true},
+		{"no requests for B", 300, 0, 100, 0, 0, false, false},
+	}
+	for _, tt := range tests {
+		path := "/" + tt.name
+		seedVersion(t, db, rng, path, "v1", tt.countA, tt.meanA, 0, at)
+		seedVersion(t, db, rng, path, "v2", tt.countB, tt.meanB, tt.errorEveryB, at)
+	}
+	// 기간 밖과 다른 버전의 요청은 비교에 포함하지 않음
+	seedVersion(t, db, rng, "/latency shift", "v1", 300, 1000, 1, at.Add(-2*time.Hour))
+	seedVersion(t, db, rng, "/latency shift", "v3", 300, 1000, 1, at)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmp, err := trace.CompareVersions(context.Background(), db, "/"+tt.name, "v1", "v2", at.Add(-time.Hour), at.Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("A=%+v B=%+v", cmp.A, cmp.B)
+			if cmp.A.Count != int64(tt.countA) || cmp.B.Count != int64(tt.countB) {
+				t.Fatalf("counts = %d, %d, want %d, %d", cmp.A.Count, cmp.B.Count, tt.countA, tt.countB)
+			}
+			if cmp.Sufficient != tt.sufficient || cmp.Regression != tt.regression {
+				t.Fatalf("sufficient = %v, regression = %v, want %v, %v", cmp.Sufficient, cmp.Regression, tt.sufficient, tt.regression)
+			}
+			if cmp.P95DeltaMs != cmp.B.P95Ms-cmp.A.P95Ms || cmp.ErrorRateDelta != cmp.B.ErrorRate-cmp.A.ErrorRate {
+				t.Fatalf("deltas do not match the stats: %+v", cmp)
+			}
+		})
+	}
+
+	// 50% 늦춘 분포는 모든 백분위에서 차이가 남
+	cmp, err := trace.CompareVersions(context.Background(), db, "/latency shift", "v1", "v2", at.Add(-time.Hour), at.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp.P50DeltaMs < 40 || cmp.P95DeltaMs < 40 || cmp.P99DeltaMs < 30 || cmp.ErrorRateDelta != 0 {
+		t.Fatalf("latency shift deltas = p50 %d, p95 %d, p99 %d, errors %v", cmp.P50DeltaMs, cmp.P95DeltaMs, cmp.P99DeltaMs, cmp.ErrorRateDelta)
+	}
+}
@@ -0,0 +1,108 @@
+package trace
+
+import (
+	"testing"
+	"time"
+)
+
+// expectReset - 워커가 타이머를 다시 설정할 때까지 대기하고 대기 시간 반환
+func expectReset(t *testing.T, clk *fakeClock) time.Duration {
+	t.Helper()
+	select {
+	case d := <-clk.resets:
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("worker did not re-arm its timer")
+		return 0
+	}
+}
+
+func expectNoReset(t *testing.T, clk *fakeClock) {
+	t.Helper()
+	select {
+	case d := <-clk.resets:
+		t.Fatalf("worker re-armed its timer (%s) while idle", d)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWorkerParksWhenIdleAndFlushesOnResume(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		schedule flushSchedule
+	}{
+		{"interval", flushSchedule{interval: time.Second}},
+		{"aligned", flushSchedule{interval: time.Second, alignTo: time.Second, offset: 300 * time.Millisecond}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := newFakeClock()
+			ch := make(chan Step)
+			flushed := make(chan int, 10)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				startWorker(clk, ch, tc.schedule, 100, func(steps []Step) { flushed <- len(steps) })
+			}()
+			defer func() {
+				close(ch)
+				<-done
+			}()
+
+			// 비어 있는 주기가 idleIntervalsBeforePark번 이어지면 타이머를 멈춘다
+			expectReset(t, clk)
+			clk.Advance(2 * time.Second)
+			for i := 1; i < idleIntervalsBeforePark; i++ {
+				expectReset(t, clk)
+				clk.Advance(2 * time.Second)
+			}
+			expectNoReset(t, clk)
+
+			// 유휴 중에는 시간이 흘러도 깨어나지 않는다
+			parked := clk.Fired()
+			for range 1000 {
+				clk.Advance(time.Minute)
+			}
+			if woke := clk.Fired() - parked; woke != 0 {
+				t.Fatalf("worker woke %d times while idle, want 0", woke)
+			}
+
+			// 트래픽이 다시 들어오면 FlushInterval 안에 플러시
+			ch <- Step{TraceID: "resume"}
+			wait := expectReset(t, clk)
+			if wait <= 0 || wait > tc.schedule.interval {
+				t.Fatalf("first flush after idle scheduled in %s, want within %s", wait, tc.schedule.interval)
+			}
+			if tc.schedule.alignTo > 0 {
+				at := clk.Now().Add(wait)
+				if phase := at.Sub(at.Truncate(tc.schedule.interval)); phase != tc.schedule.offset {
+					t.Fatalf("flush after idle at phase %s, want instance offset %s", phase, tc.schedule.offset)
+				}
+			}
+			clk.Advance(wait)
+			select {
+			case n := <-flushed:
+				if n != 1 {
+					t.Fatalf("flushed %d steps, want 1", n)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("step was not flushed after resume")
+			}
+			expectReset(t, clk)
+		})
+	}
+}
+
+func TestFlushScheduleResumeKeepsPhase(t *testing.T) {
+	s := flushSchedule{interval: 5 * time.Second, alignTo: time.Minute, offset: 1200 * time.Millisecond}
+	base := time.Unix(1_700_000_040, 0) // 분 경계
+	for _, elapsed := range []time.Duration{0, time.Second, 1200 * time.Millisecond, 3 * time.Second, 59 * time.Second} {
+		now := base.Add(elapsed)
+		wait := s.resume(now)
+		if wait <= 0 || wait > s.interval {
+			t.Fatalf("resume(%s) = %s, want (0, %s]", elapsed, wait, s.interval)
+		}
+		if phase := now.Add(wait).Sub(base) % s.interval; phase != s.offset {
+			t.Fatalf("resume(%s) lands at phase %s, want %s", elapsed, phase, s.offset)
+		}
+	}
+}
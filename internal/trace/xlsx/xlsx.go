@@ -0,0 +1,208 @@
+// Package xlsx - trace.Export의 Excel(.xlsx) 형식
+//
+// import하면 trace.Export와 tracectl export에서 "xlsx" 형식을 사용할 수 있다
+//
+//	import _ "trace/internal/trace/xlsx"
+//
+//	store.Export(ctx, filter, xlsx.Format, f)
+//
+// 행을 zip 스트림에 바로 기록하므로 백만 행 이상을 내보내도 메모리 사용량이 일정하며,
+// 시트 하나의 최대 행 수(1,048,576)를 넘으면 다음 시트에 이어서 기록한다
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"trace/internal/trace"
+)
+
+// Format trace.Export에 전달할 형식 이름
+const Format trace.ExportFormat = "xlsx"
+
+// 시트 하나의 최대 행 수 (Excel 제한, 컬럼 이름 행 포함)
+const maxSheetRows = 1 << 20
+
+// Excel 숫자(double)로 정확히 표현할 수 있는 최대 정수 (넘으면 문자열로 기록)
+const maxExactInt = 1 << 53
+
+func init() {
+	trace.RegisterExportFormat(Format, func(w io.Writer, columns []string, _ trace.ExportOptions) (trace.ExportWriter, error) {
+		return NewWriter(w, columns)
+	})
+}
+
+// Writer 행을 순서대로 기록하는 xlsx 기록기 (trace.ExportWriter)
+// 문자열은 인라인 문자열로 기록하므로 =, + 등으로 시작해도 수식으로 실행되지 않는다
+type Writer struct {
+	zw      *zip.Writer
+	sheet   *bufio.Writer
+	columns []string
+	sheets  int // 만든 시트 수
+	rows    int // 현재 시트에 기록한 행 수 (컬럼 이름 행 포함)
+	buf     []byte
+}
+
+// NewWriter - columns를 첫 행으로 하는 xlsx 기록기 생성 (Close에서 파일을 마무리하며 w는 닫지 않음)
+func NewWriter(w io.Writer, columns []string) (*Writer, error) {
+	xw := &Writer{zw: zip.NewWriter(w), columns: columns}
+	if err := xw.nextSheet(); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+// WriteRow - 행 하나 기록 (string, bool, 정수형, float64, 그 외는 fmt 형식의 문자열)
+func (w *Writer) WriteRow(values []any) error {
+	if w.rows == maxSheetRows {
+		if err := w.nextSheet(); err != nil {
+			return err
+		}
+	}
+	return w.writeRow(values)
+}
+
+// Close - 시트와 통합 문서 정보를 기록하고 zip을 마무리
+func (w *Writer) Close() error {
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+	if err := w.writeWorkbook(); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+// nextSheet - 현재 시트를 마무리하고 컬럼 이름 행으로 시작하는 새 시트 시작
+func (w *Writer) nextSheet() error {
+	if w.sheet != nil {
+		if err := w.endSheet(); err != nil {
+			return err
+		}
+	}
+	w.sheets++
+	f, err := w.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", w.sheets))
+	if err != nil {
+		return err
+	}
+	w.sheet = bufio.NewWriter(f)
+	w.rows = 0
+	w.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	header := make([]any, len(w.columns))
+	for i, name := range w.columns {
+		header[i] = name
+	}
+	return w.writeRow(header)
+}
+
+func (w *Writer) endSheet() error {
+	w.sheet.WriteString(`</sheetData></worksheet>`)
+	return w.sheet.Flush()
+}
+
+func (w *Writer) writeRow(values []any) error {
+	b := append(w.buf[:0], "<row>"...)
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			b = appendString(b, v)
+		case bool:
+			b = append(b, `<c t="b"><v>`...)
+			if v {
+				b = append(b, '1')
+			} else {
+				b = append(b, '0')
+			}
+			b = append(b, "</v></c>"...)
+		case int64:
+			b = appendInt(b, v)
+		case int:
+			b = appendInt(b, int64(v))
+		case float64:
+			b = append(b, "<c><v>"...)
+			b = strconv.AppendFloat(b, v, 'g', -1, 64)
+			b = append(b, "</v></c>"...)
+		default:
+			b = appendString(b, fmt.Sprint(v))
+		}
+	}
+	b = append(b, "</row>"...)
+	w.buf = b
+	w.rows++
+	_, err := w.sheet.Write(b)
+	return err
+}
+
+// appendInt - 정수 셀 (Excel 숫자로 정확히 표현할 수 없는 값은 문자열)
+func appendInt(b []byte, v int64) []byte {
+	if v > maxExactInt || v < -maxExactInt {
+		return appendString(b, strconv.FormatInt(v, 10))
+	}
+	b = append(b, "<c><v>"...)
+	b = strconv.AppendInt(b, v, 10)
+	return append(b, "</v></c>"...)
+}
+
+// appendString - 인라인 문자열 셀 (XML에 쓸 수 없는 제어 문자는 U+FFFD로 바뀜)
+func appendString(b []byte, s string) []byte {
+	b = append(b, `<c t="inlineStr"><is><t xml:space="preserve">`...)
+	b = appendEscaped(b, s)
+	return append(b, "</t></is></c>"...)
+}
+
+type sliceWriter struct{ b *[]byte }
+
+func (s sliceWriter) Write(p []byte) (int, error) {
+	*s.b = append(*s.b, p...)
+	return len(p), nil
+}
+
+func appendEscaped(b []byte, s string) []byte {
+	xml.EscapeText(sliceWriter{&b}, []byte(s))
+	return b
+}
+
+// writeWorkbook - 시트 목록과 패키지 관계 기록 (시트 수는 모든 행을 기록한 뒤에 알 수 있으므로 마지막에 기록)
+func (w *Writer) writeWorkbook() error {
+	var types, sheets, rels []byte
+	for i := 1; i <= w.sheets; i++ {
+		types = fmt.Appendf(types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+		sheets = fmt.Appendf(sheets, `<sheet name="steps%s" sheetId="%d" r:id="rId%d"/>`, sheetSuffix(i), i, i)
+		rels = fmt.Appendf(rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			string(types) + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + string(sheets) + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			string(rels) + `</Relationships>`},
+	} {
+		f, err := w.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xml.Header+part.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sheetSuffix - 두 번째 시트부터 붙이는 번호 (steps, steps-2, ...)
+func sheetSuffix(i int) string {
+	if i == 1 {
+		return ""
+	}
+	return "-" + strconv.Itoa(i)
+}
@@ -0,0 +1,182 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"trace/internal/trace"
+	"trace/internal/trace/tracetest"
+	"trace/internal/trace/xlsx"
+)
+
+// cell xlsx 시트의 셀 (인라인 문자열이면 T가 "inlineStr")
+type cell struct {
+	T     string `xml:"t,attr"`
+	Value string `xml:"v"`
+	Text  string `xml:"is>t"`
+}
+
+func (c cell) String() string {
+	if c.T == "inlineStr" {
+		return c.Text
+	}
+	return c.Value
+}
+
+type worksheet struct {
+	Rows []struct {
+		Cells []cell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readSheets - xlsx 파일의 시트 XML 파싱 (통합 문서 구성 파일도 함께 확인)
+// skip번째까지의 시트는 파싱하지 않고 빈 값으로 둔다
+func readSheets(t *testing.T, data []byte, skip int) []worksheet {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip file: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		if files[name] == nil {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+	var sheets []worksheet
+	for i := 1; files[fmt.Sprintf("xl/worksheets/sheet%d.xml", i)] != nil; i++ {
+		if i <= skip {
+			sheets = append(sheets, worksheet{})
+			continue
+		}
+		rc, err := files[fmt.Sprintf("xl/worksheets/sheet%d.xml", i)].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sheet worksheet
+		err = xml.NewDecoder(rc).Decode(&sheet)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("sheet %d does not parse: %v", i, err)
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets
+}
+
+func TestExportXLSXRoundTrip(t *testing.T) {
+	db := tracetest.NewTempDB(t)
+	hostile := []string{
+		"=HYPERLINK(\"http://evil\",\"x\")",
+		"+1+1",
+		"@SUM(A1)",
+		"line1\nline2\r\n\t\"quoted\", <b>&amp;</b>",
+		"control\x01char",
+		strings.Repeat("가", 20000),
+	}
+	now := time.Now().Unix()
+	for i, value := range hostile {
+		step := trace.Step{TraceID: "t", Path: "/x", Method: "GET", StatusCode: 200, UserAgent: value, CacheHit: i == 0, CreatedAt: now, EnqueuedAtNs: 1 << 60, Seq: int64(i)}
+		if err := db.Create(&step).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := trace.NewStore(db, trace.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Export(context.Background(), trace.QueryFilter{}, xlsx.Format, &buf); err != nil {
+		t.Fatal(err)
+	}
+	sheets := readSheets(t, buf.Bytes(), 0)
+	if len(sheets) != 1 || len(sheets[0].Rows) != len(hostile)+1 {
+		t.Fatalf("got %d sheets, want 1 with %d rows", len(sheets), len(hostile)+1)
+	}
+	columns := map[string]int{}
+	for i, c := range sheets[0].Rows[0].Cells {
+		columns[c.String()] = i
+	}
+
+	for i, value := range hostile {
+		row := sheets[0].Rows[i+1].Cells
+		got := row[columns["user_agent"]].String()
+		switch {
+		case strings.ContainsRune(value, '\x01'):
+			if got != "control�char" {
+				t.Errorf("control character exported as %q", got)
+			}
+		case len(value) > 32767:
+			if len(got) > 32767 || !strings.HasSuffix(got, "...[truncated]") {
+				t.Errorf("long cell exported with %d bytes, want at most 32767 ending in ...[truncated]", len(got))
+			}
+		default:
+			// 인라인 문자열은 수식으로 실행되지 않으므로 '를 붙이지 않고 그대로 기록
+			if got != value {
+				t.Errorf("cell exported as %q, want %q", got, value)
+			}
+		}
+		if c := row[columns["status_code"]]; c.T != "" || c.Value != "200" {
+			t.Errorf("status_code cell = %+v, want number 200", c)
+		}
+		if c := row[columns["enqueued_at_ns"]]; c.T != "inlineStr" || c.Text != fmt.Sprint(int64(1<<60)) {
+			t.Errorf("enqueued_at_ns cell = %+v, want exact value as text", c)
+		}
+	}
+	if c := sheets[0].Rows[1].Cells[columns["cache_hit"]]; c.T != "b" || c.Value != "1" {
+		t.Errorf("cache_hit cell = %+v, want boolean true", c)
+	}
+}
+
+func TestWriterStartsNewSheetAtRowLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes over a million rows")
+	}
+	var buf bytes.Buffer
+	w, err := xlsx.NewWriter(&buf, []string{"n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const rows = 1<<20 + 9
+	values := []any{int64(0)}
+	for i := range rows {
+		values[0] = int64(i)
+		if err := w.WriteRow(values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sheets := readSheets(t, buf.Bytes(), 1)
+	if len(sheets) != 2 {
+		t.Fatalf("got %d sheets, want 2", len(sheets))
+	}
+	// 시트마다 컬럼 이름 행이 있으므로 첫 시트에는 데이터 2^20-1행
+	second := sheets[1].Rows
+	if len(second) != 11 || second[0].Cells[0].String() != "n" || second[1].Cells[0].String() != fmt.Sprint(1<<20-1) {
+		t.Fatalf("second sheet has %d rows starting with %v", len(second), second[:2])
+	}
+}
+
+func TestUnknownFormatWithoutImport(t *testing.T) {
+	store, err := trace.NewStore(tracetest.NewTempDB(t), trace.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Export(context.Background(), trace.QueryFilter{}, "xls", io.Discard); err == nil {
+		t.Fatal("exporting an unregistered format succeeded")
+	}
+}